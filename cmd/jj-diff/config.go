@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kyleking/jj-diff/internal/config"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+// loadConfig layers the settings from least to most specific: the ones jj-diff shares with jj, such
+// as jj's diff colors, then jj's user config, the user's config file, jj's repository config, and the
+// repository's .jj-diff.toml, with the environment over all of them. Each warning is printed to
+// stderr, so a typo is reported without stopping the session.
+func loadConfig() config.Config {
+	var layers []config.Layer
+
+	var warnings []error
+
+	add := func(layer config.Layer, err error) {
+		if err != nil {
+			warnings = append(warnings, err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+
+	client := jj.NewClient(wd)
+	repoFile := config.RepoConfigPath(wd)
+
+	add(jjSettingsLayer(client))
+	add(jjConfigLayer(client, jj.ConfigUser))
+
+	if path := config.UserConfigPath(); path != "" {
+		add(config.ReadFile(path))
+	}
+
+	// jj refuses --repo outside a workspace, and there is no repository file to read there either.
+	if repoFile != "" {
+		add(jjConfigLayer(client, jj.ConfigRepo))
+		add(config.ReadFile(repoFile))
+	}
+
+	cfg, invalid := config.LoadConfig(layers...)
+
+	for _, warning := range append(warnings, invalid...) {
+		fmt.Fprintf(os.Stderr, "jj-diff: %v\n", warning)
+	}
+
+	return cfg
+}
+
+// jjConfigLayer reads the jj-diff table from one of jj's config layers. Without jj there is nothing to
+// read, which is not worth a warning, since jj-diff can browse a patch without jj.
+func jjConfigLayer(client *jj.Client, scope jj.ConfigScope) (config.Layer, error) {
+	source := "jj config " + string(scope)
+
+	output, err := client.ConfigList(context.Background(), scope, "jj-diff")
+	if err != nil {
+		return config.Layer{Source: source}, nil //nolint:nilerr // no jj, or no repository, means no settings.
+	}
+
+	return config.ParseJJConfig(source, output) //nolint:wrapcheck // the error already names jj's layer.
+}
+
+// jjSettingsLayer reads the settings jj-diff shares with jj, from every one of jj's layers merged as
+// jj diff sees them. Without jj there is nothing to read, as in jjConfigLayer.
+func jjSettingsLayer(client *jj.Client) (config.Layer, error) {
+	const source = "jj config"
+
+	output, err := client.ConfigList(context.Background(), jj.ConfigMerged, "")
+	if err != nil {
+		return config.Layer{Source: source}, nil //nolint:nilerr // no jj means no settings to share.
+	}
+
+	return config.ParseJJSettings(source, output) //nolint:wrapcheck // the error already names jj's config.
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kyleking/jj-diff/internal/model"
+)
+
+// patchFileMode is world-readable, unlike the repository copies jj-diff writes, because an exported
+// patch exists to be reviewed and shared.
+const patchFileMode = 0o644
+
+// writePatches writes what a dry run exported to output, a path or - for stdout. A move is one patch
+// written as is. A split writes one patch per tag: to stdout, one after another behind a comment line
+// naming the tag and destination, and to a path, one file per tag with the tag before the extension,
+// so split.patch becomes split.A.patch and split.B.patch. A session that applied nothing writes
+// nothing.
+func writePatches(output string, patches []model.ExportedPatch) error {
+	if len(patches) == 0 {
+		fmt.Fprintln(os.Stderr, "jj-diff: nothing was applied, so no patch was written")
+
+		return nil
+	}
+
+	if output == "-" {
+		return writePatchStream(os.Stdout, patches)
+	}
+
+	for _, patch := range patches {
+		path := output
+		if patch.Tag != 0 {
+			path = taggedPath(output, patch.Tag)
+		}
+
+		//nolint:gosec // G306: the patch is written to be read by others; see patchFileMode.
+		if err := os.WriteFile(path, []byte(patch.Patch), patchFileMode); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "jj-diff: wrote the patch for %s to %s\n", patch.Destination, path)
+	}
+
+	return nil
+}
+
+func writePatchStream(w io.Writer, patches []model.ExportedPatch) error {
+	for _, patch := range patches {
+		if patch.Tag != 0 {
+			if _, err := fmt.Fprintf(w, "# tag %c -> %s\n", patch.Tag, patch.Destination); err != nil {
+				return fmt.Errorf("writing the patch: %w", err)
+			}
+		}
+
+		if _, err := io.WriteString(w, patch.Patch); err != nil {
+			return fmt.Errorf("writing the patch: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// taggedPath inserts the tag before the extension, or appends it when there is none.
+func taggedPath(path string, tag rune) string {
+	ext := filepath.Ext(path)
+
+	return fmt.Sprintf("%s.%c%s", strings.TrimSuffix(path, ext), tag, ext)
+}
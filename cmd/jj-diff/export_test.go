@@ -0,0 +1,59 @@
+//nolint:testpackage // white-box: the flags and the patch writer are unexported parts of the command.
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/model"
+)
+
+// quitAtOnce draws one frame and quits, which is enough for the UI to write its escape sequences.
+type quitAtOnce struct{}
+
+func (quitAtOnce) Init() tea.Cmd                         { return tea.Quit }
+func (m quitAtOnce) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (quitAtOnce) View() string                          { return "the UI" }
+
+// TestDryRunPatchHasStdoutToItself checks that when the patch is written to stdout, the UI draws on
+// stderr, so a redirected or piped dry run holds only the patch.
+func TestDryRunPatchHasStdoutToItself(t *testing.T) {
+	t.Parallel()
+
+	patches := []model.ExportedPatch{{
+		Destination: "@-",
+		Patch:       "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n@@ -1,1 +1,1 @@\n-old\n+new\n",
+	}}
+
+	for name, f := range map[string]flags{
+		"dry run":          {dryRun: true},
+		"output to stdout": {outputPatch: "-"},
+	} {
+		var stdout, stderr bytes.Buffer
+
+		program := tea.NewProgram(quitAtOnce{}, tea.WithAltScreen(), tea.WithInput(nil),
+			tea.WithOutput(f.uiOutput(&stdout, &stderr)))
+		if _, err := program.Run(); err != nil {
+			t.Fatalf("%s: running the UI: %v", name, err)
+		}
+
+		if err := writePatchStream(&stdout, patches); err != nil {
+			t.Fatalf("%s: writing the patch: %v", name, err)
+		}
+
+		if stdout.String() != patches[0].Patch {
+			t.Errorf("%s: expected stdout to hold only the patch, got %q", name, stdout.String())
+		}
+
+		if stderr.Len() == 0 {
+			t.Errorf("%s: expected the UI on stderr", name)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if f := (flags{outputPatch: "move.patch"}); f.uiOutput(&stdout, &stderr) != &stdout {
+		t.Error("Expected the UI on stdout when the patch goes to a file")
+	}
+}
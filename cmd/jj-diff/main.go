@@ -4,25 +4,39 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
 	"github.com/kyleking/jj-diff/internal/config"
 	"github.com/kyleking/jj-diff/internal/diff"
 	"github.com/kyleking/jj-diff/internal/jj"
 	"github.com/kyleking/jj-diff/internal/model"
 	"github.com/kyleking/jj-diff/internal/theme"
+	"github.com/kyleking/jj-diff/internal/watcher"
 )
 
 // Sentinel errors main returns on its own rather than wrapping one from a package it calls.
 var (
-	errMissingDir     = errors.New("directory does not exist")
-	errScmRecordUnimp = errors.New("scm-record compatibility mode is not implemented")
+	errMissingDir       = errors.New("directory does not exist")
+	errRevisionAndRange = errors.New("-r cannot be combined with --from or --to")
+	errScmRecordUnimp   = errors.New("scm-record compatibility mode is not implemented")
+	errDryRunEditor     = errors.New("--dry-run and --output-patch only apply in revision mode")
+	errPatchAndStdin    = errors.New("--patch cannot be combined with reading the patch from stdin")
+	errSpecArgs         = errors.New("--spec needs LEFT RIGHT; use 'jj-diff split --spec' for a revision")
+	errHeadlessNoReplay = errors.New("--headless needs --replay")
+	errInvalidArgs      = errors.New(
+		"invalid arguments; use 'jj-diff' for revision mode, 'jj-diff -' to read a patch from stdin, " +
+			"or 'jj-diff LEFT RIGHT' for diff-editor mode",
+	)
 )
 
 var (
@@ -37,8 +51,15 @@ const diffEditorArgCount = 2
 
 type flags struct {
 	revision       string
+	from           string
+	to             string
 	scmInput       string
 	destination    string
+	outputPatch    string
+	patch          string
+	spec           string
+	record         string
+	replay         string
 	tabWidth       int
 	version        bool
 	browse         bool
@@ -46,6 +67,11 @@ type flags struct {
 	showWhitespace bool
 	sideBySide     bool
 	wordDiff       bool
+	noWatch        bool
+	watchWorking   bool
+	dryRun         bool
+	pager          bool
+	headless       bool
 }
 
 func parseFlags() flags {
@@ -55,6 +81,8 @@ func parseFlags() flags {
 	flag.BoolVar(&f.version, "v", false, "Show program version (shorthand)")
 	flag.StringVar(&f.revision, "r", "@", "Revision to view/edit")
 	flag.StringVar(&f.revision, "revision", "@", "Revision to view/edit")
+	flag.StringVar(&f.from, "from", "", "Show the interdiff from this revision (default: @)")
+	flag.StringVar(&f.to, "to", "", "Show the interdiff to this revision (default: @)")
 	flag.BoolVar(&f.browse, "browse", false, "Force browse mode (read-only)")
 	flag.BoolVar(&f.interactive, "interactive", false, "Force interactive mode")
 	flag.BoolVar(&f.interactive, "i", false, "Force interactive mode (shorthand)")
@@ -66,10 +94,30 @@ func parseFlags() flags {
 	)
 	flag.StringVar(&f.destination, "destination", "", "Pre-set destination revision")
 	flag.StringVar(&f.destination, "d", "", "Pre-set destination revision (shorthand)")
+	flag.BoolVar(&f.dryRun, "dry-run", false, "Write the patch a applies to stdout and exit instead of moving it")
+	flag.StringVar(
+		&f.outputPatch,
+		"output-patch",
+		"",
+		"Write the patch a applies to FILE (- for stdout) and exit instead of moving it",
+	)
+	flag.StringVar(&f.patch, "patch", "", "Open the unified diff in FILE instead of a revision")
+	flag.StringVar(&f.spec, "spec", "", "As a diff editor, keep the first group of the split spec FILE and exit")
+	flag.BoolVar(&f.pager, "pager", false, "Print stdin with its diffs rendered, for jj's ui.pager")
+	flag.StringVar(&f.record, "record", "", "Record the session's commands to FILE as JSON lines")
+	flag.StringVar(&f.replay, "replay", "", "Replay the commands recorded in FILE, stopping if the diff differs")
+	flag.BoolVar(&f.headless, "headless", false, "With --replay, run without the UI and exit when done")
 	flag.BoolVar(&f.showWhitespace, "show-whitespace", false, "Visualize whitespace characters")
 	flag.BoolVar(&f.sideBySide, "side-by-side", false, "Side-by-side diff view")
 	flag.BoolVar(&f.sideBySide, "s", false, "Side-by-side diff view (shorthand)")
 	flag.BoolVar(&f.wordDiff, "word-diff", false, "Enable word-level highlighting")
+	flag.BoolVar(&f.noWatch, "no-watch", false, "Do not reload when the repository changes")
+	flag.BoolVar(
+		&f.watchWorking,
+		"watch-working-copy",
+		false,
+		"Also reload when files in the working copy change",
+	)
 	flag.IntVar(
 		&f.tabWidth,
 		"tab-width",
@@ -77,31 +125,47 @@ func parseFlags() flags {
 		"Tab display width (default: 4, 0 uses config/default)",
 	)
 
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [LEFT RIGHT]\n\n", os.Args[0])
-		fmt.Fprintf(
-			os.Stderr,
-			"A TUI for interactive diff viewing and manipulation in Jujutsu (jj)\n\n",
-		)
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  jj-diff              # Browse working copy changes\n")
-		fmt.Fprintf(os.Stderr, "  jj-diff -r @-        # Browse parent's changes\n")
-		fmt.Fprintf(os.Stderr, "  jj-diff -i           # Interactive mode (move changes)\n")
-		fmt.Fprintf(os.Stderr, "  jj-diff -i -d @-     # Move changes to parent\n")
-		fmt.Fprintf(
-			os.Stderr,
-			"  jj-diff LEFT RIGHT   # Diff-editor mode (for jj split, diffedit)\n",
-		)
-	}
+	flag.Usage = printUsage
 
 	flag.Parse()
 
 	return f
 }
 
+// printUsage is the -h text: the three invocations, the flags, and an example of each mode.
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] [LEFT RIGHT | -]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s move [options] --to REV SELECTOR...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s split [options] --spec FILE\n\n", os.Args[0])
+	fmt.Fprintf(
+		os.Stderr,
+		"A TUI for interactive diff viewing and manipulation in Jujutsu (jj)\n\n",
+	)
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff              # Browse working copy changes\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -r @-        # Browse parent's changes\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff --from @-- --to @-  # Browse what changed between two revisions\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -i           # Interactive mode (move changes)\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -i -d @-     # Move changes to parent\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -d @- --output-patch move.patch  # Save the move as a patch instead\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff --patch fix.diff  # Browse a patch file\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -r @- | less -R     # Print the diff instead when stdout is not a terminal\n")
+	fmt.Fprintf(os.Stderr, "  git format-patch -1 --stdout | jj-diff -i -d @ -  # Apply a mailed patch\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -i --record session.jsonl  # Record a session to replay later\n")
+	fmt.Fprintf(os.Stderr, "  jj-diff -i --replay session.jsonl --headless  # Replay it without the UI\n")
+	fmt.Fprintf(
+		os.Stderr,
+		"  jj-diff LEFT RIGHT   # Diff-editor mode (for jj split, diffedit)\n",
+	)
+}
+
 func main() {
+	if len(os.Args) > 1 && runSubcommand(os.Args[1], os.Args[2:]) {
+		return
+	}
+
 	f := parseFlags()
 
 	if f.version {
@@ -109,9 +173,17 @@ func main() {
 		return
 	}
 
-	theme.Init()
+	// The UI draws on stderr when the patch takes stdout, so colors and the background are read there.
+	if f.patchToStdout() {
+		lipgloss.DefaultRenderer().SetOutput(termenv.NewOutput(os.Stderr))
+	}
+
+	cfg := loadConfig()
+
+	// An unknown theme was warned about with the rest of the config and resolves to the auto theme.
+	palette, _ := cfg.ResolveTheme()
+	theme.Init(palette)
 
-	cfg := config.LoadConfig()
 	if f.showWhitespace {
 		cfg.ShowWhitespace = true
 	}
@@ -125,29 +197,166 @@ func main() {
 		cfg.TabWidth = f.tabWidth
 	}
 
+	if f.spec != "" {
+		if err := runSpecEditor(f.spec, flag.Args()); err != nil {
+			log.Fatalf("Failed to apply the split spec: %v", err)
+		}
+
+		return
+	}
+
+	if f.rendersText() {
+		if err := runPager(f, cfg, flag.Args()); err != nil {
+			log.Fatalf("Failed to render the diff: %v", err)
+		}
+
+		return
+	}
+
+	initialModel, repoWatcher, options, err := initModel(f, cfg, flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
+	}
+
+	initialModel, closeSession, err := attachSession(f, initialModel)
+	if err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
+	}
+
+	final, err := runModel(f, initialModel, options)
+
+	if repoWatcher != nil {
+		_ = repoWatcher.Close()
+	}
+
+	if closeErr := closeSession(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "jj-diff: %v\n", closeErr)
+	}
+
+	if err != nil {
+		log.Fatalf("Error running program: %v", err)
+	}
+
+	if f.exportsPatch() {
+		if err := writePatches(f.patchOutput(), final.ExportedPatches()); err != nil {
+			log.Fatalf("Failed to write the patch: %v", err)
+		}
+	}
+}
+
+// initModel builds the model for the mode the arguments select, with the watcher revision mode
+// attaches and the program options the mode needs.
+func initModel(
+	f flags,
+	cfg config.Config,
+	args []string,
+) (model.Model, *watcher.Watcher, []tea.ProgramOption, error) {
 	var initialModel model.Model
+	var repoWatcher *watcher.Watcher
 	var err error
 
-	args := flag.Args()
-	switch len(args) {
-	case 0:
+	options := []tea.ProgramOption{tea.WithAltScreen(), tea.WithOutput(f.uiOutput(os.Stdout, os.Stderr))}
+
+	switch {
+	case len(args) == 0 && f.patch != "":
+		initialModel, err = initPatchMode(f, cfg, diff.NewPatchFileSource(f.patch))
+	case len(args) == 0:
 		initialModel, err = initRevisionMode(f, cfg)
-	case diffEditorArgCount:
+
+		// A headless replay waits for its own commands, so a reload from outside would only race them.
+		if err == nil && !f.noWatch && !f.headless {
+			repoWatcher = startWatcher(f)
+			initialModel = initialModel.WithWatcher(repoWatcher)
+		}
+	case len(args) == 1 && args[0] == "-":
+		initialModel, err = initStdinPatchMode(f, cfg)
+
+		// Stdin carried the patch, so keys have to come from the terminal instead.
+		options = append(options, tea.WithInputTTY())
+	case len(args) == diffEditorArgCount:
+		if f.exportsPatch() {
+			return model.Model{}, nil, nil, errDryRunEditor
+		}
+
 		initialModel, err = initDiffEditorMode(args[0], args[1], cfg)
 	default:
-		log.Fatalf(
-			"Invalid arguments. Use 'jj-diff' for revision mode or 'jj-diff LEFT RIGHT' for diff-editor mode.",
-		)
+		return model.Model{}, nil, nil, errInvalidArgs
+	}
+
+	return initialModel, repoWatcher, options, err
+}
+
+// runModel runs the UI, or drives the model without one for a headless replay, and returns the
+// model it finished with.
+func runModel(f flags, m model.Model, options []tea.ProgramOption) (model.Model, error) {
+	if f.headless {
+		final, err := model.RunHeadless(m)
+		if err != nil {
+			return final, fmt.Errorf("replaying %s: %w", f.replay, err)
+		}
+
+		return final, nil
 	}
 
+	finalModel, err := tea.NewProgram(m, options...).Run()
 	if err != nil {
-		log.Fatalf("Failed to initialize: %v", err)
+		return model.Model{}, fmt.Errorf("running the UI: %w", err)
 	}
 
-	p := tea.NewProgram(initialModel, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		log.Fatalf("Error running program: %v", err)
+	final, _ := finalModel.(model.Model)
+
+	return final, nil
+}
+
+// runSubcommand runs the command name selects, such as move, and reports whether there was one.
+// Anything else is left to the flags, so a revision-mode invocation never reaches here.
+func runSubcommand(name string, args []string) bool {
+	var run func([]string) error
+
+	switch name {
+	case moveCommand:
+		run = runMove
+	case splitCommand:
+		run = runSplit
+	default:
+		return false
+	}
+
+	if err := run(args); err != nil && !errors.Is(err, flag.ErrHelp) {
+		log.Fatalf("Failed to %s: %v", name, err)
 	}
+
+	return true
+}
+
+// exportsPatch reports whether applying should write the patch out instead of moving it.
+func (f flags) exportsPatch() bool {
+	return f.dryRun || f.outputPatch != ""
+}
+
+// patchOutput is where an exported patch goes: the --output-patch path, or stdout for a plain
+// --dry-run.
+func (f flags) patchOutput() string {
+	if f.outputPatch == "" {
+		return "-"
+	}
+
+	return f.outputPatch
+}
+
+// patchToStdout reports whether an exported patch is written to stdout.
+func (f flags) patchToStdout() bool {
+	return f.exportsPatch() && f.patchOutput() == "-"
+}
+
+// uiOutput is where the UI draws: stdout, unless the patch is written there. Then the UI draws on
+// stderr, which is still the terminal, so jj-diff -i --dry-run > move.patch writes only the patch.
+func (f flags) uiOutput(stdout, stderr io.Writer) io.Writer {
+	if f.patchToStdout() {
+		return stderr
+	}
+
+	return stdout
 }
 
 func initRevisionMode(f flags, cfg config.Config) (model.Model, error) {
@@ -158,7 +367,7 @@ func initRevisionMode(f flags, cfg config.Config) (model.Model, error) {
 
 	client := jj.NewClient(wd)
 
-	if err := client.CheckInstalled(); err != nil {
+	if err := client.CheckInstalled(context.Background()); err != nil {
 		return model.Model{}, fmt.Errorf("jj is not installed or not in PATH: %w", err)
 	}
 
@@ -166,21 +375,131 @@ func initRevisionMode(f flags, cfg config.Config) (model.Model, error) {
 		return model.Model{}, fmt.Errorf("%s: %w", f.scmInput, errScmRecordUnimp)
 	}
 
+	// A dry run only does anything when a is pressed, which browse mode ignores.
 	mode := model.ModeBrowse
-	if f.interactive {
+	if f.interactive || f.exportsPatch() {
 		mode = model.ModeInteractive
 	}
 
-	source := diff.NewRevisionSource(client, f.revision)
+	source, err := revisionModeSource(f, client)
+	if err != nil {
+		return model.Model{}, err
+	}
 
 	m, err := model.NewModelWithSource(source, client, f.destination, mode, cfg)
 	if err != nil {
 		return model.Model{}, fmt.Errorf("building the revision model: %w", err)
 	}
 
+	if f.exportsPatch() {
+		m = m.WithDryRun()
+	}
+
+	return m, nil
+}
+
+// initPatchMode opens a patch that came from outside the repository. Browsing it needs no jj, so jj
+// is only checked for when the selection may be applied to a revision.
+func initPatchMode(f flags, cfg config.Config, source *diff.PatchSource) (model.Model, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return model.Model{}, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	client := jj.NewClient(wd)
+
+	mode := model.ModeBrowse
+	if f.interactive || f.exportsPatch() {
+		mode = model.ModeInteractive
+
+		if err := client.CheckInstalled(context.Background()); err != nil {
+			return model.Model{}, fmt.Errorf("jj is not installed or not in PATH: %w", err)
+		}
+	}
+
+	m, err := model.NewModelWithSource(source, client, f.destination, mode, cfg)
+	if err != nil {
+		return model.Model{}, fmt.Errorf("building the patch model: %w", err)
+	}
+
+	if f.exportsPatch() {
+		m = m.WithDryRun()
+	}
+
 	return m, nil
 }
 
+// initStdinPatchMode reads the whole patch before the UI starts, because the UI needs the terminal
+// stdin was piped from.
+func initStdinPatchMode(f flags, cfg config.Config) (model.Model, error) {
+	if f.patch != "" {
+		return model.Model{}, errPatchAndStdin
+	}
+
+	source, err := diff.ReadPatchSource(os.Stdin)
+	if err != nil {
+		return model.Model{}, fmt.Errorf("reading stdin: %w", err)
+	}
+
+	return initPatchMode(f, cfg, source)
+}
+
+// startWatcher watches the repository so the view reloads when jj runs elsewhere. The watcher is a
+// convenience, so a repository it cannot watch is reported and the session runs without it.
+func startWatcher(f flags) *watcher.Watcher {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	w, err := watcher.New(wd, watcher.Options{WorkingCopy: f.watchWorking})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jj-diff: not watching for repository changes: %v\n", err)
+
+		return nil
+	}
+
+	return w
+}
+
+// revisionModeSource picks the interdiff when --from or --to is given and the plain revision diff
+// otherwise. A missing end defaults to @, as it does for jj diff.
+func revisionModeSource(f flags, client *jj.Client) (diff.Source, error) {
+	if f.from == "" && f.to == "" {
+		return diff.NewRevisionSource(client, f.revision), nil
+	}
+
+	if isFlagSet("r", "revision") {
+		return nil, errRevisionAndRange
+	}
+
+	from, to := f.from, f.to
+	if from == "" {
+		from = "@"
+	}
+	if to == "" {
+		to = "@"
+	}
+
+	return diff.NewInterdiffSource(client, from, to), nil
+}
+
+// isFlagSet reports whether any of names was given on the command line, which a flag's value alone
+// cannot tell when the user passed the default.
+func isFlagSet(names ...string) bool {
+	set := false
+
+	flag.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+
+	return set
+}
+
 func initDiffEditorMode(leftDir, rightDir string, cfg config.Config) (model.Model, error) {
 	if _, err := os.Stat(leftDir); os.IsNotExist(err) {
 		return model.Model{}, fmt.Errorf("left %s: %w", leftDir, errMissingDir)
@@ -190,6 +509,7 @@ func initDiffEditorMode(leftDir, rightDir string, cfg config.Config) (model.Mode
 	}
 
 	source := diff.NewDirectorySource(leftDir, rightDir)
+	source.Context = cfg.Context
 
 	m, err := model.NewModelWithSource(source, nil, "", model.ModeDiffEditor, cfg)
 	if err != nil {
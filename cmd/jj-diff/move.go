@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+var (
+	errMoveNoDestination = errors.New("move needs --to REV")
+	errMoveNoSelectors   = errors.New("move needs at least one selector")
+	errMoveEmptyPatch    = errors.New("the selectors picked no changed lines")
+)
+
+// moveCommand is the first argument that runs a move without the UI.
+const moveCommand = "move"
+
+type moveFlags struct {
+	revision    string
+	destination string
+	selectors   []string
+	dryRun      bool
+}
+
+// parseMoveFlags reads the arguments after move. Flags may come before, after, or between the
+// selectors, so a script can put --to wherever reads best.
+func parseMoveFlags(args []string) (moveFlags, error) {
+	var f moveFlags
+
+	fs := flag.NewFlagSet(moveCommand, flag.ContinueOnError)
+	fs.StringVar(&f.revision, "r", "@", "Revision to move changes out of")
+	fs.StringVar(&f.revision, "revision", "@", "Revision to move changes out of")
+	fs.StringVar(&f.destination, "to", "", "Revision to move the selected changes into")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Write the patch to stdout instead of moving it")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s move [options] --to REV SELECTOR...\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Move the changes the selectors pick without opening the UI.\n\n")
+		fmt.Fprintf(fs.Output(), "Selectors:\n")
+		fmt.Fprintf(fs.Output(), "  PATH             every hunk of a file\n")
+		fmt.Fprintf(fs.Output(), "  GLOB             every hunk of each matching file, where ** spans directories\n")
+		fmt.Fprintf(fs.Output(), "  PATH:N           the file's hunk N, counting from 0\n")
+		fmt.Fprintf(fs.Output(), "  PATH:START-END   the changed lines between those new-file line numbers\n")
+		fmt.Fprintf(fs.Output(), "  added:REGEX      each added line the regular expression matches\n")
+		fmt.Fprintf(fs.Output(), "  hunk:REGEX       each hunk with a changed line the expression matches\n\n")
+		fmt.Fprintf(fs.Output(), "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return moveFlags{}, fmt.Errorf("parsing move arguments: %w", err)
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+
+		f.selectors = append(f.selectors, args[0])
+		args = args[1:]
+	}
+
+	if f.destination == "" {
+		return moveFlags{}, errMoveNoDestination
+	}
+
+	if len(f.selectors) == 0 {
+		return moveFlags{}, errMoveNoSelectors
+	}
+
+	return f, nil
+}
+
+// runMove moves what the selectors pick out of the revision and into the destination, through the
+// same MoveChanges the UI's a uses, so a failure rolls the repository back just as it would there.
+// A selector that matches nothing fails the whole move before anything is written.
+func runMove(args []string) error {
+	f, err := parseMoveFlags(args)
+	if err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	ctx := context.Background()
+	client := jj.NewClient(wd)
+
+	if err := client.CheckInstalled(ctx); err != nil {
+		return fmt.Errorf("jj is not installed or not in PATH: %w", err)
+	}
+
+	text, err := client.Diff(ctx, f.revision)
+	if err != nil {
+		return fmt.Errorf("reading the diff for %s: %w", f.revision, err)
+	}
+
+	patch, hunks, err := selectPatch(diff.Parse(text), f.selectors)
+	if err != nil {
+		return err
+	}
+
+	if f.dryRun {
+		if _, err := io.WriteString(os.Stdout, patch); err != nil {
+			return fmt.Errorf("writing the patch: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := client.MoveChanges(ctx, patch, f.revision, f.destination); err != nil {
+		return fmt.Errorf("moving to %s: %w", f.destination, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Moved %d hunk(s) from %s to %s\n", hunks, f.revision, f.destination)
+
+	return nil
+}
+
+// selectPatch builds the selection the selectors describe over files and returns its patch with the
+// number of hunks it touches.
+func selectPatch(files []diff.FileChange, specs []string) (string, int, error) {
+	selection := diff.NewSelection()
+
+	for _, spec := range specs {
+		selector, err := diff.ParseSelector(spec)
+		if err != nil {
+			return "", 0, fmt.Errorf("reading the selector: %w", err)
+		}
+
+		if _, err := selector.Select(files, selection); err != nil {
+			return "", 0, fmt.Errorf("selecting: %w", err)
+		}
+	}
+
+	patch := diff.GeneratePatch(files, selection)
+	if patch == "" {
+		return "", 0, errMoveEmptyPatch
+	}
+
+	hunks := 0
+
+	for _, file := range files {
+		for hunkIdx := range file.Hunks {
+			if selection.IsHunkSelected(file.Path, hunkIdx) || selection.HasPartialSelection(file.Path, hunkIdx) {
+				hunks++
+			}
+		}
+	}
+
+	return patch, hunks, nil
+}
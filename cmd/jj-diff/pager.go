@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+
+	"github.com/kyleking/jj-diff/internal/config"
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/jj"
+	"github.com/kyleking/jj-diff/internal/pager"
+)
+
+var errPagerArgs = errors.New("expected no arguments, - for stdin, or LEFT RIGHT")
+
+// stdoutIsTerminal reports whether the UI has a terminal to draw on.
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(stdoutFd())
+}
+
+func stdoutFd() int {
+	return int(os.Stdout.Fd()) //nolint:gosec // G115: a file descriptor fits in an int.
+}
+
+// rendersText reports whether to print the diff rather than open the UI: when asked to with --pager,
+// or when stdout is not a terminal and nothing was asked of the UI that only it can do.
+func (f flags) rendersText() bool {
+	return f.pager || (!stdoutIsTerminal() && !f.interactive && !f.exportsPatch())
+}
+
+// runPager prints the diff the arguments name, drawn as the UI draws it, and returns. --pager and -
+// read stdin and pass any text around the diff through, which is what jj's ui.pager hands over.
+func runPager(f flags, cfg config.Config, args []string) error {
+	text, err := pagerInput(f, cfg, args)
+	if err != nil {
+		return err
+	}
+
+	profile := pagerColorProfile()
+	lipgloss.SetColorProfile(profile)
+
+	opts := pager.Options{Config: cfg, Width: pagerWidth(), Plain: profile == termenv.Ascii}
+
+	if err := pager.Render(os.Stdout, text, opts); err != nil {
+		return fmt.Errorf("rendering the diff: %w", err)
+	}
+
+	return nil
+}
+
+func pagerInput(f flags, cfg config.Config, args []string) (string, error) {
+	var source diff.Source
+
+	switch {
+	case len(args) == diffEditorArgCount:
+		dirs := diff.NewDirectorySource(args[0], args[1])
+		dirs.Context = cfg.Context
+		source = dirs
+	case f.pager || (len(args) == 1 && args[0] == "-"):
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading stdin: %w", err)
+		}
+
+		return string(text), nil
+	case len(args) != 0:
+		return "", errPagerArgs
+	case f.patch != "":
+		source = diff.NewPatchFileSource(f.patch)
+	default:
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+
+		if source, err = revisionModeSource(f, jj.NewClient(wd)); err != nil {
+			return "", err
+		}
+	}
+
+	text, err := source.GetDiff(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("reading the diff: %w", err)
+	}
+
+	return text, nil
+}
+
+// pagerColorProfile honors NO_COLOR, and otherwise keeps color on even when stdout is a pipe, because
+// whatever reads it, jj or a pager, passes the color on to a terminal.
+func pagerColorProfile() termenv.Profile {
+	switch {
+	case os.Getenv("NO_COLOR") != "":
+		return termenv.Ascii
+	case stdoutIsTerminal():
+		return lipgloss.ColorProfile()
+	case os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit":
+		return termenv.TrueColor
+	default:
+		return termenv.ANSI256
+	}
+}
+
+// pagerWidth reads $COLUMNS, which jj and most shells set for the commands they run, then asks the
+// terminal, and falls back to pager.DefaultWidth.
+func pagerWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+
+	if width, _, err := term.GetSize(stdoutFd()); err == nil && width > 0 {
+		return width
+	}
+
+	return pager.DefaultWidth
+}
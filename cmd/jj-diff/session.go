@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kyleking/jj-diff/internal/model"
+	"github.com/kyleking/jj-diff/internal/session"
+)
+
+// attachSession starts the --record file and loads the --replay one. The func it returns closes the
+// recording, and is safe to call when there is none.
+func attachSession(f flags, m model.Model) (model.Model, func() error, error) {
+	closeSession := func() error { return nil }
+
+	if f.headless && f.replay == "" {
+		return m, closeSession, errHeadlessNoReplay
+	}
+
+	if f.replay != "" {
+		steps, err := session.Load(f.replay)
+		if err != nil {
+			return m, closeSession, fmt.Errorf("loading %s: %w", f.replay, err)
+		}
+
+		m = m.WithReplay(steps, f.headless)
+	}
+
+	if f.record != "" {
+		//nolint:gosec // G304: the path is the recording the user asked for.
+		file, err := os.Create(f.record)
+		if err != nil {
+			return m, closeSession, fmt.Errorf("starting the recording: %w", err)
+		}
+
+		m = m.WithRecorder(session.NewRecorder(file))
+		closeSession = func() error {
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("closing the recording: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	return m, closeSession, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/jj"
+	"github.com/kyleking/jj-diff/internal/model"
+	"github.com/kyleking/jj-diff/internal/splitspec"
+)
+
+var (
+	errSplitNoSpec       = errors.New("split needs --spec FILE")
+	errSplitArgs         = errors.New("split takes no arguments besides its flags")
+	errSplitNothingTaken = errors.New("no group of the spec matched any change")
+	errSpecFirstGroup    = errors.New("the spec's first group matched no change")
+)
+
+// splitCommand is the first argument that runs a split from a spec file without the UI.
+const splitCommand = "split"
+
+type splitFlags struct {
+	revision string
+	spec     string
+	dryRun   bool
+}
+
+func parseSplitFlags(args []string) (splitFlags, error) {
+	var f splitFlags
+
+	fs := flag.NewFlagSet(splitCommand, flag.ContinueOnError)
+	fs.StringVar(&f.revision, "r", "@", "Revision to split")
+	fs.StringVar(&f.revision, "revision", "@", "Revision to split")
+	fs.StringVar(&f.spec, "spec", "", "Split spec file listing the groups to split into")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Write each group's patch to stdout instead of splitting")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s split [options] --spec FILE\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Split a revision the way a spec file describes, without opening the UI.\n\n")
+		fmt.Fprintf(fs.Output(), "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return splitFlags{}, fmt.Errorf("parsing split arguments: %w", err)
+	}
+
+	if fs.NArg() > 0 {
+		return splitFlags{}, errSplitArgs
+	}
+
+	if f.spec == "" {
+		return splitFlags{}, errSplitNoSpec
+	}
+
+	return f, nil
+}
+
+// runSplit splits the revision into the spec's groups through the same ApplySplit the multi-way split
+// uses, so a failure part way restores the repository to where it started. A group that matched
+// nothing is skipped with a note, because a spec is meant to be run over many changes.
+func runSplit(args []string) error {
+	f, err := parseSplitFlags(args)
+	if err != nil {
+		return err
+	}
+
+	spec, err := splitspec.Load(f.spec)
+	if err != nil {
+		return fmt.Errorf("loading the spec: %w", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	ctx := context.Background()
+	client := jj.NewClient(wd)
+
+	if err := client.CheckInstalled(ctx); err != nil {
+		return fmt.Errorf("jj is not installed or not in PATH: %w", err)
+	}
+
+	text, err := client.Diff(ctx, f.revision)
+	if err != nil {
+		return fmt.Errorf("reading the diff for %s: %w", f.revision, err)
+	}
+
+	plans, err := specPlans(ctx, client, diff.Parse(text), spec, f.revision)
+	if err != nil {
+		return err
+	}
+
+	if len(plans) == 0 {
+		return errSplitNothingTaken
+	}
+
+	if f.dryRun {
+		return writePatches("-", model.ExportSplitPlans(plans))
+	}
+
+	if err := client.ApplySplit(ctx, plans, f.revision); err != nil {
+		return fmt.Errorf("splitting %s: %w", f.revision, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Split %s into %d group(s)\n", f.revision, len(plans))
+
+	return nil
+}
+
+// specPlans turns the spec's assignments into split plans tagged A, B, and so on in group order,
+// which is the order ApplySplit stacks new commits in. Each group's patch is written against the
+// groups before it that land beneath the revision, so a hunk divided between groups applies in both.
+func specPlans(
+	ctx context.Context,
+	client *jj.Client,
+	files []diff.FileChange,
+	spec splitspec.Spec,
+	revision string,
+) ([]jj.SplitPlan, error) {
+	var plans []jj.SplitPlan
+
+	stack := diff.NewPatchStack(files)
+
+	for i, assignment := range spec.Assign(files) {
+		if assignment.Hunks == 0 {
+			fmt.Fprintf(os.Stderr, "jj-diff: group %d (%s) matched nothing, skipping it\n",
+				i+1, assignment.Group.Label())
+
+			continue
+		}
+
+		group := assignment.Group
+
+		destination := jj.SplitDestination{Type: jj.SplitDestNewCommit, Description: group.Description}
+		if group.Destination != "" {
+			destination = jj.SplitDestination{Type: jj.SplitDestExistingRevision, ChangeID: group.Destination}
+		}
+
+		plans = append(plans, jj.SplitPlan{
+			Tag:         rune('A' + len(plans)),
+			Patch:       stack.Patch(assignment.Selection),
+			Destination: destination,
+		})
+
+		beneath, err := client.StacksBeneath(ctx, destination, revision)
+		if err != nil {
+			return nil, fmt.Errorf("placing group %d (%s): %w", i+1, group.Label(), err)
+		}
+
+		if beneath {
+			stack.Push(assignment.Selection)
+		}
+	}
+
+	return plans, nil
+}
+
+// runSpecEditor is diff-editor mode driven by a spec: the first group's changes are kept in the right
+// directory and the rest reverted, which is what jj split puts in the first of its two commits. Later
+// groups are left to the second commit, since a diff editor can only draw one line through a change.
+func runSpecEditor(specPath string, args []string) error {
+	if len(args) != diffEditorArgCount {
+		return errSpecArgs
+	}
+
+	leftDir, rightDir := args[0], args[1]
+
+	spec, err := splitspec.Load(specPath)
+	if err != nil {
+		return fmt.Errorf("loading the spec: %w", err)
+	}
+
+	text, err := diff.NewDirectorySource(leftDir, rightDir).GetDiff(context.Background())
+	if err != nil {
+		return fmt.Errorf("comparing the directories: %w", err)
+	}
+
+	files := diff.Parse(text)
+
+	first := spec.Assign(files)[0]
+	if first.Hunks == 0 {
+		return fmt.Errorf("%w: %s", errSpecFirstGroup, first.Group.Label())
+	}
+
+	if err := diff.NewApplier(leftDir, rightDir).ApplySelections(files, first.Selection); err != nil {
+		return fmt.Errorf("applying the first group: %w", err)
+	}
+
+	return nil
+}
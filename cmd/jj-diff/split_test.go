@@ -0,0 +1,62 @@
+//nolint:testpackage // white-box: specPlans is an unexported part of the command.
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/jj"
+	"github.com/kyleking/jj-diff/internal/splitspec"
+	"github.com/kyleking/jj-diff/tests/integration"
+)
+
+// TestSplit_SpecDividesAHunk splits one hunk between two groups. The second group's patch only
+// applies on top of the first group's commit if it was written against it.
+func TestSplit_SpecDividesAHunk(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("f.txt", "line 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\n")
+	repo.Commit("Initial commit")
+
+	final := "line 1\nline 2\nline 3\nFOUR\nFIVE\nline 6\nline 7\nline 8\n"
+	repo.WriteFile("f.txt", final)
+
+	spec, err := splitspec.Parse(`
+[[group]]
+description = "four"
+lines = ["f.txt:4-4"]
+
+[[group]]
+description = "five"
+files = ["f.txt"]
+`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	ctx := context.Background()
+	client := jj.NewClient(repo.Dir)
+
+	plans, err := specPlans(ctx, client, diff.Parse(repo.GetDiff("@")), spec, "@")
+	if err != nil {
+		t.Fatalf("specPlans: %v", err)
+	}
+
+	if len(plans) != 2 {
+		t.Fatalf("Expected a plan per group, got %d", len(plans))
+	}
+
+	if err := client.ApplySplit(ctx, plans, "@"); err != nil {
+		t.Fatalf("ApplySplit failed on a divided hunk: %v\n%s", err, plans[1].Patch)
+	}
+
+	repo.AssertFileContent("f.txt", final)
+	repo.AssertDiffContains("@--", "+FOUR")
+	repo.AssertDiffNotContains("@--", "FIVE")
+	repo.AssertDiffContains("@-", "+FIVE")
+	repo.AssertDiffNotContains("@-", "FOUR")
+	repo.AssertDiffEmpty("@")
+}
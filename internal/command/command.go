@@ -0,0 +1,402 @@
+// Package command names the UI's semantic actions as values, so a key, a command typed at the :
+// prompt, and a test all reach the same code. Each command has a text form that Parse reads back,
+// which is what the prompt accepts and what a log of commands can record.
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// Sentinel errors for command text that does not name a command or does not fit its arguments.
+var (
+	ErrUnknown     = errors.New("unknown command")
+	ErrMissingArgs = errors.New("missing argument")
+	ErrExtraArgs   = errors.New("unexpected argument")
+	ErrInvalidArg  = errors.New("invalid argument")
+)
+
+// Target is what commands act on. The model implements it, and each method does what the matching
+// key does, refusing with an error where the key would do nothing, such as selecting in browse mode.
+type Target interface {
+	Navigate(unit Unit, delta int) tea.Cmd
+	JumpToFile(index int)
+	GoTo(file, hunk, line int) error
+	Focus(panel Panel)
+	ToggleSelection() error
+	EnterVisualMode() error
+	Select(selectors []diff.Selector) error
+	Tag(tag rune) error
+	Assign(tag rune, revision, description string) error
+	ApplySplit() (tea.Cmd, error)
+	SetDestination(revision string) (tea.Cmd, error)
+	Apply(partial bool) (tea.Cmd, error)
+	Expand(side Side, lines int) (tea.Cmd, error)
+	SplitHunk() (tea.Cmd, error)
+	JoinHunk() (tea.Cmd, error)
+}
+
+// Command is one semantic action. String returns the text Parse reads back into an equal command.
+type Command interface {
+	Execute(t Target) (tea.Cmd, error)
+	String() string
+}
+
+// Msg carries a command through Update, so a test or a replay can drive the model without keys.
+type Msg struct {
+	Command Command
+}
+
+// Unit is what Navigate steps over.
+type Unit int
+
+// Navigation units. UnitLine moves the cursor of the focused panel, which is a file in the file list.
+const (
+	UnitLine Unit = iota
+	UnitHunk
+	UnitFile
+)
+
+// Panel is one of the two panes Focus moves to.
+type Panel int
+
+// The two panes, named as Focus writes them.
+const (
+	PanelFiles Panel = iota
+	PanelDiff
+)
+
+// wrap prefixes a refusal with the command that was refused, so the prompt says which one failed.
+func wrap(c Command, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w", c, err)
+}
+
+// Navigate moves a cursor by Delta units.
+type Navigate struct {
+	Unit  Unit
+	Delta int
+}
+
+// Execute moves the cursor.
+func (c Navigate) Execute(t Target) (tea.Cmd, error) {
+	return t.Navigate(c.Unit, c.Delta), nil
+}
+
+// String names the direction, with the count when it is more than one step, as in "down 5".
+func (c Navigate) String() string {
+	back, forward := navigationNames[c.Unit][0], navigationNames[c.Unit][1]
+
+	name, count := forward, c.Delta
+	if c.Delta < 0 {
+		name, count = back, -c.Delta
+	}
+
+	if count == 1 {
+		return name
+	}
+
+	return fmt.Sprintf("%s %d", name, count)
+}
+
+// navigationNames are the backward and forward text forms of each unit.
+var navigationNames = [...][2]string{
+	UnitLine: {"up", "down"},
+	UnitHunk: {"prev-hunk", "next-hunk"},
+	UnitFile: {"prev-file", "next-file"},
+}
+
+// JumpToFile moves the file cursor to Index, where -1 is the last file.
+type JumpToFile struct {
+	Index int
+}
+
+// Execute moves the file cursor.
+//
+//nolint:nilnil // moving the file cursor starts nothing and cannot be refused.
+func (c JumpToFile) Execute(t Target) (tea.Cmd, error) {
+	t.JumpToFile(c.Index)
+
+	return nil, nil
+}
+
+func (c JumpToFile) String() string {
+	if c.Index < 0 {
+		return "last-file"
+	}
+
+	if c.Index == 0 {
+		return "first-file"
+	}
+
+	return fmt.Sprintf("file %d", c.Index+1)
+}
+
+// GoTo puts the cursor on a line of a hunk of a file, all counted from 0, where a search or a
+// conflict jump left it. It is how a recording reaches a position no other command names.
+type GoTo struct {
+	File int
+	Hunk int
+	Line int
+}
+
+// Execute moves the cursor.
+func (c GoTo) Execute(t Target) (tea.Cmd, error) {
+	return nil, wrap(c, t.GoTo(c.File, c.Hunk, c.Line))
+}
+
+// String counts from 1, as the file list and the line numbers do.
+func (c GoTo) String() string {
+	return fmt.Sprintf("goto %d %d %d", c.File+1, c.Hunk+1, c.Line+1)
+}
+
+// Focus moves the keyboard focus to a pane, as tab does.
+type Focus struct {
+	Panel Panel
+}
+
+// Execute moves the focus.
+//
+//nolint:nilnil // moving the focus starts nothing and cannot be refused.
+func (c Focus) Execute(t Target) (tea.Cmd, error) {
+	t.Focus(c.Panel)
+
+	return nil, nil
+}
+
+func (c Focus) String() string {
+	if c.Panel == PanelDiff {
+		return "focus diff"
+	}
+
+	return "focus files"
+}
+
+// ToggleSelection selects or deselects the hunk under the cursor, or the lines of the visual range.
+type ToggleSelection struct{}
+
+// Execute toggles the selection.
+func (c ToggleSelection) Execute(t Target) (tea.Cmd, error) {
+	return nil, wrap(c, t.ToggleSelection())
+}
+
+func (ToggleSelection) String() string { return "toggle" }
+
+// Visual starts selecting lines from the cursor.
+type Visual struct{}
+
+// Execute enters visual mode.
+func (c Visual) Execute(t Target) (tea.Cmd, error) {
+	return nil, wrap(c, t.EnterVisualMode())
+}
+
+func (Visual) String() string { return "visual" }
+
+// Select adds what each selector picks to the selection, as jj-diff move reads them.
+type Select struct {
+	Selectors []diff.Selector
+}
+
+// Execute adds to the selection.
+func (c Select) Execute(t Target) (tea.Cmd, error) {
+	return nil, wrap(c, t.Select(c.Selectors))
+}
+
+func (c Select) String() string {
+	specs := make([]string, len(c.Selectors))
+	for i, selector := range c.Selectors {
+		specs[i] = selector.Spec
+	}
+
+	return "select " + strings.Join(specs, " ")
+}
+
+// Tag toggles the hunk under the cursor, or the visual range, in a multi-way split tag.
+type Tag struct {
+	Tag rune
+}
+
+// Execute tags the hunk.
+func (c Tag) Execute(t Target) (tea.Cmd, error) {
+	return nil, wrap(c, t.Tag(c.Tag))
+}
+
+func (c Tag) String() string { return fmt.Sprintf("tag %c", c.Tag) }
+
+// Assign sends a multi-way split tag to an existing revision, or to a new commit with Description as
+// its message when Revision is empty, as the split assignment modal does.
+type Assign struct {
+	Revision    string
+	Description string
+	Tag         rune
+}
+
+// Execute assigns the tag.
+func (c Assign) Execute(t Target) (tea.Cmd, error) {
+	return nil, wrap(c, t.Assign(c.Tag, c.Revision, c.Description))
+}
+
+// String writes "assign A REV", or "assign A new MESSAGE" for a new commit.
+func (c Assign) String() string {
+	if c.Revision == "" {
+		return fmt.Sprintf("assign %c new %s", c.Tag, c.Description)
+	}
+
+	return fmt.Sprintf("assign %c %s", c.Tag, c.Revision)
+}
+
+// Split applies the multi-way split to the destinations its tags are assigned, as confirming the
+// split preview does.
+type Split struct{}
+
+// Execute applies the split.
+func (c Split) Execute(t Target) (tea.Cmd, error) {
+	cmd, err := t.ApplySplit()
+
+	return cmd, wrap(c, err)
+}
+
+func (Split) String() string { return "split" }
+
+// SetDestination chooses the revision a move goes to.
+type SetDestination struct {
+	Revision string
+}
+
+// Execute sets the destination, which may start checking how the selection would apply there.
+func (c SetDestination) Execute(t Target) (tea.Cmd, error) {
+	cmd, err := t.SetDestination(c.Revision)
+
+	return cmd, wrap(c, err)
+}
+
+func (c SetDestination) String() string { return "dest " + c.Revision }
+
+// Apply moves the selection, applies the split, or saves the diff editor's result, as a does. A
+// partial move lands each hunk that fits on its own, with offset and fuzz, as A does, rather than
+// failing whole when one does not.
+type Apply struct {
+	Partial bool
+}
+
+// Execute applies.
+func (c Apply) Execute(t Target) (tea.Cmd, error) {
+	cmd, err := t.Apply(c.Partial)
+
+	return cmd, wrap(c, err)
+}
+
+func (c Apply) String() string {
+	if c.Partial {
+		return "apply partial"
+	}
+
+	return "apply"
+}
+
+// Move sets the destination and applies in one step.
+type Move struct {
+	Destination string
+}
+
+// Execute sets the destination, then applies. The move reloads the diff when it is done, so a check
+// of how it would apply is not worth starting.
+func (c Move) Execute(t Target) (tea.Cmd, error) {
+	if _, err := t.SetDestination(c.Destination); err != nil {
+		return nil, wrap(c, err)
+	}
+
+	cmd, err := t.Apply(false)
+
+	return cmd, wrap(c, err)
+}
+
+func (c Move) String() string { return "move " + c.Destination }
+
+// Side is where Expand reveals context around a hunk.
+type Side int
+
+// The sides Expand reveals lines on. SideBoth is only written as the whole gap.
+const (
+	SideAbove Side = iota
+	SideBelow
+	SideBoth
+)
+
+// ExpandStep is how many lines the expand keys reveal at a time.
+const ExpandStep = 10
+
+// Expand reveals Lines more unchanged lines around the hunk under the cursor, read from the file.
+// Lines is diff.WholeGap for every line up to the neighboring hunk.
+type Expand struct {
+	Side  Side
+	Lines int
+}
+
+// Execute reveals the lines, which may start reading the file.
+func (c Expand) Execute(t Target) (tea.Cmd, error) {
+	cmd, err := t.Expand(c.Side, c.Lines)
+
+	return cmd, wrap(c, err)
+}
+
+// String writes "expand up 10", "expand down all", or "expand gap" for both whole gaps.
+func (c Expand) String() string {
+	if c.Side == SideBoth {
+		return "expand gap"
+	}
+
+	count := strconv.Itoa(c.Lines)
+	if c.Lines == diff.WholeGap {
+		count = "all"
+	}
+
+	return fmt.Sprintf("expand %s %s", expandSides[c.Side], count)
+}
+
+// expandSides are the text forms of the sides Expand takes a count for.
+var expandSides = [...]string{SideAbove: "up", SideBelow: "down"}
+
+// SplitHunk breaks the hunk under the cursor into smaller hunks at the unchanged lines between its
+// changes.
+type SplitHunk struct{}
+
+// Execute splits the hunk.
+func (c SplitHunk) Execute(t Target) (tea.Cmd, error) {
+	cmd, err := t.SplitHunk()
+
+	return cmd, wrap(c, err)
+}
+
+func (SplitHunk) String() string { return "split-hunk" }
+
+// JoinHunk merges the hunk under the cursor with the neighbors it touches, undoing SplitHunk.
+type JoinHunk struct{}
+
+// Execute joins the hunks.
+func (c JoinHunk) Execute(t Target) (tea.Cmd, error) {
+	cmd, err := t.JoinHunk()
+
+	return cmd, wrap(c, err)
+}
+
+func (JoinHunk) String() string { return "join-hunk" }
+
+// Quit ends the session.
+type Quit struct{}
+
+// Execute quits.
+func (Quit) Execute(Target) (tea.Cmd, error) {
+	return tea.Quit, nil
+}
+
+func (Quit) String() string { return "quit" }
@@ -0,0 +1,249 @@
+package command_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+var errRefused = errors.New("refused")
+
+// recordingTarget logs each call in the command's text form, refusing when refuse is set.
+type recordingTarget struct {
+	refuse error
+	calls  []string
+}
+
+func (r *recordingTarget) Navigate(unit command.Unit, delta int) tea.Cmd {
+	r.calls = append(r.calls, command.Navigate{Unit: unit, Delta: delta}.String())
+
+	return nil
+}
+
+func (r *recordingTarget) JumpToFile(index int) {
+	r.calls = append(r.calls, command.JumpToFile{Index: index}.String())
+}
+
+func (r *recordingTarget) GoTo(file, hunk, line int) error {
+	r.calls = append(r.calls, command.GoTo{File: file, Hunk: hunk, Line: line}.String())
+
+	return r.refuse
+}
+
+func (r *recordingTarget) Focus(panel command.Panel) {
+	r.calls = append(r.calls, command.Focus{Panel: panel}.String())
+}
+
+func (r *recordingTarget) ToggleSelection() error {
+	r.calls = append(r.calls, "toggle")
+
+	return r.refuse
+}
+
+func (r *recordingTarget) EnterVisualMode() error {
+	r.calls = append(r.calls, "visual")
+
+	return r.refuse
+}
+
+func (r *recordingTarget) Select(selectors []diff.Selector) error {
+	r.calls = append(r.calls, command.Select{Selectors: selectors}.String())
+
+	return r.refuse
+}
+
+func (r *recordingTarget) Tag(tag rune) error {
+	r.calls = append(r.calls, command.Tag{Tag: tag}.String())
+
+	return r.refuse
+}
+
+func (r *recordingTarget) Assign(tag rune, revision, description string) error {
+	r.calls = append(r.calls, command.Assign{Tag: tag, Revision: revision, Description: description}.String())
+
+	return r.refuse
+}
+
+func (r *recordingTarget) ApplySplit() (tea.Cmd, error) {
+	r.calls = append(r.calls, "split")
+
+	return nil, r.refuse
+}
+
+func (r *recordingTarget) SetDestination(revision string) (tea.Cmd, error) {
+	r.calls = append(r.calls, "dest "+revision)
+
+	return nil, r.refuse
+}
+
+func (r *recordingTarget) Apply(partial bool) (tea.Cmd, error) {
+	r.calls = append(r.calls, command.Apply{Partial: partial}.String())
+
+	return nil, r.refuse
+}
+
+func (r *recordingTarget) Expand(side command.Side, lines int) (tea.Cmd, error) {
+	r.calls = append(r.calls, command.Expand{Side: side, Lines: lines}.String())
+
+	return nil, r.refuse
+}
+
+func (r *recordingTarget) SplitHunk() (tea.Cmd, error) {
+	r.calls = append(r.calls, "split-hunk")
+
+	return nil, r.refuse
+}
+
+func (r *recordingTarget) JoinHunk() (tea.Cmd, error) {
+	r.calls = append(r.calls, "join-hunk")
+
+	return nil, r.refuse
+}
+
+func TestParse_RoundTrips(t *testing.T) {
+	t.Parallel()
+
+	texts := []string{
+		"up", "down 5", "prev-hunk", "next-hunk 2", "prev-file", "next-file",
+		"first-file", "last-file", "file 3", "focus files", "focus diff", "toggle", "visual",
+		"select src/**/*.go main.go:0", "tag B", "dest @-", "apply", "apply partial", "move @--", "quit",
+		"expand up 5", "expand down all", "expand gap", "split-hunk", "join-hunk",
+		"goto 2 3 1", "assign A @-", "assign B new Add the parser", "split",
+	}
+
+	for _, text := range texts {
+		c, err := command.Parse(text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", text, err)
+
+			continue
+		}
+
+		if c.String() != text {
+			t.Errorf("Parse(%q).String() = %q", text, c.String())
+		}
+	}
+}
+
+func TestParse_Aliases(t *testing.T) {
+	t.Parallel()
+
+	aliases := map[string]string{
+		"tag b":            "tag B",
+		"destination @-":   "dest @-",
+		"q":                "quit",
+		"  down   1 ":      "down",
+		"file 1":           "first-file",
+		"select *.go":      "select *.go",
+		"next-hunk 1":      "next-hunk",
+		"move  main@orig":  "move main@orig",
+		"expand up":        "expand up 10",
+		"assign c  new  x": "assign C new x",
+	}
+
+	for text, want := range aliases {
+		c, err := command.Parse(text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", text, err)
+
+			continue
+		}
+
+		if c.String() != want {
+			t.Errorf("Parse(%q).String() = %q, want %q", text, c.String(), want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]error{
+		"":                command.ErrUnknown,
+		"frobnicate":      command.ErrUnknown,
+		"apply now":       command.ErrExtraArgs,
+		"apply partial 2": command.ErrExtraArgs,
+		"move":            command.ErrMissingArgs,
+		"move a b":        command.ErrExtraArgs,
+		"tag":             command.ErrMissingArgs,
+		"tag AB":          command.ErrInvalidArg,
+		"tag 1":           command.ErrInvalidArg,
+		"down 0":          command.ErrInvalidArg,
+		"down x":          command.ErrInvalidArg,
+		"file":            command.ErrMissingArgs,
+		"focus":           command.ErrMissingArgs,
+		"focus left":      command.ErrInvalidArg,
+		"select":          command.ErrMissingArgs,
+		"select added:(":  diff.ErrInvalidSelector,
+		"expand":          command.ErrMissingArgs,
+		"expand left":     command.ErrInvalidArg,
+		"expand gap 3":    command.ErrExtraArgs,
+		"expand up 0":     command.ErrInvalidArg,
+		"split-hunk 2":    command.ErrExtraArgs,
+		"goto 1 2":        command.ErrMissingArgs,
+		"goto 1 0 1":      command.ErrInvalidArg,
+		"assign A":        command.ErrMissingArgs,
+		"assign A new":    command.ErrMissingArgs,
+		"assign A @ @-":   command.ErrExtraArgs,
+		"assign 1 @":      command.ErrInvalidArg,
+	}
+
+	for text, want := range tests {
+		if _, err := command.Parse(text); !errors.Is(err, want) {
+			t.Errorf("Parse(%q) = %v, want %v", text, err, want)
+		}
+	}
+}
+
+func TestExecute_CallsTarget(t *testing.T) {
+	t.Parallel()
+
+	target := &recordingTarget{}
+
+	for _, text := range []string{"down 3", "last-file", "select *.go", "move @-", "quit"} {
+		c, err := command.Parse(text)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", text, err)
+		}
+
+		cmd, err := c.Execute(target)
+		if err != nil {
+			t.Errorf("Execute(%q) failed: %v", text, err)
+		}
+
+		if text == "quit" {
+			if cmd == nil {
+				t.Fatal("Expected quit to return tea.Quit")
+			}
+
+			if _, ok := cmd().(tea.QuitMsg); !ok {
+				t.Error("Expected quit to return tea.Quit")
+			}
+		}
+	}
+
+	want := "down 3,last-file,select *.go,dest @-,apply"
+	if got := strings.Join(target.calls, ","); got != want {
+		t.Errorf("Expected calls %q, got %q", want, got)
+	}
+}
+
+func TestExecute_RefusalNamesTheCommand(t *testing.T) {
+	t.Parallel()
+
+	target := &recordingTarget{refuse: errRefused}
+
+	_, err := command.Move{Destination: "@-"}.Execute(target)
+	if !errors.Is(err, errRefused) || !strings.HasPrefix(err.Error(), "move @-: ") {
+		t.Errorf("Expected the refusal prefixed with the command, got %v", err)
+	}
+
+	if len(target.calls) != 1 {
+		t.Errorf("Expected a refused destination to stop the move before applying, got %v", target.calls)
+	}
+}
@@ -0,0 +1,314 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// parsers read each command's arguments, keyed by the name that starts its text form.
+var parsers = newParsers()
+
+func newParsers() map[string]func(args []string) (Command, error) {
+	parsers := map[string]func(args []string) (Command, error){
+		"first-file":  noArgs(JumpToFile{Index: 0}),
+		"last-file":   noArgs(JumpToFile{Index: -1}),
+		"file":        parseFile,
+		"goto":        parseGoTo,
+		"focus":       parseFocus,
+		"toggle":      noArgs(ToggleSelection{}),
+		"visual":      noArgs(Visual{}),
+		"select":      parseSelect,
+		"tag":         parseTag,
+		"assign":      parseAssign,
+		"split":       noArgs(Split{}),
+		"dest":        oneArg(func(arg string) Command { return SetDestination{Revision: arg} }),
+		"destination": oneArg(func(arg string) Command { return SetDestination{Revision: arg} }),
+		"apply":       parseApply,
+		"move":        oneArg(func(arg string) Command { return Move{Destination: arg} }),
+		"expand":      parseExpand,
+		"split-hunk":  noArgs(SplitHunk{}),
+		"join-hunk":   noArgs(JoinHunk{}),
+		"quit":        noArgs(Quit{}),
+		"q":           noArgs(Quit{}),
+	}
+
+	// The navigation names are the table String reads, backward first.
+	for unit, names := range navigationNames {
+		parsers[names[0]] = parseNavigate(Unit(unit), -1)
+		parsers[names[1]] = parseNavigate(Unit(unit), 1)
+	}
+
+	return parsers
+}
+
+// Parse reads a command's text form, as typed at the : prompt without the colon. Arguments are
+// separated by spaces, so a selector cannot contain one.
+//
+//nolint:ireturn // each command is its own type, and callers only run it.
+func Parse(text string) (Command, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: nothing typed", ErrUnknown)
+	}
+
+	parse, ok := parsers[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, fields[0])
+	}
+
+	return parse(fields[1:])
+}
+
+func noArgs(c Command) func([]string) (Command, error) {
+	return func(args []string) (Command, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("%w to %s: %s", ErrExtraArgs, c, args[0])
+		}
+
+		return c, nil
+	}
+}
+
+func oneArg(build func(arg string) Command) func([]string) (Command, error) {
+	return func(args []string) (Command, error) {
+		switch len(args) {
+		case 0:
+			return nil, fmt.Errorf("%w: expected a revision", ErrMissingArgs)
+		case 1:
+			return build(args[0]), nil
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrExtraArgs, args[1])
+		}
+	}
+}
+
+// parseNavigate reads an optional step count, as in "down 5".
+func parseNavigate(unit Unit, sign int) func([]string) (Command, error) {
+	return func(args []string) (Command, error) {
+		count := 1
+
+		if len(args) > 0 {
+			n, err := positive(args)
+			if err != nil {
+				return nil, err
+			}
+
+			count = n
+		}
+
+		return Navigate{Unit: unit, Delta: sign * count}, nil
+	}
+}
+
+// parseFile reads "file N", where N counts from 1 as the file list shows it.
+//
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseFile(args []string) (Command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: expected a file number", ErrMissingArgs)
+	}
+
+	n, err := positive(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return JumpToFile{Index: n - 1}, nil
+}
+
+// parseGoTo reads "goto FILE HUNK LINE", each counted from 1.
+//
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseGoTo(args []string) (Command, error) {
+	const fields = 3
+
+	if len(args) < fields {
+		return nil, fmt.Errorf("%w: expected a file, a hunk, and a line number", ErrMissingArgs)
+	}
+
+	if len(args) > fields {
+		return nil, fmt.Errorf("%w: %s", ErrExtraArgs, args[fields])
+	}
+
+	var position [fields]int
+
+	for i := range position {
+		n, err := positive(args[i : i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		position[i] = n - 1
+	}
+
+	return GoTo{File: position[0], Hunk: position[1], Line: position[2]}, nil
+}
+
+// parseFocus reads "focus files" or "focus diff".
+//
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseFocus(args []string) (Command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: expected files or diff", ErrMissingArgs)
+	}
+
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%w: %s", ErrExtraArgs, args[1])
+	}
+
+	switch args[0] {
+	case "files":
+		return Focus{Panel: PanelFiles}, nil
+	case "diff":
+		return Focus{Panel: PanelDiff}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q is not files or diff", ErrInvalidArg, args[0])
+}
+
+func positive(args []string) (int, error) {
+	if len(args) > 1 {
+		return 0, fmt.Errorf("%w: %s", ErrExtraArgs, args[1])
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %q is not a positive number", ErrInvalidArg, args[0])
+	}
+
+	return n, nil
+}
+
+// parseExpand reads "expand up [N|all]", "expand down [N|all]", or "expand gap". The count defaults
+// to ExpandStep, as the keys reveal.
+//
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseExpand(args []string) (Command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: expected up, down, or gap", ErrMissingArgs)
+	}
+
+	side, ok := map[string]Side{"up": SideAbove, "down": SideBelow, "gap": SideBoth}[args[0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not up, down, or gap", ErrInvalidArg, args[0])
+	}
+
+	if side == SideBoth {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("%w: %s", ErrExtraArgs, args[1])
+		}
+
+		return Expand{Side: side, Lines: diff.WholeGap}, nil
+	}
+
+	switch {
+	case len(args) == 1:
+		return Expand{Side: side, Lines: ExpandStep}, nil
+	case args[1] == "all" && len(args) == 2:
+		return Expand{Side: side, Lines: diff.WholeGap}, nil
+	}
+
+	n, err := positive(args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return Expand{Side: side, Lines: n}, nil
+}
+
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseApply(args []string) (Command, error) {
+	switch {
+	case len(args) == 0:
+		return Apply{}, nil
+	case args[0] != "partial":
+		return nil, fmt.Errorf("%w to apply: %s", ErrExtraArgs, args[0])
+	case len(args) > 1:
+		return nil, fmt.Errorf("%w to apply partial: %s", ErrExtraArgs, args[1])
+	}
+
+	return Apply{Partial: true}, nil
+}
+
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseSelect(args []string) (Command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: expected a selector", ErrMissingArgs)
+	}
+
+	selectors := make([]diff.Selector, len(args))
+
+	for i, spec := range args {
+		selector, err := diff.ParseSelector(spec)
+		if err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+
+		selectors[i] = selector
+	}
+
+	return Select{Selectors: selectors}, nil
+}
+
+// parseAssign reads "assign TAG REV" or "assign TAG new MESSAGE", where the message is the rest of
+// the line with its spaces collapsed.
+//
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseAssign(args []string) (Command, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%w: expected a tag letter and a revision or new", ErrMissingArgs)
+	}
+
+	tag, err := tagLetter(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if args[1] != "new" {
+		if len(args) > 2 {
+			return nil, fmt.Errorf("%w: %s", ErrExtraArgs, args[2])
+		}
+
+		return Assign{Tag: tag, Revision: args[1]}, nil
+	}
+
+	if len(args) == 2 {
+		return nil, fmt.Errorf("%w: expected the new commit's message", ErrMissingArgs)
+	}
+
+	return Assign{Tag: tag, Description: strings.Join(args[2:], " ")}, nil
+}
+
+// parseTag reads one letter, folding lowercase onto the uppercase tag as the tag keys do.
+//
+//nolint:ireturn // a parser in the table Parse dispatches through.
+func parseTag(args []string) (Command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: expected a tag letter", ErrMissingArgs)
+	}
+
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%w: %s", ErrExtraArgs, args[1])
+	}
+
+	tag, err := tagLetter(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return Tag{Tag: tag}, nil
+}
+
+// tagLetter reads a tag's one letter, folded to uppercase.
+func tagLetter(arg string) (rune, error) {
+	letter := []rune(arg)
+	if len(letter) != 1 || letter[0] > unicode.MaxASCII || !unicode.IsLetter(letter[0]) {
+		return 0, fmt.Errorf("%w: a tag is one letter, not %q", ErrInvalidArg, arg)
+	}
+
+	return unicode.ToUpper(letter[0]), nil
+}
@@ -0,0 +1,207 @@
+// Package applyreport shows how each hunk of a move fared when the destination had drifted from the
+// source's parent: which applied as written, which landed away from their header or with fuzz, and
+// which were rejected and left in the source. The move is already committed when the report opens,
+// so the parent model offers to keep it or to roll it back.
+package applyreport
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/theme"
+)
+
+// Layout of the centered modal, in terminal cells. It follows the conflict list: the modal shrinks
+// with the terminal down to the minimums, and five rows are chrome (the header, the summary, the
+// blank lines around the rows, and the footer).
+const (
+	listChromeHeight  = 5
+	maxModalHeight    = 24
+	maxModalWidth     = 100
+	minModalHeight    = 7
+	minModalWidth     = 50
+	modalHeightMargin = 4
+	modalPaddingX     = 2
+	modalPaddingY     = 1
+	modalWidthMargin  = 10
+	rowIndentWidth    = 2
+)
+
+// Model is the report modal. It only displays the report the parent hands it and has no cursor.
+type Model struct {
+	destination string
+	report      diff.ApplyReport
+	visible     bool
+}
+
+// New returns a hidden modal with an empty report.
+func New() Model {
+	return Model{}
+}
+
+// SetReport replaces the report and names the revision the hunks were moved into.
+func (m *Model) SetReport(report diff.ApplyReport, destination string) {
+	m.report = report
+	m.destination = destination
+}
+
+// Show opens the report. While it is visible the parent model routes every key here.
+func (m *Model) Show() {
+	m.visible = true
+}
+
+// Hide closes the report and leaves its contents in place.
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// IsVisible reports whether keys belong to the report rather than the main view.
+func (m *Model) IsVisible() bool {
+	return m.visible
+}
+
+// View centers the report in a terminal of the given cell dimensions. Hunks that applied as written
+// are only counted, so the rows are the ones worth checking; rows past the modal's height are
+// summarized in one last line. It returns an empty string while hidden.
+func (m Model) View(width, height int) string {
+	if !m.visible {
+		return ""
+	}
+
+	maxHeight := clamp(height-modalHeightMargin, minModalHeight, maxModalHeight)
+	modalWidth := clamp(width-modalWidthMargin, minModalWidth, maxModalWidth)
+
+	landed := len(m.report.Hunks) - m.report.Count(diff.HunkRejected)
+
+	lines := []string{
+		styleHeader(fmt.Sprintf("Moved %d of %d hunks to %s", landed, len(m.report.Hunks), m.destination), modalWidth),
+		styleInfo(fmt.Sprintf(
+			"%d applied, %d shifted, %d rejected and left in the source",
+			m.report.Count(diff.HunkApplied),
+			m.report.Count(diff.HunkShifted),
+			m.report.Count(diff.HunkRejected),
+		), modalWidth),
+		"",
+	}
+
+	var rows []diff.HunkResult
+	for _, hunk := range m.report.Hunks {
+		if hunk.Status != diff.HunkApplied {
+			rows = append(rows, hunk)
+		}
+	}
+
+	hidden := 0
+	if visibleRows := maxHeight - listChromeHeight; len(rows) > visibleRows {
+		hidden = len(rows) - visibleRows + 1
+		rows = rows[:visibleRows-1]
+	}
+
+	for _, row := range rows {
+		lines = append(lines, "  "+renderRow(row, modalWidth-rowIndentWidth))
+	}
+
+	if hidden > 0 {
+		lines = append(lines, "  "+truncateOrPad(fmt.Sprintf("... and %d more", hidden), modalWidth-rowIndentWidth))
+	}
+
+	lines = append(
+		lines,
+		"",
+		styleFooter("Enter: Keep | r: Roll back", modalWidth),
+	)
+
+	return renderModal(strings.Join(lines, "\n"), width, height)
+}
+
+func clamp(value, lower, upper int) int {
+	return min(max(value, lower), upper)
+}
+
+// renderRow describes one hunk that did not apply as written. A shifted hunk says how far it moved
+// and how much context it ignored, which is what tells a reviewer whether it landed somewhere
+// plausible.
+func renderRow(hunk diff.HunkResult, width int) string {
+	location := fmt.Sprintf("%s hunk %d", hunk.Path, hunk.Index+1)
+
+	switch hunk.Status {
+	case diff.HunkShifted:
+		detail := fmt.Sprintf("shifted %+d lines", hunk.Offset)
+		if hunk.Fuzz > 0 {
+			detail += fmt.Sprintf(", ignoring %d context lines at each end", hunk.Fuzz)
+		}
+
+		return styleShifted(truncateOrPad("~ "+location+": "+detail, width))
+	case diff.HunkRejected:
+		return styleRejected(truncateOrPad("✗ "+location+": "+hunk.Err.Err.Error(), width))
+	case diff.HunkApplied:
+	}
+
+	return truncateOrPad("✓ "+location, width)
+}
+
+func styleHeader(text string, width int) string {
+	style := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.Primary).
+		Width(width).
+		Align(lipgloss.Center)
+
+	return style.Render(text)
+}
+
+func styleInfo(text string, width int) string {
+	style := lipgloss.NewStyle().
+		Foreground(theme.SoftMutedBg).
+		Width(width).
+		Align(lipgloss.Center)
+
+	return style.Render(text)
+}
+
+func styleFooter(text string, width int) string {
+	style := lipgloss.NewStyle().
+		Foreground(theme.SoftMutedBg).
+		Width(width).
+		Align(lipgloss.Center)
+
+	return style.Render(text)
+}
+
+func styleShifted(text string) string {
+	return lipgloss.NewStyle().Foreground(theme.Accent).Render(text)
+}
+
+func styleRejected(text string) string {
+	return lipgloss.NewStyle().Foreground(theme.DeletedLine).Render(text)
+}
+
+func truncateOrPad(text string, width int) string {
+	if lipgloss.Width(text) > width {
+		runes := []rune(text)
+
+		return string(runes[:min(max(width-3, 0), len(runes))]) + "..."
+	}
+
+	return text + strings.Repeat(" ", width-lipgloss.Width(text))
+}
+
+func renderModal(content string, termWidth, termHeight int) string {
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Primary).
+		Padding(modalPaddingY, modalPaddingX)
+
+	modal := borderStyle.Render(content)
+
+	return lipgloss.Place(
+		termWidth,
+		termHeight,
+		lipgloss.Center,
+		lipgloss.Center,
+		modal,
+	)
+}
@@ -0,0 +1,106 @@
+package applyreport_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/components/applyreport"
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+func testReport() diff.ApplyReport {
+	return diff.ApplyReport{Hunks: []diff.HunkResult{
+		{Path: "a.go", Index: 0, Status: diff.HunkApplied},
+		{Path: "a.go", Index: 1, Status: diff.HunkShifted, Offset: -4},
+		{Path: "b.go", Index: 0, Status: diff.HunkShifted, Offset: 2, Fuzz: 1},
+		{
+			Path:   "c.go",
+			Index:  2,
+			Status: diff.HunkRejected,
+			Err:    &diff.HunkError{Path: "c.go", Index: 2, Err: diff.ErrHunkMismatch},
+		},
+	}}
+}
+
+func shownReport(report diff.ApplyReport) applyreport.Model {
+	m := applyreport.New()
+	m.SetReport(report, "pppppppp")
+	m.Show()
+
+	return m
+}
+
+func TestNewIsHidden(t *testing.T) {
+	t.Parallel()
+
+	m := applyreport.New()
+
+	if m.IsVisible() {
+		t.Error("Expected a new report to be hidden")
+	}
+
+	if view := m.View(120, 40); view != "" {
+		t.Errorf("Expected a hidden report to render nothing, got %q", view)
+	}
+}
+
+func TestShowAndHide(t *testing.T) {
+	t.Parallel()
+
+	m := shownReport(testReport())
+	if !m.IsVisible() {
+		t.Fatal("Expected Show to open the report")
+	}
+
+	m.Hide()
+	if m.IsVisible() {
+		t.Error("Expected Hide to close the report")
+	}
+}
+
+func TestViewSummarizesHunks(t *testing.T) {
+	t.Parallel()
+
+	view := shownReport(testReport()).View(120, 40)
+
+	for _, want := range []string{
+		"Moved 3 of 4 hunks to pppppppp",
+		"1 applied, 2 shifted, 1 rejected and left in the source",
+		"~ a.go hunk 2: shifted -4 lines",
+		"~ b.go hunk 1: shifted +2 lines, ignoring 1 context lines at each end",
+		"✗ c.go hunk 3: " + diff.ErrHunkMismatch.Error(),
+		"Enter: Keep | r: Roll back",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	if strings.Contains(view, "a.go hunk 1") {
+		t.Error("Expected a hunk that applied as written to be counted, not listed")
+	}
+}
+
+func TestViewSummarizesOverflow(t *testing.T) {
+	t.Parallel()
+
+	var report diff.ApplyReport
+	for i := range 6 {
+		report.Hunks = append(report.Hunks, diff.HunkResult{
+			Path:   "a.go",
+			Index:  i,
+			Status: diff.HunkShifted,
+			Offset: 1,
+		})
+	}
+
+	view := shownReport(report).View(120, 12)
+
+	if !strings.Contains(view, "... and 4 more") {
+		t.Errorf("Expected the rows past the modal to be summarized, got:\n%s", view)
+	}
+
+	if strings.Contains(view, "a.go hunk 3") {
+		t.Error("Expected rows past the modal's height to be left out")
+	}
+}
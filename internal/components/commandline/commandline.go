@@ -0,0 +1,67 @@
+// Package commandline shows the vim-style : prompt in place of the status bar. It only holds the
+// text being typed: the parent model parses and runs it when enter is pressed.
+package commandline
+
+import "github.com/kyleking/jj-diff/internal/theme"
+
+// Model is the prompt. Text is kept while hidden, so the parent can read it after closing.
+type Model struct {
+	text    string
+	visible bool
+}
+
+// New returns a hidden prompt.
+func New() Model {
+	return Model{}
+}
+
+// Show opens the prompt empty.
+func (m *Model) Show() {
+	m.visible = true
+	m.text = ""
+}
+
+// Hide closes the prompt.
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// IsVisible reports whether the prompt is open, which is how the parent decides to route keys here.
+func (m Model) IsVisible() bool {
+	return m.visible
+}
+
+// Insert appends typed text at the end, which is the only place the cursor goes.
+func (m *Model) Insert(text string) {
+	m.text += text
+}
+
+// Backspace removes the last character and reports whether there was one, so the parent can close
+// the prompt on a backspace over nothing, as vim does.
+func (m *Model) Backspace() bool {
+	if m.text == "" {
+		return false
+	}
+
+	runes := []rune(m.text)
+	m.text = string(runes[:len(runes)-1])
+
+	return true
+}
+
+// Value returns the typed text.
+func (m Model) Value() string {
+	return m.text
+}
+
+// View renders the prompt as one line of the given width, or the empty string while hidden.
+func (m Model) View(width int) string {
+	if !m.visible {
+		return ""
+	}
+
+	return theme.StatusBarStyle.
+		Width(width).
+		MaxWidth(width).
+		Render(":" + m.text + "█")
+}
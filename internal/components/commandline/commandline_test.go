@@ -0,0 +1,81 @@
+package commandline_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/components/commandline"
+)
+
+func TestNewIsHidden(t *testing.T) {
+	t.Parallel()
+
+	m := commandline.New()
+
+	if m.IsVisible() {
+		t.Error("Expected a new prompt to be hidden")
+	}
+
+	if view := m.View(80); view != "" {
+		t.Errorf("Expected a hidden prompt to render nothing, got %q", view)
+	}
+}
+
+func TestShowClearsText(t *testing.T) {
+	t.Parallel()
+
+	m := commandline.New()
+	m.Show()
+	m.Insert("move @-")
+	m.Hide()
+
+	if m.Value() != "move @-" {
+		t.Errorf("Expected the text to survive Hide, got %q", m.Value())
+	}
+
+	m.Show()
+	if m.Value() != "" {
+		t.Errorf("Expected Show to open an empty prompt, got %q", m.Value())
+	}
+}
+
+func TestInsertAndBackspace(t *testing.T) {
+	t.Parallel()
+
+	m := commandline.New()
+	m.Show()
+	m.Insert("tag ")
+	m.Insert("→é")
+
+	if m.Value() != "tag →é" {
+		t.Fatalf("Expected inserts to append, got %q", m.Value())
+	}
+
+	if !m.Backspace() || m.Value() != "tag →" {
+		t.Errorf("Expected backspace to remove one character, got %q", m.Value())
+	}
+
+	for range len([]rune("tag →")) {
+		m.Backspace()
+	}
+
+	if m.Value() != "" {
+		t.Errorf("Expected backspace to empty the prompt, got %q", m.Value())
+	}
+
+	if m.Backspace() {
+		t.Error("Expected backspace on an empty prompt to report nothing removed")
+	}
+}
+
+func TestViewShowsPrompt(t *testing.T) {
+	t.Parallel()
+
+	m := commandline.New()
+	m.Show()
+	m.Insert("select a.go")
+
+	if view := m.View(80); !strings.Contains(view, ":select a.go█") {
+		t.Errorf("Expected the prompt, text, and cursor, got %q", view)
+	}
+}
@@ -0,0 +1,149 @@
+// Package conflictlist lists the files jj records as conflicted in the revision on screen, with how
+// many conflicts the diff shows in each. The parent model loads the entries, routes keys here while
+// the list is visible, and reads the highlighted file back out when the user jumps to it.
+package conflictlist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyleking/jj-diff/internal/components/listmodal"
+)
+
+// rowIndentWidth is the indent in front of each row, in terminal cells.
+const rowIndentWidth = 2
+
+// Entry is one conflicted file. Count is the number of conflicts the diff on screen shows in it,
+// which is zero for a file whose conflict the revision inherited without touching.
+type Entry struct {
+	Path        string
+	Description string
+	Count       int
+}
+
+// Model is the conflict list. Mutators take a pointer receiver, so a parent holding it by value must
+// keep the same field rather than a copy.
+type Model struct {
+	entries  []Entry
+	selected int
+	visible  bool
+}
+
+// New returns a hidden list with no entries, so SetEntries must run before Show.
+func New() Model {
+	return Model{
+		entries: []Entry{},
+	}
+}
+
+// SetEntries replaces the list and puts the cursor back on the first file. The slice is retained,
+// not copied.
+func (m *Model) SetEntries(entries []Entry) {
+	m.entries = entries
+	m.selected = 0
+}
+
+// Show reveals the list. While it is visible the parent model routes every key here.
+func (m *Model) Show() {
+	m.visible = true
+}
+
+// Hide takes the list off screen and leaves the entries and cursor in place.
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// IsVisible reports whether keys belong to the list rather than the main view.
+func (m *Model) IsVisible() bool {
+	return m.visible
+}
+
+// MoveUp moves the cursor one file up and stops at the first, without wrapping.
+func (m *Model) MoveUp() {
+	if m.selected > 0 {
+		m.selected--
+	}
+}
+
+// MoveDown moves the cursor one file down and stops at the last, without wrapping.
+func (m *Model) MoveDown() {
+	if m.selected < len(m.entries)-1 {
+		m.selected++
+	}
+}
+
+// GetSelected returns the highlighted file, or nil when the list is empty.
+func (m Model) GetSelected() *Entry {
+	if m.selected >= 0 && m.selected < len(m.entries) {
+		return &m.entries[m.selected]
+	}
+
+	return nil
+}
+
+// View centers the list in a terminal of the given cell dimensions. The header totals the
+// conflicts across files, counting only those the diff shows. It returns an empty string while
+// hidden.
+func (m Model) View(width, height int) string {
+	if !m.visible {
+		return ""
+	}
+
+	modalWidth, visibleRows := listmodal.DefaultSize.Fit(width, height)
+
+	total := 0
+	for _, entry := range m.entries {
+		total += entry.Count
+	}
+
+	lines := []string{
+		listmodal.Header(fmt.Sprintf("Conflicts (%d files, %d in this diff)", len(m.entries), total), modalWidth),
+		"",
+	}
+
+	if len(m.entries) == 0 {
+		lines = append(lines, "  No conflicts")
+	}
+
+	startIdx := listmodal.ScrollStart(m.selected, len(m.entries), visibleRows)
+	endIdx := min(startIdx+visibleRows, len(m.entries))
+
+	pathWidth := 0
+	for _, entry := range m.entries {
+		pathWidth = max(pathWidth, lipgloss.Width(entry.Path))
+	}
+
+	for i := startIdx; i < endIdx; i++ {
+		line := renderEntryLine(m.entries[i], pathWidth, modalWidth-rowIndentWidth)
+		if i == m.selected {
+			line = listmodal.Selected(line)
+		}
+
+		lines = append(lines, "  "+line)
+	}
+
+	lines = append(
+		lines,
+		"",
+		listmodal.Footer("Enter: Jump to file | Esc: Close | j/k: Navigate", modalWidth),
+	)
+
+	return listmodal.Render(strings.Join(lines, "\n"), width, height)
+}
+
+func renderEntryLine(entry Entry, pathWidth, width int) string {
+	count := "none in this diff"
+	switch entry.Count {
+	case 0:
+	case 1:
+		count = "1 conflict"
+	default:
+		count = fmt.Sprintf("%d conflicts", entry.Count)
+	}
+
+	line := fmt.Sprintf("%-*s  (%s)  %s", pathWidth, entry.Path, count, entry.Description)
+
+	return listmodal.TruncateOrPad(line, width)
+}
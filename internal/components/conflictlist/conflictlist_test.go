@@ -0,0 +1,103 @@
+package conflictlist_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/components/conflictlist"
+)
+
+func loadedList() conflictlist.Model {
+	m := conflictlist.New()
+	m.SetEntries([]conflictlist.Entry{
+		{Path: "a.txt", Description: "2-sided conflict", Count: 2},
+		{Path: "dir/b.txt", Description: "2-sided conflict", Count: 1},
+		{Path: "c.txt", Description: "3-sided conflict"},
+	})
+	m.Show()
+
+	return m
+}
+
+func TestNewIsHiddenAndEmpty(t *testing.T) {
+	t.Parallel()
+
+	m := conflictlist.New()
+
+	if m.IsVisible() {
+		t.Error("Expected a new conflict list to be hidden")
+	}
+
+	if entry := m.GetSelected(); entry != nil {
+		t.Errorf("Expected no selection, got %+v", entry)
+	}
+
+	if view := m.View(120, 40); view != "" {
+		t.Errorf("Expected a hidden conflict list to render nothing, got %q", view)
+	}
+}
+
+func TestMoveClampsAtEnds(t *testing.T) {
+	t.Parallel()
+
+	m := loadedList()
+
+	m.MoveUp()
+	if entry := m.GetSelected(); entry.Path != "a.txt" {
+		t.Errorf("Expected MoveUp on the first file to stay there, got %s", entry.Path)
+	}
+
+	for range 5 {
+		m.MoveDown()
+	}
+
+	if entry := m.GetSelected(); entry.Path != "c.txt" {
+		t.Errorf("Expected MoveDown to stop at the last file, got %s", entry.Path)
+	}
+
+	m.MoveUp()
+	if entry := m.GetSelected(); entry.Path != "dir/b.txt" {
+		t.Errorf("Expected MoveUp to step back one file, got %s", entry.Path)
+	}
+}
+
+func TestSetEntriesResetsCursor(t *testing.T) {
+	t.Parallel()
+
+	m := loadedList()
+	m.MoveDown()
+	m.SetEntries([]conflictlist.Entry{{Path: "d.txt"}, {Path: "e.txt"}})
+
+	if entry := m.GetSelected(); entry.Path != "d.txt" {
+		t.Errorf("Expected the cursor back on the first file, got %s", entry.Path)
+	}
+}
+
+func TestViewCountsConflicts(t *testing.T) {
+	t.Parallel()
+
+	view := loadedList().View(120, 40)
+
+	for _, want := range []string{
+		"Conflicts (3 files, 3 in this diff)",
+		"(2 conflicts)",
+		"(1 conflict)",
+		"(none in this diff)",
+		"3-sided conflict",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q", want)
+		}
+	}
+}
+
+func TestViewWithoutEntries(t *testing.T) {
+	t.Parallel()
+
+	m := conflictlist.New()
+	m.Show()
+
+	if view := m.View(120, 40); !strings.Contains(view, "No conflicts") {
+		t.Errorf("Expected the empty message, got %q", view)
+	}
+}
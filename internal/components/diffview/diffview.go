@@ -96,6 +96,7 @@ func (idx *LineIndex) FindHunkForOffset(offset int) HunkPosition {
 // selected hunk, line cursor, search state, and tag state in before each render.
 type Model struct {
 	getHunkTags     func(hunkIdx int) []SplitTag
+	getApplicable   func(hunkIdx int) (diff.HunkStatus, bool)
 	lineIndex       *LineIndex
 	wordDiffCache   *WordDiffCache
 	highlighter     *highlight.Highlighter
@@ -229,6 +230,13 @@ func (m *Model) SetTagState(getHunkTags func(hunkIdx int) []SplitTag) {
 	m.getHunkTags = getHunkTags
 }
 
+// SetApplicability installs the lookup for how each hunk would apply to the move destination, drawn
+// beside its header as clean, offset, or conflicting. The callback reports false for a hunk that has
+// not been checked, and a nil callback draws no markers.
+func (m *Model) SetApplicability(getApplicable func(hunkIdx int) (diff.HunkStatus, bool)) {
+	m.getApplicable = getApplicable
+}
+
 // ToggleWhitespace flips visible whitespace glyphs and rebuilds the line index, because the glyphs
 // change how wide a rendered line is.
 func (m *Model) ToggleWhitespace() {
@@ -297,6 +305,18 @@ func (m *Model) Scroll(delta int) {
 	m.offset = newOffset
 }
 
+// Offset returns the first rendered line on screen, which a caller reloading the same file reads
+// before SetFileChange resets it.
+func (m *Model) Offset() int {
+	return m.offset
+}
+
+// SetOffset scrolls to an absolute line, clamped the same way Scroll clamps, so an offset saved from
+// a longer version of the file still lands on screen.
+func (m *Model) SetOffset(offset int) {
+	m.Scroll(offset - m.offset)
+}
+
 // ScrollHalfPageDown scrolls down half of viewHeight. Pass the pane's height, not the terminal's.
 func (m *Model) ScrollHalfPageDown(viewHeight int) {
 	m.Scroll(viewHeight / halfDivisor)
@@ -331,6 +351,24 @@ func (m *Model) calculateTotalLines() int {
 	return total
 }
 
+// ContentHeight is how many rows the loaded file takes in the current layout, which is the height that
+// renders it whole without padding.
+func (m *Model) ContentHeight() int {
+	if m.fileChange == nil {
+		return 0
+	}
+
+	if m.viewMode == ViewModeSideBySide {
+		return sideBySideHeight(m.fileChange, m.showWhitespace)
+	}
+
+	if m.lineIndex == nil {
+		return 0
+	}
+
+	return m.lineIndex.TotalLines
+}
+
 // View renders the pane at the given size, padding out to it when the content is shorter. The
 // focused flag only changes cursor styling, so an unfocused pane still shows where the cursor sits.
 func (m *Model) View(width, height int, focused bool) string {
@@ -430,6 +468,7 @@ func (m *Model) renderLine(line diff.Line, width, hunkIdx, lineIdx int) string {
 	)
 
 	style := lineStyle(line.Type, isInVisualRange, isCurrentLine)
+	style = conflictKindAt(m.fileChange, hunkIdx, lineIdx).apply(style)
 
 	return style.Render(truncateOrPad(lineText, width))
 }
@@ -526,6 +565,48 @@ func lineStyle(lineType diff.LineType, isInVisualRange, isCurrentLine bool) lipg
 	return style
 }
 
+// conflictKind is how a line relates to a jj conflict, which decides the styling laid over its
+// ordinary added, deleted, or context color.
+type conflictKind int
+
+const (
+	conflictNone conflictKind = iota
+	conflictSide
+	conflictMarker
+)
+
+func conflictKindAt(file *diff.FileChange, hunkIdx, lineIdx int) conflictKind {
+	if file == nil {
+		return conflictNone
+	}
+
+	region := file.ConflictAt(hunkIdx, lineIdx)
+
+	switch {
+	case region == nil:
+		return conflictNone
+	case region.IsMarker(lineIdx):
+		return conflictMarker
+	default:
+		return conflictSide
+	}
+}
+
+// apply recolors a line inside a conflict, so a conflict jj materialized into the file does not read
+// as an ordinary addition. Markers get a solid background so each side's start stands out, and the
+// sides keep whatever background the cursor gave them.
+func (k conflictKind) apply(style lipgloss.Style) lipgloss.Style {
+	switch k {
+	case conflictNone:
+	case conflictSide:
+		return style.Foreground(theme.ConflictLine)
+	case conflictMarker:
+		return style.Foreground(theme.ModalBg).Background(theme.ConflictMarker).Bold(true)
+	}
+
+	return style
+}
+
 func (m *Model) isLineInVisualRange(lineIdx int) bool {
 	if !m.isVisualMode {
 		return false
@@ -629,6 +710,16 @@ func (m *Model) renderHunkHeader(
 		suffix = " [X]"
 	}
 
+	if m.fileChange != nil && m.fileChange.HunkHasConflict(hunkIdx) {
+		suffix = " [conflict]" + suffix
+	}
+
+	if m.getApplicable != nil {
+		if status, ok := m.getApplicable(hunkIdx); ok {
+			suffix = " " + ApplicabilityMarker(status) + suffix
+		}
+	}
+
 	if m.getHunkTags != nil {
 		tags := m.getHunkTags(hunkIdx)
 		if len(tags) > 0 {
@@ -654,6 +745,22 @@ func (m *Model) renderHunkHeader(
 	return style.Render(truncateOrPad(displayText, width))
 }
 
+// ApplicabilityMarker is the hunk header marker for a pre-flight result: a hunk that applies where
+// its header says is clean, one that lands elsewhere or only with fuzz is offset, and one that does
+// not apply is conflicting.
+func ApplicabilityMarker(status diff.HunkStatus) string {
+	switch status {
+	case diff.HunkApplied:
+		return "[clean]"
+	case diff.HunkShifted:
+		return "[offset]"
+	case diff.HunkRejected:
+		return "[conflicting]"
+	default:
+		return ""
+	}
+}
+
 func truncateOrPad(text string, width int) string {
 	if width <= 0 {
 		return ""
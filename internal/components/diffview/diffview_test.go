@@ -86,6 +86,26 @@ func TestViewUnifiedMode(t *testing.T) {
 	}
 }
 
+func TestViewMarksConflictedHunk(t *testing.T) {
+	t.Parallel()
+
+	file := testFileChange()
+	file.Conflicts = []diff.ConflictRegion{{HunkIdx: 0, StartLine: 1, EndLine: 2, Markers: []int{1, 2}}}
+
+	m := diffview.New(config.DefaultConfig())
+	m.SetFileChange(file)
+
+	if output := m.View(80, 20, false); !strings.Contains(output, "[conflict]") {
+		t.Error("Expected the conflicted hunk header to be marked")
+	}
+
+	m.SetFileChange(testFileChange())
+
+	if output := m.View(80, 20, false); strings.Contains(output, "[conflict]") {
+		t.Error("Expected a clean hunk header to carry no conflict mark")
+	}
+}
+
 func TestViewSideBySideMode(t *testing.T) {
 	t.Parallel()
 
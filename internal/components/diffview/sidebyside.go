@@ -57,11 +57,13 @@ func (*SideBySideView) Render(file *diff.FileChange, ctx *RenderContext) string
 			hunkLines = diff.ProcessHunkHideWhitespace(hunk.Lines)
 		}
 
+		conflicts := conflictKindsByLine(file, hunkIdx, hunkLines)
+
 		for _, pair := range pairLines(hunkLines) {
 			if len(lines) >= ctx.Height {
 				break
 			}
-			lines = append(lines, renderPairedLine(pair, paneWidth, ctx))
+			lines = append(lines, renderPairedLine(pair, paneWidth, ctx, conflicts))
 		}
 	}
 
@@ -72,6 +74,23 @@ func (*SideBySideView) Render(file *diff.FileChange, ctx *RenderContext) string
 	return strings.Join(lines, "\n")
 }
 
+// sideBySideHeight counts the rows Render draws for file: the column headings, then each hunk's
+// header and paired lines.
+func sideBySideHeight(file *diff.FileChange, showWhitespace bool) int {
+	height := 1
+
+	for _, hunk := range file.Hunks {
+		hunkLines := hunk.Lines
+		if showWhitespace {
+			hunkLines = diff.ProcessHunkHideWhitespace(hunk.Lines)
+		}
+
+		height += 1 + len(pairLines(hunkLines))
+	}
+
+	return height
+}
+
 type linePair struct {
 	Left  *diff.Line
 	Right *diff.Line
@@ -131,9 +150,31 @@ func pairRuns(deletions, additions []diff.Line) []linePair {
 	return pairs
 }
 
-func renderPairedLine(pair linePair, paneWidth int, ctx *RenderContext) string {
-	leftContent := renderSinglePane(pair.Left, paneWidth, ctx, false)
-	rightContent := renderSinglePane(pair.Right, paneWidth, ctx, true)
+// conflictKindsByLine keys a hunk's conflict styling by line, because pairing reorders the lines and
+// loses their indices. A hunk without a conflict returns nil, which reads as no conflict anywhere.
+func conflictKindsByLine(file *diff.FileChange, hunkIdx int, hunkLines []diff.Line) map[*diff.Line]conflictKind {
+	if !file.HunkHasConflict(hunkIdx) {
+		return nil
+	}
+
+	kinds := make(map[*diff.Line]conflictKind)
+	for lineIdx := range hunkLines {
+		if kind := conflictKindAt(file, hunkIdx, lineIdx); kind != conflictNone {
+			kinds[&hunkLines[lineIdx]] = kind
+		}
+	}
+
+	return kinds
+}
+
+func renderPairedLine(
+	pair linePair,
+	paneWidth int,
+	ctx *RenderContext,
+	conflicts map[*diff.Line]conflictKind,
+) string {
+	leftContent := renderSinglePane(pair.Left, paneWidth, ctx, false, conflicts[pair.Left])
+	rightContent := renderSinglePane(pair.Right, paneWidth, ctx, true, conflicts[pair.Right])
 
 	return leftContent + " │ " + rightContent
 }
@@ -143,6 +184,7 @@ func renderSinglePane(
 	paneWidth int,
 	ctx *RenderContext,
 	isRight bool,
+	conflict conflictKind,
 ) string {
 	if line == nil {
 		return strings.Repeat(" ", max(paneWidth, 0))
@@ -174,7 +216,7 @@ func renderSinglePane(
 		style = style.Foreground(theme.DeletedLine)
 	}
 
-	return style.Render(truncateOrPad(text, paneWidth))
+	return conflict.apply(style).Render(truncateOrPad(text, paneWidth))
 }
 
 func renderSideBySideHunkHeader(text string, width int, isCurrent bool) string {
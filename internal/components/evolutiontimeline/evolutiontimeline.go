@@ -0,0 +1,182 @@
+// Package evolutiontimeline lists every evolution of one change, newest first, as jj evolog reports
+// them. The parent model loads the entries, routes keys here while the timeline is visible, and
+// reads the highlighted entry, and the marked one for an interdiff, back out when the user picks.
+package evolutiontimeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kyleking/jj-diff/internal/components/listmodal"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+// Widths, in terminal cells, of the indent the mark sits in and of the timestamp column.
+const (
+	rowIndentWidth = 2
+	timestampWidth = 16
+)
+
+// noMark is the marked index while no evolution is marked.
+const noMark = -1
+
+// Model is the timeline. Mutators take a pointer receiver, so a parent holding it by value must keep
+// the same field rather than a copy.
+type Model struct {
+	revision string
+	entries  []jj.EvologEntry
+	selected int
+	marked   int
+	visible  bool
+}
+
+// New returns a hidden timeline with no entries, so SetEntries must run before Show.
+func New() Model {
+	return Model{
+		entries: []jj.EvologEntry{},
+		marked:  noMark,
+	}
+}
+
+// SetEntries replaces the timeline with the evolutions of the change revision names, puts the cursor
+// back on the newest one, and clears the mark, because a different load's entries share nothing
+// with the last. The slice is retained, not copied.
+func (m *Model) SetEntries(revision string, entries []jj.EvologEntry) {
+	m.revision = revision
+	m.entries = entries
+	m.selected = 0
+	m.marked = noMark
+}
+
+// Revision returns the revset the entries were loaded for.
+func (m *Model) Revision() string {
+	return m.revision
+}
+
+// Show reveals the timeline. While it is visible the parent model routes every key here.
+func (m *Model) Show() {
+	m.visible = true
+}
+
+// Hide takes the timeline off screen and leaves the entries and cursor in place.
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// IsVisible reports whether keys belong to the timeline rather than the main view.
+func (m *Model) IsVisible() bool {
+	return m.visible
+}
+
+// MoveUp moves the cursor one evolution newer and stops at the newest, without wrapping.
+func (m *Model) MoveUp() {
+	if m.selected > 0 {
+		m.selected--
+	}
+}
+
+// MoveDown moves the cursor one evolution older and stops at the oldest, without wrapping.
+func (m *Model) MoveDown() {
+	if m.selected < len(m.entries)-1 {
+		m.selected++
+	}
+}
+
+// GetSelected returns the highlighted entry and its index, where index 0 is the change as it is now.
+// It returns nil and -1 when the timeline is empty.
+func (m Model) GetSelected() (*jj.EvologEntry, int) {
+	if m.selected >= 0 && m.selected < len(m.entries) {
+		return &m.entries[m.selected], m.selected
+	}
+
+	return nil, -1
+}
+
+// ToggleMark marks the highlighted evolution as one end of an interdiff, or clears the mark if it is
+// already there. Marking another evolution moves the mark rather than adding a second one.
+func (m *Model) ToggleMark() {
+	if m.selected < 0 || m.selected >= len(m.entries) {
+		return
+	}
+
+	if m.marked == m.selected {
+		m.marked = noMark
+	} else {
+		m.marked = m.selected
+	}
+}
+
+// GetInterdiffRange returns the marked and highlighted evolutions ordered oldest first, which is the
+// direction an interdiff reads in whichever of the two was marked first. It returns false until an
+// evolution other than the highlighted one is marked.
+func (m Model) GetInterdiffRange() (jj.EvologEntry, jj.EvologEntry, bool) {
+	if m.marked == noMark || m.marked == m.selected || m.marked >= len(m.entries) {
+		return jj.EvologEntry{}, jj.EvologEntry{}, false
+	}
+
+	older, newer := max(m.marked, m.selected), min(m.marked, m.selected)
+
+	return m.entries[older], m.entries[newer], true
+}
+
+// View centers the timeline in a terminal of the given cell dimensions. Entries are numbered from the
+// oldest, so the original version is [1] however many times the change was rewritten, and the marked
+// entry carries a leading asterisk. It returns an empty string while hidden.
+func (m Model) View(width, height int) string {
+	if !m.visible {
+		return ""
+	}
+
+	modalWidth, visibleRows := listmodal.DefaultSize.Fit(width, height)
+
+	lines := []string{
+		listmodal.Header("Evolution of "+m.revision, modalWidth),
+		"",
+	}
+
+	if len(m.entries) == 0 {
+		lines = append(lines, "  No evolutions found")
+	}
+
+	startIdx := listmodal.ScrollStart(m.selected, len(m.entries), visibleRows)
+	endIdx := min(startIdx+visibleRows, len(m.entries))
+
+	for i := startIdx; i < endIdx; i++ {
+		line := renderEntryLine(m.entries[i], len(m.entries)-i, modalWidth-rowIndentWidth)
+		if i == m.selected {
+			line = listmodal.Selected(line)
+		}
+
+		marker := "  "
+		if i == m.marked {
+			marker = "* "
+		}
+
+		lines = append(lines, marker+line)
+	}
+
+	lines = append(
+		lines,
+		"",
+		listmodal.Footer(
+			"Enter: View diff | Space: Mark | I: Interdiff with mark | Esc: Close | j/k: Navigate",
+			modalWidth,
+		),
+	)
+
+	return listmodal.Render(strings.Join(lines, "\n"), width, height)
+}
+
+func renderEntryLine(entry jj.EvologEntry, number, width int) string {
+	line := fmt.Sprintf(
+		"[%d] %-*s %s  %s: %s",
+		number,
+		timestampWidth,
+		entry.Timestamp,
+		jj.ShortCommitID(entry.CommitID),
+		entry.Operation,
+		entry.Description,
+	)
+
+	return listmodal.TruncateOrPad(line, width)
+}
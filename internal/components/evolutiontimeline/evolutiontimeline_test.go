@@ -0,0 +1,195 @@
+package evolutiontimeline_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/components/evolutiontimeline"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+func testEntries() []jj.EvologEntry {
+	return []jj.EvologEntry{
+		{CommitID: "3333333333333333", Operation: "describe commit", Description: "final"},
+		{CommitID: "2222222222222222", Operation: "snapshot working copy", Description: "fix typo"},
+		{CommitID: "1111111111111111", Operation: "new empty commit", Description: "initial"},
+	}
+}
+
+func loadedTimeline() evolutiontimeline.Model {
+	m := evolutiontimeline.New()
+	m.SetEntries("@", testEntries())
+	m.Show()
+
+	return m
+}
+
+func TestNewIsHiddenAndEmpty(t *testing.T) {
+	t.Parallel()
+
+	m := evolutiontimeline.New()
+
+	if m.IsVisible() {
+		t.Error("Expected a new timeline to be hidden")
+	}
+
+	if entry, idx := m.GetSelected(); entry != nil || idx != -1 {
+		t.Errorf("Expected no selection, got %+v at %d", entry, idx)
+	}
+
+	if view := m.View(120, 40); view != "" {
+		t.Errorf("Expected a hidden timeline to render nothing, got %q", view)
+	}
+}
+
+func TestMoveClampsAtEnds(t *testing.T) {
+	t.Parallel()
+
+	m := loadedTimeline()
+
+	m.MoveUp()
+	if _, idx := m.GetSelected(); idx != 0 {
+		t.Errorf("Expected MoveUp at the newest to stay at 0, got %d", idx)
+	}
+
+	for range 5 {
+		m.MoveDown()
+	}
+
+	entry, idx := m.GetSelected()
+	if idx != 2 || entry.CommitID != "1111111111111111" {
+		t.Errorf("Expected MoveDown to stop at the oldest, got %+v at %d", entry, idx)
+	}
+
+	m.MoveUp()
+	if _, idx := m.GetSelected(); idx != 1 {
+		t.Errorf("Expected MoveUp to step back to 1, got %d", idx)
+	}
+}
+
+func TestSetEntriesResetsCursorAndMark(t *testing.T) {
+	t.Parallel()
+
+	m := loadedTimeline()
+	m.MoveDown()
+	m.ToggleMark()
+	m.MoveDown()
+
+	m.SetEntries("xyz", testEntries())
+
+	if m.Revision() != "xyz" {
+		t.Errorf("Expected revision xyz, got %q", m.Revision())
+	}
+
+	if _, idx := m.GetSelected(); idx != 0 {
+		t.Errorf("Expected the cursor back on the newest, got %d", idx)
+	}
+
+	if _, _, ok := m.GetInterdiffRange(); ok {
+		t.Error("Expected new entries to clear the mark")
+	}
+}
+
+func TestInterdiffRange(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		older, newer string
+		mark, cursor int
+	}{
+		"marked newest": {mark: 0, cursor: 2, older: "1111111111111111", newer: "3333333333333333"},
+		"marked oldest": {mark: 2, cursor: 0, older: "1111111111111111", newer: "3333333333333333"},
+		"adjacent":      {mark: 1, cursor: 2, older: "1111111111111111", newer: "2222222222222222"},
+	}
+
+	for name, tt := range tests {
+		m := loadedTimeline()
+		for range tt.mark {
+			m.MoveDown()
+		}
+
+		m.ToggleMark()
+
+		for range 2 {
+			m.MoveUp()
+		}
+
+		for range tt.cursor {
+			m.MoveDown()
+		}
+
+		older, newer, ok := m.GetInterdiffRange()
+		if !ok {
+			t.Errorf("%s: expected a range", name)
+			continue
+		}
+
+		if older.CommitID != tt.older || newer.CommitID != tt.newer {
+			t.Errorf("%s: expected %s to %s, got %s to %s", name, tt.older, tt.newer, older.CommitID, newer.CommitID)
+		}
+	}
+}
+
+func TestToggleMark(t *testing.T) {
+	t.Parallel()
+
+	m := loadedTimeline()
+	m.ToggleMark()
+
+	if _, _, ok := m.GetInterdiffRange(); ok {
+		t.Error("Expected no range while the mark is under the cursor")
+	}
+
+	m.MoveDown()
+	if _, _, ok := m.GetInterdiffRange(); !ok {
+		t.Error("Expected a range once the cursor leaves the mark")
+	}
+
+	m.MoveUp()
+	m.ToggleMark()
+	m.MoveDown()
+
+	if _, _, ok := m.GetInterdiffRange(); ok {
+		t.Error("Expected a second toggle to clear the mark")
+	}
+
+	empty := evolutiontimeline.New()
+	empty.ToggleMark()
+
+	if _, _, ok := empty.GetInterdiffRange(); ok {
+		t.Error("Expected an empty timeline to ignore the mark")
+	}
+}
+
+func TestViewNumbersFromOldest(t *testing.T) {
+	t.Parallel()
+
+	m := loadedTimeline()
+	m.ToggleMark()
+
+	view := m.View(120, 40)
+
+	for _, want := range []string{"Evolution of @", "[3]", "[1]", "* ", "111111111111", "fix typo"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q", want)
+		}
+	}
+
+	newest := strings.Index(view, "[3]")
+	oldest := strings.Index(view, "[1]")
+
+	if newest > oldest {
+		t.Error("Expected the newest evolution, numbered highest, to come first")
+	}
+}
+
+func TestViewWithoutEntries(t *testing.T) {
+	t.Parallel()
+
+	m := evolutiontimeline.New()
+	m.Show()
+
+	if view := m.View(120, 40); !strings.Contains(view, "No evolutions found") {
+		t.Errorf("Expected the empty message, got %q", view)
+	}
+}
@@ -32,14 +32,15 @@ type MatchRange struct {
 // Model is the file list's state. Every mutator takes a pointer receiver, so a caller holding a
 // value must copy the result back into its own state.
 type Model struct {
-	getMatches   func(fileIdx int) []MatchRange
-	filterQuery  string
-	files        []diff.FileChange
-	selected     int
-	scrollOffset int
-	isSearching  bool
-	expanded     bool
-	filterMode   bool
+	getMatches    func(fileIdx int) []MatchRange
+	getApplicable func(fileIdx int) (diff.HunkStatus, bool)
+	filterQuery   string
+	files         []diff.FileChange
+	selected      int
+	scrollOffset  int
+	isSearching   bool
+	expanded      bool
+	filterMode    bool
 }
 
 // New returns a collapsed, unfiltered list with no files.
@@ -107,6 +108,13 @@ func (m *Model) SetSearchState(isSearching bool, getMatches func(fileIdx int) []
 	m.getMatches = getMatches
 }
 
+// SetApplicability installs the lookup for how each file would apply to the move destination, as
+// the worst status among its hunks. The callback reports false for a file that has not been checked,
+// and a nil callback draws no markers.
+func (m *Model) SetApplicability(getApplicable func(fileIdx int) (diff.HunkStatus, bool)) {
+	m.getApplicable = getApplicable
+}
+
 // View renders the list at the given size, using only one row when collapsed regardless of height.
 func (m Model) View(width, height int, focused bool) string {
 	if len(m.files) == 0 {
@@ -138,6 +146,10 @@ func (m Model) renderCollapsed(width int, focused bool) string {
 	counts := countChanges(file.Hunks)
 	stats := fmt.Sprintf("+%d -%d", counts.additions, counts.deletions)
 
+	if word := m.applicabilityWord(m.selected); word != "" {
+		stats += " (" + word + ")"
+	}
+
 	// Format: [M] path/to/file.go +10 -5 [3/10]
 	// Match diff header styling: Primary color, bold
 	line := fmt.Sprintf("[%s] %s %s%s", changeType, path, stats, counter)
@@ -264,10 +276,15 @@ func (m Model) renderRow(originalIdx, pathColWidth, width int, focused bool) str
 		path = path[:pathColWidth-len(ellipsis)] + ellipsis
 	}
 
+	changeType := file.ChangeType.String()
+	if glyph := m.applicabilityGlyph(originalIdx); glyph != "" {
+		changeType += " " + glyph
+	}
+
 	line := fmt.Sprintf(
 		"%-*s  %-*s  %*s",
 		typeColWidth,
-		file.ChangeType.String(),
+		changeType,
 		pathColWidth,
 		path,
 		statsColWidth,
@@ -288,6 +305,55 @@ func (m Model) renderRow(originalIdx, pathColWidth, width int, focused bool) str
 	return styled + strings.Repeat(" ", max(width-len(line), 0))
 }
 
+// applicabilityGlyph is the one-character pre-flight marker drawn after the change type: = for a
+// file whose hunks all apply cleanly, ~ for one where a hunk lands at an offset, and ! for one where
+// a hunk conflicts. A file that has not been checked gets none.
+func (m Model) applicabilityGlyph(fileIdx int) string {
+	if m.getApplicable == nil {
+		return ""
+	}
+
+	status, ok := m.getApplicable(fileIdx)
+	if !ok {
+		return ""
+	}
+
+	switch status {
+	case diff.HunkApplied:
+		return "="
+	case diff.HunkShifted:
+		return "~"
+	case diff.HunkRejected:
+		return "!"
+	default:
+		return ""
+	}
+}
+
+// applicabilityWord spells out the pre-flight marker for the collapsed one-line view, which has room
+// for it.
+func (m Model) applicabilityWord(fileIdx int) string {
+	if m.getApplicable == nil {
+		return ""
+	}
+
+	status, ok := m.getApplicable(fileIdx)
+	if !ok {
+		return ""
+	}
+
+	switch status {
+	case diff.HunkApplied:
+		return "clean"
+	case diff.HunkShifted:
+		return "offset"
+	case diff.HunkRejected:
+		return "conflicting"
+	default:
+		return ""
+	}
+}
+
 type changeCounts struct {
 	additions int
 	deletions int
@@ -1,12 +1,14 @@
-// Package help renders the keybinding overlay. The bindings are written out here by hand, so adding a
-// key to the model means adding its row here too.
+// Package help renders the keybinding overlay. Every row is generated from the session's keymap, so
+// a key rebound in the config is listed under its new key.
 package help
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/kyleking/jj-diff/internal/keymap"
 	"github.com/kyleking/jj-diff/internal/theme"
 )
 
@@ -29,6 +31,7 @@ const (
 // Model is the overlay's state. The mode selects which mode-specific bindings are listed and must
 // match the mode strings the parent passes to Show.
 type Model struct {
+	keys    keymap.Keymap
 	mode    string
 	visible bool
 }
@@ -40,11 +43,12 @@ func New() Model {
 	}
 }
 
-// Show opens the overlay for a mode, where "Diff-Editor" and "Interactive" each add their own
-// bindings and any other value lists only the shared ones.
-func (m *Model) Show(mode string) {
+// Show opens the overlay for a mode, listing keys' bindings. "Diff-Editor" and "Interactive" each add
+// their own bindings and any other value lists only the shared ones.
+func (m *Model) Show(mode string, keys keymap.Keymap) {
 	m.visible = true
 	m.mode = mode
+	m.keys = keys
 }
 
 // Hide closes the overlay.
@@ -65,105 +69,157 @@ func (m Model) View(width, height int) string {
 	}
 
 	modalWidth := min(preferredModalWidth, width-modalWidthMargin)
+	rows := rows{keys: m.keys, width: modalWidth}
 
 	lines := make([]string, 0, estimatedLineCount)
 	lines = append(lines, styleHeader("Keybindings", modalWidth), "")
-	lines = append(lines, navigationSection(modalWidth)...)
-	lines = append(lines, actionSection(m.mode, modalWidth)...)
-	lines = append(lines, viewOptionSection(modalWidth)...)
-	lines = append(lines, globalSection(m.mode, modalWidth)...)
-	lines = append(lines, interactiveGuideSection(m.mode, modalWidth)...)
-	lines = append(lines, styleFooter("Press ? or Esc to close", modalWidth))
+	lines = append(lines, rows.navigationSection()...)
+	lines = append(lines, rows.actionSection(m.mode)...)
+	lines = append(lines, rows.viewOptionSection()...)
+	lines = append(lines, rows.globalSection(m.mode)...)
+	lines = append(lines, rows.interactiveGuideSection(m.mode)...)
+	lines = append(lines, styleFooter(fmt.Sprintf("Press %s or Esc to close", m.keys.Key(keymap.Help)), modalWidth))
 
 	content := strings.Join(lines, "\n")
 
 	return renderModal(content, width, height)
 }
 
-func navigationSection(width int) []string {
+// rows renders the overlay's lines for one keymap and width.
+type rows struct {
+	keys  keymap.Keymap
+	width int
+}
+
+// action is the row for one action, with the keymap's description.
+func (r rows) action(action keymap.Action) string {
+	return keyBinding(r.keys.Keys(action), r.keys.Description(action), r.width)
+}
+
+// described is the row for one action with a description of its own, where the mode changes what the
+// action does.
+func (r rows) described(action keymap.Action, description string) string {
+	return keyBinding(r.keys.Keys(action), description, r.width)
+}
+
+// pair is one row for two opposite actions, such as down and up.
+func (r rows) pair(first, second keymap.Action, description string) string {
+	return keyBinding(r.keys.Keys(first)+", "+r.keys.Keys(second), description, r.width)
+}
+
+func (r rows) navigationSection() []string {
+	conflicts := fmt.Sprintf("%sx, %sx", r.keys.Key(keymap.PrevFile), r.keys.Key(keymap.NextFile))
+
 	return []string{
-		styleSection("Navigation", width),
-		keyBinding("j/k or ↓/↑", "Move down/up", width),
-		keyBinding("Ctrl-d/Ctrl-u", "Half-page down/up", width),
-		keyBinding("Ctrl-f/Ctrl-b", "Full-page down/up", width),
-		keyBinding("g", "Go to first file/hunk", width),
-		keyBinding("G", "Go to last file/hunk", width),
-		keyBinding("n", "Next hunk (when in diff view)", width),
-		keyBinding("N/p", "Previous hunk (when in diff view)", width),
-		keyBinding("[/]", "Previous/next file (when in diff view)", width),
-		keyBinding("Tab", "Switch focus (file list ↔ diff view)", width),
+		styleSection("Navigation", r.width),
+		r.pair(keymap.Down, keymap.Up, "Move down/up"),
+		r.pair(keymap.HalfPageDown, keymap.HalfPageUp, "Half-page down/up"),
+		r.pair(keymap.PageDown, keymap.PageUp, "Full-page down/up"),
+		r.action(keymap.FirstFile),
+		r.action(keymap.LastFile),
+		r.action(keymap.NextHunk),
+		r.action(keymap.PrevMatch),
+		r.action(keymap.PrevHunk),
+		r.pair(keymap.PrevFile, keymap.NextFile, "Previous/next file (when in diff view)"),
+		keyBinding(conflicts, "Previous/next jj conflict", r.width),
+		r.action(keymap.Focus),
 		"",
 	}
 }
 
-func actionSection(mode string, width int) []string {
+func (r rows) actionSection(mode string) []string {
 	lines := []string{
-		styleSection("Actions", width),
-		keyBinding("r", "Refresh diff from jj", width),
-		keyBinding("/", "Search in files and diff content", width),
-		keyBinding("f", "Filter files (type to search)", width),
+		styleSection("Actions", r.width),
+		r.action(keymap.Refresh),
+		r.action(keymap.Search),
+		r.action(keymap.Filter),
+		r.action(keymap.CommandLine),
 	}
 
 	if mode == modeDiffEditor {
 		lines = append(lines,
-			keyBinding("Space", "Keep or drop the current hunk", width),
-			keyBinding("a", "Apply and return to jj", width),
+			r.described(keymap.Toggle, "Keep or drop the current hunk"),
+			r.described(keymap.Apply, "Apply and return to jj"),
+		)
+	} else {
+		lines = append(lines,
+			r.action(keymap.Evolution),
+			r.action(keymap.Conflicts),
+			r.action(keymap.Smartlog),
+			r.action(keymap.OpLog),
+			r.action(keymap.Undo),
+			r.action(keymap.Redo),
 		)
 	}
 
 	return append(lines, "")
 }
 
-func viewOptionSection(width int) []string {
+func (r rows) viewOptionSection() []string {
 	return []string{
-		styleSection("View Options", width),
-		keyBinding("w", "Hide whitespace-only changes", width),
-		keyBinding("W", "Toggle word-level diff highlighting", width),
-		keyBinding("s", "Toggle side-by-side view", width),
-		keyBinding("l", "Toggle line numbers", width),
+		styleSection("View Options", r.width),
+		r.action(keymap.Whitespace),
+		r.action(keymap.WordDiff),
+		r.action(keymap.SideBySide),
+		r.action(keymap.LineNumbers),
+		r.pair(keymap.ExpandUp, keymap.ExpandDown, "Reveal more context above/below the hunk"),
+		r.action(keymap.ExpandGap),
+		r.pair(keymap.SplitHunk, keymap.JoinHunk, "Split the hunk at unchanged lines/join it back"),
 		"",
 	}
 }
 
-func globalSection(mode string, width int) []string {
+func (r rows) globalSection(mode string) []string {
 	var lines []string
 	if mode == modeInteractive {
 		lines = append(lines,
-			keyBinding("d", "Select destination revision", width),
-			keyBinding("Space", "Toggle hunk selection", width),
-			keyBinding("v", "Enter visual mode (line selection)", width),
-			keyBinding("j/k in visual", "Extend/contract line selection", width),
-			keyBinding("Space in visual", "Confirm line selection", width),
-			keyBinding("Esc", "Exit visual mode", width),
-			keyBinding("a", "Apply selected changes to destination", width),
+			r.action(keymap.Destination),
+			r.action(keymap.Toggle),
+			r.action(keymap.Visual),
+			r.pair(keymap.Down, keymap.Up, "Extend/contract line selection in visual mode"),
+			r.described(keymap.Toggle, "Confirm line selection in visual mode"),
+			keyBinding("Esc", "Exit visual mode", r.width),
+			r.action(keymap.Apply),
+			r.action(keymap.MultiSplit),
 		)
 	}
 
 	return append(lines,
-		keyBinding("?", "Toggle this help", width),
-		keyBinding("q or Ctrl-C", "Quit", width),
+		r.action(keymap.Cancel),
+		r.action(keymap.Help),
+		r.action(keymap.Quit),
 		"",
 	)
 }
 
-func interactiveGuideSection(mode string, width int) []string {
+func (r rows) interactiveGuideSection(mode string) []string {
 	if mode != modeInteractive {
 		return nil
 	}
 
 	return []string{
-		styleSection("Interactive Mode", width),
-		wrapText("1. Press 'd' to select a destination revision", width),
-		wrapText("2. Navigate to hunks with 'n'/'p'", width),
-		wrapText("3. Press Space to select whole hunks", width),
-		wrapText("4. Press 'v' for line-level selection (visual mode)", width),
-		wrapText("   - Use j/k to extend selection range", width),
-		wrapText("   - Press Space to confirm selection", width),
-		wrapText("5. Press 'a' to apply selected hunks/lines", width),
+		styleSection("Interactive Mode", r.width),
+		r.guide("1. Press %s to select a destination revision", keymap.Destination),
+		r.guide("2. Navigate to hunks with %s/%s", keymap.NextHunk, keymap.PrevHunk),
+		r.guide("3. Press %s to select whole hunks", keymap.Toggle),
+		r.guide("4. Press %s for line-level selection (visual mode)", keymap.Visual),
+		r.guide("   - Use %s/%s to extend selection range", keymap.Down, keymap.Up),
+		r.guide("   - Press %s to confirm selection", keymap.Toggle),
+		r.guide("5. Press %s to apply selected hunks/lines", keymap.Apply),
 		"",
 	}
 }
 
+// guide is a wrapped line of the interactive guide, with each action's first key filled into format.
+func (r rows) guide(format string, actions ...keymap.Action) string {
+	keys := make([]any, len(actions))
+	for i, action := range actions {
+		keys[i] = r.keys.Key(action)
+	}
+
+	return wrapText(fmt.Sprintf(format, keys...), r.width)
+}
+
 func styleHeader(text string, width int) string {
 	style := lipgloss.NewStyle().
 		Bold(true).
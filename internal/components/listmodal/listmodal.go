@@ -0,0 +1,116 @@
+// Package listmodal lays out and styles a centered modal around a scrolling list of one-line rows.
+// Each list keeps its own rows and keys and draws them in the frame this package sizes.
+package listmodal
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyleking/jj-diff/internal/theme"
+)
+
+// Layout of the modal, in terminal cells. The modal shrinks with the terminal down to the minimums,
+// and three rows are chrome (the header, the blank line under it, and the footer).
+const (
+	chromeHeight = 3
+	halfDivisor  = 2
+	heightMargin = 4
+	minHeight    = 5
+	minWidth     = 40
+	paddingX     = 2
+	paddingY     = 1
+	widthMargin  = 10
+)
+
+// Size is how far a modal grows with the terminal, in terminal cells.
+type Size struct {
+	MaxWidth  int
+	MaxHeight int
+}
+
+// DefaultSize suits a list of one-line entries.
+var DefaultSize = Size{MaxWidth: 100, MaxHeight: 24}
+
+// Fit returns the modal's content width and how many list rows fit under its chrome, in a terminal
+// of the given cell dimensions.
+func (s Size) Fit(width, height int) (int, int) {
+	modalHeight := clamp(height-heightMargin, minHeight, s.MaxHeight)
+
+	return clamp(width-widthMargin, minWidth, s.MaxWidth), modalHeight - chromeHeight
+}
+
+// ScrollStart returns the first of count rows to draw in a window of rows, keeping the selected row
+// centered until the window reaches either end of the list.
+func ScrollStart(selected, count, rows int) int {
+	if count <= rows {
+		return 0
+	}
+
+	centered := max(selected-rows/halfDivisor, 0)
+
+	return min(centered, count-rows)
+}
+
+// Header renders a modal's title centered across width.
+func Header(text string, width int) string {
+	style := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.Primary).
+		Width(width).
+		Align(lipgloss.Center)
+
+	return style.Render(text)
+}
+
+// Footer renders a modal's key hints centered across width.
+func Footer(text string, width int) string {
+	style := lipgloss.NewStyle().
+		Foreground(theme.SoftMutedBg).
+		Width(width).
+		Align(lipgloss.Center)
+
+	return style.Render(text)
+}
+
+// Selected highlights the row under the cursor.
+func Selected(text string) string {
+	return lipgloss.NewStyle().
+		Background(theme.SelectedBg).
+		Foreground(theme.Text).
+		Render(text)
+}
+
+// TruncateOrPad fits a row to width, cutting it with an ellipsis or padding it with spaces, so the
+// highlight on a selected row spans the modal.
+func TruncateOrPad(text string, width int) string {
+	if lipgloss.Width(text) > width {
+		runes := []rune(text)
+
+		return string(runes[:min(max(width-3, 0), len(runes))]) + "..."
+	}
+
+	return text + strings.Repeat(" ", width-lipgloss.Width(text))
+}
+
+// Render borders content and centers it in a terminal of the given cell dimensions.
+func Render(content string, termWidth, termHeight int) string {
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Primary).
+		Padding(paddingY, paddingX)
+
+	modal := borderStyle.Render(content)
+
+	return lipgloss.Place(
+		termWidth,
+		termHeight,
+		lipgloss.Center,
+		lipgloss.Center,
+		modal,
+	)
+}
+
+func clamp(value, lower, upper int) int {
+	return min(max(value, lower), upper)
+}
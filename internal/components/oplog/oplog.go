@@ -0,0 +1,138 @@
+// Package oplog lists the repository's recent jj operations, newest first, as jj op log reports
+// them. The parent model loads the entries, routes keys here while the list is visible, and reads the
+// highlighted operation back out when the user restores to it.
+package oplog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kyleking/jj-diff/internal/components/listmodal"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+// Widths of the row's columns, in terminal cells, and the indent the current operation's @ sits in.
+const (
+	rowIndentWidth = 2
+	timeWidth      = 16
+	userWidth      = 20
+)
+
+// Model is the operation list. Mutators take a pointer receiver, so a parent holding it by value must
+// keep the same field rather than a copy.
+type Model struct {
+	entries  []jj.OpLogEntry
+	selected int
+	visible  bool
+}
+
+// New returns a hidden list with no entries, so SetEntries must run before Show.
+func New() Model {
+	return Model{
+		entries: []jj.OpLogEntry{},
+	}
+}
+
+// SetEntries replaces the list and puts the cursor back on the current operation, because any
+// operation since the last load has shifted every index. The slice is retained, not copied.
+func (m *Model) SetEntries(entries []jj.OpLogEntry) {
+	m.entries = entries
+	m.selected = 0
+}
+
+// Show reveals the list. While it is visible the parent model routes every key here.
+func (m *Model) Show() {
+	m.visible = true
+}
+
+// Hide takes the list off screen and leaves the entries and cursor in place.
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// IsVisible reports whether keys belong to the list rather than the main view.
+func (m *Model) IsVisible() bool {
+	return m.visible
+}
+
+// MoveUp moves the cursor one operation newer and stops at the current one, without wrapping.
+func (m *Model) MoveUp() {
+	if m.selected > 0 {
+		m.selected--
+	}
+}
+
+// MoveDown moves the cursor one operation older and stops at the oldest loaded, without wrapping.
+func (m *Model) MoveDown() {
+	if m.selected < len(m.entries)-1 {
+		m.selected++
+	}
+}
+
+// GetSelected returns the highlighted operation and its index, where index 0 is the current
+// operation. It returns nil and -1 when the list is empty.
+func (m Model) GetSelected() (*jj.OpLogEntry, int) {
+	if m.selected >= 0 && m.selected < len(m.entries) {
+		return &m.entries[m.selected], m.selected
+	}
+
+	return nil, -1
+}
+
+// View centers the list in a terminal of the given cell dimensions, with an @ beside the current
+// operation as jj op log draws it. It returns an empty string while hidden.
+func (m Model) View(width, height int) string {
+	if !m.visible {
+		return ""
+	}
+
+	modalWidth, visibleRows := listmodal.DefaultSize.Fit(width, height)
+
+	lines := []string{
+		listmodal.Header("Operation Log", modalWidth),
+		"",
+	}
+
+	if len(m.entries) == 0 {
+		lines = append(lines, "  No operations found")
+	}
+
+	startIdx := listmodal.ScrollStart(m.selected, len(m.entries), visibleRows)
+	endIdx := min(startIdx+visibleRows, len(m.entries))
+
+	for i := startIdx; i < endIdx; i++ {
+		line := renderEntryLine(m.entries[i], modalWidth-rowIndentWidth)
+		if i == m.selected {
+			line = listmodal.Selected(line)
+		}
+
+		marker := "  "
+		if i == 0 {
+			marker = "@ "
+		}
+
+		lines = append(lines, marker+line)
+	}
+
+	lines = append(
+		lines,
+		"",
+		listmodal.Footer("Enter: Restore | u: Undo | Ctrl-R: Redo | Esc: Close | j/k: Navigate", modalWidth),
+	)
+
+	return listmodal.Render(strings.Join(lines, "\n"), width, height)
+}
+
+func renderEntryLine(entry jj.OpLogEntry, width int) string {
+	line := fmt.Sprintf(
+		"%s  %-*s %-*s %s",
+		jj.ShortOperationID(entry.ID),
+		timeWidth,
+		entry.Time,
+		userWidth,
+		entry.User,
+		entry.Description,
+	)
+
+	return listmodal.TruncateOrPad(line, width)
+}
@@ -0,0 +1,121 @@
+package oplog_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/components/oplog"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+func loadedLog() oplog.Model {
+	m := oplog.New()
+	m.SetEntries([]jj.OpLogEntry{
+		{ID: "cccccccccccc", Description: "squash commits", Time: "1 minute ago", User: "kyle@laptop"},
+		{ID: "bbbbbbbbbbbb", Description: "snapshot working copy", Time: "2 minutes ago", User: "kyle@laptop"},
+		{ID: "aaaaaaaaaaaa", Description: "add workspace 'default'", Time: "1 year ago", User: "kyle@laptop"},
+	})
+	m.Show()
+
+	return m
+}
+
+func TestNewIsHiddenAndEmpty(t *testing.T) {
+	t.Parallel()
+
+	m := oplog.New()
+
+	if m.IsVisible() {
+		t.Error("Expected a new op log to be hidden")
+	}
+
+	if entry, idx := m.GetSelected(); entry != nil || idx != -1 {
+		t.Errorf("Expected no selection, got %+v at %d", entry, idx)
+	}
+
+	if view := m.View(120, 40); view != "" {
+		t.Errorf("Expected a hidden op log to render nothing, got %q", view)
+	}
+}
+
+func TestMoveClampsAtEnds(t *testing.T) {
+	t.Parallel()
+
+	m := loadedLog()
+
+	m.MoveUp()
+	if _, idx := m.GetSelected(); idx != 0 {
+		t.Errorf("Expected MoveUp on the current operation to stay at 0, got %d", idx)
+	}
+
+	for range 5 {
+		m.MoveDown()
+	}
+
+	entry, idx := m.GetSelected()
+	if idx != 2 || entry.ID != "aaaaaaaaaaaa" {
+		t.Errorf("Expected MoveDown to stop at the oldest, got %+v at %d", entry, idx)
+	}
+
+	m.MoveUp()
+	if _, idx := m.GetSelected(); idx != 1 {
+		t.Errorf("Expected MoveUp to step back to 1, got %d", idx)
+	}
+}
+
+func TestSetEntriesResetsCursor(t *testing.T) {
+	t.Parallel()
+
+	m := loadedLog()
+	m.MoveDown()
+	m.MoveDown()
+
+	m.SetEntries([]jj.OpLogEntry{{ID: "dddddddddddd"}, {ID: "cccccccccccc"}})
+
+	if entry, idx := m.GetSelected(); idx != 0 || entry.ID != "dddddddddddd" {
+		t.Errorf("Expected the cursor back on the current operation, got %+v at %d", entry, idx)
+	}
+}
+
+func TestHideKeepsCursor(t *testing.T) {
+	t.Parallel()
+
+	m := loadedLog()
+	m.MoveDown()
+	m.Hide()
+
+	if m.IsVisible() {
+		t.Error("Expected Hide to hide the op log")
+	}
+
+	if _, idx := m.GetSelected(); idx != 1 {
+		t.Errorf("Expected the cursor to survive Hide, got %d", idx)
+	}
+}
+
+func TestViewMarksCurrentOperation(t *testing.T) {
+	t.Parallel()
+
+	view := loadedLog().View(120, 40)
+
+	for _, want := range []string{"Operation Log", "@ ", "squash commits", "2 minutes ago", "Ctrl-R: Redo"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q", want)
+		}
+	}
+
+	if strings.Count(view, "@ ") != 1 {
+		t.Errorf("Expected only the current operation to carry @, got %d", strings.Count(view, "@ "))
+	}
+}
+
+func TestViewWithoutEntries(t *testing.T) {
+	t.Parallel()
+
+	m := oplog.New()
+	m.Show()
+
+	if view := m.View(120, 40); !strings.Contains(view, "No operations found") {
+		t.Errorf("Expected the empty message, got %q", view)
+	}
+}
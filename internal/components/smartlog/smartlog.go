@@ -0,0 +1,170 @@
+// Package smartlog draws jj log's change graph with each revision selectable, so the user can see
+// where a change sits in the stack before acting on it. The parent model loads the graph, routes keys
+// here while the view is visible, and reads the highlighted revision back out for each action.
+package smartlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kyleking/jj-diff/internal/components/listmodal"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+// modalSize is larger than a list's, since the graph is wide and deep.
+var modalSize = listmodal.Size{MaxWidth: 120, MaxHeight: 40}
+
+// noDescription is what jj log prints for a revision with an empty description.
+const noDescription = "(no description set)"
+
+// Model is the smartlog. The cursor is an index into the graph's lines that always rests on a line
+// carrying a revision, so the edge lines between them are drawn but never selected. Mutators take a
+// pointer receiver, so a parent holding it by value must keep the same field rather than a copy.
+type Model struct {
+	lines    []jj.LogLine
+	selected int
+	visible  bool
+}
+
+// New returns a hidden view with no graph, so SetLines must run before Show.
+func New() Model {
+	return Model{
+		lines: []jj.LogLine{},
+	}
+}
+
+// SetLines replaces the graph and puts the cursor on the working copy, or on the first revision when
+// the working copy is outside the log revset. The slice is retained, not copied.
+func (m *Model) SetLines(lines []jj.LogLine) {
+	m.lines = lines
+	m.selected = -1
+
+	for i, line := range lines {
+		if line.Entry == nil {
+			continue
+		}
+
+		if m.selected < 0 {
+			m.selected = i
+		}
+
+		if line.Entry.IsWorkingCopy {
+			m.selected = i
+			break
+		}
+	}
+}
+
+// Show reveals the view. While it is visible the parent model routes every key here.
+func (m *Model) Show() {
+	m.visible = true
+}
+
+// Hide takes the view off screen and leaves the graph and cursor in place.
+func (m *Model) Hide() {
+	m.visible = false
+}
+
+// IsVisible reports whether keys belong to the smartlog rather than the main view.
+func (m *Model) IsVisible() bool {
+	return m.visible
+}
+
+// MoveUp moves the cursor to the revision drawn above, skipping edge lines, and stops at the first.
+func (m *Model) MoveUp() {
+	for i := m.selected - 1; i >= 0; i-- {
+		if m.lines[i].Entry != nil {
+			m.selected = i
+			return
+		}
+	}
+}
+
+// MoveDown moves the cursor to the revision drawn below, skipping edge lines, and stops at the last.
+func (m *Model) MoveDown() {
+	for i := m.selected + 1; i < len(m.lines); i++ {
+		if m.lines[i].Entry != nil {
+			m.selected = i
+			return
+		}
+	}
+}
+
+// GetSelected returns the highlighted revision, or nil when the graph has none.
+func (m Model) GetSelected() *jj.LogEntry {
+	if m.selected >= 0 && m.selected < len(m.lines) {
+		return m.lines[m.selected].Entry
+	}
+
+	return nil
+}
+
+// View centers the graph in a terminal of the given cell dimensions. It returns an empty string while
+// hidden.
+func (m Model) View(width, height int) string {
+	if !m.visible {
+		return ""
+	}
+
+	modalWidth, visibleRows := modalSize.Fit(width, height)
+
+	lines := []string{
+		listmodal.Header("Smartlog", modalWidth),
+		"",
+	}
+
+	if m.GetSelected() == nil {
+		lines = append(lines, "  No revisions found")
+	}
+
+	startIdx := listmodal.ScrollStart(m.selected, len(m.lines), visibleRows)
+	endIdx := min(startIdx+visibleRows, len(m.lines))
+
+	for i := startIdx; i < endIdx; i++ {
+		line := renderLine(m.lines[i], modalWidth)
+		if i == m.selected {
+			line = listmodal.Selected(line)
+		}
+
+		lines = append(lines, line)
+	}
+
+	lines = append(
+		lines,
+		"",
+		listmodal.Footer(
+			"Enter: View diff | n: New | e: Edit | d: Destination | Esc: Close | j/k: Navigate",
+			modalWidth,
+		),
+	)
+
+	return listmodal.Render(strings.Join(lines, "\n"), width, height)
+}
+
+// renderLine lays a revision out the way jj log's compact template does: change ID, author, and
+// bookmarks, then the description.
+func renderLine(line jj.LogLine, width int) string {
+	if line.Entry == nil {
+		return listmodal.TruncateOrPad(line.Graph, width)
+	}
+
+	entry := line.Entry
+	fields := []string{entry.ChangeID, entry.Author}
+
+	if len(entry.Bookmarks) > 0 {
+		fields = append(fields, strings.Join(entry.Bookmarks, " "))
+	}
+
+	description := entry.Description
+	if description == "" {
+		description = noDescription
+	}
+
+	if entry.Empty {
+		description = "(empty) " + description
+	}
+
+	text := fmt.Sprintf("%s  %s  %s", line.Graph, strings.Join(fields, " "), description)
+
+	return listmodal.TruncateOrPad(text, width)
+}
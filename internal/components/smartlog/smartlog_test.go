@@ -0,0 +1,153 @@
+package smartlog_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/components/smartlog"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+// testLines is a graph with a child above the working copy, a bookmarked parent below it, and edge
+// lines between each.
+func testLines() []jj.LogLine {
+	return []jj.LogLine{
+		{Graph: "○", Entry: &jj.LogEntry{ChangeID: "cccccccc", Author: "Kyle King", Description: "child"}},
+		{Graph: "│"},
+		{Graph: "@", Entry: &jj.LogEntry{ChangeID: "wwwwwwww", Author: "Kyle King", IsWorkingCopy: true, Empty: true}},
+		{Graph: "│"},
+		{Graph: "○", Entry: &jj.LogEntry{
+			ChangeID:  "pppppppp",
+			Author:    "Kyle King",
+			Bookmarks: []string{"main", "feature"},
+		}},
+		{Graph: "~"},
+	}
+}
+
+func loadedSmartlog() smartlog.Model {
+	m := smartlog.New()
+	m.SetLines(testLines())
+	m.Show()
+
+	return m
+}
+
+func TestNewIsHiddenAndEmpty(t *testing.T) {
+	t.Parallel()
+
+	m := smartlog.New()
+
+	if m.IsVisible() {
+		t.Error("Expected a new smartlog to be hidden")
+	}
+
+	if entry := m.GetSelected(); entry != nil {
+		t.Errorf("Expected no selection, got %+v", entry)
+	}
+
+	if view := m.View(120, 40); view != "" {
+		t.Errorf("Expected a hidden smartlog to render nothing, got %q", view)
+	}
+}
+
+func TestSetLinesSelectsWorkingCopy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		lines []jj.LogLine
+		want  string
+	}{
+		"working copy below another revision": {lines: testLines(), want: "wwwwwwww"},
+		"working copy outside the revset": {
+			lines: []jj.LogLine{{Graph: "~"}, {Graph: "○", Entry: &jj.LogEntry{ChangeID: "pppppppp"}}},
+			want:  "pppppppp",
+		},
+		"only edges": {lines: []jj.LogLine{{Graph: "~"}}},
+	}
+
+	for name, tt := range tests {
+		m := smartlog.New()
+		m.SetLines(tt.lines)
+
+		got := ""
+		if entry := m.GetSelected(); entry != nil {
+			got = entry.ChangeID
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: expected %q selected, got %q", name, tt.want, got)
+		}
+	}
+}
+
+func TestMoveSkipsEdgesAndClamps(t *testing.T) {
+	t.Parallel()
+
+	m := loadedSmartlog()
+
+	m.MoveDown()
+	if entry := m.GetSelected(); entry.ChangeID != "pppppppp" {
+		t.Errorf("Expected MoveDown to skip the edge to the parent, got %s", entry.ChangeID)
+	}
+
+	m.MoveDown()
+	if entry := m.GetSelected(); entry.ChangeID != "pppppppp" {
+		t.Errorf("Expected MoveDown to stop at the last revision, got %s", entry.ChangeID)
+	}
+
+	m.MoveUp()
+	m.MoveUp()
+	if entry := m.GetSelected(); entry.ChangeID != "cccccccc" {
+		t.Errorf("Expected MoveUp to reach the first revision, got %s", entry.ChangeID)
+	}
+
+	m.MoveUp()
+	if entry := m.GetSelected(); entry.ChangeID != "cccccccc" {
+		t.Errorf("Expected MoveUp to stop at the first revision, got %s", entry.ChangeID)
+	}
+}
+
+func TestMoveWithoutRevisions(t *testing.T) {
+	t.Parallel()
+
+	m := smartlog.New()
+	m.SetLines([]jj.LogLine{{Graph: "~"}})
+	m.MoveDown()
+	m.MoveUp()
+
+	if entry := m.GetSelected(); entry != nil {
+		t.Errorf("Expected a graph without revisions to select nothing, got %+v", entry)
+	}
+}
+
+func TestViewDrawsGraph(t *testing.T) {
+	t.Parallel()
+
+	view := loadedSmartlog().View(120, 40)
+
+	for _, want := range []string{
+		"Smartlog",
+		"cccccccc Kyle King  child",
+		"(empty) (no description set)",
+		"pppppppp Kyle King main feature",
+		"│",
+		"~",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q", want)
+		}
+	}
+}
+
+func TestViewWithoutRevisions(t *testing.T) {
+	t.Parallel()
+
+	m := smartlog.New()
+	m.SetLines([]jj.LogLine{{Graph: "~"}})
+	m.Show()
+
+	if view := m.View(120, 40); !strings.Contains(view, "No revisions found") {
+		t.Errorf("Expected the empty message, got %q", view)
+	}
+}
@@ -149,19 +149,37 @@ func (m *Model) MoveDown() {
 	}
 }
 
+// SelectedRevision returns the tag and the revision under the two cursors, and false when either
+// cursor sits outside its list.
+func (m *Model) SelectedRevision() (SplitTag, jj.RevisionEntry, bool) {
+	if m.selectedTag < 0 || m.selectedTag >= len(m.tags) ||
+		m.selectedRev < 0 || m.selectedRev >= len(m.revisions) {
+		return 0, jj.RevisionEntry{}, false
+	}
+
+	return m.tags[m.selectedTag], m.revisions[m.selectedRev], true
+}
+
 // AssignRevisionToCurrentTag points the selected tag at the selected revision, replacing any earlier
 // assignment. It does nothing when either cursor sits outside its list.
 func (m *Model) AssignRevisionToCurrentTag() {
-	if m.selectedTag >= 0 && m.selectedTag < len(m.tags) &&
-		m.selectedRev >= 0 && m.selectedRev < len(m.revisions) {
-		tag := m.tags[m.selectedTag]
-		rev := m.revisions[m.selectedRev]
-		m.destinations[tag] = &DestinationSpec{
-			Type:        DestExistingRevision,
-			ChangeID:    rev.ChangeID,
-			Description: rev.Description,
+	if tag, rev, ok := m.SelectedRevision(); ok {
+		m.AssignRevisionToTag(tag, rev.ChangeID)
+	}
+}
+
+// AssignRevisionToTag points a named tag at the revision changeID, without regard to the cursors. The
+// description is the revision's when it is in the candidate list, and empty otherwise.
+func (m *Model) AssignRevisionToTag(tag SplitTag, changeID string) {
+	spec := &DestinationSpec{Type: DestExistingRevision, ChangeID: changeID}
+
+	for _, rev := range m.revisions {
+		if rev.ChangeID == changeID {
+			spec.Description = rev.Description
 		}
 	}
+
+	m.destinations[tag] = spec
 }
 
 // AssignNewCommitToCurrentTag sends the selected tag to a commit to be created with description.
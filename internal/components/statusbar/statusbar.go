@@ -1,5 +1,5 @@
 // Package statusbar renders the single-line footer: the mode, the diff's source and destination, and
-// the keybinding hints for whatever the user is doing.
+// the keybinding hints for whatever the user is doing, generated from the session's keymap.
 package statusbar
 
 import (
@@ -8,19 +8,29 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/kyleking/jj-diff/internal/keymap"
 	"github.com/kyleking/jj-diff/internal/theme"
 )
 
 const panelFiles = "files"
 
-// Context is what the footer describes. Destination is omitted from the render when empty, and
-// FocusedPanel is "files" or the diff pane, which selects which hints are shown.
+// refreshedMarker flags a diff that was reloaded because the repository changed outside the app, so
+// the user knows the hunks under the cursor may not be the ones they were reading.
+const refreshedMarker = "↻ changed outside jj-diff"
+
+// Context is what the footer describes. Destination and Notice are omitted from the render when
+// empty, and FocusedPanel is "files" or the diff pane, which selects which hints are shown.
+// Refreshed adds the marker for a diff that changed underneath the user. Keys spells the hints; the
+// zero Keymap is the default one.
 type Context struct {
+	Keys         keymap.Keymap
 	Destination  string
 	FocusedPanel string
 	Mode         string
+	Notice       string
 	Source       string
 	IsVisualMode bool
+	Refreshed    bool
 }
 
 // Model is stateless: the footer is rendered entirely from the Context passed to each call.
@@ -58,6 +68,14 @@ func (m Model) ViewWithContext(width int, ctx Context) string {
 		parts = append(parts, "→ Dest: "+ctx.Destination)
 	}
 
+	if ctx.Refreshed {
+		parts = append(parts, refreshedMarker)
+	}
+
+	if ctx.Notice != "" {
+		parts = append(parts, ctx.Notice)
+	}
+
 	parts = append(parts, m.getContextHints(ctx))
 
 	content := strings.Join(parts, " | ")
@@ -70,28 +88,69 @@ func (m Model) ViewWithContext(width int, ctx Context) string {
 	return style.Render(truncateOrPad(content, width))
 }
 
+// getContextHints lists the keys for what the user is most likely to do next, read from the keymap so
+// a rebound key is hinted under its new name.
 func (Model) getContextHints(ctx Context) string {
+	k := ctx.Keys
+
 	if ctx.IsVisualMode {
-		return "j/k:select | Space:confirm | Esc:cancel"
+		return hints(hint(k, "select", keymap.Down, keymap.Up), hint(k, "confirm", keymap.Toggle), "Esc:cancel")
 	}
 
 	if ctx.Mode == "Diff-Editor" {
 		if ctx.FocusedPanel == panelFiles {
-			return "j/k:nav | Tab:diff | a:apply | ?:help"
+			return hints(hint(k, "nav", keymap.Down, keymap.Up), hint(k, "diff", keymap.Focus),
+				hint(k, "apply", keymap.Apply), hint(k, "help", keymap.Help))
 		}
 
-		return "j/k:scroll | Space:keep/drop | a:apply | ?:help"
+		return hints(hint(k, "scroll", keymap.Down, keymap.Up), hint(k, "keep/drop", keymap.Toggle),
+			hint(k, "apply", keymap.Apply), hint(k, "help", keymap.Help))
 	}
 
 	if ctx.FocusedPanel == panelFiles {
-		return "j/k:nav | Tab:diff | /:search | f:find | ?:help"
+		return hints(hint(k, "nav", keymap.Down, keymap.Up), hint(k, "diff", keymap.Focus),
+			hint(k, "search", keymap.Search), hint(k, "find", keymap.Filter), hint(k, "help", keymap.Help))
 	}
 
 	if ctx.Mode == "Interactive" {
-		return "j/k:scroll | Space:select | a:apply | w/s/l:view | ?:help"
+		return hints(hint(k, "scroll", keymap.Down, keymap.Up), hint(k, "select", keymap.Toggle),
+			hint(k, "apply", keymap.Apply), hint(k, "view", keymap.Whitespace, keymap.SideBySide, keymap.LineNumbers),
+			hint(k, "help", keymap.Help))
+	}
+
+	return hints(hint(k, "scroll", keymap.Down, keymap.Up), hint(k, "page", keymap.HalfPageDown, keymap.HalfPageUp),
+		hint(k, "ws", keymap.Whitespace), hint(k, "sbs", keymap.SideBySide), hint(k, "help", keymap.Help))
+}
+
+// hint is one "keys:label" hint, with the first key of each action. It is empty when none of the
+// actions has a key.
+func hint(keys keymap.Keymap, label string, actions ...keymap.Action) string {
+	names := make([]string, 0, len(actions))
+
+	for _, action := range actions {
+		if name := keys.Key(action); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	return strings.Join(names, "/") + ":" + label
+}
+
+// hints joins the hints that are not empty.
+func hints(all ...string) string {
+	kept := make([]string, 0, len(all))
+
+	for _, h := range all {
+		if h != "" {
+			kept = append(kept, h)
+		}
 	}
 
-	return "j/k:scroll | Ctrl-d/u:page | w:ws | s:sbs | ?:help"
+	return strings.Join(kept, " | ")
 }
 
 func truncateOrPad(text string, width int) string {
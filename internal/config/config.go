@@ -1,16 +1,17 @@
 // Package config resolves the diff render settings for a session, layering the
-// JJ_DIFF_* environment variables over a set of built-in defaults.
+// user's and the repository's config files, the matching settings in jj's own
+// config, and the JJ_DIFF_* environment variables over a set of built-in defaults.
 package config
 
 import (
-	"os"
-	"strconv"
+	"github.com/kyleking/jj-diff/internal/keymap"
+	"github.com/kyleking/jj-diff/internal/theme"
 )
 
 // ViewModeType selects how the two sides of a diff are laid out.
 type ViewModeType string
 
-// Diff layouts. The string values are also what JJ_DIFF_VIEW_MODE accepts,
+// Diff layouts. The string values are also what view-mode accepts,
 // which is why they are spelled as user-facing words rather than as an int enum.
 const (
 	ViewModeUnified    ViewModeType = "unified"
@@ -20,66 +21,69 @@ const (
 // Config is the fully resolved settings for a session. Every field has a usable
 // zero-value replacement from DefaultConfig, so callers never build one by hand.
 type Config struct {
-	ViewMode        ViewModeType
-	TabWidth        int
+	// Keys maps action names to the keys that replace the preset's, layer by layer, so a repository
+	// can rebind one action without repeating the user's other overrides.
+	Keys map[string][]string
+	// Themes are the themes the config defines, by name, merged layer by layer like Keys.
+	Themes map[string]theme.Definition
+	// JJColors are the slots jj's own [colors] table sets, which adjust the auto theme beneath any
+	// definition of it here.
+	JJColors theme.Definition
+	ViewMode ViewModeType
+	// Theme names a built-in theme, one in Themes, or auto.
+	Theme string
+	// Keymap names the preset the Keys overrides apply to.
+	Keymap   string
+	TabWidth int
+	// Context is how many unchanged lines surround each change in the diffs jj-diff computes itself,
+	// such as the diff editor's. jj's own diffs follow its diff.git.context, which sets this as well.
+	Context int
+	// ApplyFuzz is how many context lines at each end of a hunk a move may ignore when the
+	// destination has drifted from the source's parent.
+	ApplyFuzz       int
 	ShowWhitespace  bool
 	ShowLineNumbers bool
 	WordLevelDiff   bool
 }
 
-// defaultTabWidth is the column width a tab renders as when JJ_DIFF_TAB_WIDTH is unset.
-const defaultTabWidth = 4
+// defaultTabWidth is the column width a tab renders as when nothing sets tab-width, and
+// maxTabWidth is the widest tab-width accepts.
+const (
+	defaultTabWidth = 4
+	maxTabWidth     = 16
+)
+
+// defaultContext matches jj's and git's own, and maxContext is the most context accepts.
+const (
+	defaultContext = 3
+	maxContext     = 100
+)
 
-// DefaultConfig returns the settings that apply when no environment variable is
-// set: unified layout, line numbers on, whitespace and word-level diff off, tabs
-// four columns wide.
+// defaultApplyFuzz matches patch's own default, and maxApplyFuzz is the most apply-fuzz
+// accepts, past which a hunk is mostly matched on its changed lines alone.
+const (
+	defaultApplyFuzz = 2
+	maxApplyFuzz     = 5
+)
+
+// DefaultConfig returns the settings that apply when nothing is configured:
+// unified layout, line numbers on, whitespace and word-level diff off, tabs four
+// columns wide, three lines of context, a fuzz of two lines when a move's destination has drifted,
+// the vim keymap, and the theme that suits the terminal's background.
 func DefaultConfig() Config {
 	return Config{
+		Keymap:          keymap.DefaultPreset,
+		Theme:           theme.Auto,
 		ViewMode:        ViewModeUnified,
 		ShowWhitespace:  false,
 		ShowLineNumbers: true,
 		TabWidth:        defaultTabWidth,
+		Context:         defaultContext,
 		WordLevelDiff:   false,
+		ApplyFuzz:       defaultApplyFuzz,
 	}
 }
 
-// LoadConfig reads the JJ_DIFF_* environment variables over DefaultConfig. A
-// value that cannot be understood is ignored rather than reported, so the
-// default survives and startup never fails on a typo. Booleans are true only for
-// "1", "true", "yes", or "on", and JJ_DIFF_TAB_WIDTH is honored for 1 to 16.
-func LoadConfig() Config {
-	cfg := DefaultConfig()
-
-	if v := os.Getenv("JJ_DIFF_VIEW_MODE"); v != "" {
-		switch v {
-		case "side-by-side", "sidebyside":
-			cfg.ViewMode = ViewModeSideBySide
-		case "unified":
-			cfg.ViewMode = ViewModeUnified
-		}
-	}
-
-	if v := os.Getenv("JJ_DIFF_SHOW_WHITESPACE"); v != "" {
-		cfg.ShowWhitespace = parseBool(v)
-	}
-
-	if v := os.Getenv("JJ_DIFF_SHOW_LINE_NUMBERS"); v != "" {
-		cfg.ShowLineNumbers = parseBool(v)
-	}
-
-	if v := os.Getenv("JJ_DIFF_TAB_WIDTH"); v != "" {
-		if width, err := strconv.Atoi(v); err == nil && width > 0 && width <= 16 {
-			cfg.TabWidth = width
-		}
-	}
-
-	if v := os.Getenv("JJ_DIFF_WORD_DIFF"); v != "" {
-		cfg.WordLevelDiff = parseBool(v)
-	}
-
-	return cfg
-}
-
 func parseBool(s string) bool {
 	switch s {
 	case "1", "true", "yes", "on":
@@ -107,3 +111,23 @@ func (c Config) ToRenderOptions() RenderOptions {
 		WordLevelDiff:   c.WordLevelDiff,
 	}
 }
+
+// KeyMap builds the session's keymap. Its warnings are the ones LoadConfig already returned, so a
+// caller that has printed those can drop them.
+func (c Config) KeyMap() (keymap.Keymap, []error) {
+	return keymap.New(c.Keymap, c.Keys)
+}
+
+// ResolveTheme builds the session's theme. An unknown name, which LoadConfig has already warned about,
+// resolves to the auto theme. The auto theme takes jj's diff colors, so jj-diff looks like jj diff
+// until a theme is named.
+func (c Config) ResolveTheme() (theme.Theme, error) {
+	t, err := theme.Select(c.Theme, c.Themes)
+
+	if c.Theme == theme.Auto {
+		// jj's colors sit beneath jj-diff's own, so a definition of auto still wins over them.
+		t = c.Themes[theme.Auto].Adjust(c.JJColors.Adjust(t))
+	}
+
+	return t, err //nolint:wrapcheck // the error names the theme.
+}
@@ -1,9 +1,16 @@
 package config_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/kyleking/jj-diff/internal/config"
+	"github.com/kyleking/jj-diff/internal/keymap"
+	"github.com/kyleking/jj-diff/internal/theme"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -26,6 +33,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.WordLevelDiff {
 		t.Error("Expected WordLevelDiff=false")
 	}
+	if cfg.ApplyFuzz != 2 {
+		t.Errorf("Expected ApplyFuzz=2, got %d", cfg.ApplyFuzz)
+	}
 }
 
 func TestLoadConfigFromEnv(t *testing.T) {
@@ -89,6 +99,18 @@ func TestLoadConfigFromEnv(t *testing.T) {
 			checkFn:  func(c config.Config) bool { return c.WordLevelDiff },
 			expected: true,
 		},
+		{
+			name:     "apply fuzz 0",
+			envVars:  map[string]string{"JJ_DIFF_APPLY_FUZZ": "0"},
+			checkFn:  func(c config.Config) bool { return c.ApplyFuzz == 0 },
+			expected: true,
+		},
+		{
+			name:     "apply fuzz negative stays default",
+			envVars:  map[string]string{"JJ_DIFF_APPLY_FUZZ": "-1"},
+			checkFn:  func(c config.Config) bool { return c.ApplyFuzz == 2 },
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -97,7 +119,7 @@ func TestLoadConfigFromEnv(t *testing.T) {
 				t.Setenv(k, v)
 			}
 
-			cfg := config.LoadConfig()
+			cfg, _ := config.LoadConfig()
 			if got := tt.checkFn(cfg); got != tt.expected {
 				t.Errorf("Expected %v, got %v", tt.expected, got)
 			}
@@ -105,6 +127,257 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	}
 }
 
+func TestLoadConfigLayers(t *testing.T) {
+	t.Setenv("JJ_DIFF_TAB_WIDTH", "2")
+
+	user := config.Layer{Source: "user", Values: map[string]any{
+		"tab-width": int64(8), "word-diff": true, "view-mode": "side-by-side",
+	}}
+	repo := config.Layer{Source: "repo", Values: map[string]any{"word-diff": false}}
+
+	cfg, warnings := config.LoadConfig(user, repo)
+	if len(warnings) != 0 {
+		t.Fatalf("Expected no warnings, got %v", warnings)
+	}
+
+	if cfg.TabWidth != 2 {
+		t.Errorf("Expected the environment over the files, got TabWidth=%d", cfg.TabWidth)
+	}
+
+	if cfg.WordLevelDiff {
+		t.Error("Expected the repository over the user")
+	}
+
+	if cfg.ViewMode != config.ViewModeSideBySide {
+		t.Errorf("Expected a setting only the user set to survive, got %s", cfg.ViewMode)
+	}
+}
+
+func TestLoadConfigWarnings(t *testing.T) {
+	t.Setenv("JJ_DIFF_SHOW_LINE_NUMBERS", "maybe")
+
+	layer := config.Layer{Source: "repo", Values: map[string]any{
+		"tab-width":  int64(100),
+		"apply-fuzz": "three",
+		"colour":     "red",
+		"view-mode":  "split",
+		"word-diff":  int64(1),
+	}}
+
+	cfg, warnings := config.LoadConfig(layer)
+	if !reflect.DeepEqual(cfg, config.DefaultConfig()) {
+		t.Errorf("Expected every invalid value to leave the default, got %+v", cfg)
+	}
+
+	if len(warnings) != 6 {
+		t.Fatalf("Expected one warning per bad setting, got %v", warnings)
+	}
+
+	if !errors.Is(warnings[1], config.ErrUnknownKey) || !strings.HasPrefix(warnings[1].Error(), "repo: ") {
+		t.Errorf("Expected the unknown key second, in key order, got %v", warnings[1])
+	}
+
+	last := warnings[len(warnings)-1]
+	if !errors.Is(last, config.ErrInvalidValue) || !strings.Contains(last.Error(), "JJ_DIFF_SHOW_LINE_NUMBERS") {
+		t.Errorf("Expected the environment's warning last, naming the variable, got %v", last)
+	}
+}
+
+func TestLoadConfigKeys(t *testing.T) {
+	t.Setenv("JJ_DIFF_KEYMAP", "emacs")
+
+	user := config.Layer{Source: "user", Values: map[string]any{
+		"keys": map[string]any{"apply": "A", "refresh": []any{"R", "ctrl+l"}},
+	}}
+	repo := config.Layer{Source: "repo", Values: map[string]any{
+		"keys": map[string]any{"apply": "x", "teleport": "t", "undo": int64(1), "quit": "x"},
+	}}
+
+	cfg, warnings := config.LoadConfig(user, repo)
+
+	if cfg.Keymap != "emacs" {
+		t.Errorf("Expected the environment's preset, got %q", cfg.Keymap)
+	}
+
+	if got := cfg.Keys["apply"]; len(got) != 1 || got[0] != "x" {
+		t.Errorf("Expected the repository's apply key, got %v", got)
+	}
+
+	if got := cfg.Keys["refresh"]; len(got) != 2 {
+		t.Errorf("Expected the user's refresh keys to survive the repository layer, got %v", got)
+	}
+
+	if len(warnings) != 3 {
+		t.Fatalf("Expected the unknown action, the bad value and the conflict, got %v", warnings)
+	}
+
+	if !errors.Is(warnings[0], keymap.ErrUnknownAction) || !strings.HasPrefix(warnings[0].Error(), "repo: keys: ") {
+		t.Errorf("Expected the unknown action first, naming its layer, got %v", warnings[0])
+	}
+
+	if !errors.Is(warnings[2], keymap.ErrConflict) {
+		t.Errorf("Expected the conflict last, got %v", warnings[2])
+	}
+
+	preset := config.Layer{Source: "user", Values: map[string]any{"keymap": "nano"}}
+	if _, warnings := config.LoadConfig(preset); len(warnings) != 1 || !errors.Is(warnings[0], config.ErrInvalidValue) {
+		t.Errorf("Expected an unknown preset to be an invalid value, got %v", warnings)
+	}
+}
+
+func TestLoadConfigThemes(t *testing.T) {
+	t.Setenv("JJ_DIFF_THEME", "mine")
+
+	user := config.Layer{Source: "user", Values: map[string]any{
+		"themes": map[string]any{
+			"mine":  map[string]any{"base": "frappe", "added": "#00ff00"},
+			"other": map[string]any{"deleted": "red"},
+		},
+	}}
+	repo := config.Layer{Source: "repo", Values: map[string]any{
+		"themes": map[string]any{"mine": map[string]any{"base": "mocha"}},
+	}}
+
+	cfg, warnings := config.LoadConfig(user, repo)
+
+	if len(warnings) != 1 || !errors.Is(warnings[0], theme.ErrInvalidDefinition) ||
+		!strings.HasPrefix(warnings[0].Error(), "user: themes: other.deleted: ") {
+		t.Errorf("Expected the bad slot, named by theme and slot, got %v", warnings)
+	}
+
+	palette, err := cfg.ResolveTheme()
+	if err != nil {
+		t.Fatalf("ResolveTheme failed: %v", err)
+	}
+
+	if palette.Name != "mine" || palette.Syntax != "catppuccin-mocha" || palette.AddedLine != theme.Mocha().AddedLine {
+		t.Errorf("Expected the repository's definition to replace the user's, got %+v", palette)
+	}
+
+	t.Setenv("JJ_DIFF_THEME", "nord")
+
+	cfg, warnings = config.LoadConfig()
+	if len(warnings) != 1 || !errors.Is(warnings[0], theme.ErrUnknownTheme) || cfg.Theme != theme.Auto {
+		t.Errorf("Expected an unknown theme to warn and fall back to auto, got %q, %v", cfg.Theme, warnings)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	layer, err := config.ReadFile(filepath.Join(dir, "missing.toml"))
+	if err != nil || len(layer.Values) != 0 {
+		t.Errorf("Expected a missing file to be an empty layer, got %+v, %v", layer, err)
+	}
+
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("tab-width = 8\nview-mode = \"side-by-side\"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	layer, err = config.ReadFile(path)
+	if err != nil || layer.Values["tab-width"] != int64(8) || layer.Source != path {
+		t.Errorf("Expected the file's settings, got %+v, %v", layer, err)
+	}
+
+	if err := os.WriteFile(path, []byte("tab-width = \n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if _, err := config.ReadFile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected a malformed file to be named in the error, got %v", err)
+	}
+}
+
+func TestParseJJConfig(t *testing.T) {
+	t.Parallel()
+
+	output := "jj-diff.tab-width = 8\njj-diff.word-diff = true\nui.color = \"always\"\n"
+
+	layer, err := config.ParseJJConfig("jj config --user", output)
+	if err != nil {
+		t.Fatalf("ParseJJConfig failed: %v", err)
+	}
+
+	if len(layer.Values) != 2 || layer.Values["word-diff"] != true {
+		t.Errorf("Expected only the jj-diff table, got %+v", layer.Values)
+	}
+
+	if layer, err := config.ParseJJConfig("jj config --repo", ""); err != nil || len(layer.Values) != 0 {
+		t.Errorf("Expected no output to be an empty layer, got %+v, %v", layer, err)
+	}
+}
+
+func TestParseJJSettings(t *testing.T) {
+	t.Setenv("CATPPUCCIN_THEME", "mocha")
+
+	output := strings.Join([]string{
+		`colors."diff added" = "bright green"`,
+		`colors."diff removed token" = { bg = "purple" }`,
+		`diff.git.context = 5`,
+		`ui.diff.format = "git"`,
+		`ui.diff-formatter = ":color-words"`,
+		`jj-diff.tab-width = 8`,
+	}, "\n")
+
+	layer, err := config.ParseJJSettings("jj config", output)
+	if err != nil {
+		t.Fatalf("ParseJJSettings failed: %v", err)
+	}
+
+	cfg, warnings := config.LoadConfig(layer)
+
+	if len(warnings) != 1 || !errors.Is(warnings[0], theme.ErrInvalidColor) ||
+		!strings.HasPrefix(warnings[0].Error(), `jj config: colors."diff removed token": `) {
+		t.Errorf("Expected the unknown color, named by jj's label, got %v", warnings)
+	}
+
+	if cfg.Context != 5 || !cfg.WordLevelDiff || cfg.TabWidth != 4 {
+		t.Errorf("Expected jj's context and the newer diff formatter, and not the jj-diff table, got %+v", cfg)
+	}
+
+	palette, _ := cfg.ResolveTheme()
+	if palette.AddedLine != (theme.Slot{ANSI: "10"}) || palette.DeletedLine != theme.Mocha().DeletedLine {
+		t.Errorf("Expected jj's added color over the auto theme, got %+v", palette)
+	}
+
+	named := config.Layer{Source: "user", Values: map[string]any{"theme": "latte", "context": int64(500)}}
+
+	cfg, warnings = config.LoadConfig(layer, named)
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[1].Error(), "user: context: ") {
+		t.Errorf("Expected the user's context rejected, got %v", warnings)
+	}
+
+	if palette, _ := cfg.ResolveTheme(); palette.AddedLine != theme.Latte().AddedLine {
+		t.Errorf("Expected a named theme to keep its own colors, got %+v", palette.AddedLine)
+	}
+}
+
+func TestRepoConfigPath(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+
+	if err := os.MkdirAll(filepath.Join(root, ".jj"), 0o750); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	if err := os.MkdirAll(nested, 0o750); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	if got := config.RepoConfigPath(nested); got != filepath.Join(root, config.RepoConfigName) {
+		t.Errorf("Expected the workspace root's file, got %q", got)
+	}
+
+	if got := config.RepoConfigPath(t.TempDir()); got != "" {
+		t.Errorf("Expected nothing outside a workspace, got %q", got)
+	}
+}
+
 func TestToRenderOptions(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,494 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/kyleking/jj-diff/internal/keymap"
+	"github.com/kyleking/jj-diff/internal/theme"
+)
+
+// Sentinel errors for a setting LoadConfig could not use. Both are warnings: the setting is skipped
+// and the value from the layer below survives.
+var (
+	ErrUnknownKey   = errors.New("unknown setting")
+	ErrInvalidValue = errors.New("invalid value")
+)
+
+// RepoConfigName is the file at the root of a workspace whose settings apply to that repository. It
+// is meant to be committed, so a team shares one set of settings.
+const RepoConfigName = ".jj-diff.toml"
+
+// jjConfigSection is the table in jj's own config whose keys are read as settings, as in
+// `jj config set --user jj-diff.tab-width 8`.
+const jjConfigSection = "jj-diff"
+
+// Layer is one source of settings, keyed by the setting names the config file uses. Source names the
+// layer in warnings.
+type Layer struct {
+	Values map[string]any
+	// names are what warnings call the settings that came from somewhere else under another name, such
+	// as an environment variable or one of jj's own settings.
+	names map[string]string
+	// colors are jj's [colors] table, from the layer of jj's own settings.
+	colors map[string]any
+	Source string
+}
+
+// setting is one key a layer may set: how it is applied, and the environment variable that sets it,
+// if any.
+type setting struct {
+	apply func(cfg *Config, value any) error
+	env   string
+}
+
+// settings are the keys every layer accepts. The names match `jj config`'s kebab-case style.
+var settings = map[string]setting{
+	"view-mode":         {env: "JJ_DIFF_VIEW_MODE", apply: setViewMode},
+	"show-whitespace":   {env: "JJ_DIFF_SHOW_WHITESPACE", apply: setBool(showWhitespace)},
+	"show-line-numbers": {env: "JJ_DIFF_SHOW_LINE_NUMBERS", apply: setBool(showLineNumbers)},
+	"word-diff":         {env: "JJ_DIFF_WORD_DIFF", apply: setBool(wordLevelDiff)},
+	"tab-width":         {env: "JJ_DIFF_TAB_WIDTH", apply: setInt(1, maxTabWidth, tabWidth)},
+	"apply-fuzz":        {env: "JJ_DIFF_APPLY_FUZZ", apply: setInt(0, maxApplyFuzz, applyFuzz)},
+	"context":           {env: "JJ_DIFF_CONTEXT", apply: setInt(0, maxContext, diffContext)},
+	"keymap":            {env: "JJ_DIFF_KEYMAP", apply: setKeymap},
+	"keys":              {apply: setKeys},
+	"theme":             {env: "JJ_DIFF_THEME", apply: setTheme},
+	"themes":            {apply: setThemes},
+}
+
+func showWhitespace(c *Config) *bool  { return &c.ShowWhitespace }
+func showLineNumbers(c *Config) *bool { return &c.ShowLineNumbers }
+func wordLevelDiff(c *Config) *bool   { return &c.WordLevelDiff }
+func tabWidth(c *Config) *int         { return &c.TabWidth }
+func applyFuzz(c *Config) *int        { return &c.ApplyFuzz }
+func diffContext(c *Config) *int      { return &c.Context }
+
+// LoadConfig layers the settings over DefaultConfig: each layer in order, each overriding the ones
+// before it, and then the JJ_DIFF_* environment variables over all of them. Callers pass the layers
+// from least to most specific, user before repository, and apply flags to the result.
+//
+// A setting that cannot be used is skipped with a warning rather than failing startup, so a typo in
+// one file leaves the rest of the settings, and the value from the layer below, in place. A theme no
+// layer defines and keys the final keymap binds twice are warned about last, since they come from
+// the layers together.
+func LoadConfig(layers ...Layer) (Config, []error) {
+	cfg := DefaultConfig()
+
+	var warnings []error
+
+	for _, layer := range append(layers, envLayer()) {
+		warnings = append(warnings, layer.applyTo(&cfg)...)
+	}
+
+	if err := theme.Check(cfg.Theme, cfg.Themes); err != nil {
+		warnings = append(warnings, fmt.Errorf("theme: %w", err))
+		cfg.Theme = theme.Auto
+	}
+
+	_, conflicts := cfg.KeyMap()
+
+	return cfg, append(warnings, conflicts...)
+}
+
+// applyTo sets each of the layer's values on cfg, in key order so the warnings come out stable.
+func (l Layer) applyTo(cfg *Config) []error {
+	keys := make([]string, 0, len(l.Values))
+	for key := range l.Values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var warnings []error
+
+	for _, key := range keys {
+		s, ok := settings[key]
+		if !ok {
+			warnings = append(warnings, fmt.Errorf("%s: %w %q", l.Source, ErrUnknownKey, key))
+
+			continue
+		}
+
+		name := key
+		if n, ok := l.names[key]; ok {
+			name = n
+		}
+
+		for _, err := range split(s.apply(cfg, l.Values[key])) {
+			warnings = append(warnings, fmt.Errorf("%s: %s: %w", l.Source, name, err))
+		}
+	}
+
+	if l.colors != nil {
+		def, err := theme.ParseJJColors(l.colors)
+		for _, err := range split(err) {
+			warnings = append(warnings, fmt.Errorf("%s: colors.%w", l.Source, err))
+		}
+
+		cfg.JJColors = def
+	}
+
+	return warnings
+}
+
+// split unpacks an errors.Join from a setting with several entries, such as keys, so each bad entry
+// is its own warning.
+func split(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
+
+// envLayer reads the JJ_DIFF_* variables that are set. Their values are strings, which the setters
+// parse as they would a string in a file.
+func envLayer() Layer {
+	values := make(map[string]any)
+	names := make(map[string]string)
+
+	for key, s := range settings {
+		if s.env == "" {
+			continue
+		}
+
+		if v := os.Getenv(s.env); v != "" {
+			values[key] = v
+			names[key] = s.env
+		}
+	}
+
+	return Layer{Source: "environment", Values: values, names: names}
+}
+
+// ReadFile reads a TOML file of settings. A file that does not exist is an empty layer, because every
+// layer is optional.
+func ReadFile(path string) (Layer, error) {
+	layer := Layer{Source: path, Values: map[string]any{}}
+
+	//nolint:gosec // G304: the path is one of the config locations, not user input.
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return layer, nil
+	}
+
+	if err != nil {
+		return layer, fmt.Errorf("reading config: %w", err)
+	}
+
+	if err := toml.Unmarshal(content, &layer.Values); err != nil {
+		return layer, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return layer, nil
+}
+
+// ParseJJConfig reads the jj-diff table out of `jj config list` output, which is TOML with dotted
+// keys such as `jj-diff.tab-width = 8`. Source names the jj layer the output came from.
+func ParseJJConfig(source, output string) (Layer, error) {
+	layer := Layer{Source: source, Values: map[string]any{}}
+
+	var parsed map[string]any
+	if err := toml.Unmarshal([]byte(output), &parsed); err != nil {
+		return layer, fmt.Errorf("%s: %w", source, err)
+	}
+
+	if section, ok := parsed[jjConfigSection].(map[string]any); ok {
+		layer.Values = section
+	}
+
+	return layer, nil
+}
+
+// ParseJJSettings reads the settings jj-diff shares with jj out of `jj config list` output for every
+// setting: diff.git.context as context, ui.diff-formatter or the older ui.diff.format as word-diff, and
+// the diff labels of the [colors] table as the auto theme's colors. Other formats, such as stat, have
+// no jj-diff equivalent and leave word-diff alone. Source names the output in warnings.
+func ParseJJSettings(source, output string) (Layer, error) {
+	layer := Layer{Source: source, Values: map[string]any{}, names: map[string]string{}}
+
+	var parsed map[string]any
+	if err := toml.Unmarshal([]byte(output), &parsed); err != nil {
+		return layer, fmt.Errorf("%s: %w", source, err)
+	}
+
+	if v, ok := lookup(parsed, "diff", "git", "context"); ok {
+		layer.Values["context"] = v
+		layer.names["context"] = "diff.git.context"
+	}
+
+	// The newer name comes last, so it wins when both are set.
+	for _, path := range [][]string{{"ui", "diff", "format"}, {"ui", "diff-formatter"}} {
+		format, _ := lookup(parsed, path...)
+
+		switch format {
+		case "color-words", ":color-words":
+			layer.Values["word-diff"] = true
+		case "git", ":git":
+			layer.Values["word-diff"] = false
+		}
+	}
+
+	layer.colors, _ = parsed["colors"].(map[string]any)
+
+	return layer, nil
+}
+
+// lookup follows a dotted key through nested tables.
+func lookup(table map[string]any, path ...string) (any, bool) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := table[key].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		table = next
+	}
+
+	value, ok := table[path[len(path)-1]]
+
+	return value, ok
+}
+
+// UserConfigPath is the user's config file: $XDG_CONFIG_HOME/jj-diff/config.toml, falling back to
+// ~/.config as on every platform, which is where jj's own users expect it. It is empty when neither
+// directory is known.
+func UserConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "jj-diff", "config.toml")
+}
+
+// RepoConfigPath is the RepoConfigName file at the root of the workspace holding dir, found by
+// walking up to the directory with a .jj in it. It is empty outside a workspace.
+func RepoConfigPath(dir string) string {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, ".jj")); err == nil && info.IsDir() {
+			return filepath.Join(dir, RepoConfigName)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+func setViewMode(cfg *Config, value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%w: %v, expected unified or side-by-side", ErrInvalidValue, value)
+	}
+
+	switch s {
+	case "side-by-side", "sidebyside":
+		cfg.ViewMode = ViewModeSideBySide
+	case "unified":
+		cfg.ViewMode = ViewModeUnified
+	default:
+		return fmt.Errorf("%w: %q, expected unified or side-by-side", ErrInvalidValue, s)
+	}
+
+	return nil
+}
+
+// setBool accepts a TOML boolean, or a string in the forms the environment has always taken. A string
+// that is neither clearly true nor clearly false is a mistake rather than false.
+func setBool(field func(*Config) *bool) func(*Config, any) error {
+	return func(cfg *Config, value any) error {
+		switch v := value.(type) {
+		case bool:
+			*field(cfg) = v
+		case string:
+			if !parseBool(v) && !isFalse(v) {
+				return fmt.Errorf("%w: %q, expected true or false", ErrInvalidValue, v)
+			}
+
+			*field(cfg) = parseBool(v)
+		default:
+			return fmt.Errorf("%w: %v, expected true or false", ErrInvalidValue, value)
+		}
+
+		return nil
+	}
+}
+
+func isFalse(s string) bool {
+	switch s {
+	case "0", "false", "no", "off":
+		return true
+	default:
+		return false
+	}
+}
+
+// setInt accepts a TOML integer, or a string holding one, within [lowest, highest].
+func setInt(lowest, highest int, field func(*Config) *int) func(*Config, any) error {
+	return func(cfg *Config, value any) error {
+		n, ok := 0, false
+
+		switch v := value.(type) {
+		case int64:
+			n, ok = int(v), true
+		case string:
+			parsed, err := strconv.Atoi(v)
+			n, ok = parsed, err == nil
+		}
+
+		if !ok || n < lowest || n > highest {
+			return fmt.Errorf("%w: %v, expected %d to %d", ErrInvalidValue, value, lowest, highest)
+		}
+
+		*field(cfg) = n
+
+		return nil
+	}
+}
+
+func setKeymap(cfg *Config, value any) error {
+	expected := strings.Join(keymap.Presets(), " or ")
+
+	s, ok := value.(string)
+	if !ok || !slices.Contains(keymap.Presets(), s) {
+		return fmt.Errorf("%w: %v, expected %s", ErrInvalidValue, value, expected)
+	}
+
+	cfg.Keymap = s
+
+	return nil
+}
+
+// setKeys merges a table of action = key, or action = [keys], over the layers below, so each layer
+// replaces only the actions it names. A bad entry is skipped and the rest still apply.
+func setKeys(cfg *Config, value any) error {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%w: %v, expected a table of action = keys", ErrInvalidValue, value)
+	}
+
+	merged := maps.Clone(cfg.Keys)
+	if merged == nil {
+		merged = make(map[string][]string, len(table))
+	}
+
+	var errs []error
+
+	for _, action := range slices.Sorted(maps.Keys(table)) {
+		if !keymap.IsAction(action) {
+			errs = append(errs, fmt.Errorf("%w %q", keymap.ErrUnknownAction, action))
+
+			continue
+		}
+
+		keys, ok := keyList(table[action])
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s = %v, expected a key or a list of keys",
+				ErrInvalidValue, action, table[action]))
+
+			continue
+		}
+
+		merged[action] = keys
+	}
+
+	cfg.Keys = merged
+
+	return errors.Join(errs...)
+}
+
+func keyList(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []any:
+		keys := make([]string, 0, len(v))
+
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+
+			keys = append(keys, s)
+		}
+
+		return keys, true
+	default:
+		return nil, false
+	}
+}
+
+// setTheme takes any name, because the theme it names may be defined in a later layer. LoadConfig
+// checks the name once every layer is in.
+func setTheme(cfg *Config, value any) error {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return fmt.Errorf("%w: %v, expected a theme name", ErrInvalidValue, value)
+	}
+
+	cfg.Theme = s
+
+	return nil
+}
+
+// setThemes merges a table of theme definitions over the layers below, each name replacing the
+// definition of the same name. A definition with bad entries keeps the rest.
+func setThemes(cfg *Config, value any) error {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%w: %v, expected a table of themes", ErrInvalidValue, value)
+	}
+
+	merged := maps.Clone(cfg.Themes)
+	if merged == nil {
+		merged = make(map[string]theme.Definition, len(table))
+	}
+
+	var errs []error
+
+	for _, name := range slices.Sorted(maps.Keys(table)) {
+		entries, ok := table[name].(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s = %v, expected a table of slots",
+				ErrInvalidValue, name, table[name]))
+
+			continue
+		}
+
+		def, err := theme.ParseDefinition(entries)
+		for _, err := range split(err) {
+			errs = append(errs, fmt.Errorf("%s.%w", name, err))
+		}
+
+		merged[name] = def
+	}
+
+	cfg.Themes = merged
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package diff
+
+import "strings"
+
+// ConflictRegion is one conflict jj materialized into a file, from its <<<<<<< line to its >>>>>>>
+// line. StartLine, EndLine, and Markers index Hunk.Lines of the hunk at HunkIdx. A conflict the
+// hunk cuts off ends at the hunk's last line rather than spilling into the next hunk.
+type ConflictRegion struct {
+	Markers   []int
+	HunkIdx   int
+	StartLine int
+	EndLine   int
+}
+
+// minConflictMarkerLength is the shortest run of marker characters jj writes. jj lengthens the
+// markers when the file already holds a run that long, so longer runs are markers too.
+const minConflictMarkerLength = 7
+
+// Contains reports whether lineIdx falls inside the region, markers included.
+func (r *ConflictRegion) Contains(lineIdx int) bool {
+	return lineIdx >= r.StartLine && lineIdx <= r.EndLine
+}
+
+// IsMarker reports whether lineIdx is one of the region's marker lines rather than content from one
+// of the sides.
+func (r *ConflictRegion) IsMarker(lineIdx int) bool {
+	for _, marker := range r.Markers {
+		if marker == lineIdx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConflictAt returns the conflict that covers a line, or nil when the line is outside every
+// conflict.
+func (fc *FileChange) ConflictAt(hunkIdx, lineIdx int) *ConflictRegion {
+	for i := range fc.Conflicts {
+		region := &fc.Conflicts[i]
+		if region.HunkIdx == hunkIdx && region.Contains(lineIdx) {
+			return region
+		}
+	}
+
+	return nil
+}
+
+// HunkHasConflict reports whether any conflict starts in the hunk, which is what marks its header.
+func (fc *FileChange) HunkHasConflict(hunkIdx int) bool {
+	for _, region := range fc.Conflicts {
+		if region.HunkIdx == hunkIdx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findConflicts scans each hunk's new side for jj conflict markers. Deleted lines are skipped,
+// because markers there are a conflict the change resolved, not one it still carries. An opening
+// marker must say "conflict", as jj's always do, so a file that merely quotes a marker line is not
+// flagged.
+func findConflicts(hunks []Hunk) []ConflictRegion {
+	var regions []ConflictRegion
+
+	for hunkIdx, hunk := range hunks {
+		var open *ConflictRegion
+
+		for lineIdx, line := range hunk.Lines {
+			if line.Type == LineDeletion {
+				continue
+			}
+
+			marker, rest := conflictMarker(line.Content)
+
+			switch {
+			case marker == '<' && strings.Contains(strings.ToLower(rest), "conflict"):
+				if open != nil {
+					open.EndLine = lineIdx - 1
+					regions = append(regions, *open)
+				}
+
+				open = &ConflictRegion{HunkIdx: hunkIdx, StartLine: lineIdx, Markers: []int{lineIdx}}
+			case open == nil || marker == 0 || marker == '<':
+			case marker == '>':
+				open.EndLine = lineIdx
+				open.Markers = append(open.Markers, lineIdx)
+				regions = append(regions, *open)
+				open = nil
+			default:
+				open.Markers = append(open.Markers, lineIdx)
+			}
+		}
+
+		if open != nil {
+			open.EndLine = len(hunk.Lines) - 1
+			regions = append(regions, *open)
+		}
+	}
+
+	return regions
+}
+
+// conflictMarker returns the marker character a line opens with and the text after the run, or 0
+// when the line is not a marker. jj writes <<<<<<< and >>>>>>> around a conflict, %%%%%%% and
+// \\\\\\\ before a diff of one side, +++++++ and ------- before a snapshot of one, and with
+// git-style markers ||||||| and ======= between the sides.
+func conflictMarker(content string) (byte, string) {
+	if len(content) < minConflictMarkerLength || !strings.ContainsRune(`<>%\+-|=`, rune(content[0])) {
+		return 0, ""
+	}
+
+	char := content[0]
+	run := len(content) - len(strings.TrimLeft(content, string(char)))
+
+	rest := content[run:]
+	if run < minConflictMarkerLength || (rest != "" && rest[0] != ' ') {
+		return 0, ""
+	}
+
+	return char, rest
+}
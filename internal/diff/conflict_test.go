@@ -0,0 +1,134 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// conflictDiff is what jj diff --git prints for a commit that left a two-sided conflict in a file
+// its parent had clean, so every marker arrives as an added line.
+const conflictDiff = `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,9 @@
+ line 1
+-line 2
++<<<<<<< Conflict 1 of 1
++%%%%%%% Changes from base to side #1
++-line 2
+++line 2 from main
+++++++++ Contents of side #2
++line 2 from feature
++>>>>>>> Conflict 1 of 1 ends
+ line 3
+`
+
+func TestParse_ConflictMarkersKeepTheirLines(t *testing.T) {
+	t.Parallel()
+
+	file := diff.Parse(conflictDiff)[0]
+
+	if got := len(file.Hunks[0].Lines); got != 10 {
+		t.Fatalf("Expected 10 lines including the +++++++ marker, got %d", got)
+	}
+
+	if got := file.Hunks[0].Lines[6].Content; got != "+++++++ Contents of side #2" {
+		t.Errorf("Expected the snapshot marker as content, got %q", got)
+	}
+}
+
+func TestParse_ConflictRegion(t *testing.T) {
+	t.Parallel()
+
+	file := diff.Parse(conflictDiff)[0]
+
+	if len(file.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(file.Conflicts))
+	}
+
+	region := file.Conflicts[0]
+	if region.HunkIdx != 0 || region.StartLine != 2 || region.EndLine != 8 {
+		t.Errorf("Expected lines 2-8 of hunk 0, got %+v", region)
+	}
+
+	for _, lineIdx := range []int{2, 3, 6, 8} {
+		if !region.IsMarker(lineIdx) {
+			t.Errorf("Expected line %d to be a marker", lineIdx)
+		}
+	}
+
+	for _, lineIdx := range []int{4, 5, 7} {
+		if region.IsMarker(lineIdx) {
+			t.Errorf("Expected line %d to be side content", lineIdx)
+		}
+	}
+
+	if file.ConflictAt(0, 5) == nil || file.ConflictAt(0, 9) != nil {
+		t.Error("Expected ConflictAt to cover only the region's lines")
+	}
+
+	if !file.HunkHasConflict(0) {
+		t.Error("Expected hunk 0 to carry the conflict")
+	}
+}
+
+func TestParse_ConflictMarkerShapes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		diffText  string
+		conflicts int
+	}{
+		{
+			name: "quoted marker without the word conflict",
+			diffText: `diff --git a/doc.md b/doc.md
+--- a/doc.md
++++ b/doc.md
+@@ -1,1 +1,3 @@
+ intro
++<<<<<<< HEAD
++>>>>>>> branch
+`,
+			conflicts: 0,
+		},
+		{
+			name: "marker run longer than seven",
+			diffText: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,1 +1,4 @@
+ intro
++<<<<<<<<<<< conflict 1 of 1
++side
++>>>>>>>>>>> conflict 1 of 1 ends
+`,
+			conflicts: 1,
+		},
+		{
+			name: "resolved conflict appears as deletions",
+			diffText: `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,1 @@
+-<<<<<<< Conflict 1 of 1
+-side
+->>>>>>> Conflict 1 of 1 ends
++resolved
+`,
+			conflicts: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := diff.Parse(tt.diffText)[0]
+			if len(file.Conflicts) != tt.conflicts {
+				t.Errorf("Expected %d conflicts, got %+v", tt.conflicts, file.Conflicts)
+			}
+		})
+	}
+}
@@ -10,9 +10,14 @@ import (
 	godiff "github.com/sergi/go-diff/diffmatchpatch"
 )
 
-// CompareDirectories generates a unified diff comparing two directories.
+// CompareDirectories generates a unified diff comparing two directories, with DefaultContext lines of
+// context around each change.
 // Returns git-format diff text suitable for parsing by diff.Parse().
 func CompareDirectories(leftDir, rightDir string) (string, error) {
+	return compareDirectories(leftDir, rightDir, DefaultContext)
+}
+
+func compareDirectories(leftDir, rightDir string, contextLines int) (string, error) {
 	leftFiles, err := walkDirectory(leftDir)
 	if err != nil {
 		return "", fmt.Errorf("walking left directory: %w", err)
@@ -34,7 +39,7 @@ func CompareDirectories(leftDir, rightDir string) (string, error) {
 		inLeft := leftFiles[path]
 		inRight := rightFiles[path]
 
-		fileDiff, err := generateFileDiff(path, leftPath, rightPath, inLeft, inRight)
+		fileDiff, err := generateFileDiff(path, leftPath, rightPath, inLeft, inRight, contextLines)
 		if err != nil {
 			return "", fmt.Errorf("generating diff for %s: %w", path, err)
 		}
@@ -90,7 +95,7 @@ func mergeFilePaths(left, right map[string]bool) []string {
 	return paths
 }
 
-func generateFileDiff(relPath, leftPath, rightPath string, inLeft, inRight bool) (string, error) {
+func generateFileDiff(relPath, leftPath, rightPath string, inLeft, inRight bool, contextLines int) (string, error) {
 	var leftContent, rightContent string
 	var err error
 
@@ -112,7 +117,7 @@ func generateFileDiff(relPath, leftPath, rightPath string, inLeft, inRight bool)
 		return "", nil
 	}
 
-	return generateUnifiedDiff(relPath, leftContent, rightContent, inLeft, inRight), nil
+	return generateUnifiedDiff(relPath, leftContent, rightContent, inLeft, inRight, contextLines), nil
 }
 
 func readFileContent(path string) (string, error) {
@@ -125,7 +130,7 @@ func readFileContent(path string) (string, error) {
 	return string(content), nil
 }
 
-func generateUnifiedDiff(path, leftContent, rightContent string, inLeft, inRight bool) string {
+func generateUnifiedDiff(path, leftContent, rightContent string, inLeft, inRight bool, contextLines int) string {
 	var builder strings.Builder
 
 	fmt.Fprintf(&builder, "diff --git a/%s b/%s\n", path, path)
@@ -150,7 +155,7 @@ func generateUnifiedDiff(path, leftContent, rightContent string, inLeft, inRight
 
 	fmt.Fprintf(&builder, "--- a/%s\n", path)
 	fmt.Fprintf(&builder, "+++ b/%s\n", path)
-	builder.WriteString(generateModifiedFileHunks(leftContent, rightContent))
+	builder.WriteString(generateModifiedFileHunks(leftContent, rightContent, contextLines))
 
 	return builder.String()
 }
@@ -189,7 +194,7 @@ func generateDeletedFileHunks(content string) string {
 	return builder.String()
 }
 
-func generateModifiedFileHunks(leftContent, rightContent string) string {
+func generateModifiedFileHunks(leftContent, rightContent string, contextLines int) string {
 	// Line mode, not DiffMain's checklines heuristic. computeHunks splits every
 	// segment on newlines, so a character-level diff hands it fragments such as
 	// "p" / "fmt.P" / "rintln(" and the reconstructed file comes out corrupt.
@@ -197,7 +202,7 @@ func generateModifiedFileHunks(leftContent, rightContent string) string {
 	leftRunes, rightRunes, lineArray := dmp.DiffLinesToRunes(leftContent, rightContent)
 	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(leftRunes, rightRunes, false), lineArray)
 
-	return computeHunks(diffs)
+	return computeHunks(diffs, contextLines)
 }
 
 func splitLines(content string) []string {
@@ -212,9 +217,10 @@ func splitLines(content string) []string {
 	return lines
 }
 
-// hunkContextLines is how many unchanged lines are kept on each side of a change, and also the gap
-// below which two nearby changes are merged into one hunk.
-const hunkContextLines = 3
+// DefaultContext is how many unchanged lines are kept on each side of a change unless a source asks
+// for another number, as git and jj keep. It is also the gap below which two nearby changes are
+// merged into one hunk.
+const DefaultContext = 3
 
 // diffLine is one line of the flattened diff. A deletion carries no newNum and an addition carries no
 // oldNum, so the missing side is left at 0.
@@ -231,7 +237,7 @@ type hunkRange struct {
 	end   int
 }
 
-func computeHunks(diffs []godiff.Diff) string {
+func computeHunks(diffs []godiff.Diff, contextLines int) string {
 	allLines := flattenDiff(diffs)
 
 	changed := changedIndices(allLines)
@@ -240,7 +246,7 @@ func computeHunks(diffs []godiff.Diff) string {
 	}
 
 	var builder strings.Builder
-	for _, hr := range groupHunkRanges(changed, len(allLines)) {
+	for _, hr := range groupHunkRanges(changed, len(allLines), contextLines) {
 		writeHunk(&builder, allLines[hr.start:hr.end+1])
 	}
 
@@ -305,20 +311,20 @@ func changedIndices(lines []diffLine) []int {
 
 // groupHunkRanges pads each change with context and merges the ranges that end up touching, so two
 // changes closer than twice the context land in one hunk rather than two overlapping ones.
-func groupHunkRanges(changed []int, total int) []hunkRange {
+func groupHunkRanges(changed []int, total, contextLines int) []hunkRange {
 	var ranges []hunkRange
 
 	i := 0
 	for i < len(changed) {
-		start := max(changed[i]-hunkContextLines, 0)
-		end := min(changed[i]+hunkContextLines, total-1)
+		start := max(changed[i]-contextLines, 0)
+		end := min(changed[i]+contextLines, total-1)
 
 		for i < len(changed)-1 {
-			if changed[i+1]-hunkContextLines > end+1 {
+			if changed[i+1]-contextLines > end+1 {
 				break
 			}
 
-			end = min(changed[i+1]+hunkContextLines, total-1)
+			end = min(changed[i+1]+contextLines, total-1)
 			i++
 		}
 
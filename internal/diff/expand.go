@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// WholeGap asks ExpandContext for every unchanged line between a hunk and its neighbor, or the edge
+// of the file.
+const WholeGap = -1
+
+// ExpandContext reveals up to above unchanged lines before the hunk at hunkIdx and up to below after
+// it, read from content, the file as the diff's new side has it. WholeGap reveals the whole gap on
+// that side. A hunk grows only into its gaps, so revealing a whole gap leaves two hunks adjacent
+// rather than overlapping, and each still applies on its own.
+//
+// The revealed lines are context with both line numbers set, and the header is rewritten to count
+// them, so GeneratePatch and the applier treat them like the context jj emitted. The section heading
+// after the header is dropped once lines go in above, since it names what precedes the old start.
+//
+// It returns a copy of file and how many lines went in above, which is how far every line index of
+// the hunk moved. Content that does not reach the hunk, as when the file changed since the diff was
+// read, reveals nothing.
+func ExpandContext(file FileChange, hunkIdx int, content string, above, below int) (FileChange, int) {
+	if hunkIdx < 0 || hunkIdx >= len(file.Hunks) {
+		return file, 0
+	}
+
+	lines := splitLines(content)
+	hunk := file.Hunks[hunkIdx]
+
+	oldFirst, newFirst := firstLine(hunk.OldStart, hunk.OldLines), firstLine(hunk.NewStart, hunk.NewLines)
+	oldEnd, newEnd := oldFirst+hunk.OldLines, newFirst+hunk.NewLines
+
+	if newEnd-1 > len(lines) {
+		return file, 0
+	}
+
+	// floor and ceiling are the first and last lines of the new side the hunk may grow to.
+	floor, ceiling := 1, len(lines)
+	if hunkIdx > 0 {
+		previous := file.Hunks[hunkIdx-1]
+		floor = firstLine(previous.NewStart, previous.NewLines) + previous.NewLines
+	}
+
+	if hunkIdx+1 < len(file.Hunks) {
+		next := file.Hunks[hunkIdx+1]
+		ceiling = firstLine(next.NewStart, next.NewLines) - 1
+	}
+
+	up := revealed(above, newFirst-floor)
+	down := revealed(below, ceiling-newEnd+1)
+
+	if up == 0 && down == 0 {
+		return file, 0
+	}
+
+	expanded := make([]Line, 0, up+len(hunk.Lines)+down)
+
+	for i := range up {
+		expanded = append(expanded, contextLine(lines, oldFirst-up+i, newFirst-up+i))
+	}
+
+	expanded = append(expanded, hunk.Lines...)
+
+	for i := range down {
+		line := contextLine(lines, oldEnd+i, newEnd+i)
+		line.NoNewline = newEnd+i == len(lines) && !strings.HasSuffix(content, "\n")
+		expanded = append(expanded, line)
+	}
+
+	heading := ""
+	if up == 0 {
+		heading = headingOf(hunk.Header)
+	}
+
+	hunk.Lines = expanded
+	hunk.OldLines += up + down
+	hunk.NewLines += up + down
+	setHeader(&hunk, oldFirst-up, newFirst-up, heading)
+
+	file.Hunks = slices.Clone(file.Hunks)
+	file.Hunks[hunkIdx] = hunk
+	file.Conflicts = findConflicts(file.Hunks)
+
+	return file, up
+}
+
+// firstLine is the first line a hunk side covers. A side with no lines, such as the old side of an
+// added file, names the line it follows rather than one it covers.
+func firstLine(start, count int) int {
+	if count == 0 {
+		return start + 1
+	}
+
+	return start
+}
+
+// revealed is how many of a gap's lines a request reveals.
+func revealed(requested, gap int) int {
+	if requested == WholeGap || requested > gap {
+		return max(gap, 0)
+	}
+
+	return max(requested, 0)
+}
+
+func contextLine(lines []string, oldNum, newNum int) Line {
+	return Line{Content: lines[newNum-1], Type: LineContext, OldLineNum: oldNum, NewLineNum: newNum}
+}
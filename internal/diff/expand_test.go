@@ -0,0 +1,82 @@
+package diff_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// numbered returns lines "line 1" through "line n", with the lines in edits replaced.
+func numbered(n int, edits map[int]string) string {
+	var builder strings.Builder
+
+	for i := 1; i <= n; i++ {
+		line, ok := edits[i]
+		if !ok {
+			line = fmt.Sprintf("line %d", i)
+		}
+
+		builder.WriteString(line + "\n")
+	}
+
+	return builder.String()
+}
+
+func TestExpandContext(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	left, right := filepath.Join(base, "left"), filepath.Join(base, "right")
+	newContent := numbered(20, map[int]string{5: "FIVE", 15: "FIFTEEN"})
+
+	writeTree(t, left, "f.txt", numbered(20, nil))
+	writeTree(t, right, "f.txt", newContent)
+
+	source := diff.NewDirectorySource(left, right)
+	source.Context = 1
+
+	text, err := source.GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+
+	file := diff.Parse(text)[0]
+
+	expanded, shift := diff.ExpandContext(file, 0, newContent, 2, diff.WholeGap)
+	if shift != 2 {
+		t.Errorf("Expected the two lines above to shift the hunk, got %d", shift)
+	}
+
+	hunk := expanded.Hunks[0]
+	if hunk.Header != "@@ -2,12 +2,12 @@" || hunk.Lines[0].Content != "line 2" || hunk.Lines[0].OldLineNum != 2 {
+		t.Errorf("Expected lines 2 to 13, up to the next hunk, got %s from %+v", hunk.Header, hunk.Lines[0])
+	}
+
+	if file.Hunks[0].Header != "@@ -4,3 +4,3 @@" {
+		t.Errorf("Expected the original file untouched, got %s", file.Hunks[0].Header)
+	}
+
+	if again, shift := diff.ExpandContext(expanded, 0, newContent, 0, 5); shift != 0 ||
+		len(again.Hunks[0].Lines) != len(hunk.Lines) {
+		t.Error("Expected nothing left to reveal below a hunk adjacent to the next")
+	}
+
+	expanded, _ = diff.ExpandContext(expanded, 1, newContent, 0, diff.WholeGap)
+	if last := expanded.Hunks[1].Lines[len(expanded.Hunks[1].Lines)-1]; last.NewLineNum != 20 {
+		t.Errorf("Expected the last hunk to reach the end of the file, got line %d", last.NewLineNum)
+	}
+
+	dir := t.TempDir()
+	writeTree(t, dir, "f.txt", numbered(20, nil))
+
+	patch := diff.GeneratePatch([]diff.FileChange{expanded}, allOrNothing{keep: true})
+	if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyPatch failed on the expanded hunks: %v\n%s", err, patch)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "f.txt"), newContent)
+}
@@ -9,10 +9,12 @@ import (
 )
 
 // FileChange holds one file's hunks in the order the diff lists them. Path is the "b/" side of the
-// diff header, so a renamed file carries its new path.
+// diff header, so a renamed file carries its new path. Conflicts lists the jj conflicts the new side
+// still carries, in diff order.
 type FileChange struct {
 	Path       string
 	Hunks      []Hunk
+	Conflicts  []ConflictRegion
 	ChangeType ChangeType
 }
 
@@ -57,12 +59,14 @@ type Hunk struct {
 
 // Line is one diff line with the leading +, -, or space stripped from Content. OldLineNum and
 // NewLineNum are 1-based and both are always set, so an addition still records where it falls on
-// the old side.
+// the old side. NoNewline marks the last line of a file that does not end in a newline, which the
+// diff records as a "\ No newline at end of file" line after it.
 type Line struct {
 	Content    string
 	Type       LineType
 	OldLineNum int
 	NewLineNum int
+	NoNewline  bool
 }
 
 // LineType marks a diff line as context, an addition, or a deletion. String returns the diff
@@ -120,6 +124,10 @@ func Parse(diffText string) []FileChange {
 	return files
 }
 
+// noNewlinePrefix starts the line a diff puts after a file's last line when the file has no trailing
+// newline. Only the backslash is checked, because git translates the rest of the message.
+const noNewlinePrefix = `\`
+
 // Submatch counts the header patterns must produce, which is one more than the group count each
 // pattern defines.
 const (
@@ -166,6 +174,14 @@ func parseFileChange(section string) *FileChange {
 			continue
 		}
 
+		if strings.HasPrefix(line, noNewlinePrefix) {
+			if last := len(currentHunk.Lines) - 1; last >= 0 {
+				currentHunk.Lines[last].NoNewline = true
+			}
+
+			continue
+		}
+
 		parsed := classifyLine(line)
 		parsed.OldLineNum = oldLineNum
 		parsed.NewLineNum = newLineNum
@@ -186,13 +202,18 @@ func parseFileChange(section string) *FileChange {
 		file.Hunks = append(file.Hunks, *currentHunk)
 	}
 
+	file.Conflicts = findConflicts(file.Hunks)
+
 	return file
 }
 
 // isMetadataLine reports the header lines that can appear inside a section after a hunk header and
-// carry no diff content, including the ones that start with a + or - marker.
+// carry no diff content, including the ones that start with a + or - marker. Only the --- a/ and
+// +++ b/ shapes count, because an added "+++++++" or deleted "-------" line is a jj conflict marker
+// and has to reach the hunk.
 func isMetadataLine(line string) bool {
-	return strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") ||
+	return strings.HasPrefix(line, "--- a/") || strings.HasPrefix(line, "+++ b/") ||
+		strings.HasPrefix(line, "--- /dev/null") || strings.HasPrefix(line, "+++ /dev/null") ||
 		strings.HasPrefix(line, "index ") || strings.HasPrefix(line, "new file") ||
 		strings.HasPrefix(line, "deleted file")
 }
@@ -148,6 +148,44 @@ diff --git a/file2.txt b/file2.txt
 	}
 }
 
+// TestParse_NoNewlineAtEndOfFile tests that the marker flags the line before it rather than becoming
+// a context line of its own, and that GeneratePatch writes it back.
+func TestParse_NoNewlineAtEndOfFile(t *testing.T) {
+	t.Parallel()
+
+	input := `diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,2 +1,2 @@
+ line 1
+-line 2
+\ No newline at end of file
++line two
+\ No newline at end of file
+`
+
+	result := diff.Parse(input)
+	if len(result) != 1 || len(result[0].Hunks) != 1 {
+		t.Fatalf("Expected one file with one hunk, got %+v", result)
+	}
+
+	lines := result[0].Hunks[0].Lines
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %+v", len(lines), lines)
+	}
+
+	if lines[0].NoNewline || !lines[1].NoNewline || !lines[2].NoNewline {
+		t.Errorf("Expected only the last line of each side flagged, got %+v", lines)
+	}
+
+	patch := diff.GeneratePatch(result, &mockSelectionState{
+		selections: map[string]map[int]bool{"file.txt": {0: true}},
+	})
+	if patch != input {
+		t.Errorf("Expected the patch to round-trip, got:\n%s", patch)
+	}
+}
+
 func TestFileChange_AddedLines(t *testing.T) {
 	t.Parallel()
 
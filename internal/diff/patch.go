@@ -12,7 +12,7 @@ type SelectionState interface {
 	IsLineSelected(filePath string, hunkIdx, lineIdx int) bool
 }
 
-// GeneratePatch renders the selected hunks as a unified diff that git apply accepts. A fully
+// GeneratePatch renders the selected hunks as a unified diff that ApplyPatch accepts. A fully
 // selected hunk is copied verbatim, and a partially selected one keeps the selected lines plus three
 // lines of context on each side with recalculated header counts. A file with nothing selected is
 // left out, so an empty selection returns the empty string.
@@ -71,14 +71,25 @@ func renderWholeHunk(hunk Hunk) string {
 	buf.WriteString("\n")
 
 	for _, line := range hunk.Lines {
-		buf.WriteString(line.Type.String())
-		buf.WriteString(line.Content)
-		buf.WriteString("\n")
+		writePatchLine(&buf, line)
 	}
 
 	return buf.String()
 }
 
+// noNewlineMarker is the line a unified diff puts after a line that ends its file without a newline.
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+func writePatchLine(buf *strings.Builder, line Line) {
+	buf.WriteString(line.Type.String())
+	buf.WriteString(line.Content)
+	buf.WriteString("\n")
+
+	if line.NoNewline {
+		buf.WriteString(noNewlineMarker)
+	}
+}
+
 func renderPartialHunk(hunk Hunk, hunkIdx int, filePath string, selection SelectionState) string {
 	// Build selected lines with context
 	selectedLines := make(map[int]bool)
@@ -96,12 +107,24 @@ func renderPartialHunk(hunk Hunk, hunkIdx int, filePath string, selection Select
 	const contextLines = 3
 	expandedSelection := expandWithContext(selectedLines, len(hunk.Lines), contextLines)
 
-	// Build lines
+	// Build lines. A change that was not selected stays where it is, so the patch must not carry it:
+	// an unselected addition is not on the old side the patch applies to and is dropped, and an
+	// unselected deletion is still there and becomes context.
 	var lines []Line
 	for lineIdx, line := range hunk.Lines {
-		if expandedSelection[lineIdx] {
-			lines = append(lines, line)
+		if !expandedSelection[lineIdx] {
+			continue
+		}
+
+		if !selectedLines[lineIdx] {
+			if line.Type == LineAddition {
+				continue
+			}
+
+			line.Type = LineContext
 		}
+
+		lines = append(lines, line)
 	}
 
 	if len(lines) == 0 {
@@ -116,9 +139,7 @@ func renderPartialHunk(hunk Hunk, hunkIdx int, filePath string, selection Select
 	buf.WriteString("\n")
 
 	for _, line := range lines {
-		buf.WriteString(line.Type.String())
-		buf.WriteString(line.Content)
-		buf.WriteString("\n")
+		writePatchLine(&buf, line)
 	}
 
 	return buf.String()
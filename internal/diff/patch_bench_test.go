@@ -205,3 +205,15 @@ func BenchmarkPatchGeneration_FewLargeHunks(b *testing.B) {
 		_ = diff.GeneratePatch(files, selection)
 	}
 }
+
+// BenchmarkSplitPatch_LargeDiff benchmarks cutting a 100,000-line patch into segments, which the
+// pager and a patch read from a file or stdin go through before parsing.
+func BenchmarkSplitPatch_LargeDiff(b *testing.B) {
+	files := generateBenchmarkFiles(100, 20, 50)
+	patch := diff.GeneratePatch(files, createFullSelection(files))
+
+	b.ResetTimer()
+	for range b.N {
+		_ = diff.SplitPatch(patch)
+	}
+}
@@ -435,6 +435,34 @@ func TestGeneratePatch_PartialHunk(t *testing.T) {
 	}
 }
 
+func TestGeneratePatch_PartialHunkLeavesUnselectedChanges(t *testing.T) {
+	t.Parallel()
+
+	files := diff.Parse(`diff --git a/file.txt b/file.txt
+--- a/file.txt
++++ b/file.txt
+@@ -1,3 +1,3 @@
+ keep
+-old
++new
++other
+-gone
+`)
+
+	mock := &mockSelectionState{
+		selections:     make(map[string]map[int]bool),
+		lineSelections: map[string]map[int]map[int]bool{"file.txt": {0: {2: true}}},
+		partialHunks:   map[string]map[int]bool{"file.txt": {0: true}},
+	}
+
+	patch := diff.GeneratePatch(files, mock)
+
+	want := "@@ -1,3 +1,4 @@\n keep\n old\n+new\n gone\n"
+	if !strings.HasSuffix(patch, want) {
+		t.Errorf("Expected only +new to change, with the other deletions as context, got:\n%s", patch)
+	}
+}
+
 // TestExpandWithContext tests context expansion algorithm.
 func TestExpandWithContext(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,582 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reasons a hunk or a whole patch fails to apply. A HunkError wraps one of the hunk reasons, so
+// callers can tell them apart with errors.Is.
+var (
+	ErrEmptyPatch     = errors.New("patch contains no file changes")
+	ErrHunkMismatch   = errors.New("hunk does not match the file")
+	ErrFileMissing    = errors.New("file to patch does not exist")
+	ErrFileExists     = errors.New("file to create already exists")
+	ErrFileNotEmptied = errors.New("file to delete still has content after the patch")
+)
+
+// ApplyOptions changes how ApplyPatch reads a patch.
+type ApplyOptions struct {
+	// Reverse applies the patch backwards, taking its changes out of files that already have them.
+	Reverse bool
+	// Partial writes the hunks that apply and leaves the rest out, rather than writing nothing when
+	// any hunk fails. The report says which hunks were left out.
+	Partial bool
+	// Fuzz is how many context lines a hunk may ignore at each end when it matches nowhere whole, as
+	// patch's --fuzz does. The changed lines themselves must always match.
+	Fuzz int
+}
+
+// HunkStatus is how a hunk fared against the file it was applied to.
+type HunkStatus int
+
+// Hunk outcomes. HunkApplied is the zero value: the hunk matched at the line its header names with
+// all of its context.
+const (
+	HunkApplied HunkStatus = iota
+	HunkShifted
+	HunkRejected
+)
+
+func (s HunkStatus) String() string {
+	switch s {
+	case HunkApplied:
+		return "applied"
+	case HunkShifted:
+		return "shifted"
+	case HunkRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// HunkResult is one hunk's outcome. Offset is how many lines from its header's position the hunk
+// landed, and Fuzz how many context lines at each end it had to ignore to land there; either being
+// non-zero makes the hunk shifted. A rejected hunk carries the *HunkError saying why.
+type HunkResult struct {
+	Err    *HunkError
+	Path   string
+	Header string
+	Index  int
+	Offset int
+	Fuzz   int
+	Status HunkStatus
+}
+
+// ApplyReport lists every hunk of a patch in patch order with its outcome.
+type ApplyReport struct {
+	Hunks []HunkResult
+}
+
+// Count returns how many hunks ended with status.
+func (r ApplyReport) Count(status HunkStatus) int {
+	count := 0
+
+	for _, hunk := range r.Hunks {
+		if hunk.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Clean reports whether every hunk applied exactly where and as its header said.
+func (r ApplyReport) Clean() bool {
+	return r.Count(HunkApplied) == len(r.Hunks)
+}
+
+// Err returns a *PatchError naming every rejected hunk, or nil when none was.
+func (r ApplyReport) Err() error {
+	var failures []*HunkError
+
+	for _, hunk := range r.Hunks {
+		if hunk.Err != nil {
+			failures = append(failures, hunk.Err)
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &PatchError{Hunks: failures}
+}
+
+// Landed narrows patch, the patch the report was made from, to the hunks that were applied or
+// shifted. Hunks are copied as written, so a shifted hunk keeps its original header.
+func (r ApplyReport) Landed(patch string) string {
+	return GeneratePatch(Parse(patch), landedHunks(r))
+}
+
+// landedHunks selects the hunks a report says were written, so GeneratePatch can narrow a patch to
+// them.
+type landedHunks ApplyReport
+
+func (l landedHunks) IsHunkSelected(filePath string, hunkIdx int) bool {
+	for _, hunk := range l.Hunks {
+		if hunk.Path == filePath && hunk.Index == hunkIdx {
+			return hunk.Status != HunkRejected
+		}
+	}
+
+	return false
+}
+
+func (landedHunks) HasPartialSelection(string, int) bool {
+	return false
+}
+
+func (landedHunks) IsLineSelected(string, int, int) bool {
+	return false
+}
+
+// HunkError is one hunk that did not apply. Index counts hunks within the file from 0, and Header is
+// the hunk's @@ line as the patch wrote it.
+type HunkError struct {
+	Err    error
+	Path   string
+	Header string
+	Index  int
+}
+
+func (e *HunkError) Error() string {
+	return fmt.Sprintf("%s: hunk %d (%s): %v", e.Path, e.Index+1, e.Header, e.Err)
+}
+
+func (e *HunkError) Unwrap() error {
+	return e.Err
+}
+
+// PatchError lists every hunk that did not apply, in patch order, rather than stopping at the first.
+type PatchError struct {
+	Hunks []*HunkError
+}
+
+func (e *PatchError) Error() string {
+	messages := make([]string, 0, len(e.Hunks))
+	for _, hunk := range e.Hunks {
+		messages = append(messages, hunk.Error())
+	}
+
+	return "patch does not apply: " + strings.Join(messages, "; ")
+}
+
+func (e *PatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Hunks))
+	for _, hunk := range e.Hunks {
+		errs = append(errs, hunk)
+	}
+
+	return errs
+}
+
+// ApplyPatch applies a unified diff, as GeneratePatch renders one, to the files under dir, and reports
+// what happened to each hunk. Each hunk is looked for at the line its header names first and then at
+// growing distances either side, so a file that shifted since the diff was taken still takes it, and
+// each hunk's shift carries over to the next. Only when a hunk matches nowhere are its outer context
+// lines dropped, one more at each end per pass up to opts.Fuzz. Unless opts.Partial is set, nothing is
+// written when a hunk fails, and the error is a *PatchError naming each hunk that did; a partial apply
+// writes what landed and leaves the failures to the report.
+func ApplyPatch(dir, patch string, opts ApplyOptions) (ApplyReport, error) {
+	report, results, err := patchFiles(patch, opts, func(name string, changeType ChangeType) (patchedFile, error) {
+		path, err := containedPath(dir, name)
+		if err != nil {
+			return patchedFile{}, err
+		}
+
+		content, mode, err := readPatchTarget(path, changeType)
+
+		return patchedFile{path: path, content: content, mode: mode}, err
+	})
+	if err != nil {
+		return report, err
+	}
+
+	if !opts.Partial {
+		if err := report.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	for _, result := range results {
+		if err := result.write(); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// CheckPatch reports how each hunk of patch would apply to the given file contents, keyed by the
+// paths the patch names, without writing anything; a path missing from contents is a file that does
+// not exist. It matches hunks exactly as ApplyPatch does, so a patch that checks clean applies clean.
+func CheckPatch(patch string, contents map[string]string, opts ApplyOptions) (ApplyReport, error) {
+	report, _, err := patchFiles(patch, opts, func(name string, changeType ChangeType) (patchedFile, error) {
+		content, exists := contents[name]
+
+		switch {
+		case changeType == ChangeTypeAdded && exists:
+			return patchedFile{}, ErrFileExists
+		case changeType != ChangeTypeAdded && !exists:
+			return patchedFile{}, ErrFileMissing
+		}
+
+		return patchedFile{path: name, content: content}, nil
+	})
+
+	return report, err
+}
+
+// targetReader loads the file a patch names, as the path to write it back to and its current content
+// and permissions. It returns one of the hunk reasons, such as ErrFileMissing, when the file cannot
+// take the patch.
+type targetReader func(name string, changeType ChangeType) (patchedFile, error)
+
+// patchFiles applies every file of patch in memory, reading each through read, and returns the report
+// and the files that have something to write.
+func patchFiles(patch string, opts ApplyOptions, read targetReader) (ApplyReport, []patchedFile, error) {
+	files := Parse(patch)
+	if len(files) == 0 {
+		return ApplyReport{}, nil, ErrEmptyPatch
+	}
+
+	var report ApplyReport
+
+	results := make([]patchedFile, 0, len(files))
+
+	for _, file := range files {
+		result, hunks := patchFile(file, opts, read)
+		report.Hunks = append(report.Hunks, hunks...)
+
+		if result.path != "" {
+			results = append(results, result)
+		}
+	}
+
+	return report, results, nil
+}
+
+// patchedFile is one file's content after the patch, held until every file has applied.
+type patchedFile struct {
+	path    string
+	content string
+	mode    fs.FileMode
+	remove  bool
+}
+
+func (f patchedFile) write() error {
+	if f.remove {
+		return removeFile(f.path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), reconstructedDirMode); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", f.path, err)
+	}
+
+	if err := os.WriteFile(f.path, []byte(f.content), f.mode); err != nil {
+		return fmt.Errorf("writing %s: %w", f.path, err)
+	}
+
+	return nil
+}
+
+// patchFile applies one file's hunks in memory. A file-level problem, such as a missing file, rejects
+// every hunk in it, because none of them has anything to apply to. The returned file has no path when
+// there is nothing to write, either because a hunk failed outside a partial apply or because none
+// landed.
+func patchFile(file FileChange, opts ApplyOptions, read targetReader) (patchedFile, []HunkResult) {
+	rejectAll := func(err error) []HunkResult {
+		results := make([]HunkResult, 0, max(len(file.Hunks), 1))
+		for i, hunk := range file.Hunks {
+			results = append(results, rejected(file.Path, i, hunk.Header, err))
+		}
+
+		if len(results) == 0 {
+			results = append(results, rejected(file.Path, 0, "", err))
+		}
+
+		return results
+	}
+
+	changeType := file.ChangeType
+	if opts.Reverse {
+		changeType = reverseChangeType(changeType)
+	}
+
+	target, err := read(file.Path, changeType)
+	if err != nil {
+		return patchedFile{}, rejectAll(err)
+	}
+
+	content, results := applyHunks(file, splitKeepingNewlines(target.content), opts)
+
+	landed := 0
+	for _, result := range results {
+		if result.Status != HunkRejected {
+			landed++
+		}
+	}
+
+	if landed < len(results) && (!opts.Partial || landed == 0) {
+		return patchedFile{}, results
+	}
+
+	if changeType == ChangeTypeDeleted {
+		if content != "" {
+			return patchedFile{}, rejectAll(ErrFileNotEmptied)
+		}
+
+		return patchedFile{path: target.path, remove: true}, results
+	}
+
+	target.content = content
+
+	return target, results
+}
+
+func rejected(path string, index int, header string, err error) HunkResult {
+	return HunkResult{
+		Path:   path,
+		Index:  index,
+		Header: header,
+		Status: HunkRejected,
+		Err:    &HunkError{Path: path, Index: index, Header: header, Err: err},
+	}
+}
+
+func reverseChangeType(changeType ChangeType) ChangeType {
+	switch changeType {
+	case ChangeTypeAdded:
+		return ChangeTypeDeleted
+	case ChangeTypeDeleted:
+		return ChangeTypeAdded
+	case ChangeTypeModified, ChangeTypeRenamed:
+		return changeType
+	default:
+		return changeType
+	}
+}
+
+// readPatchTarget reads the file a patch edits, with the permissions to write it back with. A file
+// the patch creates must not exist yet and starts empty.
+func readPatchTarget(path string, changeType ChangeType) (string, fs.FileMode, error) {
+	info, err := os.Stat(path)
+
+	if changeType == ChangeTypeAdded {
+		if err == nil {
+			return "", 0, ErrFileExists
+		}
+
+		return "", reconstructedFileMode, nil
+	}
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", 0, ErrFileMissing
+	}
+
+	if err != nil {
+		return "", 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	//nolint:gosec // G304: callers resolve path through containedPath first.
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return string(content), info.Mode().Perm(), nil
+}
+
+// applyHunks replays the hunks over lines in order and returns the new content, with the hunks that
+// did not match left out, and each hunk's outcome. A hunk may not start inside the lines an earlier
+// hunk already replaced.
+func applyHunks(file FileChange, lines []string, opts ApplyOptions) (string, []HunkResult) {
+	var out []string
+
+	results := make([]HunkResult, 0, len(file.Hunks))
+	cursor, shift := 0, 0
+
+	for i, hunk := range file.Hunks {
+		before, after, start := hunkImages(hunk, opts.Reverse)
+
+		placed, ok := placeHunk(lines, before, after, start, shift, cursor, opts.Fuzz)
+		if !ok {
+			results = append(results, rejected(file.Path, i, hunk.Header, ErrHunkMismatch))
+
+			continue
+		}
+
+		out = append(out, lines[cursor:placed.at]...)
+		out = append(out, placed.after...)
+		cursor = placed.at + len(placed.before)
+		shift = placed.at - placed.start
+
+		result := HunkResult{
+			Path:   file.Path,
+			Index:  i,
+			Header: hunk.Header,
+			Offset: placed.at - placed.start,
+			Fuzz:   placed.fuzz,
+		}
+		if result.Offset != 0 || result.Fuzz != 0 {
+			result.Status = HunkShifted
+		}
+
+		results = append(results, result)
+	}
+
+	out = append(out, lines[cursor:]...)
+
+	return strings.Join(out, ""), results
+}
+
+// placement is where a hunk landed: at is the index its before image starts at once trimmed, and
+// start where its header alone puts that image.
+type placement struct {
+	before []string
+	after  []string
+	at     int
+	start  int
+	fuzz   int
+}
+
+// placeHunk finds the hunk with all of its context first and then with up to fuzz context lines
+// dropped from each end. Dropping a leading line moves where the rest is expected by one, and only
+// context can be dropped, so the changed lines always have to match.
+func placeHunk(lines, before, after []string, start, shift, floor, fuzz int) (placement, bool) {
+	leading, trailing := contextRun(before, after)
+
+	for level := 0; level <= fuzz; level++ {
+		lead, trail := min(level, leading), min(level, trailing)
+		if level > 0 && lead+trail == 0 {
+			break
+		}
+
+		trimmed := before[lead : len(before)-trail]
+		expected := start + lead + shift
+
+		if at, ok := findImage(lines, trimmed, expected, floor); ok {
+			return placement{
+				before: trimmed,
+				after:  after[lead : len(after)-trail],
+				at:     at,
+				start:  start + lead,
+				fuzz:   level,
+			}, true
+		}
+
+		if lead < level && trail < level {
+			break
+		}
+	}
+
+	return placement{}, false
+}
+
+// contextRun counts the context lines the before and after images share at each end, which is how
+// many a fuzzy match may drop. The counts never overlap and always leave one line of the before
+// image, because an empty image would match anywhere.
+func contextRun(before, after []string) (leading, trailing int) {
+	shorter := min(len(before), len(after))
+
+	for leading < shorter && before[leading] == after[leading] {
+		leading++
+	}
+
+	for trailing < shorter-leading &&
+		before[len(before)-1-trailing] == after[len(after)-1-trailing] {
+		trailing++
+	}
+
+	droppable := max(len(before)-1, 0)
+	leading = min(leading, droppable)
+	trailing = min(trailing, droppable-leading)
+
+	return leading, trailing
+}
+
+// hunkImages returns the lines a hunk expects to find, the lines it leaves in their place, and the
+// 0-based index it expects to find them at. Each line keeps its newline unless the diff marked it as
+// the end of a file without one, so a changed trailing newline compares like any other edit.
+func hunkImages(hunk Hunk, reverse bool) (before, after []string, start int) {
+	removed, added := LineDeletion, LineAddition
+	start = hunk.OldStart
+
+	if reverse {
+		removed, added = LineAddition, LineDeletion
+		start = hunk.NewStart
+	}
+
+	for _, line := range hunk.Lines {
+		text := line.Content
+		if !line.NoNewline {
+			text += "\n"
+		}
+
+		switch line.Type {
+		case LineContext:
+			before = append(before, text)
+			after = append(after, text)
+		case removed:
+			before = append(before, text)
+		case added:
+			after = append(after, text)
+		}
+	}
+
+	// A header's start names the first line the hunk covers, except when it covers none, where it
+	// names the line the insertion follows.
+	if len(before) > 0 {
+		start--
+	}
+
+	return before, after, start
+}
+
+// findImage looks for image in lines at expected, then one line either side, then two, and so on,
+// never before floor. An empty image only fits where it was expected, because there is nothing to
+// match it against.
+func findImage(lines, image []string, expected, floor int) (int, bool) {
+	last := len(lines) - len(image)
+
+	if len(image) == 0 {
+		return expected, expected >= floor && expected <= len(lines)
+	}
+
+	for distance := 0; expected-distance >= floor || expected+distance <= last; distance++ {
+		for _, at := range []int{expected - distance, expected + distance} {
+			if at >= floor && at <= last && imageAt(lines, image, at) {
+				return at, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func imageAt(lines, image []string, at int) bool {
+	for i, text := range image {
+		if lines[at+i] != text {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitKeepingNewlines splits content into lines that each keep their trailing newline, so the last
+// line of a file without one is the only line without one.
+func splitKeepingNewlines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
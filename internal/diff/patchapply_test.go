@@ -0,0 +1,331 @@
+package diff_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+const modifyPatch = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -2,3 +2,3 @@
+ two
+-three
++THREE
+ four
+`
+
+func TestApplyPatch_AppliesAtTheHeaderLine(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "main.go", "one\ntwo\nthree\nfour\nfive\n")
+
+	if _, err := diff.ApplyPatch(dir, modifyPatch, diff.ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "main.go"), "one\ntwo\nTHREE\nfour\nfive\n")
+}
+
+// TestApplyPatch_FollowsShiftedLines tests that a hunk is found away from its header line, and that
+// the shift carries to the next hunk.
+func TestApplyPatch_FollowsShiftedLines(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "main.go", "new 1\nnew 2\none\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n")
+
+	patch := modifyPatch + `@@ -7,2 +7,3 @@
+ seven
++SEVEN AND A HALF
+ eight
+`
+
+	report, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{})
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+
+	assertFileBytes(
+		t,
+		filepath.Join(dir, "main.go"),
+		"new 1\nnew 2\none\ntwo\nTHREE\nfour\nfive\nsix\nseven\nSEVEN AND A HALF\neight\n",
+	)
+
+	for i, hunk := range report.Hunks {
+		if hunk.Status != diff.HunkShifted || hunk.Offset != 2 || hunk.Fuzz != 0 {
+			t.Errorf("Expected hunk %d shifted by 2 without fuzz, got %+v", i, hunk)
+		}
+	}
+}
+
+// TestApplyPatch_FuzzIgnoresDriftedContext tests that a hunk whose context changed applies only once
+// fuzz lets it ignore that context, and is then reported as shifted.
+func TestApplyPatch_FuzzIgnoresDriftedContext(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "main.go", "one\nTWO\nthree\nfour\nfive\n")
+
+	if _, err := diff.ApplyPatch(dir, modifyPatch, diff.ApplyOptions{}); !errors.Is(err, diff.ErrHunkMismatch) {
+		t.Fatalf("Expected ErrHunkMismatch without fuzz, got %v", err)
+	}
+
+	report, err := diff.ApplyPatch(dir, modifyPatch, diff.ApplyOptions{Fuzz: 1})
+	if err != nil {
+		t.Fatalf("ApplyPatch with fuzz failed: %v", err)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "main.go"), "one\nTWO\nTHREE\nfour\nfive\n")
+
+	if got := report.Hunks[0]; got.Status != diff.HunkShifted || got.Fuzz != 1 || got.Offset != 0 {
+		t.Errorf("Expected the hunk shifted with fuzz 1 at offset 0, got %+v", got)
+	}
+}
+
+// TestApplyPatch_FuzzNeverIgnoresChangedLines tests that fuzz only drops context, so a hunk whose
+// deleted line is gone is rejected however much fuzz is allowed.
+func TestApplyPatch_FuzzNeverIgnoresChangedLines(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "main.go", "one\ntwo\n3\nfour\nfive\n")
+
+	_, err := diff.ApplyPatch(dir, modifyPatch, diff.ApplyOptions{Fuzz: 10})
+	if !errors.Is(err, diff.ErrHunkMismatch) {
+		t.Errorf("Expected ErrHunkMismatch, got %v", err)
+	}
+}
+
+// TestApplyPatch_PartialWritesWhatLands tests that a partial apply writes the hunks that landed,
+// reports the rest, and narrows the patch to what landed.
+func TestApplyPatch_PartialWritesWhatLands(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "main.go", "one\ntwo\nthree\nfour\nfive\nsix\n")
+
+	rejectedHunk := `@@ -5,2 +5,2 @@
+ five
+-not in the file
++anything
+`
+	patch := modifyPatch + rejectedHunk
+
+	report, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{Partial: true})
+	if err != nil {
+		t.Fatalf("Partial ApplyPatch failed: %v", err)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "main.go"), "one\ntwo\nTHREE\nfour\nfive\nsix\n")
+
+	if report.Count(diff.HunkApplied) != 1 || report.Count(diff.HunkRejected) != 1 || report.Clean() {
+		t.Errorf("Expected one applied and one rejected hunk, got %+v", report.Hunks)
+	}
+
+	if !errors.Is(report.Err(), diff.ErrHunkMismatch) {
+		t.Errorf("Expected the report's error to name the mismatch, got %v", report.Err())
+	}
+
+	if landed := report.Landed(patch); landed != modifyPatch {
+		t.Errorf("Expected the landed patch to be the first hunk alone, got:\n%s", landed)
+	}
+}
+
+func TestApplyPatch_AddsAndDeletesFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "old.txt", "gone\nsoon\n")
+
+	patch := `diff --git a/src/new.txt b/src/new.txt
+new file mode 100644
+--- /dev/null
++++ b/src/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-gone
+-soon
+`
+
+	if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "src", "new.txt"), "hello\nworld\n")
+
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected old.txt deleted, got %v", err)
+	}
+
+	if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{Reverse: true}); err != nil {
+		t.Fatalf("Reverse ApplyPatch failed: %v", err)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "old.txt"), "gone\nsoon\n")
+
+	if _, err := os.Stat(filepath.Join(dir, "src", "new.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the reverse to delete src/new.txt, got %v", err)
+	}
+}
+
+func TestApplyPatch_NoNewlineAtEndOfFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		before string
+		patch  string
+		after  string
+	}{
+		{
+			name:   "edit keeps the missing newline",
+			before: "one\ntwo",
+			patch:  "@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+TWO\n\\ No newline at end of file\n",
+			after:  "one\nTWO",
+		},
+		{
+			name:   "add the newline",
+			before: "one\ntwo",
+			patch:  "@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+two\n",
+			after:  "one\ntwo\n",
+		},
+		{
+			name:   "drop the newline",
+			before: "one\ntwo\n",
+			patch:  "@@ -1,2 +1,2 @@\n one\n-two\n+two\n\\ No newline at end of file\n",
+			after:  "one\ntwo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeTree(t, dir, "file.txt", tt.before)
+
+			patch := "diff --git a/file.txt b/file.txt\n--- a/file.txt\n+++ b/file.txt\n" + tt.patch
+			if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{}); err != nil {
+				t.Fatalf("ApplyPatch failed: %v", err)
+			}
+
+			assertFileBytes(t, filepath.Join(dir, "file.txt"), tt.after)
+
+			if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{Reverse: true}); err != nil {
+				t.Fatalf("Reverse ApplyPatch failed: %v", err)
+			}
+
+			assertFileBytes(t, filepath.Join(dir, "file.txt"), tt.before)
+		})
+	}
+}
+
+// TestApplyPatch_ReportsEveryFailedHunk tests that a failure names each hunk that did not apply, and
+// that no file is written, including the ones whose hunks did apply.
+func TestApplyPatch_ReportsEveryFailedHunk(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTree(t, dir, "main.go", "one\ntwo\nthree\nfour\nfive\n")
+
+	patch := modifyPatch + `diff --git a/other.txt b/other.txt
+--- a/other.txt
++++ b/other.txt
+@@ -1 +1 @@
+-a
++b
+diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,1 +1,1 @@
+-not in the file
++anything
+`
+
+	_, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{})
+
+	var patchErr *diff.PatchError
+	if !errors.As(err, &patchErr) {
+		t.Fatalf("Expected a *PatchError, got %v", err)
+	}
+
+	if len(patchErr.Hunks) != 2 {
+		t.Fatalf("Expected 2 failed hunks, got %d: %v", len(patchErr.Hunks), err)
+	}
+
+	missing, mismatch := patchErr.Hunks[0], patchErr.Hunks[1]
+	if missing.Path != "other.txt" || !errors.Is(missing, diff.ErrFileMissing) {
+		t.Errorf("Expected other.txt reported missing, got %v", missing)
+	}
+
+	if mismatch.Path != "main.go" || mismatch.Header != "@@ -1,1 +1,1 @@" ||
+		!errors.Is(mismatch, diff.ErrHunkMismatch) {
+		t.Errorf("Expected main.go's second section reported as a mismatch, got %v", mismatch)
+	}
+
+	if !errors.Is(err, diff.ErrHunkMismatch) {
+		t.Error("Expected errors.Is to see through PatchError to the hunk reasons")
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "main.go"), "one\ntwo\nthree\nfour\nfive\n")
+}
+
+func TestApplyPatch_RejectsPathsOutsideDir(t *testing.T) {
+	t.Parallel()
+
+	patch := "diff --git a/../escape.txt b/../escape.txt\nnew file mode 100644\n--- /dev/null\n" +
+		"+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"
+
+	_, err := diff.ApplyPatch(t.TempDir(), patch, diff.ApplyOptions{})
+	if !errors.Is(err, diff.ErrPathEscapesBase) {
+		t.Errorf("Expected ErrPathEscapesBase, got %v", err)
+	}
+}
+
+// TestCheckPatch_ReportsWithoutFiles tests that CheckPatch judges hunks against contents in memory
+// the same way ApplyPatch judges them on disk.
+func TestCheckPatch_ReportsWithoutFiles(t *testing.T) {
+	t.Parallel()
+
+	patch := modifyPatch + `diff --git a/gone.txt b/gone.txt
+--- a/gone.txt
++++ b/gone.txt
+@@ -1 +1 @@
+-a
++b
+`
+	contents := map[string]string{"main.go": "zero\none\ntwo\nthree\nfour\nfive\n"}
+
+	report, err := diff.CheckPatch(patch, contents, diff.ApplyOptions{Partial: true})
+	if err != nil {
+		t.Fatalf("CheckPatch failed: %v", err)
+	}
+
+	if len(report.Hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %+v", report.Hunks)
+	}
+
+	if got := report.Hunks[0]; got.Status != diff.HunkShifted || got.Offset != 1 {
+		t.Errorf("Expected main.go's hunk shifted by 1, got %+v", got)
+	}
+
+	if got := report.Hunks[1]; got.Status != diff.HunkRejected || !errors.Is(got.Err, diff.ErrFileMissing) {
+		t.Errorf("Expected gone.txt's hunk rejected as missing, got %+v", got)
+	}
+
+	if contents["main.go"] != "zero\none\ntwo\nthree\nfour\nfive\n" {
+		t.Errorf("Expected contents left untouched, got %q", contents["main.go"])
+	}
+}
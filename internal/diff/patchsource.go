@@ -0,0 +1,254 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PatchSource reads a unified diff that came from outside jj: a file, or text already read from
+// stdin. The text may be a mail from git format-patch or the output of diff -u, so GetDiff keeps only
+// the file sections and hunks and drops everything around them.
+type PatchSource struct {
+	Path string
+	Text string
+}
+
+// NewPatchFileSource reads the diff at path. The file is read again on each GetDiff call, so a
+// reload picks up edits to it.
+func NewPatchFileSource(path string) *PatchSource {
+	return &PatchSource{Path: path}
+}
+
+// ReadPatchSource reads r to the end now, because stdin can only be read once.
+func ReadPatchSource(r io.Reader) (*PatchSource, error) {
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading the patch: %w", err)
+	}
+
+	return &PatchSource{Text: string(text)}, nil
+}
+
+// GetDiff returns the patch's file sections and hunks. Reading a file is quick, so ctx is only checked
+// before it starts.
+func (s *PatchSource) GetDiff(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("reading %s: %w", s.GetSourceLabel(), err)
+	}
+
+	text := s.Text
+	if s.Path != "" {
+		//nolint:gosec // G304: the path is the patch the user asked to open.
+		content, err := os.ReadFile(s.Path)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", s.Path, err)
+		}
+
+		text = string(content)
+	}
+
+	return extractPatch(text), nil
+}
+
+// GetSourceLabel names the file, or stdin when the patch was piped in.
+func (s *PatchSource) GetSourceLabel() string {
+	if s.Path == "" {
+		return "stdin"
+	}
+
+	return s.Path
+}
+
+// SupportsRevisions reports false, because the hunks belong to no revision they could be moved out
+// of. Interactive mode applies them to the destination instead.
+func (*PatchSource) SupportsRevisions() bool {
+	return false
+}
+
+// PatchSegment is a run of lines from text that may carry a patch: either file sections that Parse
+// reads, or the text around them, such as a mail's headers or a log's commit lines.
+type PatchSegment struct {
+	Text   string
+	IsDiff bool
+}
+
+// SplitPatch cuts text into its file sections and the text around them, in order, so a caller can
+// render the sections and pass the rest through. A hunk ends once it holds as many lines as its
+// header counts, which is what tells the last hunk apart from whatever follows it. A file section
+// from diff -u, which has --- and +++ lines but no diff --git line, gets one, so it parses like a
+// section jj or git wrote.
+func SplitPatch(text string) []PatchSegment {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	var s patchSplitter
+
+	for i, line := range lines {
+		if s.oldLeft > 0 || s.newLeft > 0 || (strings.HasPrefix(line, noNewlinePrefix) && s.inFile) {
+			s.hunkLine(line)
+
+			continue
+		}
+
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+
+		s.headerLine(line, next)
+	}
+
+	s.flush()
+
+	return s.segments
+}
+
+// extractPatch keeps the parts of a patch Parse reads: each file's header and its hunks. The commit
+// message and mail headers of git format-patch, and its signature after the last hunk, are dropped.
+func extractPatch(text string) string {
+	var out strings.Builder
+
+	for _, segment := range SplitPatch(text) {
+		if segment.IsDiff {
+			out.WriteString(segment.Text)
+		}
+	}
+
+	return out.String()
+}
+
+// patchSplitter walks a patch line by line, sorting each line into a file section or the text
+// around it. The segment being built is kept in a builder until a line of the other kind ends it, so
+// a long patch is copied once rather than once per line.
+type patchSplitter struct {
+	current  strings.Builder
+	segments []PatchSegment
+	oldLeft  int
+	newLeft  int
+	isDiff   bool
+	inHeader bool
+	inFile   bool
+}
+
+// add appends line to the current segment when it is of the same kind, and starts a new one
+// otherwise.
+func (s *patchSplitter) add(line string, isDiff bool) {
+	if isDiff != s.isDiff {
+		s.flush()
+	}
+
+	s.isDiff = isDiff
+	s.current.WriteString(line)
+	s.current.WriteByte('\n')
+}
+
+// flush ends the current segment.
+func (s *patchSplitter) flush() {
+	if s.current.Len() == 0 {
+		return
+	}
+
+	s.segments = append(s.segments, PatchSegment{Text: s.current.String(), IsDiff: s.isDiff})
+	s.current.Reset()
+}
+
+func (s *patchSplitter) hunkLine(line string) {
+	s.add(line, true)
+
+	switch {
+	case strings.HasPrefix(line, noNewlinePrefix):
+	case strings.HasPrefix(line, "+"):
+		s.newLeft--
+	case strings.HasPrefix(line, "-"):
+		s.oldLeft--
+	default:
+		// Some mailers strip the space from a blank context line, so an empty line is context too.
+		s.oldLeft--
+		s.newLeft--
+	}
+}
+
+func (s *patchSplitter) headerLine(line, next string) {
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		s.add(line, true)
+		s.inFile, s.inHeader = true, true
+	case strings.HasPrefix(line, "--- ") && strings.HasPrefix(next, "+++ "):
+		if !s.inHeader {
+			for _, header := range unifiedHeader(line, next) {
+				s.add(header, true)
+			}
+
+			s.inFile, s.inHeader = true, true
+		}
+
+		s.add(line, true)
+	case strings.HasPrefix(line, "@@ ") && s.inFile:
+		hunk := parseHunkHeader(line)
+		if hunk == nil {
+			s.passThrough(line)
+
+			return
+		}
+
+		s.add(line, true)
+		s.oldLeft, s.newLeft = hunk.OldLines, hunk.NewLines
+		s.inHeader = false
+	case s.inHeader && isExtendedHeader(line):
+		s.add(line, true)
+	default:
+		s.passThrough(line)
+		s.inHeader = false
+	}
+}
+
+// extendedHeaders start the lines git writes between a diff --git line and the first hunk.
+var extendedHeaders = []string{
+	"--- ", "+++ ", "index ", "old mode ", "new mode ", "new file mode ", "deleted file mode ",
+	"similarity index ", "dissimilarity index ", "rename from ", "rename to ", "copy from ", "copy to ",
+	"Binary files ",
+}
+
+func isExtendedHeader(line string) bool {
+	for _, prefix := range extendedHeaders {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// passThrough keeps a line that belongs to no file section, which also ends the section before it.
+func (s *patchSplitter) passThrough(line string) {
+	s.add(line, false)
+	s.inFile = false
+}
+
+// devNull is the path a unified diff names for the missing side of an added or deleted file.
+const devNull = "/dev/null"
+
+// unifiedHeader writes the diff --git line, and the new or deleted file line, that a diff -u section
+// lacks, from its --- and +++ lines.
+func unifiedHeader(oldLine, newLine string) []string {
+	oldPath := unifiedPath(strings.TrimPrefix(oldLine, "--- "), "a/")
+	newPath := unifiedPath(strings.TrimPrefix(newLine, "+++ "), "b/")
+
+	switch {
+	case oldPath == devNull:
+		return []string{fmt.Sprintf("diff --git a/%s b/%s", newPath, newPath), "new file mode 100644"}
+	case newPath == devNull:
+		return []string{fmt.Sprintf("diff --git a/%s b/%s", oldPath, oldPath), "deleted file mode 100644"}
+	default:
+		return []string{fmt.Sprintf("diff --git a/%s b/%s", oldPath, newPath)}
+	}
+}
+
+// unifiedPath strips the timestamp diff -u appends after a tab, and the a/ or b/ prefix git adds.
+func unifiedPath(name, prefix string) string {
+	name, _, _ = strings.Cut(name, "\t")
+
+	return strings.TrimPrefix(name, prefix)
+}
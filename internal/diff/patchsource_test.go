@@ -0,0 +1,102 @@
+package diff_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// formatPatchMail is what git format-patch writes: mail headers, the message and a diffstat before
+// the diff, and a signature after it whose "-- " line would read as a deletion if it reached a hunk.
+const formatPatchMail = `From 1234567890abcdef Mon Sep 17 00:00:00 2001
+From: A Contributor <someone@example.com>
+Subject: [PATCH] Fix the greeting
+
+The old greeting was wrong.
+---
+ hello.txt | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/hello.txt b/hello.txt
+index 1111111..2222222 100644
+--- a/hello.txt
++++ b/hello.txt
+@@ -1,2 +1,2 @@
+ greeting:
+-helo
++hello
+-- 
+2.43.0
+
+`
+
+func TestPatchSource_DropsMailAroundTheDiff(t *testing.T) {
+	t.Parallel()
+
+	source, err := diff.ReadPatchSource(strings.NewReader(formatPatchMail))
+	if err != nil {
+		t.Fatalf("ReadPatchSource failed: %v", err)
+	}
+
+	text, err := source.GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff failed: %v", err)
+	}
+
+	if strings.Contains(text, "Subject:") || strings.Contains(text, "2.43.0") {
+		t.Errorf("Expected only the diff, got:\n%s", text)
+	}
+
+	files := diff.Parse(text)
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("Expected one file with one hunk, got %+v", files)
+	}
+
+	if lines := files[0].Hunks[0].Lines; len(lines) != 3 {
+		t.Errorf("Expected the hunk to end at its counted lines, got %+v", lines)
+	}
+
+	if source.GetSourceLabel() != "stdin" || source.SupportsRevisions() {
+		t.Errorf("Expected a stdin source that names no revision, got %q", source.GetSourceLabel())
+	}
+}
+
+func TestPatchSource_ReadsUnifiedDiffFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "change.diff")
+	patch := "--- notes.txt\t2024-01-01 00:00:00\n+++ notes.txt\t2024-01-02 00:00:00\n@@ -1 +1,2 @@\n one\n+two\n" +
+		"--- /dev/null\n+++ b/added.txt\n@@ -0,0 +1 @@\n+new\n"
+
+	if err := os.WriteFile(path, []byte(patch), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	source := diff.NewPatchFileSource(path)
+
+	text, err := source.GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff failed: %v", err)
+	}
+
+	files := diff.Parse(text)
+	if len(files) != 2 {
+		t.Fatalf("Expected two files, got %+v from:\n%s", files, text)
+	}
+
+	if files[0].Path != "notes.txt" || files[0].ChangeType != diff.ChangeTypeModified {
+		t.Errorf("Expected notes.txt modified, got %s %s", files[0].ChangeType, files[0].Path)
+	}
+
+	if files[1].Path != "added.txt" || files[1].ChangeType != diff.ChangeTypeAdded {
+		t.Errorf("Expected added.txt added, got %s %s", files[1].ChangeType, files[1].Path)
+	}
+
+	if source.GetSourceLabel() != path {
+		t.Errorf("Expected the path as the label, got %q", source.GetSourceLabel())
+	}
+}
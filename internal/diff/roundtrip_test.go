@@ -1,6 +1,7 @@
 package diff_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -169,3 +170,27 @@ func TestCompareDirectories_LineGranularity(t *testing.T) {
 		}
 	}
 }
+
+func TestDirectorySource_Context(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	left := filepath.Join(base, "left")
+	right := filepath.Join(base, "right")
+
+	writeTree(t, left, "NOTES.md", leftDoc)
+	writeTree(t, right, "NOTES.md", rightDoc)
+
+	source := diff.NewDirectorySource(left, right)
+	source.Context = 0
+
+	text, err := source.GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+
+	hunks := diff.Parse(text)[0].Hunks
+	if len(hunks) != 2 || hunks[0].Header != "@@ -4,1 +4,1 @@" {
+		t.Errorf("Expected two hunks without context, got %+v", hunks)
+	}
+}
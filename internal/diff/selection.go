@@ -0,0 +1,220 @@
+package diff
+
+import "slices"
+
+// HunkSelection is one hunk's selection. WholeHunk wins over SelectedLines, and selecting the whole
+// hunk discards the per-line set, so the two are never both meaningful.
+type HunkSelection struct {
+	SelectedLines map[int]bool
+	WholeHunk     bool
+}
+
+// FileSelection holds one file's selected hunks, keyed by the hunk's index in the parsed file. The
+// indices go stale when the diff is reloaded.
+type FileSelection struct {
+	Hunks map[int]*HunkSelection
+}
+
+// Selection is what the user has picked across every file, keyed by the diff's path. It is the
+// SelectionState GeneratePatch reads and the Selectable a Selector writes to. Build it with
+// NewSelection, because the mutators assume the map exists.
+type Selection struct {
+	Files map[string]*FileSelection
+}
+
+// NewSelection returns an empty selection.
+func NewSelection() *Selection {
+	return &Selection{
+		Files: make(map[string]*FileSelection),
+	}
+}
+
+// IsHunkSelected reports whether the whole hunk is selected, which is false for a hunk that only has
+// individual lines picked.
+func (s *Selection) IsHunkSelected(filePath string, hunkIdx int) bool {
+	if fileSelection, ok := s.Files[filePath]; ok {
+		if hunkSelection, ok := fileSelection.Hunks[hunkIdx]; ok {
+			return hunkSelection.WholeHunk
+		}
+	}
+
+	return false
+}
+
+// IsLineSelected reports whether one line is selected, which is true for every line of a hunk
+// selected as a whole.
+func (s *Selection) IsLineSelected(filePath string, hunkIdx, lineIdx int) bool {
+	if fileSelection, ok := s.Files[filePath]; ok {
+		if hunkSelection, ok := fileSelection.Hunks[hunkIdx]; ok {
+			if hunkSelection.WholeHunk {
+				return true
+			}
+
+			return hunkSelection.SelectedLines[lineIdx]
+		}
+	}
+
+	return false
+}
+
+// ToggleHunk flips whole-hunk selection, creating the file and hunk entries as needed. Selecting a
+// hunk discards any lines picked inside it, so a toggle out and back in loses the line selection.
+func (s *Selection) ToggleHunk(filePath string, hunkIdx int) {
+	if _, ok := s.Files[filePath]; !ok {
+		s.Files[filePath] = &FileSelection{
+			Hunks: make(map[int]*HunkSelection),
+		}
+	}
+
+	fileSelection := s.Files[filePath]
+	if _, ok := fileSelection.Hunks[hunkIdx]; !ok {
+		fileSelection.Hunks[hunkIdx] = &HunkSelection{
+			SelectedLines: make(map[int]bool),
+		}
+	}
+
+	hunkSelection := fileSelection.Hunks[hunkIdx]
+	hunkSelection.WholeHunk = !hunkSelection.WholeHunk
+	if hunkSelection.WholeHunk {
+		hunkSelection.SelectedLines = make(map[int]bool)
+	}
+}
+
+// ToggleLine flips one line's selection. It does nothing while the hunk is selected as a whole,
+// because that state has no per-line detail to change.
+func (s *Selection) ToggleLine(filePath string, hunkIdx, lineIdx int) {
+	if _, ok := s.Files[filePath]; !ok {
+		s.Files[filePath] = &FileSelection{
+			Hunks: make(map[int]*HunkSelection),
+		}
+	}
+
+	fileSelection := s.Files[filePath]
+	if _, ok := fileSelection.Hunks[hunkIdx]; !ok {
+		fileSelection.Hunks[hunkIdx] = &HunkSelection{
+			SelectedLines: make(map[int]bool),
+		}
+	}
+
+	hunkSelection := fileSelection.Hunks[hunkIdx]
+	if hunkSelection.WholeHunk {
+		return
+	}
+
+	hunkSelection.SelectedLines[lineIdx] = !hunkSelection.SelectedLines[lineIdx]
+}
+
+// SelectLineRange selects an inclusive range of lines, accepting the bounds in either order. It
+// clears whole-hunk selection, and it only adds, so lines already selected outside the range stay.
+func (s *Selection) SelectLineRange(filePath string, hunkIdx, startLine, endLine int) {
+	if startLine > endLine {
+		startLine, endLine = endLine, startLine
+	}
+
+	if _, ok := s.Files[filePath]; !ok {
+		s.Files[filePath] = &FileSelection{
+			Hunks: make(map[int]*HunkSelection),
+		}
+	}
+
+	fileSelection := s.Files[filePath]
+	if _, ok := fileSelection.Hunks[hunkIdx]; !ok {
+		fileSelection.Hunks[hunkIdx] = &HunkSelection{
+			SelectedLines: make(map[int]bool),
+		}
+	}
+
+	hunkSelection := fileSelection.Hunks[hunkIdx]
+	hunkSelection.WholeHunk = false
+
+	for i := startLine; i <= endLine; i++ {
+		hunkSelection.SelectedLines[i] = true
+	}
+}
+
+// HasPartialSelection reports whether a hunk has lines picked without being selected as a whole,
+// which is what the renderer draws the partial marker for.
+func (s *Selection) HasPartialSelection(filePath string, hunkIdx int) bool {
+	if fileSelection, ok := s.Files[filePath]; ok {
+		if hunkSelection, ok := fileSelection.Hunks[hunkIdx]; ok {
+			return !hunkSelection.WholeHunk && len(hunkSelection.SelectedLines) > 0
+		}
+	}
+
+	return false
+}
+
+// ShiftLines moves a hunk's selected lines down by delta, for lines revealed above them. A hunk
+// selected as a whole has no line indices to move.
+func (s *Selection) ShiftLines(filePath string, hunkIdx, delta int) {
+	fileSelection, ok := s.Files[filePath]
+	if !ok || delta == 0 {
+		return
+	}
+
+	hunkSelection, ok := fileSelection.Hunks[hunkIdx]
+	if !ok {
+		return
+	}
+
+	shifted := make(map[int]bool, len(hunkSelection.SelectedLines))
+	for lineIdx, selected := range hunkSelection.SelectedLines {
+		shifted[lineIdx+delta] = selected
+	}
+
+	hunkSelection.SelectedLines = shifted
+}
+
+// Regroup carries a file's selection from one cut of its lines into hunks to another, as a split or
+// join makes. A hunk made only of lines from hunks selected as a whole is selected as a whole, and
+// otherwise each line keeps its own state.
+func (s *Selection) Regroup(filePath string, before, after []Hunk) {
+	fileSelection, ok := s.Files[filePath]
+	if !ok {
+		return
+	}
+
+	// whole and picked are indexed by a line's position in the hunks laid end to end.
+	var whole, picked []bool
+
+	for hunkIdx, hunk := range before {
+		hunkSelection := fileSelection.Hunks[hunkIdx]
+
+		for lineIdx := range hunk.Lines {
+			whole = append(whole, hunkSelection != nil && hunkSelection.WholeHunk)
+			picked = append(picked, hunkSelection != nil && hunkSelection.SelectedLines[lineIdx])
+		}
+	}
+
+	regrouped := make(map[int]*HunkSelection)
+	position := 0
+
+	for hunkIdx, hunk := range after {
+		span := position + len(hunk.Lines)
+		if span > len(whole) {
+			break
+		}
+
+		if len(hunk.Lines) > 0 && !slices.Contains(whole[position:span], false) {
+			regrouped[hunkIdx] = &HunkSelection{SelectedLines: make(map[int]bool), WholeHunk: true}
+		} else if lines := pickedLines(whole[position:span], picked[position:span]); len(lines) > 0 {
+			regrouped[hunkIdx] = &HunkSelection{SelectedLines: lines}
+		}
+
+		position = span
+	}
+
+	fileSelection.Hunks = regrouped
+}
+
+func pickedLines(whole, picked []bool) map[int]bool {
+	lines := make(map[int]bool)
+
+	for lineIdx := range whole {
+		if whole[lineIdx] || picked[lineIdx] {
+			lines[lineIdx] = true
+		}
+	}
+
+	return lines
+}
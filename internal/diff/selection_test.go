@@ -0,0 +1,155 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// TestSelection_ToggleHunk tests Selection hunk toggling.
+func TestSelection_ToggleHunk(t *testing.T) {
+	t.Parallel()
+
+	s := diff.NewSelection()
+
+	if s.IsHunkSelected("file.txt", 0) {
+		t.Error("Expected hunk to not be selected initially")
+	}
+
+	s.ToggleHunk("file.txt", 0)
+	if !s.IsHunkSelected("file.txt", 0) {
+		t.Error("Expected hunk to be selected after toggle")
+	}
+
+	s.ToggleHunk("file.txt", 0)
+	if s.IsHunkSelected("file.txt", 0) {
+		t.Error("Expected hunk to be deselected after second toggle")
+	}
+}
+
+// TestSelection_ToggleLine tests Selection line toggling.
+func TestSelection_ToggleLine(t *testing.T) {
+	t.Parallel()
+
+	s := diff.NewSelection()
+
+	s.ToggleLine("file.txt", 0, 5)
+	if !s.IsLineSelected("file.txt", 0, 5) {
+		t.Error("Expected line to be selected")
+	}
+
+	s.ToggleLine("file.txt", 0, 5)
+	if s.IsLineSelected("file.txt", 0, 5) {
+		t.Error("Expected line to be deselected")
+	}
+
+	// Line toggle should not work if whole hunk is selected
+	s.ToggleHunk("file.txt", 0)
+	initialLineState := s.IsLineSelected("file.txt", 0, 10)
+
+	s.ToggleLine("file.txt", 0, 10)
+	if s.IsLineSelected("file.txt", 0, 10) != initialLineState {
+		t.Error("Expected line toggle to be ignored when whole hunk is selected")
+	}
+}
+
+// TestSelection_WholeHunkClearsLines tests that selecting whole hunk clears line selection map.
+func TestSelection_WholeHunkClearsLines(t *testing.T) {
+	t.Parallel()
+
+	s := diff.NewSelection()
+
+	// Select individual lines
+	s.ToggleLine("file.txt", 0, 1)
+	s.ToggleLine("file.txt", 0, 2)
+	s.ToggleLine("file.txt", 0, 3)
+
+	if !s.IsLineSelected("file.txt", 0, 1) {
+		t.Error("Expected line 1 to be selected")
+	}
+
+	// Toggle whole hunk - this clears the SelectedLines map
+	s.ToggleHunk("file.txt", 0)
+
+	// Whole hunk should be selected
+	if !s.IsHunkSelected("file.txt", 0) {
+		t.Error("Expected whole hunk to be selected")
+	}
+
+	// Lines should still appear selected because WholeHunk=true means all lines are selected
+	if !s.IsLineSelected("file.txt", 0, 1) {
+		t.Error("Expected lines to be selected when whole hunk is selected")
+	}
+
+	// Verify the internal SelectedLines map was cleared
+	fileSelection := s.Files["file.txt"]
+	hunkSelection := fileSelection.Hunks[0]
+	if len(hunkSelection.SelectedLines) != 0 {
+		t.Errorf(
+			"Expected SelectedLines map to be cleared, got %d entries",
+			len(hunkSelection.SelectedLines),
+		)
+	}
+}
+
+// TestSelection_SelectLineRange tests selecting a range of lines.
+func TestSelection_SelectLineRange(t *testing.T) {
+	t.Parallel()
+
+	s := diff.NewSelection()
+
+	s.SelectLineRange("file.txt", 0, 2, 5)
+
+	for i := 2; i <= 5; i++ {
+		if !s.IsLineSelected("file.txt", 0, i) {
+			t.Errorf("Expected line %d to be selected", i)
+		}
+	}
+
+	if s.IsLineSelected("file.txt", 0, 1) {
+		t.Error("Expected line 1 to not be selected")
+	}
+	if s.IsLineSelected("file.txt", 0, 6) {
+		t.Error("Expected line 6 to not be selected")
+	}
+
+	if s.IsHunkSelected("file.txt", 0) {
+		t.Error("Expected whole hunk to not be selected")
+	}
+}
+
+// TestSelection_SelectLineRangeReversed tests selecting line range with reversed bounds.
+func TestSelection_SelectLineRangeReversed(t *testing.T) {
+	t.Parallel()
+
+	s := diff.NewSelection()
+
+	s.SelectLineRange("file.txt", 0, 5, 2)
+
+	for i := 2; i <= 5; i++ {
+		if !s.IsLineSelected("file.txt", 0, i) {
+			t.Errorf("Expected line %d to be selected", i)
+		}
+	}
+}
+
+// TestSelection_HasPartialSelection tests partial selection detection.
+func TestSelection_HasPartialSelection(t *testing.T) {
+	t.Parallel()
+
+	s := diff.NewSelection()
+
+	if s.HasPartialSelection("file.txt", 0) {
+		t.Error("Expected no partial selection initially")
+	}
+
+	s.ToggleLine("file.txt", 0, 3)
+	if !s.HasPartialSelection("file.txt", 0) {
+		t.Error("Expected partial selection after selecting individual line")
+	}
+
+	s.ToggleHunk("file.txt", 0)
+	if s.HasPartialSelection("file.txt", 0) {
+		t.Error("Expected no partial selection when whole hunk is selected")
+	}
+}
@@ -0,0 +1,265 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors for selectors that cannot be read or that pick nothing.
+var (
+	ErrInvalidSelector = errors.New("invalid selector")
+	ErrNoMatch         = errors.New("selector matched nothing")
+)
+
+// SelectorKind says what part of a diff a Selector picks.
+type SelectorKind int
+
+// Selector kinds. A path selects every hunk of one file and a glob every hunk of the files it matches.
+const (
+	SelectPath SelectorKind = iota
+	SelectGlob
+	SelectHunk
+	SelectLines
+	SelectAdded
+	SelectHunkMatch
+)
+
+// Prefixes that start a selector matching by regular expression: added lines one at a time, or whole
+// hunks by any of their changed lines.
+const (
+	addedPrefix = "added:"
+	hunkPrefix  = "hunk:"
+)
+
+// lineRangeRE matches the line-range suffix of path:start-end.
+var lineRangeRE = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// Selector picks hunks or lines out of a parsed diff, the way a user would with space and v, so a
+// script can build the same selection without the UI. Spec is the text it was parsed from.
+type Selector struct {
+	Pattern   *regexp.Regexp
+	Spec      string
+	Path      string
+	Kind      SelectorKind
+	Hunk      int
+	LineStart int
+	LineEnd   int
+}
+
+// ParseSelector reads one selector:
+//
+//   - path selects every hunk of the file;
+//   - a glob such as src/**/*.go selects every hunk of each file it matches, where ** spans
+//     directories;
+//   - path:N selects the file's hunk at index N, counting from 0;
+//   - path:start-end selects the changed lines that fall between those line numbers of the new file,
+//     inclusive;
+//   - added:REGEX selects each added line, in any file, that the regular expression matches;
+//   - hunk:REGEX selects each whole hunk, in any file, with an added or deleted line it matches.
+func ParseSelector(spec string) (Selector, error) {
+	if pattern, ok := strings.CutPrefix(spec, addedPrefix); ok {
+		return newRegexSelector(spec, pattern, SelectAdded)
+	}
+
+	if pattern, ok := strings.CutPrefix(spec, hunkPrefix); ok {
+		return NewHunkMatchSelector(pattern)
+	}
+
+	if spec == "" {
+		return Selector{}, fmt.Errorf("%w: empty", ErrInvalidSelector)
+	}
+
+	if filePath, suffix, ok := cutLast(spec, ":"); ok {
+		if hunk, err := strconv.Atoi(suffix); err == nil && hunk >= 0 {
+			return Selector{Spec: spec, Kind: SelectHunk, Path: filePath, Hunk: hunk}, nil
+		}
+
+		if match := lineRangeRE.FindStringSubmatch(suffix); match != nil {
+			start, _ := strconv.Atoi(match[1])
+			end, _ := strconv.Atoi(match[2])
+
+			return Selector{
+				Spec: spec, Kind: SelectLines, Path: filePath, LineStart: min(start, end), LineEnd: max(start, end),
+			}, nil
+		}
+	}
+
+	if strings.ContainsAny(spec, "*?[") {
+		return NewGlobSelector(spec)
+	}
+
+	return Selector{Spec: spec, Kind: SelectPath, Path: spec}, nil
+}
+
+// NewGlobSelector selects every hunk of each file pattern matches, as MatchGlob reads it. A pattern
+// with no wildcards matches one path exactly, so a list of files can mix paths and globs.
+func NewGlobSelector(pattern string) (Selector, error) {
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return Selector{}, fmt.Errorf("%w %q: %w", ErrInvalidSelector, pattern, err)
+	}
+
+	return Selector{Spec: pattern, Kind: SelectGlob, Path: pattern}, nil
+}
+
+// NewHunkMatchSelector selects each whole hunk with an added or deleted line that pattern matches.
+func NewHunkMatchSelector(pattern string) (Selector, error) {
+	return newRegexSelector(hunkPrefix+pattern, pattern, SelectHunkMatch)
+}
+
+func newRegexSelector(spec, pattern string, kind SelectorKind) (Selector, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Selector{}, fmt.Errorf("%w %q: %w", ErrInvalidSelector, spec, err)
+	}
+
+	return Selector{Spec: spec, Kind: kind, Pattern: re}, nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	idx := strings.LastIndex(s, sep)
+	if idx < 0 {
+		return s, "", false
+	}
+
+	return s[:idx], s[idx+len(sep):], true
+}
+
+// Selectable is the part of a selection a Selector writes to. It only ever adds: a hunk already
+// selected stays selected, which is why it asks before toggling.
+type Selectable interface {
+	IsHunkSelected(filePath string, hunkIdx int) bool
+	ToggleHunk(filePath string, hunkIdx int)
+	SelectLineRange(filePath string, hunkIdx, startLine, endLine int)
+}
+
+// Select adds what the selector picks in files to selection and returns how many hunks it touched. A
+// selector that picks nothing returns ErrNoMatch, because a script that names a hunk that is not
+// there would otherwise move less than it meant to without noticing.
+func (s Selector) Select(files []FileChange, selection Selectable) (int, error) {
+	touched := 0
+
+	for _, file := range files {
+		for hunkIdx, hunk := range file.Hunks {
+			lines := s.matchLines(file, hunkIdx, hunk)
+			if len(lines) == 0 {
+				continue
+			}
+
+			touched++
+
+			if selection.IsHunkSelected(file.Path, hunkIdx) {
+				continue
+			}
+
+			if len(lines) == len(hunk.Lines) {
+				selection.ToggleHunk(file.Path, hunkIdx)
+
+				continue
+			}
+
+			for _, lineIdx := range lines {
+				selection.SelectLineRange(file.Path, hunkIdx, lineIdx, lineIdx)
+			}
+		}
+	}
+
+	if touched == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNoMatch, s.Spec)
+	}
+
+	return touched, nil
+}
+
+// matchLines returns the indexes of the hunk's lines the selector picks. A selector that picks the
+// whole hunk returns every index.
+func (s Selector) matchLines(file FileChange, hunkIdx int, hunk Hunk) []int {
+	all := func() []int {
+		lines := make([]int, len(hunk.Lines))
+		for i := range lines {
+			lines[i] = i
+		}
+
+		return lines
+	}
+
+	switch s.Kind {
+	case SelectPath:
+		if file.Path == s.Path {
+			return all()
+		}
+	case SelectGlob:
+		if MatchGlob(s.Path, file.Path) {
+			return all()
+		}
+	case SelectHunk:
+		if file.Path == s.Path && hunkIdx == s.Hunk {
+			return all()
+		}
+	case SelectLines:
+		if file.Path == s.Path {
+			return s.linesWhere(hunk, func(line Line) bool {
+				return line.NewLineNum >= s.LineStart && line.NewLineNum <= s.LineEnd
+			})
+		}
+	case SelectAdded:
+		return s.linesWhere(hunk, func(line Line) bool {
+			return line.Type == LineAddition && s.Pattern.MatchString(line.Content)
+		})
+	case SelectHunkMatch:
+		if len(s.linesWhere(hunk, func(line Line) bool { return s.Pattern.MatchString(line.Content) })) > 0 {
+			return all()
+		}
+	}
+
+	return nil
+}
+
+// linesWhere returns the indexes of the hunk's added and deleted lines that match. Context is never
+// selected, because moving it changes nothing.
+func (Selector) linesWhere(hunk Hunk, match func(Line) bool) []int {
+	var lines []int
+
+	for i, line := range hunk.Lines {
+		if line.Type != LineContext && match(line) {
+			lines = append(lines, i)
+		}
+	}
+
+	return lines
+}
+
+// MatchGlob reports whether name matches pattern, where each / separated element is matched as
+// path.Match does and an element that is exactly ** matches any number of directories, none included.
+func MatchGlob(pattern, name string) bool {
+	return matchGlobParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchGlobParts(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,227 @@
+package diff_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+const selectorDiff = `diff --git a/src/app/main.go b/src/app/main.go
+index 1111111..2222222 100644
+--- a/src/app/main.go
++++ b/src/app/main.go
+@@ -1,3 +1,4 @@
+ package main
++// TODO: remove
+ func main() {
+ }
+@@ -10,3 +11,3 @@
+ func helper() {
+-	return
++	panic("x")
+ }
+diff --git a/README.md b/README.md
+index 3333333..4444444 100644
+--- a/README.md
++++ b/README.md
+@@ -1,1 +1,2 @@
+ # Title
++TODO: docs
+`
+
+// recordingSelection is a Selectable that remembers what was selected.
+type recordingSelection struct {
+	hunks map[string]map[int]bool
+	lines map[string]map[int][]int
+}
+
+func newRecordingSelection() *recordingSelection {
+	return &recordingSelection{hunks: map[string]map[int]bool{}, lines: map[string]map[int][]int{}}
+}
+
+func (r *recordingSelection) IsHunkSelected(filePath string, hunkIdx int) bool {
+	return r.hunks[filePath][hunkIdx]
+}
+
+func (r *recordingSelection) ToggleHunk(filePath string, hunkIdx int) {
+	if r.hunks[filePath] == nil {
+		r.hunks[filePath] = map[int]bool{}
+	}
+
+	r.hunks[filePath][hunkIdx] = !r.hunks[filePath][hunkIdx]
+}
+
+func (r *recordingSelection) SelectLineRange(filePath string, hunkIdx, startLine, endLine int) {
+	if r.lines[filePath] == nil {
+		r.lines[filePath] = map[int][]int{}
+	}
+
+	for i := startLine; i <= endLine; i++ {
+		r.lines[filePath][hunkIdx] = append(r.lines[filePath][hunkIdx], i)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec string
+		want diff.Selector
+	}{
+		{"src/app/main.go", diff.Selector{Kind: diff.SelectPath, Path: "src/app/main.go"}},
+		{"src/**/*.go", diff.Selector{Kind: diff.SelectGlob, Path: "src/**/*.go"}},
+		{"src/app/main.go:1", diff.Selector{Kind: diff.SelectHunk, Path: "src/app/main.go", Hunk: 1}},
+		{"a.go:9-3", diff.Selector{Kind: diff.SelectLines, Path: "a.go", LineStart: 3, LineEnd: 9}},
+		{"notes:draft", diff.Selector{Kind: diff.SelectPath, Path: "notes:draft"}},
+	}
+
+	for _, tt := range tests {
+		got, err := diff.ParseSelector(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSelector(%q) failed: %v", tt.spec, err)
+
+			continue
+		}
+
+		tt.want.Spec = tt.spec
+		if got != tt.want {
+			t.Errorf("ParseSelector(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "added:(", "hunk:[", "src/[.go"} {
+		if _, err := diff.ParseSelector(spec); !errors.Is(err, diff.ErrInvalidSelector) {
+			t.Errorf("ParseSelector(%q) = %v, want ErrInvalidSelector", spec, err)
+		}
+	}
+}
+
+func TestSelectorSelect(t *testing.T) {
+	t.Parallel()
+
+	files := diff.Parse(selectorDiff)
+
+	tests := []struct {
+		wantHunks map[string][]int
+		wantLines map[string]map[int][]int
+		spec      string
+		touched   int
+	}{
+		{spec: "src/**/*.go", touched: 2, wantHunks: map[string][]int{"src/app/main.go": {0, 1}}},
+		{spec: "*.md", touched: 1, wantHunks: map[string][]int{"README.md": {0}}},
+		{spec: "src/app/main.go:1", touched: 1, wantHunks: map[string][]int{"src/app/main.go": {1}}},
+		{
+			spec:      "src/app/main.go:11-12",
+			touched:   1,
+			wantLines: map[string]map[int][]int{"src/app/main.go": {1: {1, 2}}},
+		},
+		{spec: "hunk:panic", touched: 1, wantHunks: map[string][]int{"src/app/main.go": {1}}},
+		{
+			spec:    "added:TODO",
+			touched: 2,
+			wantLines: map[string]map[int][]int{
+				"src/app/main.go": {0: {1}},
+				"README.md":       {0: {1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		selector, err := diff.ParseSelector(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q) failed: %v", tt.spec, err)
+		}
+
+		selection := newRecordingSelection()
+
+		touched, err := selector.Select(files, selection)
+		if err != nil || touched != tt.touched {
+			t.Errorf("%s: Select = %d, %v, want %d hunks", tt.spec, touched, err, tt.touched)
+		}
+
+		for path, hunks := range tt.wantHunks {
+			for _, hunkIdx := range hunks {
+				if !selection.IsHunkSelected(path, hunkIdx) {
+					t.Errorf("%s: expected %s hunk %d selected", tt.spec, path, hunkIdx)
+				}
+			}
+		}
+
+		for path, hunks := range tt.wantLines {
+			for hunkIdx, lines := range hunks {
+				if got := selection.lines[path][hunkIdx]; !equalInts(got, lines) {
+					t.Errorf("%s: %s hunk %d lines = %v, want %v", tt.spec, path, hunkIdx, got, lines)
+				}
+			}
+		}
+	}
+}
+
+func TestSelectorSelect_KeepsSelectedHunks(t *testing.T) {
+	t.Parallel()
+
+	files := diff.Parse(selectorDiff)
+	selection := newRecordingSelection()
+
+	for _, spec := range []string{"src/app/main.go", "src/app/main.go:0"} {
+		selector, _ := diff.ParseSelector(spec)
+		if _, err := selector.Select(files, selection); err != nil {
+			t.Fatalf("Select(%q) failed: %v", spec, err)
+		}
+	}
+
+	if !selection.IsHunkSelected("src/app/main.go", 0) {
+		t.Error("Expected a second selector over the same hunk to leave it selected")
+	}
+}
+
+func TestSelectorSelect_NoMatch(t *testing.T) {
+	t.Parallel()
+
+	files := diff.Parse(selectorDiff)
+
+	for _, spec := range []string{"missing.go", "src/app/main.go:5", "README.md:40-50", "added:^nope$"} {
+		selector, _ := diff.ParseSelector(spec)
+		if _, err := selector.Select(files, newRecordingSelection()); !errors.Is(err, diff.ErrNoMatch) {
+			t.Errorf("Select(%q) = %v, want ErrNoMatch", spec, err)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "lib/main.go", false},
+		{"**/*_test.go", "internal/diff/parser_test.go", true},
+		{"*.go", "internal/main.go", false},
+		{"docs/**", "docs/a/b.md", true},
+	}
+
+	for _, tt := range tests {
+		if got := diff.MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
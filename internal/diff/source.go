@@ -1,28 +1,35 @@
 package diff
 
 import (
+	"context"
 	"fmt"
-
-	"github.com/kyleking/jj-diff/internal/jj"
 )
 
-// Source is where a diff comes from, either a jj revision or a pair of directories. GetDiff blocks
-// on external work, so the UI calls it from a tea.Cmd rather than from Update.
+// Source is where a diff comes from: a jj revision, a pair of directories, or a patch. GetDiff blocks
+// on external work, so the UI calls it from a tea.Cmd rather than from Update, under a context it
+// can cancel.
 type Source interface {
-	GetDiff() (string, error)
+	GetDiff(ctx context.Context) (string, error)
 	GetSourceLabel() string
 	SupportsRevisions() bool
 }
 
+// RevisionReader is the part of the jj client the revision-backed sources read through. Taking it as
+// an interface keeps this package free of jj, so the client can apply patches with it.
+type RevisionReader interface {
+	Diff(ctx context.Context, revision string) (string, error)
+	DiffRange(ctx context.Context, from, to string) (string, error)
+}
+
 // RevisionSource generates diffs from jj revisions.
 type RevisionSource struct {
-	Client   *jj.Client
+	Client   RevisionReader
 	Revision string
 }
 
 // NewRevisionSource reads diffs from a jj revision through client. The revision is resolved on each
 // GetDiff call, so a revset such as @ follows the working copy as it moves.
-func NewRevisionSource(client *jj.Client, revision string) *RevisionSource {
+func NewRevisionSource(client RevisionReader, revision string) *RevisionSource {
 	return &RevisionSource{
 		Client:   client,
 		Revision: revision,
@@ -30,9 +37,9 @@ func NewRevisionSource(client *jj.Client, revision string) *RevisionSource {
 }
 
 // GetDiff shells out to jj for the revision's diff, so it blocks and belongs in a tea.Cmd rather
-// than in Update.
-func (s *RevisionSource) GetDiff() (string, error) {
-	text, err := s.Client.Diff(s.Revision)
+// than in Update. Cancelling ctx interrupts jj.
+func (s *RevisionSource) GetDiff(ctx context.Context) (string, error) {
+	text, err := s.Client.Diff(ctx, s.Revision)
 	if err != nil {
 		return "", fmt.Errorf("reading the diff for %s: %w", s.Revision, err)
 	}
@@ -51,26 +58,131 @@ func (*RevisionSource) SupportsRevisions() bool {
 	return true
 }
 
+// EvolutionSource generates the diff of one past evolution of a change, as jj evolog lists it.
+type EvolutionSource struct {
+	Client   RevisionReader
+	Revision string
+	CommitID string
+}
+
+// NewEvolutionSource reads the diff of commitID, one evolution of the change revision names. Pinning
+// the commit rather than the change keeps the diff the same however the change moves on afterwards.
+func NewEvolutionSource(client RevisionReader, revision, commitID string) *EvolutionSource {
+	return &EvolutionSource{
+		Client:   client,
+		Revision: revision,
+		CommitID: commitID,
+	}
+}
+
+// GetDiff shells out to jj for the evolution's diff against its own parents, so it blocks and
+// belongs in a tea.Cmd rather than in Update.
+func (s *EvolutionSource) GetDiff(ctx context.Context) (string, error) {
+	text, err := s.Client.Diff(ctx, s.CommitID)
+	if err != nil {
+		return "", fmt.Errorf("reading the diff for evolution %s of %s: %w", s.CommitID, s.Revision, err)
+	}
+
+	return text, nil
+}
+
+// GetSourceLabel names the change and the evolution's short commit ID, so the header says which
+// version is on screen.
+func (s *EvolutionSource) GetSourceLabel() string {
+	return fmt.Sprintf("%s @ %s", s.Revision, s.CommitID[:min(len(s.CommitID), shortCommitIDLength)])
+}
+
+// shortCommitIDLength matches the prefix jj.ShortCommitID keeps, so the header and the timeline agree.
+const shortCommitIDLength = 12
+
+// SupportsRevisions reports false, because an old evolution is a hidden commit and moving hunks out
+// of it would not take them out of the change.
+func (*EvolutionSource) SupportsRevisions() bool {
+	return false
+}
+
+// InterdiffSource generates the diff between two revisions' trees, so a change that was rewritten
+// shows only what the rewrite did rather than the whole change again.
+type InterdiffSource struct {
+	Client RevisionReader
+	From   string
+	To     string
+}
+
+// NewInterdiffSource diffs the tree of from against the tree of to. Both are revsets resolved on
+// each GetDiff call, and either may name a hidden commit by commit ID, which is how the evolution
+// timeline compares two versions of one change.
+func NewInterdiffSource(client RevisionReader, from, to string) *InterdiffSource {
+	return &InterdiffSource{
+		Client: client,
+		From:   from,
+		To:     to,
+	}
+}
+
+// GetDiff shells out to jj diff --from --to, so it blocks and belongs in a tea.Cmd rather than in
+// Update.
+func (s *InterdiffSource) GetDiff(ctx context.Context) (string, error) {
+	text, err := s.Client.DiffRange(ctx, s.From, s.To)
+	if err != nil {
+		return "", fmt.Errorf("reading the interdiff %s → %s: %w", s.From, s.To, err)
+	}
+
+	return text, nil
+}
+
+// GetSourceLabel names both ends, because either bare revset alone would read as that revision's own
+// diff.
+func (s *InterdiffSource) GetSourceLabel() string {
+	return fmt.Sprintf("interdiff %s → %s", s.From, s.To)
+}
+
+// SupportsRevisions reports false, because an interdiff belongs to no single revision that hunks
+// could be moved out of.
+func (*InterdiffSource) SupportsRevisions() bool {
+	return false
+}
+
 // DirectorySource generates diffs by comparing two directories.
 // Used for diff-editor mode where jj passes $left and $right directories.
 type DirectorySource struct {
 	LeftPath  string
 	RightPath string
+	// Context is how many unchanged lines surround each change.
+	Context int
 }
 
 // NewDirectorySource compares two directory trees, which is how jj invokes a diff editor. Pass
-// jj's $left and $right in that order, because the diff reads left as the old side.
+// jj's $left and $right in that order, because the diff reads left as the old side. It keeps
+// DefaultContext lines of context until Context is set.
 func NewDirectorySource(leftPath, rightPath string) *DirectorySource {
 	return &DirectorySource{
 		LeftPath:  leftPath,
 		RightPath: rightPath,
+		Context:   DefaultContext,
 	}
 }
 
 // GetDiff walks both trees and diffs every file that differs, so it blocks for as long as the trees
-// are large and belongs in a tea.Cmd rather than in Update.
-func (s *DirectorySource) GetDiff() (string, error) {
-	return CompareDirectories(s.LeftPath, s.RightPath)
+// are large and belongs in a tea.Cmd rather than in Update. The walk itself does not watch ctx, so
+// a cancellation is only noticed before it starts.
+func (s *DirectorySource) GetDiff(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("comparing %s and %s: %w", s.LeftPath, s.RightPath, err)
+	}
+
+	return compareDirectories(s.LeftPath, s.RightPath, s.Context)
+}
+
+// NewContent reads a file from the right tree, which is the diff's new side, for revealing context
+// around its hunks. The path is resolved under the tree and rejected if it escapes.
+func (s *DirectorySource) NewContent(path string) (string, error) {
+	full, err := containedPath(s.RightPath, path)
+	if err != nil {
+		return "", err
+	}
+
+	return readFileContent(full)
 }
 
 // GetSourceLabel returns a fixed label, because the directories jj passes are temporary paths that
@@ -0,0 +1,155 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SplitHunk breaks the hunk at hunkIdx at each run of context lines between two of its changes, as
+// git add -p's s does. Each run is shared out rather than repeated, its first half trailing the
+// change above and the rest leading the change below, so the pieces stay adjacent and never overlap,
+// and each still applies on its own. The earlier piece gets the odd line, because a hunk with no
+// trailing context only applies at the end of a file.
+//
+// Each piece gets a header with its own starts and counts. Only the first keeps the section heading.
+// It returns a copy of file and how many pieces the hunk became, which is 1 when it has nothing to
+// split at.
+func SplitHunk(file FileChange, hunkIdx int) (FileChange, int) {
+	if hunkIdx < 0 || hunkIdx >= len(file.Hunks) {
+		return file, 1
+	}
+
+	hunk := file.Hunks[hunkIdx]
+	cuts := splitPoints(hunk.Lines)
+
+	if len(cuts) == 0 {
+		return file, 1
+	}
+
+	pieces := make([]Hunk, 0, len(cuts)+1)
+	oldFirst, newFirst := firstLine(hunk.OldStart, hunk.OldLines), firstLine(hunk.NewStart, hunk.NewLines)
+	heading := headingOf(hunk.Header)
+	start := 0
+
+	for _, end := range append(cuts, len(hunk.Lines)) {
+		piece := Hunk{Lines: hunk.Lines[start:end:end]}
+
+		for _, line := range piece.Lines {
+			if line.Type != LineAddition {
+				piece.OldLines++
+			}
+
+			if line.Type != LineDeletion {
+				piece.NewLines++
+			}
+		}
+
+		setHeader(&piece, oldFirst, newFirst, heading)
+		pieces = append(pieces, piece)
+
+		oldFirst += piece.OldLines
+		newFirst += piece.NewLines
+		heading = ""
+		start = end
+	}
+
+	file.Hunks = slices.Concat(file.Hunks[:hunkIdx], pieces, file.Hunks[hunkIdx+1:])
+	file.Conflicts = findConflicts(file.Hunks)
+
+	return file, len(pieces)
+}
+
+// JoinHunks merges the hunk at hunkIdx with every neighbor it touches, with no unchanged line
+// between them, which undoes SplitHunk. A hunk grown by ExpandContext up to its neighbor joins it
+// too. The joined hunk keeps the first one's section heading.
+//
+// It returns a copy of file, the index of the joined hunk, and how many hunks went into it, which is
+// 1 when the hunk touches neither neighbor.
+func JoinHunks(file FileChange, hunkIdx int) (FileChange, int, int) {
+	if hunkIdx < 0 || hunkIdx >= len(file.Hunks) {
+		return file, hunkIdx, 1
+	}
+
+	first, last := hunkIdx, hunkIdx
+	for first > 0 && Touches(file.Hunks[first-1], file.Hunks[first]) {
+		first--
+	}
+
+	for last+1 < len(file.Hunks) && Touches(file.Hunks[last], file.Hunks[last+1]) {
+		last++
+	}
+
+	if first == last {
+		return file, hunkIdx, 1
+	}
+
+	joined := Hunk{}
+	for _, hunk := range file.Hunks[first : last+1] {
+		joined.Lines = append(joined.Lines, hunk.Lines...)
+		joined.OldLines += hunk.OldLines
+		joined.NewLines += hunk.NewLines
+	}
+
+	head := file.Hunks[first]
+	setHeader(&joined, firstLine(head.OldStart, head.OldLines), firstLine(head.NewStart, head.NewLines),
+		headingOf(head.Header))
+
+	file.Hunks = slices.Concat(file.Hunks[:first], []Hunk{joined}, file.Hunks[last+1:])
+	file.Conflicts = findConflicts(file.Hunks)
+
+	return file, first, last - first + 1
+}
+
+// splitPoints lists where SplitHunk cuts a hunk's lines: partway into each run of context lines that
+// has a change on both sides.
+func splitPoints(lines []Line) []int {
+	var cuts []int
+
+	lastChange := -1
+
+	for i, line := range lines {
+		if line.Type == LineContext {
+			continue
+		}
+
+		if run := i - lastChange - 1; lastChange >= 0 && run > 0 {
+			cuts = append(cuts, lastChange+1+(run+1)/2)
+		}
+
+		lastChange = i
+	}
+
+	return cuts
+}
+
+// Touches reports whether b starts on both sides right where a ends, so JoinHunks joins them.
+func Touches(a, b Hunk) bool {
+	return firstLine(a.OldStart, a.OldLines)+a.OldLines == firstLine(b.OldStart, b.OldLines) &&
+		firstLine(a.NewStart, a.NewLines)+a.NewLines == firstLine(b.NewStart, b.NewLines)
+}
+
+// setHeader sets a hunk's starts from the first line each side covers, with the counts already set,
+// and rewrites its header to match.
+func setHeader(hunk *Hunk, oldFirst, newFirst int, heading string) {
+	hunk.OldStart, hunk.NewStart = oldFirst, newFirst
+
+	if hunk.OldLines == 0 {
+		hunk.OldStart--
+	}
+
+	if hunk.NewLines == 0 {
+		hunk.NewStart--
+	}
+
+	hunk.Header = fmt.Sprintf("@@ -%d,%d +%d,%d @@%s", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines,
+		heading)
+}
+
+// headingOf is the section heading jj writes after a hunk header, with its leading space.
+func headingOf(header string) string {
+	if match := hunkHeaderRE.FindStringSubmatch(header); len(match) == hunkHeaderGroups {
+		return match[hunkHeaderGroups-1]
+	}
+
+	return ""
+}
@@ -0,0 +1,67 @@
+package diff_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+func TestSplitHunk(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	left, right := filepath.Join(base, "left"), filepath.Join(base, "right")
+
+	writeTree(t, left, "f.txt", numbered(20, nil))
+	writeTree(t, right, "f.txt", numbered(20, map[int]string{5: "FIVE", 9: "NINE"}))
+
+	text, err := diff.NewDirectorySource(left, right).GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+
+	file := diff.Parse(text)[0]
+	if len(file.Hunks) != 1 {
+		t.Fatalf("Expected the two edits in one hunk, got %d hunks", len(file.Hunks))
+	}
+
+	split, pieces := diff.SplitHunk(file, 0)
+	if pieces != 2 {
+		t.Fatalf("Expected two pieces, got %d", pieces)
+	}
+
+	for i, want := range []string{"@@ -2,6 +2,6 @@", "@@ -8,5 +8,5 @@"} {
+		if got := split.Hunks[i].Header; got != want {
+			t.Errorf("Expected piece %d to be %s, got %s", i, want, got)
+		}
+	}
+
+	if again, pieces := diff.SplitHunk(split, 1); pieces != 1 || len(again.Hunks) != 2 {
+		t.Error("Expected a hunk with one change to stay whole")
+	}
+
+	selection := newMockSelection(map[string]map[int]bool{"f.txt": {1: true}})
+
+	dir := t.TempDir()
+	writeTree(t, dir, "f.txt", numbered(20, nil))
+
+	patch := diff.GeneratePatch([]diff.FileChange{split}, selection)
+	if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyPatch failed on the second piece: %v\n%s", err, patch)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "f.txt"), numbered(20, map[int]string{9: "NINE"}))
+
+	joined, first, count := diff.JoinHunks(split, 1)
+	if first != 0 || count != 2 || !reflect.DeepEqual(joined.Hunks, file.Hunks) {
+		t.Errorf("Expected the pieces to join back into the original hunk, got %d from %d: %+v",
+			count, first, joined.Hunks)
+	}
+
+	if _, _, count := diff.JoinHunks(file, 0); count != 1 {
+		t.Errorf("Expected a lone hunk to join nothing, got %d", count)
+	}
+}
@@ -0,0 +1,150 @@
+package diff
+
+// PatchStack renders patches that apply one on top of another, as a split's groups land. Each group's
+// patch is written against the files with the groups pushed before it already applied: their
+// deletions are gone and their additions are context, so a hunk divided between two groups still
+// applies in its second half.
+//
+// The hunks and their indices stay those of the files the stack starts from, so the selections passed
+// in are the ones the caller made over those files.
+type PatchStack struct {
+	index map[string]int
+	files []FileChange
+	// origin maps each line of a rebased hunk to its index in the hunk as it started.
+	origin [][][]int
+}
+
+// NewPatchStack starts a stack over files with nothing applied yet.
+func NewPatchStack(files []FileChange) *PatchStack {
+	stack := &PatchStack{
+		index:  make(map[string]int, len(files)),
+		files:  make([]FileChange, len(files)),
+		origin: make([][][]int, len(files)),
+	}
+
+	for fileIdx, file := range files {
+		stack.index[file.Path] = fileIdx
+		stack.files[fileIdx] = file
+		stack.origin[fileIdx] = make([][]int, len(file.Hunks))
+
+		for hunkIdx, hunk := range file.Hunks {
+			stack.origin[fileIdx][hunkIdx] = make([]int, len(hunk.Lines))
+			for lineIdx := range hunk.Lines {
+				stack.origin[fileIdx][hunkIdx][lineIdx] = lineIdx
+			}
+		}
+	}
+
+	return stack
+}
+
+// Patch renders selection as GeneratePatch does, against the groups pushed so far.
+func (s *PatchStack) Patch(selection SelectionState) string {
+	return GeneratePatch(s.files, stackedSelection{stack: s, selection: selection})
+}
+
+// Push applies selection beneath the groups still to come.
+func (s *PatchStack) Push(selection SelectionState) {
+	stacked := stackedSelection{stack: s, selection: selection}
+
+	for fileIdx, file := range s.files {
+		hunks := make([]Hunk, len(file.Hunks))
+		applied := false
+		delta := 0
+
+		for hunkIdx, hunk := range file.Hunks {
+			rebased, landed := s.rebaseHunk(stacked, fileIdx, hunkIdx, delta)
+			hunks[hunkIdx] = rebased
+			applied = applied || landed
+			delta += rebased.OldLines - hunk.OldLines
+		}
+
+		file.Hunks = hunks
+		if applied && file.ChangeType == ChangeTypeAdded {
+			file.ChangeType = ChangeTypeModified
+		}
+
+		s.files[fileIdx] = file
+	}
+}
+
+// rebaseHunk rewrites one hunk with the changes stacked selects applied, its old side moved by delta
+// for the lines applied above it in the file, and reports whether any change landed.
+func (s *PatchStack) rebaseHunk(stacked stackedSelection, fileIdx, hunkIdx, delta int) (Hunk, bool) {
+	hunk := s.files[fileIdx].Hunks[hunkIdx]
+	path := s.files[fileIdx].Path
+	whole := stacked.IsHunkSelected(path, hunkIdx)
+	partial := !whole && stacked.HasPartialSelection(path, hunkIdx)
+
+	var origin []int
+
+	oldFirst := firstLine(hunk.OldStart, hunk.OldLines) + delta
+	oldNum := oldFirst
+	rebased := Hunk{}
+	landed := false
+
+	for lineIdx, line := range hunk.Lines {
+		lands := line.Type != LineContext && (whole || partial && stacked.IsLineSelected(path, hunkIdx, lineIdx))
+		landed = landed || lands
+
+		switch {
+		case lands && line.Type == LineDeletion:
+			continue
+		case lands:
+			line.Type = LineContext
+		}
+
+		line.OldLineNum = oldNum
+		if line.Type != LineAddition {
+			oldNum++
+			rebased.OldLines++
+		}
+
+		if line.Type != LineDeletion {
+			rebased.NewLines++
+		}
+
+		rebased.Lines = append(rebased.Lines, line)
+		origin = append(origin, s.origin[fileIdx][hunkIdx][lineIdx])
+	}
+
+	setHeader(&rebased, oldFirst, firstLine(hunk.NewStart, hunk.NewLines), headingOf(hunk.Header))
+	s.origin[fileIdx][hunkIdx] = origin
+
+	return rebased, landed
+}
+
+// stackedSelection reads a selection made over the files a stack started from as one over its
+// rebased hunks. A hunk or line left with nothing to change is never selected.
+type stackedSelection struct {
+	stack     *PatchStack
+	selection SelectionState
+}
+
+func (s stackedSelection) IsHunkSelected(filePath string, hunkIdx int) bool {
+	fileIdx, ok := s.stack.index[filePath]
+	if !ok || !s.selection.IsHunkSelected(filePath, hunkIdx) {
+		return false
+	}
+
+	for _, line := range s.stack.files[fileIdx].Hunks[hunkIdx].Lines {
+		if line.Type != LineContext {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s stackedSelection) HasPartialSelection(filePath string, hunkIdx int) bool {
+	return s.selection.HasPartialSelection(filePath, hunkIdx)
+}
+
+func (s stackedSelection) IsLineSelected(filePath string, hunkIdx, lineIdx int) bool {
+	fileIdx, ok := s.stack.index[filePath]
+	if !ok || s.stack.files[fileIdx].Hunks[hunkIdx].Lines[lineIdx].Type == LineContext {
+		return false
+	}
+
+	return s.selection.IsLineSelected(filePath, hunkIdx, s.stack.origin[fileIdx][hunkIdx][lineIdx])
+}
@@ -0,0 +1,65 @@
+package diff_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// linesOf selects the given lines of one hunk of f.txt.
+func linesOf(hunkIdx int, lines ...int) *mockSelectionState {
+	selection := newMockSelection(nil)
+	selection.partialHunks["f.txt"] = map[int]bool{hunkIdx: true}
+	selection.lineSelections["f.txt"] = map[int]map[int]bool{hunkIdx: {}}
+
+	for _, lineIdx := range lines {
+		selection.lineSelections["f.txt"][hunkIdx][lineIdx] = true
+	}
+
+	return selection
+}
+
+func TestPatchStack_DividedHunk(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	left, right := filepath.Join(base, "left"), filepath.Join(base, "right")
+	final := numbered(12, map[int]string{5: "FIVE", 6: "SIX"})
+
+	writeTree(t, left, "f.txt", numbered(12, nil))
+	writeTree(t, right, "f.txt", final)
+
+	text, err := diff.NewDirectorySource(left, right).GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+
+	files := diff.Parse(text)
+	if len(files) != 1 || len(files[0].Hunks) != 1 || len(files[0].Hunks[0].Lines) != 10 {
+		t.Fatalf("Expected one hunk of ten lines, got %+v", files)
+	}
+
+	// Lines 3 and 4 delete lines 5 and 6, and lines 5 and 6 add FIVE and SIX.
+	groups := []*mockSelectionState{linesOf(0, 3, 5), linesOf(0, 4, 6)}
+	stack := diff.NewPatchStack(files)
+	dir := t.TempDir()
+
+	writeTree(t, dir, "f.txt", numbered(12, nil))
+
+	for i, group := range groups {
+		patch := stack.Patch(group)
+		if _, err := diff.ApplyPatch(dir, patch, diff.ApplyOptions{}); err != nil {
+			t.Fatalf("Group %d's patch did not apply over the ones before it: %v\n%s", i, err, patch)
+		}
+
+		stack.Push(group)
+	}
+
+	assertFileBytes(t, filepath.Join(dir, "f.txt"), final)
+
+	if patch := stack.Patch(newMockSelection(map[string]map[int]bool{"f.txt": {0: true}})); patch != "" {
+		t.Errorf("Expected nothing left to select once both groups landed, got\n%s", patch)
+	}
+}
@@ -17,13 +17,19 @@ import (
 // Highlighter provides syntax highlighting for code.
 type Highlighter struct {
 	style *chroma.Style
+	// plain is the style's text color, which a token is left unstyled for so the diff's own line
+	// colors show through.
+	plain chroma.Colour
 }
 
-// New creates a new syntax highlighter.
+// New creates a syntax highlighter in the theme's chroma style, or chroma's fallback style before
+// theme.Init has run.
 func New() *Highlighter {
-	// Use a minimal style that works well with terminal colors
+	style := styles.Get(theme.Syntax)
+
 	return &Highlighter{
-		style: styles.Get("monokai"),
+		style: style,
+		plain: style.Get(chroma.Background).Colour,
 	}
 }
 
@@ -113,49 +119,20 @@ func (*Highlighter) detectLexer(filePath string) chroma.Lexer {
 	return nil
 }
 
-func (*Highlighter) styleToken(token chroma.Token) string {
-	value := token.Value
-	tokenType := token.Type
-
-	// Map chroma token types to lipgloss styles
-	// Use subtle colors that don't conflict with diff colors
-	style := lipgloss.NewStyle()
-
-	switch tokenType {
-	case chroma.Comment, chroma.CommentSingle, chroma.CommentMultiline:
-		// Comments: muted/soft color
-		style = style.Foreground(theme.SoftMutedBg)
-
-	case chroma.Keyword, chroma.KeywordNamespace, chroma.KeywordType:
-		// Keywords: accent color (but not too bright)
-		style = style.Foreground(theme.Accent).Bold(true)
-
-	case chroma.LiteralString, chroma.LiteralStringDouble:
-		// Strings: subtle green (different from diff additions)
-		style = style.Foreground(lipgloss.Color("#a6e3a1"))
-
-	case chroma.LiteralNumber:
-		// Numbers: subtle orange
-		style = style.Foreground(lipgloss.Color("#fab387"))
-
-	case chroma.Name, chroma.NameFunction:
-		// Function names: subtle blue
-		style = style.Foreground(lipgloss.Color("#89b4fa"))
-
-	case chroma.NameClass, chroma.NameBuiltin:
-		// Class names: subtle yellow
-		style = style.Foreground(lipgloss.Color("#f9e2af"))
-
-	case chroma.Operator:
-		// Operators: text color
-		style = style.Foreground(theme.Text)
-
-	default:
-		// Default: normal text color
-		return value
+// styleToken colors a token as the chroma style does, foreground only, since the diff view owns the
+// line backgrounds. Tokens in the style's plain text color are left as they are.
+func (h *Highlighter) styleToken(token chroma.Token) string {
+	entry := h.style.Get(token.Type)
+	if !entry.Colour.IsSet() || (entry.Colour == h.plain && entry.Bold != chroma.Yes) {
+		return token.Value
 	}
 
-	return style.Render(value)
+	style := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(entry.Colour.String())).
+		Bold(entry.Bold == chroma.Yes).
+		Italic(entry.Italic == chroma.Yes)
+
+	return style.Render(token.Value)
 }
 
 // IsEnabled returns whether syntax highlighting is available for a file.
@@ -10,21 +10,21 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
-)
+	"time"
 
-// patchFileMode is the permission the temporary patch file is written with. It holds the user's
-// unsquashed changes, so it stays readable only by them.
-const patchFileMode = 0o600
+	"github.com/kyleking/jj-diff/internal/diff"
+)
 
 // statusFieldCount is the smallest number of whitespace-separated fields a jj status line carries: a
 // one-letter change type and a path.
 const statusFieldCount = 2
 
 // Client runs jj in one repository. Every call shells out and blocks, so callers in the UI wrap them
-// in a tea.Cmd. No call is cancellable, so each one runs under a background context.
+// in a tea.Cmd and hold the context's cancel func for as long as the call runs.
 type Client struct {
 	baseDir string
 }
@@ -35,19 +35,29 @@ func NewClient(baseDir string) *Client {
 	return &Client{baseDir: baseDir}
 }
 
-// jjCommand builds a jj invocation rooted at the client's repository.
-func (c *Client) jjCommand(args ...string) *exec.Cmd {
+// cancelGracePeriod is how long a cancelled jj gets to finish after SIGINT before it is killed. jj
+// commits its operation atomically on the way out of an interrupt, so killing it outright would only
+// lose that chance to stop cleanly.
+const cancelGracePeriod = 5 * time.Second
+
+// jjCommand builds a jj invocation rooted at the client's repository. Cancelling ctx interrupts jj
+// rather than killing it, and kills it only once cancelGracePeriod has passed.
+func (c *Client) jjCommand(ctx context.Context, args ...string) *exec.Cmd {
 	//nolint:gosec // G204: the binary is a literal; only the arguments vary and no shell is involved.
-	cmd := exec.CommandContext(context.Background(), "jj", args...)
+	cmd := exec.CommandContext(ctx, "jj", args...)
 	cmd.Dir = c.baseDir
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = cancelGracePeriod
 
 	return cmd
 }
 
 // CheckInstalled reports whether a jj binary is on PATH. It runs outside the repository, so it says
 // nothing about baseDir being a jj repo.
-func (*Client) CheckInstalled() error {
-	cmd := exec.CommandContext(context.Background(), "jj", "--version")
+func (*Client) CheckInstalled(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "jj", "--version")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("jj command not found: %w", err)
 	}
@@ -55,10 +65,96 @@ func (*Client) CheckInstalled() error {
 	return nil
 }
 
+// EvologMinVersion is the oldest jj release GetEvolog works with: the first whose evolog template has
+// the commit and operation keywords. Nothing else jj-diff runs needs it, so only the evolution
+// timeline checks for it.
+const EvologMinVersion = "0.31.0"
+
+// ErrVersionTooOld marks a jj binary older than a feature needs.
+var ErrVersionTooOld = errors.New("jj is too old")
+
+// versionRE finds the release in jj --version's output, such as "jj 0.31.0" or
+// "jj 0.32.0-0a1b2c3d".
+var versionRE = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// checkVersion reports whether the jj on PATH is at least minimum, naming feature when it is not. A
+// version it cannot read, as from a build of its own, is let through.
+func (*Client) checkVersion(ctx context.Context, minimum, feature string) error {
+	output, err := exec.CommandContext(ctx, "jj", "--version").Output()
+	if err != nil {
+		return fmt.Errorf("jj --version failed: %w", err)
+	}
+
+	installed, ok := parseVersion(string(output))
+	if !ok {
+		return nil
+	}
+
+	if required, _ := parseVersion(minimum); slices.Compare(installed, required) < 0 {
+		return fmt.Errorf("%w: %s needs jj %s or newer, found %s", ErrVersionTooOld, feature, minimum,
+			strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// parseVersion reads the first major.minor.patch in text.
+func parseVersion(text string) ([]int, bool) {
+	match := versionRE.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+
+	version := make([]int, 0, len(match)-1)
+
+	for _, part := range match[1:] {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+
+		version = append(version, n)
+	}
+
+	return version, true
+}
+
+// ConfigScope picks which of jj's config layers ConfigList reads. The zero value reads all of them
+// merged, as jj itself sees the settings.
+type ConfigScope string
+
+// The config layers jj config list can be limited to.
+const (
+	ConfigMerged ConfigScope = ""
+	ConfigUser   ConfigScope = "--user"
+	ConfigRepo   ConfigScope = "--repo"
+)
+
+// ConfigList returns the settings under name from one of jj's config layers, as the TOML lines jj
+// config list prints, such as `jj-diff.tab-width = 8`. A name with nothing set yields an empty string,
+// and an empty name lists every setting.
+func (c *Client) ConfigList(ctx context.Context, scope ConfigScope, name string) (string, error) {
+	args := []string{"config", "list"}
+	if scope != ConfigMerged {
+		args = append(args, string(scope))
+	}
+
+	if name != "" {
+		args = append(args, name)
+	}
+
+	output, err := c.executeJJ(ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to read jj config %s: %w", name, err)
+	}
+
+	return output, nil
+}
+
 // Diff returns the git-format diff for a revset, uncolored. The revset is resolved by jj at call
 // time, so a moving revset such as @ follows the working copy.
-func (c *Client) Diff(revision string) (string, error) {
-	cmd := c.jjCommand("diff", "-r", revision, "--git", "--color=never")
+func (c *Client) Diff(ctx context.Context, revision string) (string, error) {
+	cmd := c.jjCommand(ctx, "diff", "-r", revision, "--git", "--color=never")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -68,10 +164,73 @@ func (c *Client) Diff(revision string) (string, error) {
 	return string(output), nil
 }
 
+// DiffRange returns the git-format diff between two revisions' trees, which is how an interdiff
+// shows what changed between two versions of a change rather than either version's own diff.
+func (c *Client) DiffRange(ctx context.Context, from, to string) (string, error) {
+	cmd := c.jjCommand(ctx, "diff", "--from", from, "--to", to, "--git", "--color=never")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("jj diff failed: %w: %s", err, output)
+	}
+
+	return string(output), nil
+}
+
+// FileShow returns a file's content at revision, where path is relative to the repository root as
+// diffs print it rather than to the client's directory.
+func (c *Client) FileShow(ctx context.Context, revision, path string) (string, error) {
+	output, err := c.executeJJ(ctx, "file", "show", "-r", revision, rootFileset(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s at %s: %w", path, revision, err)
+	}
+
+	return output, nil
+}
+
+// FileList returns which of paths exist at revision, relative to the repository root. jj prints
+// paths relative to the directory it runs in, so the listing runs from the root to match.
+func (c *Client) FileList(ctx context.Context, revision string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return []string{}, nil
+	}
+
+	root, err := c.executeJJ(ctx, "root")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the repository root: %w", err)
+	}
+
+	args := []string{"file", "list", "-r", revision}
+	for _, path := range paths {
+		args = append(args, rootFileset(path))
+	}
+
+	output, err := (&Client{baseDir: strings.TrimSpace(root)}).executeJJ(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %w", revision, err)
+	}
+
+	var existing []string
+
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			existing = append(existing, line)
+		}
+	}
+
+	return existing, nil
+}
+
+// rootFileset names exactly one file by its path from the repository root, quoted so that a path
+// with glob characters or spaces is not read as a pattern.
+func rootFileset(path string) string {
+	return "root-file:" + strconv.Quote(path)
+}
+
 // Status lists the working copy's changed files. Lines jj prints that are not file entries are
 // dropped, so an unparsable output yields an empty slice rather than an error.
-func (c *Client) Status() ([]FileStatus, error) {
-	cmd := c.jjCommand("status", "--no-pager")
+func (c *Client) Status(ctx context.Context) ([]FileStatus, error) {
+	cmd := c.jjCommand(ctx, "status", "--no-pager")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -83,8 +242,8 @@ func (c *Client) Status() ([]FileStatus, error) {
 
 // ShowRevision returns one revision's metadata. Fields jj did not print are left empty rather than
 // reported, so the result is never nil on success.
-func (c *Client) ShowRevision(revision string) (*RevisionInfo, error) {
-	cmd := c.jjCommand("show", "-r", revision, "--no-graph", "--summary")
+func (c *Client) ShowRevision(ctx context.Context, revision string) (*RevisionInfo, error) {
+	cmd := c.jjCommand(ctx, "show", "-r", revision, "--no-graph", "--summary")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -94,79 +253,466 @@ func (c *Client) ShowRevision(revision string) (*RevisionInfo, error) {
 	return parseRevisionInfo(string(output)), nil
 }
 
-// Undo reverts the last jj operation in the repository, whether or not this client caused it.
-func (c *Client) Undo() error {
-	cmd := c.jjCommand("undo")
+// Undo reverts the last jj operation in the repository, whether or not this client caused it. It
+// returns the operation that was current before the undo, because restoring that operation is how
+// the undo is redone.
+func (c *Client) Undo(ctx context.Context) (string, error) {
+	opID, err := c.CurrentOperationID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := c.jjCommand(ctx, "undo")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("jj undo failed: %w: %s", err, output)
+		return "", fmt.Errorf("jj undo failed: %w: %s", err, output)
+	}
+
+	return opID, nil
+}
+
+// RestoreOperation puts the whole repository back to how it was after opID, which is recorded as a
+// new operation, so a restore can itself be undone.
+func (c *Client) RestoreOperation(ctx context.Context, opID string) error {
+	if _, err := c.executeJJ(ctx, "op", "restore", opID); err != nil {
+		return fmt.Errorf("failed to restore operation: %w", err)
 	}
 
 	return nil
 }
 
-// MoveChanges applies a patch onto destination and squashes it there. Nothing the patch does not carry
-// is touched: the caller's working copy is never read, written, or moved by the sequence, because every
-// write happens in a throwaway workspace. The temp file holding the patch is removed even on failure,
-// and a failure to remove it is joined onto the returned error.
-func (c *Client) MoveChanges(patch, _, destination string) (err error) {
-	tmpDir, err := os.MkdirTemp("", "jj-diff-*")
+// NewChange creates an empty change on top of revision and makes it the working copy, as jj new does.
+func (c *Client) NewChange(ctx context.Context, revision string) error {
+	if _, err := c.executeJJ(ctx, "new", revision); err != nil {
+		return fmt.Errorf("failed to create a change on %s: %w", revision, err)
+	}
+
+	return nil
+}
+
+// Edit makes revision the working copy, so edits in the workspace amend it directly.
+func (c *Client) Edit(ctx context.Context, revision string) error {
+	if _, err := c.executeJJ(ctx, "edit", revision); err != nil {
+		return fmt.Errorf("failed to edit %s: %w", revision, err)
+	}
+
+	return nil
+}
+
+// ListConflicts lists the files jj records as conflicted in revision, with jj's description of each,
+// such as "2-sided conflict". A revision with no conflicts yields an empty slice, not the error jj
+// exits with.
+func (c *Client) ListConflicts(ctx context.Context, revision string) ([]ConflictedFile, error) {
+	cmd := c.jjCommand(ctx, "resolve", "--list", "-r", revision)
+
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("failed to create temp dir: %w", err)
+		if strings.Contains(string(output), noConflictsMessage) {
+			return []ConflictedFile{}, nil
+		}
+
+		return nil, fmt.Errorf("jj resolve --list failed: %w: %s", err, output)
 	}
-	defer func() {
-		if rmErr := os.RemoveAll(tmpDir); rmErr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to remove temp dir %s: %w", tmpDir, rmErr))
+
+	return parseConflictedFiles(string(output)), nil
+}
+
+// MoveChanges moves the changes a patch carries out of source and into destination, where the patch
+// is a diff of source against its parent. Both revisions are pinned before any command runs, and the
+// move takes the route their relationship allows. Nothing the patch does not carry is touched, and
+// the caller's working copy is never moved, because every write happens in a throwaway workspace. A
+// failure anywhere, including a cancelled ctx, rolls the repository back to the operation recorded up
+// front, and a rollback that itself failed is reported alongside the cause.
+func (c *Client) MoveChanges(ctx context.Context, patch, source, destination string) error {
+	_, err := c.moveChanges(ctx, patch, source, destination, diff.ApplyOptions{})
+
+	return err
+}
+
+// MoveChangesPartial moves what it can of the patch, for a destination that has drifted from the
+// source's parent. Each hunk is applied to the destination on its own, allowing up to fuzz lines of
+// context to be ignored at each end, and only the hunks that landed are taken out of the source, so
+// a rejected hunk stays where it was. The report says which hunks landed, shifted, or were rejected;
+// the result is committed either way, and the caller offers to restore the operation before it. A
+// patch of which nothing lands fails and rolls back like MoveChanges.
+func (c *Client) MoveChangesPartial(
+	ctx context.Context,
+	patch, source, destination string,
+	fuzz int,
+) (diff.ApplyReport, error) {
+	return c.moveChanges(ctx, patch, source, destination, diff.ApplyOptions{Partial: true, Fuzz: fuzz})
+}
+
+func (c *Client) moveChanges(
+	ctx context.Context,
+	patch, source, destination string,
+	opts diff.ApplyOptions,
+) (diff.ApplyReport, error) {
+	sourceID, destID, relation, err := c.resolveMove(ctx, source, destination)
+	if err != nil {
+		return diff.ApplyReport{}, err
+	}
+
+	opID, err := c.CurrentOperationID(ctx)
+	if err != nil {
+		return diff.ApplyReport{}, fmt.Errorf("failed to get operation ID for rollback: %w", err)
+	}
+
+	report, err := c.movePatch(ctx, patch, sourceID, destID, relation, opts)
+	if err != nil {
+		return report, c.restoreOperationAfter(ctx, opID, err)
+	}
+
+	return report, nil
+}
+
+// CheckMove reports how each hunk of the patch would fare if MoveChangesPartial moved it now, without
+// writing anything. The patch is checked in memory against the revision the move would apply it to:
+// the destination, or the source when the destination is its descendant and the move only takes the
+// patch back out. Reading files records no operation, so a check neither disturbs u nor wakes the
+// watcher.
+func (c *Client) CheckMove(
+	ctx context.Context,
+	patch, source, destination string,
+	fuzz int,
+) (diff.ApplyReport, error) {
+	sourceID, destID, relation, err := c.resolveMove(ctx, source, destination)
+	if err != nil {
+		return diff.ApplyReport{}, err
+	}
+
+	target, opts := destID, diff.ApplyOptions{Partial: true, Fuzz: fuzz}
+	if relation == relationDescendant {
+		target, opts = sourceID, diff.ApplyOptions{Partial: true, Reverse: true}
+	}
+
+	return c.checkPatchAt(ctx, patch, target, opts)
+}
+
+// ApplyPatch lands what it can of a patch that came from outside the repository, such as a mailed
+// patch, in destination. It is the destination half of MoveChangesPartial: each hunk is applied on
+// its own with up to fuzz lines of context ignored at each end, there is no source to take the patch
+// out of, and a patch of which nothing lands fails and rolls back. A destination of @ lands in the
+// files on disk as well.
+func (c *Client) ApplyPatch(ctx context.Context, patch, destination string, fuzz int) (diff.ApplyReport, error) {
+	destID, err := c.resolveChangeID(ctx, destination)
+	if err != nil {
+		return diff.ApplyReport{}, fmt.Errorf("failed to resolve destination %q: %w", destination, err)
+	}
+
+	opID, err := c.CurrentOperationID(ctx)
+	if err != nil {
+		return diff.ApplyReport{}, fmt.Errorf("failed to get operation ID for rollback: %w", err)
+	}
+
+	report, err := c.squashPatchInto(ctx, patch, destID, diff.ApplyOptions{Partial: true, Fuzz: fuzz})
+	if err != nil {
+		return report, c.restoreOperationAfter(ctx, opID, err)
+	}
+
+	return report, nil
+}
+
+// CheckApply reports how each hunk would fare if ApplyPatch applied it now, without writing anything.
+func (c *Client) CheckApply(ctx context.Context, patch, destination string, fuzz int) (diff.ApplyReport, error) {
+	return c.checkPatchAt(ctx, patch, destination, diff.ApplyOptions{Partial: true, Fuzz: fuzz})
+}
+
+// checkPatchAt checks the patch in memory against the files it names at revision.
+func (c *Client) checkPatchAt(
+	ctx context.Context,
+	patch, revision string,
+	opts diff.ApplyOptions,
+) (diff.ApplyReport, error) {
+	contents, err := c.fileContents(ctx, revision, diff.Parse(patch))
+	if err != nil {
+		return diff.ApplyReport{}, err
+	}
+
+	report, err := diff.CheckPatch(patch, contents, opts)
+	if err != nil {
+		return report, fmt.Errorf("failed to check patch: %w", err)
+	}
+
+	return report, nil
+}
+
+// fileContents reads every file the diff names at revision, leaving out the ones that do not exist
+// there.
+func (c *Client) fileContents(
+	ctx context.Context,
+	revision string,
+	files []diff.FileChange,
+) (map[string]string, error) {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	existing, err := c.FileList(ctx, revision, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make(map[string]string, len(existing))
+
+	for _, path := range existing {
+		content, err := c.FileShow(ctx, revision, path)
+		if err != nil {
+			return nil, err
 		}
-	}()
 
-	patchFile := filepath.Join(tmpDir, "changes.patch")
-	if err := os.WriteFile(patchFile, []byte(patch), patchFileMode); err != nil {
-		return fmt.Errorf("failed to write patch: %w", err)
+		contents[path] = content
 	}
 
-	return c.moveChangesWithPatch(patchFile, destination)
+	return contents, nil
 }
 
-// moveChangesWithPatch pins the destination before any command runs, then builds the patch into a
-// commit from a scratch workspace. A failure anywhere rolls the repository back to the operation
-// recorded up front, and a rollback that itself failed is reported alongside the cause.
-func (c *Client) moveChangesWithPatch(patchFile, destination string) error {
-	destID, err := c.resolveChangeID(destination)
+// resolveMove pins a move's source and destination to change IDs and relates them, refusing a move
+// onto the source itself.
+func (c *Client) resolveMove(
+	ctx context.Context,
+	source, destination string,
+) (sourceID, destID string, relation revisionRelation, err error) {
+	sourceID, err = c.resolveChangeID(ctx, source)
 	if err != nil {
-		return fmt.Errorf("failed to resolve destination %q: %w", destination, err)
+		return "", "", relationUnrelated, fmt.Errorf("failed to resolve source %q: %w", source, err)
 	}
 
-	opID, err := c.getCurrentOperationID()
+	destID, err = c.resolveChangeID(ctx, destination)
 	if err != nil {
-		return fmt.Errorf("failed to get operation ID for rollback: %w", err)
+		return "", "", relationUnrelated, fmt.Errorf("failed to resolve destination %q: %w", destination, err)
+	}
+
+	if sourceID == destID {
+		return "", "", relationUnrelated, fmt.Errorf("%q and %q: %w", source, destination, errMoveOntoSource)
+	}
+
+	relation, err = c.relate(ctx, sourceID, destID)
+
+	return sourceID, destID, relation, err
+}
+
+// revisionRelation is where a move's destination sits relative to its source in the commit graph.
+type revisionRelation int
+
+const (
+	relationUnrelated revisionRelation = iota
+	relationAncestor
+	relationDescendant
+)
+
+// relate reports whether destID is an ancestor or a descendant of sourceID, or neither, as for a
+// sibling.
+func (c *Client) relate(ctx context.Context, sourceID, destID string) (revisionRelation, error) {
+	for _, check := range []struct {
+		revset   string
+		relation revisionRelation
+	}{
+		{destID + " & ::" + sourceID, relationAncestor},
+		{destID + " & " + sourceID + "::", relationDescendant},
+	} {
+		output, err := c.executeJJ(ctx, "log", "-r", check.revset, "--no-graph", "-T", "change_id")
+		if err != nil {
+			return relationUnrelated, fmt.Errorf("failed to relate %s to %s: %w", destID, sourceID, err)
+		}
+
+		if strings.TrimSpace(output) != "" {
+			return check.relation, nil
+		}
 	}
 
-	if err := c.applyPatchInScratchWorkspace(patchFile, destID); err != nil {
-		return c.restoreOperationAfter(opID, err)
+	return relationUnrelated, nil
+}
+
+// movePatch takes the patch out of sourceID and puts it into destID, and reports how its hunks
+// applied to the end that had to take them. A revision on the path between the two already sees the
+// change through its parents, so only the end that loses or gains it needs the patch: moving into an
+// ancestor applies it there and gives the source its own content back, leaving the change in the
+// ancestor alone, and moving into a descendant removes it from the source and gives the destination
+// its content back. Unrelated revisions need both halves, and the destination goes first so that
+// only what landed there is taken out of the source. The source always holds the patch as written,
+// so taking it out never needs opts.
+func (c *Client) movePatch(
+	ctx context.Context,
+	patch, sourceID, destID string,
+	relation revisionRelation,
+	opts diff.ApplyOptions,
+) (diff.ApplyReport, error) {
+	var report diff.ApplyReport
+
+	switch relation {
+	case relationAncestor:
+		err := c.keepingContent(ctx, sourceID, func() (err error) {
+			report, err = c.squashPatchInto(ctx, patch, destID, opts)
+
+			return err
+		})
+
+		return report, err
+	case relationDescendant:
+		err := c.keepingContent(ctx, destID, func() (err error) {
+			report, err = c.removePatchInScratchWorkspace(ctx, patch, sourceID)
+
+			return err
+		})
+
+		return report, err
+	case relationUnrelated:
+		report, err := c.squashPatchInto(ctx, patch, destID, opts)
+		if err != nil {
+			return report, err
+		}
+
+		_, err = c.removePatchInScratchWorkspace(ctx, report.Landed(patch), sourceID)
+
+		return report, err
+	default:
+		return report, fmt.Errorf("%w: %d", errUnknownRelation, relation)
+	}
+}
+
+// keepingContent runs rewrite, then gives changeID back the content it had before. Rebasing a
+// revision onto a rewritten ancestor merges its content back in, and two edits close together in one
+// file can merge as a conflict; the content is known, so it is put back outright rather than trusted
+// to the merge.
+func (c *Client) keepingContent(ctx context.Context, changeID string, rewrite func() error) error {
+	commitID, err := c.resolveCommitID(ctx, changeID)
+	if err != nil {
+		return fmt.Errorf("failed to pin %s's content: %w", changeID, err)
+	}
+
+	if err := rewrite(); err != nil {
+		return err
+	}
+
+	if _, err := c.executeJJ(ctx, "restore", "--from", commitID, "--into", changeID); err != nil {
+		return fmt.Errorf("failed to restore %s's content: %w", changeID, err)
 	}
 
 	return nil
 }
 
-// Sentinel errors the move path returns on its own rather than wrapping one from jj or git.
+// Sentinel errors the move path returns on its own rather than wrapping one from jj or the applier.
 var (
 	errRevsetNoMatch       = errors.New("revset matched no revision")
-	errNewCommitNotFound   = errors.New("jj created a commit that could not be found afterwards")
 	errNoSplitPlans        = errors.New("no split plans provided")
 	errPatchChangedNothing = errors.New("the patch applied cleanly but changed nothing, so there is nothing to move")
+	errMoveOntoSource      = errors.New("the source and destination are the same revision")
+	errUnknownRelation     = errors.New("unknown revision relation")
 )
 
+// ErrRollbackFailed marks a failed write whose rollback also failed, so the repository may be left
+// part way through the operation. A caller that would otherwise treat the failure as harmless, such as
+// one the user cancelled, has to surface this one.
+var ErrRollbackFailed = errors.New("rollback failed, repository may be left modified")
+
 // scratchWorkspacePrefix names both the temp directory and the jj workspace, so a leaked workspace is
 // identifiable in jj workspace list.
 const scratchWorkspacePrefix = "jj-diff-scratch"
 
-// applyPatchInScratchWorkspace builds the patch into a commit on destID from a workspace of its own.
-// No command here names @, so the caller's working copy is untouched whether the run succeeds or
-// fails. The workspace is forgotten and its directory removed on every return path, including a panic,
-// though a panic discards the cleanup's own error along with the return value.
-func (c *Client) applyPatchInScratchWorkspace(patchFile, destID string) (err error) {
+// removePatchInScratchWorkspace takes the patch back out of sourceID by applying it in reverse on
+// top of it, so the source must contain what the patch adds.
+func (c *Client) removePatchInScratchWorkspace(
+	ctx context.Context,
+	patch, sourceID string,
+) (diff.ApplyReport, error) {
+	return c.squashPatchInto(ctx, patch, sourceID, diff.ApplyOptions{Reverse: true})
+}
+
+// squashPatchInto applies the patch in a scratch commit on targetID and squashes the result into it.
+// No command here names @, so the caller's working copy only changes when targetID is its @, and
+// inScratchWorkspace then checks the new content out.
+func (c *Client) squashPatchInto(
+	ctx context.Context,
+	patch, targetID string,
+	opts diff.ApplyOptions,
+) (diff.ApplyReport, error) {
+	var report diff.ApplyReport
+
+	err := c.inScratchWorkspace(ctx, func(scratch *Client, dir string) (err error) {
+		if _, err := scratch.executeJJ(ctx, "new", targetID); err != nil {
+			return fmt.Errorf("failed to create scratch commit on %s: %w", targetID, err)
+		}
+
+		if report, err = applyScratchPatch(ctx, scratch, dir, patch, opts); err != nil {
+			return err
+		}
+
+		if _, err := scratch.executeJJ(ctx, "squash", "--into", targetID); err != nil {
+			return fmt.Errorf("failed to squash changes into %s: %w", targetID, err)
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// insertCommitWithPatch creates a described commit between sourceID and its parents, as jj split
+// does for the part it takes out, and builds the patch into it. The scratch workspace edits the new
+// commit directly, so the patch lands in it without a squash, and jj rebases the source onto it.
+func (c *Client) insertCommitWithPatch(ctx context.Context, patch, sourceID, description string) error {
+	return c.inScratchWorkspace(ctx, func(scratch *Client, dir string) error {
+		if _, err := scratch.executeJJ(ctx, "new", "--insert-before", sourceID, "-m", description); err != nil {
+			return fmt.Errorf("failed to create a commit before %s: %w", sourceID, err)
+		}
+
+		_, err := applyScratchPatch(ctx, scratch, dir, patch, diff.ApplyOptions{})
+
+		return err
+	})
+}
+
+// applyScratchPatch applies the patch to the scratch workspace's working copy and snapshots it into
+// the workspace's @. A patch that changes nothing is an error, because it would leave behind a commit
+// the user never asked for, and so is a partial apply where no hunk landed. Applying runs in process
+// and does not watch ctx, so a cancellation is checked before it starts.
+func applyScratchPatch(
+	ctx context.Context,
+	scratch *Client,
+	dir, patch string,
+	opts diff.ApplyOptions,
+) (diff.ApplyReport, error) {
+	if err := ctx.Err(); err != nil {
+		return diff.ApplyReport{}, fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	report, err := diff.ApplyPatch(dir, patch, opts)
+	if err == nil && report.Count(diff.HunkRejected) == len(report.Hunks) {
+		err = report.Err()
+	}
+
+	if err != nil {
+		return report, fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	changed, err := scratch.Diff(ctx, "@")
+	if err != nil {
+		return report, fmt.Errorf("failed to read the scratch commit: %w", err)
+	}
+
+	if strings.TrimSpace(changed) == "" {
+		return report, errPatchChangedNothing
+	}
+
+	return report, nil
+}
+
+// inScratchWorkspace runs fn against a throwaway workspace of the repository, passing a client rooted
+// there and the workspace directory. The workspace is forgotten and
+// its directory removed on every return path, including a panic, though a panic discards the
+// cleanup's own error along with the return value. The cleanup runs outside ctx, so a cancelled
+// write still forgets its workspace.
+//
+// A write that changes the tree of the caller's @, as moving out of @ or into it does, leaves the
+// caller's workspace stale, and jj refuses to run there until it is brought up to date. The cleanup
+// therefore updates it too, which does nothing when @ was left alone.
+func (c *Client) inScratchWorkspace(
+	ctx context.Context,
+	fn func(scratch *Client, dir string) error,
+) (err error) {
 	root, err := os.MkdirTemp("", scratchWorkspacePrefix+"-*")
 	if err != nil {
 		return fmt.Errorf("failed to create scratch workspace directory: %w", err)
@@ -177,7 +723,7 @@ func (c *Client) applyPatchInScratchWorkspace(patchFile, destID string) (err err
 	name := filepath.Base(root)
 	dir := filepath.Join(root, "workspace")
 
-	if _, addErr := c.executeJJ("workspace", "add", "--name", name, dir); addErr != nil {
+	if _, addErr := c.executeJJ(ctx, "workspace", "add", "--name", name, dir); addErr != nil {
 		addErr = fmt.Errorf("failed to create scratch workspace: %w", addErr)
 		if rmErr := os.RemoveAll(root); rmErr != nil {
 			addErr = errors.Join(addErr, fmt.Errorf("failed to remove %s: %w", root, rmErr))
@@ -187,41 +733,19 @@ func (c *Client) applyPatchInScratchWorkspace(patchFile, destID string) (err err
 	}
 
 	defer func() {
-		err = errors.Join(err, c.removeScratchWorkspace(name, root))
+		cleanupCtx := context.WithoutCancel(ctx)
+		err = errors.Join(err, c.removeScratchWorkspace(cleanupCtx, name, root), c.updateStale(cleanupCtx))
 	}()
 
-	scratch := &Client{baseDir: dir}
-
-	if _, err := scratch.executeJJ("new", destID); err != nil {
-		return fmt.Errorf("failed to create scratch commit on %s: %w", destID, err)
-	}
-
-	if err := applyPatchFile(dir, root, patchFile); err != nil {
-		return err
-	}
-
-	changed, err := scratch.Diff("@")
-	if err != nil {
-		return fmt.Errorf("failed to read the scratch commit: %w", err)
-	}
-
-	if strings.TrimSpace(changed) == "" {
-		return errPatchChangedNothing
-	}
-
-	if _, err := scratch.executeJJ("squash", "--into", destID); err != nil {
-		return fmt.Errorf("failed to squash changes into %s: %w", destID, err)
-	}
-
-	return nil
+	return fn(&Client{baseDir: dir}, dir)
 }
 
 // removeScratchWorkspace drops the workspace from the repository and deletes its directory, reporting
 // both failures rather than the first, because either one left behind is a leak the caller should see.
-func (c *Client) removeScratchWorkspace(name, root string) error {
+func (c *Client) removeScratchWorkspace(ctx context.Context, name, root string) error {
 	var errs error
 
-	if _, err := c.executeJJ("workspace", "forget", name); err != nil {
+	if _, err := c.executeJJ(ctx, "workspace", "forget", name); err != nil {
 		errs = errors.Join(errs, fmt.Errorf("failed to forget scratch workspace %s: %w", name, err))
 	}
 
@@ -232,21 +756,11 @@ func (c *Client) removeScratchWorkspace(name, root string) error {
 	return errs
 }
 
-// applyPatchFile applies patchFile inside dir. Git resolves a patch's paths against the nearest
-// enclosing repository rather than against the working directory, so an unrelated repository above
-// ceiling would turn the apply into a silent no-op. The ceiling stops that search.
-func applyPatchFile(dir, ceiling, patchFile string) error {
-	if resolved, err := filepath.EvalSymlinks(ceiling); err == nil {
-		ceiling = resolved
-	}
-
-	//nolint:gosec // G204: the binary is a literal; only the arguments vary and no shell is involved.
-	cmd := exec.CommandContext(context.Background(), "git", "apply", patchFile)
-	cmd.Dir = dir
-	cmd.Env = append(os.Environ(), "GIT_CEILING_DIRECTORIES="+ceiling)
-
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to apply patch: %w: %s", err, output)
+// updateStale checks the caller's workspace out at its @ again after another workspace rewrote it,
+// so the files on disk and the next jj command see the rewrite.
+func (c *Client) updateStale(ctx context.Context) error {
+	if _, err := c.executeJJ(ctx, "workspace", "update-stale"); err != nil {
+		return fmt.Errorf("failed to update the stale working copy: %w", err)
 	}
 
 	return nil
@@ -255,8 +769,8 @@ func applyPatchFile(dir, ceiling, patchFile string) error {
 // resolveChangeID pins a revset to the change ID it names right now. A revset such as @- moves as the
 // repository changes underneath it, so anything that outlives a single command has to hold the change
 // ID instead of the revset that produced it.
-func (c *Client) resolveChangeID(revset string) (string, error) {
-	output, err := c.executeJJ("log", "-r", revset, "--no-graph", "--limit", "1", "-T", "change_id")
+func (c *Client) resolveChangeID(ctx context.Context, revset string) (string, error) {
+	output, err := c.executeJJ(ctx, "log", "-r", revset, "--no-graph", "--limit", "1", "-T", "change_id")
 	if err != nil {
 		return "", err
 	}
@@ -270,12 +784,13 @@ func (c *Client) resolveChangeID(revset string) (string, error) {
 }
 
 // restoreOperationAfter reports cause, and additionally reports when restoring
-// opID failed and the repository is therefore left modified.
-func (c *Client) restoreOperationAfter(opID string, cause error) error {
-	if restoreErr := c.restoreOperation(opID); restoreErr != nil {
+// opID failed and the repository is therefore left modified. The restore runs
+// outside ctx, because a cancelled call is exactly the one that needs it.
+func (c *Client) restoreOperationAfter(ctx context.Context, opID string, cause error) error {
+	if restoreErr := c.RestoreOperation(context.WithoutCancel(ctx), opID); restoreErr != nil {
 		return errors.Join(
 			cause,
-			fmt.Errorf("rollback to operation %s failed, repository may be left modified: %w", opID, restoreErr),
+			fmt.Errorf("%w: restoring operation %s: %w", ErrRollbackFailed, opID, restoreErr),
 		)
 	}
 
@@ -284,7 +799,7 @@ func (c *Client) restoreOperationAfter(opID string, cause error) error {
 
 // GetRevisions lists recent revisions newest first for the destination picker, defaulting to 20 when
 // limit is not positive.
-func (c *Client) GetRevisions(limit int) ([]RevisionEntry, error) {
+func (c *Client) GetRevisions(ctx context.Context, limit int) ([]RevisionEntry, error) {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -296,7 +811,7 @@ func (c *Client) GetRevisions(limit int) ([]RevisionEntry, error) {
 		`change_id.shortest(),` +
 		`if(description, description.first_line(), "(no description)")) ++ "\n---\n"`
 
-	cmd := c.jjCommand("log",
+	cmd := c.jjCommand(ctx, "log",
 		"--no-graph",
 		"--limit", strconv.Itoa(limit),
 		"--template", template)
@@ -309,6 +824,143 @@ func (c *Client) GetRevisions(limit int) ([]RevisionEntry, error) {
 	return parseRevisionEntries(string(output)), nil
 }
 
+// GetLog returns jj log as jj draws it, graph included, over the user's default log revset. Each
+// revision's line carries its fields after the graph glyphs, tab-separated with a leading tab so
+// the parser can find where the graph ends; the description comes last, so a tab inside it
+// survives.
+func (c *Client) GetLog(ctx context.Context, limit int) ([]LogLine, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	template := `"\t" ++ change_id.shortest(8) ++ ` +
+		`"\t" ++ bookmarks ++ ` +
+		`"\t" ++ author.name() ++ ` +
+		`"\t" ++ if(current_working_copy, "@") ++ ` +
+		`"\t" ++ if(empty, "empty") ++ ` +
+		`"\t" ++ description.first_line() ++ "\n"`
+
+	output, err := c.executeJJ(ctx, "log",
+		"--color=never",
+		"--limit", strconv.Itoa(limit),
+		"-T", template)
+	if err != nil {
+		return nil, fmt.Errorf("jj log failed: %w", err)
+	}
+
+	return parseLogGraph(output), nil
+}
+
+// LogLine is one line of jj log's graph. Graph is the glyphs jj drew in front of the line. Entry is
+// nil for a line that only carries the graph's edges, such as the │ between two revisions or an
+// elided-revisions marker.
+type LogLine struct {
+	Entry *LogEntry
+	Graph string
+}
+
+// LogEntry is one revision in the graph. ChangeID is jj's shortest unique prefix, padded to at least
+// eight characters, so it is only valid against the repository it came from.
+type LogEntry struct {
+	ChangeID      string
+	Author        string
+	Description   string
+	Bookmarks     []string
+	IsWorkingCopy bool
+	Empty         bool
+}
+
+// GetOpLog lists the repository's most recent operations, newest first, so the first entry is the
+// current operation. Records are separator-terminated for the same reason GetEvolog's are.
+func (c *Client) GetOpLog(ctx context.Context, limit int) ([]OpLogEntry, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	template := `id ++ "\n" ++ ` +
+		`description.first_line() ++ "\n" ++ ` +
+		`time.end().ago() ++ "\n" ++ ` +
+		`user ++ "\n---\n"`
+
+	output, err := c.executeJJ(ctx, "op", "log",
+		"--no-graph",
+		"--limit", strconv.Itoa(limit),
+		"-T", template)
+	if err != nil {
+		return nil, fmt.Errorf("jj op log failed: %w", err)
+	}
+
+	return parseOpLogEntries(output), nil
+}
+
+// OpLogEntry is one jj operation. ID is the full operation ID, which RestoreOperation accepts, and
+// Time is jj's relative rendering of when the operation finished.
+type OpLogEntry struct {
+	ID          string
+	Description string
+	Time        string
+	User        string
+}
+
+// shortOperationIDLength is how many hex digits of an operation ID the UI shows, matching jj op log's
+// default output.
+const shortOperationIDLength = 12
+
+// ShortOperationID abbreviates a full operation ID for display. An ID already shorter is returned as
+// is.
+func ShortOperationID(opID string) string {
+	if len(opID) <= shortOperationIDLength {
+		return opID
+	}
+
+	return opID[:shortOperationIDLength]
+}
+
+// GetEvolog lists every evolution of the change revision names, newest first, so the first entry is
+// the commit the change points at now. Each record is terminated by a separator line, because an
+// empty field would otherwise shift every field after it. A jj older than EvologMinVersion is refused
+// with ErrVersionTooOld before evolog runs.
+func (c *Client) GetEvolog(ctx context.Context, revision string) ([]EvologEntry, error) {
+	if err := c.checkVersion(ctx, EvologMinVersion, "the evolution timeline"); err != nil {
+		return nil, err
+	}
+
+	template := `commit.commit_id() ++ "\n" ++ ` +
+		`commit.committer().timestamp().ago() ++ "\n" ++ ` +
+		`operation.description().first_line() ++ "\n" ++ ` +
+		`if(commit.description(), commit.description().first_line(), "(no description)") ++ "\n---\n"`
+
+	output, err := c.executeJJ(ctx, "evolog", "-r", revision, "--no-graph", "-T", template)
+	if err != nil {
+		return nil, fmt.Errorf("jj evolog failed: %w", err)
+	}
+
+	return parseEvologEntries(output), nil
+}
+
+// EvologEntry is one evolution of a change. CommitID is the full ID, because an old evolution is a
+// hidden commit and only its commit ID still names it. Timestamp is jj's relative rendering, such as
+// "2 hours ago", and Operation is the description of the operation that created the commit.
+type EvologEntry struct {
+	CommitID    string
+	Timestamp   string
+	Operation   string
+	Description string
+}
+
+// shortCommitIDLength is how many hex digits of a commit ID the UI shows, which is what jj's own
+// short() prints.
+const shortCommitIDLength = 12
+
+// ShortCommitID abbreviates a full commit ID for display. An ID already shorter is returned as is.
+func ShortCommitID(commitID string) string {
+	if len(commitID) <= shortCommitIDLength {
+		return commitID
+	}
+
+	return commitID[:shortCommitIDLength]
+}
+
 // RevisionEntry is one row of the destination picker. ChangeID is jj's shortest unique prefix, so it
 // is only valid against the repository it came from.
 type RevisionEntry struct {
@@ -342,6 +994,30 @@ type SplitPlan struct {
 	Tag         rune
 }
 
+// StacksBeneath reports whether a split plan sent to destination leaves its change beneath source, so
+// the plans after it must be written against it, as diff.PatchStack does. A new commit is inserted
+// below source and a move into an ancestor stays in source's parents, while a move anywhere else
+// takes the change out of source and leaves its parent as it was.
+func (c *Client) StacksBeneath(ctx context.Context, destination SplitDestination, source string) (bool, error) {
+	if destination.Type == SplitDestNewCommit {
+		return true, nil
+	}
+
+	_, _, relation, err := c.resolveMove(ctx, source, destination.ChangeID)
+
+	return relation == relationAncestor, err
+}
+
+// noConflictsMessage is how jj resolve --list reports, as a failure, that there is nothing to list.
+const noConflictsMessage = "No conflicts found"
+
+// ConflictedFile is one line of jj resolve --list. Description is jj's summary of the conflict and is
+// empty when jj printed none.
+type ConflictedFile struct {
+	Path        string
+	Description string
+}
+
 // FileStatus is one entry from jj status.
 type FileStatus struct {
 	Path       string
@@ -426,6 +1102,29 @@ func parseStatus(output string) []FileStatus {
 	return files
 }
 
+// conflictListLineRE splits a jj resolve --list line at the run of spaces jj pads the path with,
+// which leaves a path that itself holds a single space intact.
+var conflictListLineRE = regexp.MustCompile(`^(.*?)\s{2,}(\S.*)$`)
+
+func parseConflictedFiles(output string) []ConflictedFile {
+	files := []ConflictedFile{}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" {
+			continue
+		}
+
+		if match := conflictListLineRE.FindStringSubmatch(line); match != nil {
+			files = append(files, ConflictedFile{Path: match[1], Description: match[2]})
+		} else {
+			files = append(files, ConflictedFile{Path: line})
+		}
+	}
+
+	return files
+}
+
 func parseRevisionInfo(output string) *RevisionInfo {
 	info := &RevisionInfo{}
 	lines := strings.Split(output, "\n")
@@ -477,90 +1176,152 @@ func parseRevisionEntries(output string) []RevisionEntry {
 	return entries
 }
 
-func (c *Client) getCurrentOperationID() (string, error) {
-	output, err := c.executeJJ("op", "log", "--no-graph", "--limit", "1", "-T", "id")
-	if err != nil {
-		return "", fmt.Errorf("failed to get current operation ID: %w", err)
+// logFieldCount is the number of tab-separated fields GetLog's template writes after the graph.
+const logFieldCount = 6
+
+// parseLogGraph splits jj log's graph output into lines, reading the fields from each line that
+// carries a revision. Graph glyphs never include a tab, so the first tab ends the graph.
+func parseLogGraph(output string) []LogLine {
+	var lines []LogLine
+
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		graph, record, found := strings.Cut(line, "\t")
+		if !found {
+			lines = append(lines, LogLine{Graph: strings.TrimRight(line, " ")})
+			continue
+		}
+
+		fields := strings.SplitN(record, "\t", logFieldCount)
+		if len(fields) != logFieldCount || fields[0] == "" {
+			lines = append(lines, LogLine{Graph: strings.TrimRight(graph, " ")})
+			continue
+		}
+
+		lines = append(lines, LogLine{
+			Graph: strings.TrimRight(graph, " "),
+			Entry: &LogEntry{
+				ChangeID:      fields[0],
+				Bookmarks:     strings.Fields(fields[1]),
+				Author:        fields[2],
+				IsWorkingCopy: fields[3] == "@",
+				Empty:         fields[4] == "empty",
+				Description:   strings.TrimSpace(fields[5]),
+			},
+		})
 	}
 
-	return strings.TrimSpace(output), nil
+	return lines
 }
 
-// createNewCommit adds an empty described commit as a child of the working copy and returns its change
-// ID. Because jj does not report the ID it created and --no-edit leaves @ where it was, the ID is
-// found by diffing the working copy's children across the call rather than by reading @ afterwards.
-func (c *Client) createNewCommit(description string) (string, error) {
-	before, err := c.changeIDs("@+")
-	if err != nil {
-		return "", fmt.Errorf("failed to list existing children: %w", err)
-	}
+// evologFieldCount is the number of lines GetEvolog's template writes per record before the
+// separator.
+const evologFieldCount = 4
 
-	if _, err := c.executeJJ("new", "-m", description, "--no-edit"); err != nil {
-		return "", fmt.Errorf("failed to create new commit: %w", err)
-	}
+func parseEvologEntries(output string) []EvologEntry {
+	var entries []EvologEntry
 
-	after, err := c.changeIDs("@+")
-	if err != nil {
-		return "", fmt.Errorf("failed to list children after creating the commit: %w", err)
+	for _, record := range strings.Split(output, "\n---\n") {
+		fields := strings.Split(record, "\n")
+		if len(fields) != evologFieldCount || strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+
+		entries = append(entries, EvologEntry{
+			CommitID:    strings.TrimSpace(fields[0]),
+			Timestamp:   strings.TrimSpace(fields[1]),
+			Operation:   strings.TrimSpace(fields[2]),
+			Description: strings.TrimSpace(fields[3]),
+		})
 	}
 
-	for _, changeID := range after {
-		if !slices.Contains(before, changeID) {
-			return changeID, nil
+	return entries
+}
+
+// opLogFieldCount is the number of lines GetOpLog's template writes per record before the separator.
+const opLogFieldCount = 4
+
+func parseOpLogEntries(output string) []OpLogEntry {
+	var entries []OpLogEntry
+
+	for _, record := range strings.Split(output, "\n---\n") {
+		fields := strings.Split(record, "\n")
+		if len(fields) != opLogFieldCount || strings.TrimSpace(fields[0]) == "" {
+			continue
 		}
+
+		entries = append(entries, OpLogEntry{
+			ID:          strings.TrimSpace(fields[0]),
+			Description: strings.TrimSpace(fields[1]),
+			Time:        strings.TrimSpace(fields[2]),
+			User:        strings.TrimSpace(fields[3]),
+		})
 	}
 
-	return "", errNewCommitNotFound
+	return entries
 }
 
-func (c *Client) changeIDs(revset string) ([]string, error) {
-	output, err := c.executeJJ("log", "-r", revset, "--no-graph", "-T", `change_id ++ "\n"`)
+// CurrentOperationID returns the ID of the repository's latest operation. A caller that runs several
+// jj commands as one action records it first, so the whole action can be restored away at once.
+func (c *Client) CurrentOperationID(ctx context.Context) (string, error) {
+	output, err := c.executeJJ(ctx, "op", "log", "--no-graph", "--limit", "1", "-T", "id")
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to get current operation ID: %w", err)
 	}
 
-	return strings.Fields(output), nil
+	return strings.TrimSpace(output), nil
 }
 
-func (c *Client) restoreOperation(opID string) error {
-	if _, err := c.executeJJ("op", "restore", opID); err != nil {
-		return fmt.Errorf("failed to restore operation: %w", err)
+// resolveCommitID pins a revset to the commit it names right now. Unlike a change ID, a commit ID
+// keeps naming the same content after the change is rewritten, which is what restoring that content
+// later needs.
+func (c *Client) resolveCommitID(ctx context.Context, revset string) (string, error) {
+	output, err := c.executeJJ(ctx, "log", "-r", revset, "--no-graph", "--limit", "1", "-T", "commit_id")
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	commitID := strings.TrimSpace(output)
+	if commitID == "" {
+		return "", fmt.Errorf("%q: %w", revset, errRevsetNoMatch)
+	}
+
+	return commitID, nil
 }
 
-// ApplySplit runs the plans in order, creating a commit first for each plan that needs one. A failure
-// part way through restores the operation recorded before the first plan, so the repository goes back
-// to where it started rather than keeping the plans that already succeeded.
-func (c *Client) ApplySplit(plans []SplitPlan, source string) error {
+// ApplySplit runs the plans in order. A plan for a new commit works as jj split does: the commit is
+// inserted between the source and its parents with the plan's changes in it, so new commits stack in
+// plan order with the first nearest the parents, and the source keeps its content, which leaves its
+// diff smaller by exactly what the plan took. A plan for an existing revision is a move out of the
+// source. A failure part way through restores the operation recorded before the first plan, so the
+// repository goes back to where it started rather than keeping the plans that already succeeded, and
+// a cancelled ctx counts as such a failure.
+func (c *Client) ApplySplit(ctx context.Context, plans []SplitPlan, source string) error {
 	if len(plans) == 0 {
 		return errNoSplitPlans
 	}
 
-	opID, err := c.getCurrentOperationID()
+	sourceID, err := c.resolveChangeID(ctx, source)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source %q: %w", source, err)
+	}
+
+	opID, err := c.CurrentOperationID(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get operation ID for rollback: %w", err)
 	}
 
 	for i, plan := range plans {
-		var destChangeID string
-
 		if plan.Destination.Type == SplitDestNewCommit {
-			changeID, err := c.createNewCommit(plan.Destination.Description)
-			if err != nil {
-				return c.restoreOperationAfter(
-					opID,
-					fmt.Errorf("failed to create new commit for tag %c: %w", plan.Tag, err),
-				)
-			}
-			destChangeID = changeID
+			err = c.keepingContent(ctx, sourceID, func() error {
+				return c.insertCommitWithPatch(ctx, plan.Patch, sourceID, plan.Destination.Description)
+			})
 		} else {
-			destChangeID = plan.Destination.ChangeID
+			err = c.MoveChanges(ctx, plan.Patch, sourceID, plan.Destination.ChangeID)
 		}
 
-		if err := c.MoveChanges(plan.Patch, source, destChangeID); err != nil {
+		if err != nil {
 			return c.restoreOperationAfter(
+				ctx,
 				opID,
 				fmt.Errorf("failed to apply patch for tag %c (plan %d): %w", plan.Tag, i+1, err),
 			)
@@ -570,8 +1331,8 @@ func (c *Client) ApplySplit(plans []SplitPlan, source string) error {
 	return nil
 }
 
-func (c *Client) executeJJ(args ...string) (string, error) {
-	cmd := c.jjCommand(args...)
+func (c *Client) executeJJ(ctx context.Context, args ...string) (string, error) {
+	cmd := c.jjCommand(ctx, args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -0,0 +1,249 @@
+//nolint:testpackage // white-box: the parsers are unexported halves of the jj commands they read.
+package jj
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// evologOutput is what GetEvolog's template prints for a change rewritten twice since it was created:
+// one record per evolution, newest first, each ended by a separator line.
+const evologOutput = `0f3c9a7d5e2b41c8a6f09d3e7b5c2a1f4e8d6b90
+2 minutes ago
+describe commit 5b1e8c0a2d4f
+Add the parser
+---
+5b1e8c0a2d4f6e8a0c2e4a6c8e0a2c4e6a8c0e2a
+1 hour ago
+snapshot working copy
+(no description)
+---
+9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c
+3 hours ago
+
+(no description)
+---
+`
+
+func TestParseEvologEntries(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		output string
+		want   []EvologEntry
+	}{
+		"three evolutions": {
+			output: evologOutput,
+			want: []EvologEntry{
+				{
+					CommitID:    "0f3c9a7d5e2b41c8a6f09d3e7b5c2a1f4e8d6b90",
+					Timestamp:   "2 minutes ago",
+					Operation:   "describe commit 5b1e8c0a2d4f",
+					Description: "Add the parser",
+				},
+				{
+					CommitID:    "5b1e8c0a2d4f6e8a0c2e4a6c8e0a2c4e6a8c0e2a",
+					Timestamp:   "1 hour ago",
+					Operation:   "snapshot working copy",
+					Description: "(no description)",
+				},
+				{
+					CommitID:    "9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c",
+					Timestamp:   "3 hours ago",
+					Description: "(no description)",
+				},
+			},
+		},
+		"no output": {output: ""},
+		"a record short a field is skipped": {
+			output: "0f3c9a7d5e2b\n2 minutes ago\nAdd the parser\n---\n" +
+				"5b1e8c0a2d4f\n1 hour ago\nnew empty commit\n(no description)\n---\n",
+			want: []EvologEntry{{
+				CommitID:    "5b1e8c0a2d4f",
+				Timestamp:   "1 hour ago",
+				Operation:   "new empty commit",
+				Description: "(no description)",
+			}},
+		},
+		"a record without a commit ID is skipped": {
+			output: "\n2 minutes ago\ndescribe commit\nAdd the parser\n---\n",
+		},
+	}
+
+	for name, tt := range tests {
+		if got := parseEvologEntries(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]int{
+		"jj 0.31.0\n":              {0, 31, 0},
+		"jj 0.32.0-0a1b2c3d4e5f\n": {0, 32, 0},
+		"jj 1.2.10":                {1, 2, 10},
+		"jj dev\n":                 nil,
+		"":                         nil,
+	}
+
+	for text, want := range tests {
+		got, ok := parseVersion(text)
+		if ok != (want != nil) || !slices.Equal(got, want) {
+			t.Errorf("parseVersion(%q) = %v, %v, want %v", text, got, ok, want)
+		}
+	}
+
+	older, _ := parseVersion("jj 0.30.1")
+	minimum, _ := parseVersion(EvologMinVersion)
+
+	if slices.Compare(older, minimum) >= 0 {
+		t.Errorf("Expected 0.30.1 to be older than %s", EvologMinVersion)
+	}
+}
+
+// opLogOutput is what GetOpLog's template prints for the three newest operations of a repository,
+// newest first, each ended by a separator line. The operation IDs are cut short for readability.
+const opLogOutput = `8a1f3e5c7b9d
+squash commits into 3e6f1a2b4c5d
+12 seconds ago
+kyle@laptop
+---
+2c4e6a8b0d2f
+snapshot working copy
+5 minutes ago
+kyle@laptop
+---
+000000000000
+
+1 year ago
+@
+---
+`
+
+func TestParseOpLogEntries(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		output string
+		want   []OpLogEntry
+	}{
+		"three operations": {
+			output: opLogOutput,
+			want: []OpLogEntry{
+				{
+					ID:          "8a1f3e5c7b9d",
+					Description: "squash commits into 3e6f1a2b4c5d",
+					Time:        "12 seconds ago",
+					User:        "kyle@laptop",
+				},
+				{
+					ID:          "2c4e6a8b0d2f",
+					Description: "snapshot working copy",
+					Time:        "5 minutes ago",
+					User:        "kyle@laptop",
+				},
+				{
+					ID:   "000000000000",
+					Time: "1 year ago",
+					User: "@",
+				},
+			},
+		},
+		"no output": {output: ""},
+		"a record short a field is skipped": {
+			output: "8a1f3e5c7b9d\n12 seconds ago\nkyle@laptop\n---\n" +
+				"2c4e6a8b0d2f\nnew empty commit\n5 minutes ago\nkyle@laptop\n---\n",
+			want: []OpLogEntry{{
+				ID:          "2c4e6a8b0d2f",
+				Description: "new empty commit",
+				Time:        "5 minutes ago",
+				User:        "kyle@laptop",
+			}},
+		},
+		"a record without an ID is skipped": {
+			output: "\nsnapshot working copy\n5 minutes ago\nkyle@laptop\n---\n",
+		},
+	}
+
+	for name, tt := range tests {
+		if got := parseOpLogEntries(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, tt.want)
+		}
+	}
+}
+
+// logGraphOutput is what GetLog's template prints under jj's graph for a working copy on top of a
+// bookmarked revision, with the revisions below them elided.
+const logGraphOutput = "@  \tqpvuntsm\t\tKyle King\t@\tempty\t\n" +
+	"│\n" +
+	"○  \tkkmpptxz\tmain feature\tKyle King\t\t\tAdd the parser \n" +
+	"│\n" +
+	"~  (elided revisions)\n" +
+	"◆  \tzzzzzzzz\t\t\t\tempty\t\n"
+
+func TestParseLogGraph(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		output string
+		want   []LogLine
+	}{
+		"working copy over a bookmarked revision": {
+			output: logGraphOutput,
+			want: []LogLine{
+				{Graph: "@", Entry: &LogEntry{
+					ChangeID:      "qpvuntsm",
+					Bookmarks:     []string{},
+					Author:        "Kyle King",
+					IsWorkingCopy: true,
+					Empty:         true,
+				}},
+				{Graph: "│"},
+				{Graph: "○", Entry: &LogEntry{
+					ChangeID:    "kkmpptxz",
+					Bookmarks:   []string{"main", "feature"},
+					Author:      "Kyle King",
+					Description: "Add the parser",
+				}},
+				{Graph: "│"},
+				{Graph: "~  (elided revisions)"},
+				{Graph: "◆", Entry: &LogEntry{ChangeID: "zzzzzzzz", Bookmarks: []string{}, Empty: true}},
+			},
+		},
+		"a description keeps its tabs": {
+			output: "○  \tkkmpptxz\t\tKyle King\t\t\tcolumns\tand\trows\n",
+			want: []LogLine{{Graph: "○", Entry: &LogEntry{
+				ChangeID:    "kkmpptxz",
+				Bookmarks:   []string{},
+				Author:      "Kyle King",
+				Description: "columns\tand\trows",
+			}}},
+		},
+		"a line short a field is only graph": {
+			output: "○  \tkkmpptxz\tmain\tKyle King\n",
+			want:   []LogLine{{Graph: "○"}},
+		},
+		"a line without a change ID is only graph": {
+			output: "○  \t\tmain\tKyle King\t\t\tAdd the parser\n",
+			want:   []LogLine{{Graph: "○"}},
+		},
+	}
+
+	for name, tt := range tests {
+		got := parseLogGraph(tt.output)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %d lines, got %d", name, len(tt.want), len(got))
+			continue
+		}
+
+		for i := range got {
+			if got[i].Graph != tt.want[i].Graph || !reflect.DeepEqual(got[i].Entry, tt.want[i].Entry) {
+				t.Errorf("%s: line %d: got %q %+v, want %q %+v",
+					name, i, got[i].Graph, got[i].Entry, tt.want[i].Graph, tt.want[i].Entry)
+			}
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Package keymap names the actions the main screen's keys run and binds each one to a key.Binding.
+// The model dispatches on the action rather than on the key, and the help overlay and the status bar
+// read the bindings back, so a key rebound in the config changes everywhere at once.
+package keymap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sentinel errors for a keymap New could not build as asked. All three are warnings: the preset's
+// binding, or no binding, is used instead.
+var (
+	ErrUnknownPreset = errors.New("unknown keymap")
+	ErrUnknownAction = errors.New("unknown action")
+	ErrConflict      = errors.New("key bound twice")
+)
+
+// DefaultPreset is the keymap used when none is configured.
+const DefaultPreset = "vim"
+
+// Action names something a key does on the main screen. The names are the ones the config's keys
+// table uses, and match the command line's where the two overlap.
+type Action string
+
+// Cursor and scrolling actions.
+const (
+	Down         Action = "down"
+	Up           Action = "up"
+	HalfPageDown Action = "half-page-down"
+	HalfPageUp   Action = "half-page-up"
+	PageDown     Action = "page-down"
+	PageUp       Action = "page-up"
+	FirstFile    Action = "first-file"
+	LastFile     Action = "last-file"
+	NextHunk     Action = "next-hunk"
+	PrevHunk     Action = "prev-hunk"
+	PrevMatch    Action = "prev-match"
+	NextFile     Action = "next-file"
+	PrevFile     Action = "prev-file"
+	Focus        Action = "focus"
+)
+
+// Actions that open an overlay, change the selection, or run jj.
+const (
+	Refresh      Action = "refresh"
+	Search       Action = "search"
+	Filter       Action = "filter"
+	CommandLine  Action = "command-line"
+	Destination  Action = "destination"
+	Toggle       Action = "toggle"
+	Visual       Action = "visual"
+	Apply        Action = "apply"
+	ApplyPartial Action = "apply-partial"
+	MultiSplit   Action = "multi-split"
+	SplitAssign  Action = "split-assign"
+	SplitPreview Action = "split-preview"
+	Evolution    Action = "evolution"
+	Conflicts    Action = "conflicts"
+	Smartlog     Action = "smartlog"
+	OpLog        Action = "op-log"
+	Undo         Action = "undo"
+	Redo         Action = "redo"
+)
+
+// Display toggles, and the context revealed around a hunk and where hunks are cut.
+const (
+	Whitespace  Action = "whitespace"
+	WordDiff    Action = "word-diff"
+	SideBySide  Action = "side-by-side"
+	LineNumbers Action = "line-numbers"
+	ExpandUp    Action = "expand-up"
+	ExpandDown  Action = "expand-down"
+	ExpandGap   Action = "expand-gap"
+	SplitHunk   Action = "split-hunk"
+	JoinHunk    Action = "join-hunk"
+)
+
+// Actions available everywhere.
+const (
+	Cancel Action = "cancel"
+	Help   Action = "help"
+	Quit   Action = "quit"
+)
+
+// actionInfo is an action and the description the help overlay shows for it.
+type actionInfo struct {
+	action      Action
+	description string
+}
+
+// actions is every action in help order. When two actions are given the same key, the earlier one
+// keeps it.
+var actions = []actionInfo{
+	{Down, "Move down"},
+	{Up, "Move up"},
+	{HalfPageDown, "Half-page down"},
+	{HalfPageUp, "Half-page up"},
+	{PageDown, "Full-page down"},
+	{PageUp, "Full-page up"},
+	{FirstFile, "Go to first file/hunk"},
+	{LastFile, "Go to last file/hunk"},
+	{NextHunk, "Next hunk, or next search match"},
+	{PrevHunk, "Previous hunk"},
+	{PrevMatch, "Previous search match, or previous hunk"},
+	{NextFile, "Next file"},
+	{PrevFile, "Previous file"},
+	{Focus, "Switch focus (file list ↔ diff view)"},
+	{Refresh, "Refresh diff from jj"},
+	{Search, "Search in files and diff content"},
+	{Filter, "Filter files (type to search)"},
+	{CommandLine, "Command line (:select, :tag, :move, :apply)"},
+	{Destination, "Select destination revision"},
+	{Toggle, "Toggle hunk selection"},
+	{Visual, "Enter visual mode (line selection)"},
+	{Apply, "Apply selected changes to destination"},
+	{ApplyPartial, "Apply the hunks that fit, with offset and fuzz"},
+	{MultiSplit, "Toggle multi-split mode"},
+	{SplitAssign, "Assign split tags to commits"},
+	{SplitPreview, "Preview and apply the split"},
+	{Evolution, "Evolution timeline of the revision"},
+	{Conflicts, "Conflicted files (jj resolve --list)"},
+	{Smartlog, "Smartlog (change graph: view, new, edit)"},
+	{OpLog, "Operation log (restore to any operation)"},
+	{Undo, "Undo the last jj operation"},
+	{Redo, "Redo what undo undid"},
+	{Whitespace, "Hide whitespace-only changes"},
+	{WordDiff, "Toggle word-level diff highlighting"},
+	{SideBySide, "Toggle side-by-side view"},
+	{LineNumbers, "Toggle line numbers"},
+	{ExpandUp, "Reveal more context above the hunk"},
+	{ExpandDown, "Reveal more context below the hunk"},
+	{ExpandGap, "Reveal everything up to the neighboring hunks"},
+	{SplitHunk, "Split the hunk at the unchanged lines between its changes"},
+	{JoinHunk, "Join the hunk with the hunks it touches"},
+	{Cancel, "Cancel the running jj command"},
+	{Help, "Toggle this help"},
+	{Quit, "Quit"},
+}
+
+// preset is a complete set of bindings. Every preset binds every action and no key twice.
+type preset map[Action][]string
+
+var presets = map[string]preset{
+	"vim": {
+		Down: {"j", "down"}, Up: {"k", "up"},
+		HalfPageDown: {"ctrl+d"}, HalfPageUp: {"ctrl+u"}, PageDown: {"ctrl+f"}, PageUp: {"ctrl+b"},
+		FirstFile: {"g"}, LastFile: {"G"},
+		NextHunk: {"n"}, PrevHunk: {"p"}, PrevMatch: {"N"}, NextFile: {"]"}, PrevFile: {"["},
+		Focus:   {"tab"},
+		Refresh: {"r"}, Search: {"/"}, Filter: {"f"}, CommandLine: {":"}, Destination: {"d"},
+		Toggle: {" "}, Visual: {"v"}, Apply: {"a"}, ApplyPartial: {"alt+a"},
+		MultiSplit: {"S"}, SplitAssign: {"D"}, SplitPreview: {"P"},
+		Evolution: {"E"}, Conflicts: {"C"}, Smartlog: {"L"}, OpLog: {"O"}, Undo: {"u"}, Redo: {"ctrl+r"},
+		Whitespace: {"w"}, WordDiff: {"W"}, SideBySide: {"s"}, LineNumbers: {"l"},
+		ExpandUp: {"{"}, ExpandDown: {"}"}, ExpandGap: {"="}, SplitHunk: {"|"}, JoinHunk: {"J"},
+		Cancel: {"ctrl+g"}, Help: {"?"}, Quit: {"q", "ctrl+c"},
+	},
+	// emacs follows Emacs's motion keys and diff-mode's n, p, { and }. Without key sequences, the
+	// C-x prefixes have no equivalent, so the rest keep their vim keys where nothing clashes. The
+	// expand keys, { and } in vim, move to [ and ].
+	"emacs": {
+		Down: {"ctrl+n", "down"}, Up: {"ctrl+p", "up"},
+		HalfPageDown: {"ctrl+v"}, HalfPageUp: {"alt+v"}, PageDown: {"pgdown"}, PageUp: {"pgup"},
+		FirstFile: {"alt+<"}, LastFile: {"alt+>"},
+		NextHunk: {"n"}, PrevHunk: {"p"}, PrevMatch: {"ctrl+r"}, NextFile: {"}"}, PrevFile: {"{"},
+		Focus:   {"tab"},
+		Refresh: {"g"}, Search: {"ctrl+s"}, Filter: {"f"}, CommandLine: {"alt+x"}, Destination: {"d"},
+		Toggle: {" "}, Visual: {"ctrl+@"}, Apply: {"a"}, ApplyPartial: {"alt+a"},
+		MultiSplit: {"S"}, SplitAssign: {"D"}, SplitPreview: {"P"},
+		Evolution: {"E"}, Conflicts: {"C"}, Smartlog: {"L"}, OpLog: {"O"}, Undo: {"ctrl+_"}, Redo: {"alt+_"},
+		Whitespace: {"w"}, WordDiff: {"W"}, SideBySide: {"s"}, LineNumbers: {"l"},
+		ExpandUp: {"["}, ExpandDown: {"]"}, ExpandGap: {"="}, SplitHunk: {"|"}, JoinHunk: {"J"},
+		Cancel: {"ctrl+g"}, Help: {"?"}, Quit: {"q", "ctrl+c"},
+	},
+}
+
+// defaultKeymap is what the zero Keymap resolves to.
+var defaultKeymap, _ = New(DefaultPreset, nil)
+
+// Keymap binds every action to its keys. The zero Keymap is the default preset.
+type Keymap struct {
+	bindings map[Action]key.Binding
+	actions  map[string]Action
+}
+
+// Presets lists the built-in keymaps by name.
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// IsAction reports whether name is an action the keys table can bind.
+func IsAction(name string) bool {
+	return slices.ContainsFunc(actions, func(info actionInfo) bool { return string(info.action) == name })
+}
+
+// New builds the named preset with overrides applied. Each override replaces all of an action's
+// keys, and an empty list unbinds the action. Keys are written as Bubble Tea names them, such as
+// "ctrl+d" or "alt+x", with "space" accepted for the space bar.
+//
+// A key bound to two actions stays with one of them and the conflict is returned as a warning: an
+// overridden action takes the key from a preset one, and otherwise the action earlier in the help
+// keeps it. An unknown preset falls back to DefaultPreset, also with a warning.
+func New(presetName string, overrides map[string][]string) (Keymap, []error) {
+	var warnings []error
+
+	base, ok := presets[presetName]
+	if !ok {
+		warnings = append(warnings, fmt.Errorf("%w %q, expected one of %s, using %s",
+			ErrUnknownPreset, presetName, strings.Join(Presets(), ", "), DefaultPreset))
+		base = presets[DefaultPreset]
+	}
+
+	wanted := make(map[Action][]string, len(base))
+	for action, keys := range base {
+		wanted[action] = keys
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !IsAction(name) {
+			warnings = append(warnings, fmt.Errorf("keys: %w %q", ErrUnknownAction, name))
+
+			continue
+		}
+
+		wanted[Action(name)] = overrides[name]
+	}
+
+	k := Keymap{bindings: make(map[Action]key.Binding, len(actions)), actions: make(map[string]Action)}
+
+	// Overridden actions claim their keys first, so a rebinding wins over the preset.
+	for _, overridden := range []bool{true, false} {
+		for _, info := range actions {
+			if _, ok := overrides[string(info.action)]; ok != overridden {
+				continue
+			}
+
+			warnings = append(warnings, k.bind(info, wanted[info.action])...)
+		}
+	}
+
+	return k, warnings
+}
+
+// bind gives info's action whichever of keys no other action holds yet.
+func (k Keymap) bind(info actionInfo, keys []string) []error {
+	var (
+		warnings []error
+		kept     []string
+	)
+
+	for _, name := range keys {
+		if name == "space" {
+			name = " "
+		}
+
+		if holder, taken := k.actions[name]; taken {
+			warnings = append(warnings, fmt.Errorf("keys: %w: %s is bound to %s and %s, keeping %s",
+				ErrConflict, Display(name), holder, info.action, holder))
+
+			continue
+		}
+
+		k.actions[name] = info.action
+		kept = append(kept, name)
+	}
+
+	display := make([]string, len(kept))
+	for i, name := range kept {
+		display[i] = Display(name)
+	}
+
+	k.bindings[info.action] = key.NewBinding(
+		key.WithKeys(kept...),
+		key.WithHelp(strings.Join(display, "/"), info.description),
+	)
+
+	return warnings
+}
+
+func (k Keymap) resolved() Keymap {
+	if k.bindings == nil {
+		return defaultKeymap
+	}
+
+	return k
+}
+
+// Action is the action keyName runs, as tea.KeyMsg.String spells it.
+func (k Keymap) Action(keyName string) (Action, bool) {
+	action, ok := k.resolved().actions[keyName]
+
+	return action, ok
+}
+
+// Matches reports whether msg is one of action's keys.
+func (k Keymap) Matches(msg tea.KeyMsg, action Action) bool {
+	return key.Matches(msg, k.Binding(action))
+}
+
+// Binding is action's binding, whose help text is what the help overlay shows.
+func (k Keymap) Binding(action Action) key.Binding {
+	return k.resolved().bindings[action]
+}
+
+// Key is action's first key as the help shows it, or the empty string when the action is unbound.
+// The status bar hints use it, where there is only room for one key.
+func (k Keymap) Key(action Action) string {
+	keys := k.Binding(action).Keys()
+	if len(keys) == 0 {
+		return ""
+	}
+
+	return Display(keys[0])
+}
+
+// Keys is every one of action's keys as the help shows them, separated by slashes.
+func (k Keymap) Keys(action Action) string {
+	return k.Binding(action).Help().Key
+}
+
+// Description is the help overlay's description of action.
+func (k Keymap) Description(action Action) string {
+	return k.Binding(action).Help().Desc
+}
+
+// Display spells a key name the way the help and status bar show it: "Ctrl-D" for "ctrl+d", "Space"
+// for " ", and arrows for the arrow keys.
+func Display(keyName string) string {
+	switch keyName {
+	case " ":
+		return "Space"
+	case "tab":
+		return "Tab"
+	case "enter":
+		return "Enter"
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "pgup":
+		return "PgUp"
+	case "pgdown":
+		return "PgDn"
+	}
+
+	if rest, ok := strings.CutPrefix(keyName, "ctrl+"); ok {
+		return "Ctrl-" + strings.ToUpper(rest)
+	}
+
+	if rest, ok := strings.CutPrefix(keyName, "alt+"); ok {
+		return "Alt-" + rest
+	}
+
+	return keyName
+}
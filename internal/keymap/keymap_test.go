@@ -0,0 +1,132 @@
+package keymap_test
+
+import (
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/keymap"
+)
+
+func TestPresets_BindEveryActionOnce(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range keymap.Presets() {
+		keys, warnings := keymap.New(name, nil)
+		if len(warnings) != 0 {
+			t.Errorf("Expected preset %s to have no conflicts, got %v", name, warnings)
+		}
+
+		for _, action := range []keymap.Action{keymap.Down, keymap.Apply, keymap.Quit, keymap.LineNumbers} {
+			if keys.Key(action) == "" {
+				t.Errorf("Expected preset %s to bind %s", name, action)
+			}
+		}
+	}
+}
+
+func TestNew_Overrides(t *testing.T) {
+	t.Parallel()
+
+	keys, warnings := keymap.New("vim", map[string][]string{
+		"apply":  {"ctrl+a", "space"},
+		"visual": {},
+	})
+
+	if len(warnings) != 1 || !errors.Is(warnings[0], keymap.ErrConflict) {
+		t.Fatalf("Expected the space conflict with toggle, got %v", warnings)
+	}
+
+	if action, _ := keys.Action(" "); action != keymap.Apply {
+		t.Errorf("Expected the override to take space from the preset, got %s", action)
+	}
+
+	if keys.Key(keymap.Toggle) != "" {
+		t.Errorf("Expected toggle to lose its only key, got %q", keys.Key(keymap.Toggle))
+	}
+
+	if _, ok := keys.Action("a"); ok {
+		t.Error("Expected a to be unbound once apply moved")
+	}
+
+	if keys.Keys(keymap.Apply) != "Ctrl-A/Space" {
+		t.Errorf("Expected the help to show the new keys, got %q", keys.Keys(keymap.Apply))
+	}
+
+	if keys.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")}, keymap.Visual) {
+		t.Error("Expected an empty list to unbind visual")
+	}
+}
+
+func TestNew_ConflictKeepsTheEarlierAction(t *testing.T) {
+	t.Parallel()
+
+	keys, warnings := keymap.New("vim", map[string][]string{"refresh": {"x"}, "undo": {"x"}})
+
+	if len(warnings) != 1 || !errors.Is(warnings[0], keymap.ErrConflict) {
+		t.Fatalf("Expected one conflict, got %v", warnings)
+	}
+
+	if action, _ := keys.Action("x"); action != keymap.Refresh {
+		t.Errorf("Expected refresh, earlier in the help, to keep x, got %s", action)
+	}
+}
+
+func TestNew_Unknown(t *testing.T) {
+	t.Parallel()
+
+	keys, warnings := keymap.New("nano", map[string][]string{"teleport": {"t"}})
+
+	if len(warnings) != 2 || !errors.Is(warnings[0], keymap.ErrUnknownPreset) ||
+		!errors.Is(warnings[1], keymap.ErrUnknownAction) {
+		t.Fatalf("Expected an unknown preset and action, got %v", warnings)
+	}
+
+	if action, _ := keys.Action("j"); action != keymap.Down {
+		t.Errorf("Expected the default preset, got %s for j", action)
+	}
+}
+
+func TestZeroKeymapIsTheDefault(t *testing.T) {
+	t.Parallel()
+
+	var keys keymap.Keymap
+
+	if action, ok := keys.Action("?"); !ok || action != keymap.Help {
+		t.Errorf("Expected ? to open help, got %s, %v", action, ok)
+	}
+
+	if keys.Keys(keymap.Down) != "j/↓" {
+		t.Errorf("Expected j/↓, got %q", keys.Keys(keymap.Down))
+	}
+}
+
+func TestEmacsPreset(t *testing.T) {
+	t.Parallel()
+
+	keys, _ := keymap.New("emacs", nil)
+
+	msg := tea.KeyMsg{Type: tea.KeyCtrlN}
+	if !keys.Matches(msg, keymap.Down) {
+		t.Errorf("Expected %s to move down", msg)
+	}
+
+	if keys.Key(keymap.Undo) != "Ctrl-_" {
+		t.Errorf("Expected Ctrl-_ to undo, got %q", keys.Key(keymap.Undo))
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		" ": "Space", "tab": "Tab", "down": "↓", "ctrl+d": "Ctrl-D", "alt+x": "Alt-x", "G": "G",
+	}
+
+	for name, want := range tests {
+		if got := keymap.Display(name); got != want {
+			t.Errorf("Display(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
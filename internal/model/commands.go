@@ -0,0 +1,351 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/components/splitassign"
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/keymap"
+)
+
+// Refusals a command returns where the matching key would silently do nothing.
+var (
+	errNotInteractive   = errors.New("only interactive mode moves changes")
+	errSelectionBlocked = errors.New("selecting needs interactive or diff-editor mode and the diff panel")
+	errNoCurrentHunk    = errors.New("no hunk under the cursor")
+	errNoDestination    = errors.New("no destination; choose one with d or :dest REV")
+	errNotSplittable    = errors.New("only a revision can be split")
+	errNoSuchPosition   = errors.New("no such file, hunk, or line in this diff")
+	errNotSplitting     = errors.New("no multi-way split is being assembled")
+	errNoSuchTag        = errors.New("no hunk carries that tag")
+)
+
+// actionCommands are the actions that run a command, so a key and the same command typed at the :
+// prompt take one path. Actions that only change the view or open an overlay are handled directly.
+var actionCommands = map[keymap.Action]command.Command{
+	keymap.Down:         command.Navigate{Unit: command.UnitLine, Delta: 1},
+	keymap.Up:           command.Navigate{Unit: command.UnitLine, Delta: -1},
+	keymap.PrevHunk:     command.Navigate{Unit: command.UnitHunk, Delta: -1},
+	keymap.PrevFile:     command.Navigate{Unit: command.UnitFile, Delta: -1},
+	keymap.NextFile:     command.Navigate{Unit: command.UnitFile, Delta: 1},
+	keymap.FirstFile:    command.JumpToFile{Index: 0},
+	keymap.LastFile:     command.JumpToFile{Index: -1},
+	keymap.Toggle:       command.ToggleSelection{},
+	keymap.Visual:       command.Visual{},
+	keymap.Apply:        command.Apply{},
+	keymap.ApplyPartial: command.Apply{Partial: true},
+	keymap.ExpandUp:     command.Expand{Side: command.SideAbove, Lines: command.ExpandStep},
+	keymap.ExpandDown:   command.Expand{Side: command.SideBelow, Lines: command.ExpandStep},
+	keymap.ExpandGap:    command.Expand{Side: command.SideBoth, Lines: diff.WholeGap},
+	keymap.SplitHunk:    command.SplitHunk{},
+	keymap.JoinHunk:     command.JoinHunk{},
+	keymap.Quit:         command.Quit{},
+}
+
+// runCommand executes c against the model and records it when a recording is running. The refusal,
+// if any, is left for the caller: a key that does nothing in the current mode stays silent, as it
+// always has, while a typed command says why. A refused command is not recorded.
+func (m Model) runCommand(c command.Command) (Model, tea.Cmd, error) {
+	m.recordCursor()
+
+	cmd, err := c.Execute(commandTarget{m: &m})
+	if err == nil {
+		m.record(c)
+	}
+
+	return m, cmd, err
+}
+
+// runTypedCommand runs a command typed at the prompt or sent as a command.Msg, showing a refusal as
+// the status bar notice.
+func (m Model) runTypedCommand(c command.Command) (Model, tea.Cmd) {
+	m, cmd, err := m.runCommand(c)
+	if err != nil {
+		m.notice = err.Error()
+	}
+
+	return m, cmd
+}
+
+// tagCommand claims a letter key no action is bound to for the multi-way split. The tag letters are
+// not actions because they only mean anything while a split is being assembled.
+func (m *Model) tagCommand(key string) (command.Command, bool) {
+	if !m.multiSplitState.Active || m.mode != ModeInteractive ||
+		m.focusedPanel != PanelDiffView || len(key) != 1 {
+		return nil, false
+	}
+
+	tag, ok := splitTagFromKey(key[0])
+	if !ok {
+		return nil, false
+	}
+
+	return command.Tag{Tag: rune(tag)}, true
+}
+
+// focusCommand is what tab runs: focus on the pane that does not have it.
+func (m *Model) focusCommand() command.Command {
+	if m.focusedPanel == PanelFileList {
+		return command.Focus{Panel: command.PanelDiff}
+	}
+
+	return command.Focus{Panel: command.PanelFiles}
+}
+
+// openCommandLine shows the : prompt in place of the status bar.
+func (m *Model) openCommandLine() Model {
+	m.closeAllModals()
+	m.commandLine.Show()
+
+	return *m
+}
+
+// handleCommandLineKeyPress edits the prompt, and runs what was typed on enter. Backspace over an
+// empty prompt closes it, as in vim.
+func (m Model) handleCommandLineKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch key := msg.String(); key {
+	case keyEnter:
+		m.commandLine.Hide()
+
+		c, err := command.Parse(m.commandLine.Value())
+		if err != nil {
+			m.notice = err.Error()
+
+			return m, nil
+		}
+
+		return m.runTypedCommand(c)
+	case keyBackspace:
+		if !m.commandLine.Backspace() {
+			m.commandLine.Hide()
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.commandLine.Insert(string(msg.Runes))
+		}
+
+		if msg.Type == tea.KeySpace && len(msg.Runes) == 0 {
+			m.commandLine.Insert(" ")
+		}
+	}
+
+	return m, nil
+}
+
+// commandTarget lets a command act on a model. The model's handlers return an updated copy, so each
+// method stores that copy back through the pointer.
+type commandTarget struct {
+	m *Model
+}
+
+func (t commandTarget) Navigate(unit command.Unit, delta int) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch unit {
+	case command.UnitLine:
+		*t.m, cmd = t.m.navigate(delta)
+	case command.UnitHunk:
+		*t.m, cmd = t.m.selectAdjacentHunk(delta)
+	case command.UnitFile:
+		*t.m = t.m.selectAdjacentFile(delta)
+	}
+
+	return cmd
+}
+
+// JumpToFile counts a negative index from the end, so -1 is the last file.
+func (t commandTarget) JumpToFile(index int) {
+	if index < 0 {
+		index += len(t.m.changes)
+	}
+
+	*t.m = t.m.jumpToFile(index)
+}
+
+// GoTo moves the file, hunk, and line cursors together, refusing a position the diff does not have.
+func (t commandTarget) GoTo(file, hunk, line int) error {
+	if file < 0 || file >= len(t.m.changes) {
+		return errNoSuchPosition
+	}
+
+	hunks := t.m.changes[file].Hunks
+	if hunk < 0 || hunk >= len(hunks) || line < 0 || line >= len(hunks[hunk].Lines) {
+		return errNoSuchPosition
+	}
+
+	if file != t.m.selectedFile {
+		*t.m = t.m.jumpToFile(file)
+	}
+
+	t.m.selectedHunk = hunk
+	t.m.lineCursor = line
+
+	return nil
+}
+
+func (t commandTarget) Focus(panel command.Panel) {
+	t.m.focusedPanel = PanelFileList
+	if panel == command.PanelDiff {
+		t.m.focusedPanel = PanelDiffView
+	}
+}
+
+func (t commandTarget) ToggleSelection() error {
+	if !t.m.selectionAllowed() {
+		return errSelectionBlocked
+	}
+
+	if !t.m.hasCurrentHunk() {
+		return errNoCurrentHunk
+	}
+
+	*t.m = t.m.toggleCurrentSelection()
+
+	return nil
+}
+
+func (t commandTarget) EnterVisualMode() error {
+	if !t.m.selectionAllowed() {
+		return errSelectionBlocked
+	}
+
+	if !t.m.hasCurrentHunk() {
+		return errNoCurrentHunk
+	}
+
+	*t.m = t.m.enterVisualMode()
+
+	return nil
+}
+
+// Select adds to the selection from any panel, since a selector names its hunks itself.
+func (t commandTarget) Select(selectors []diff.Selector) error {
+	if t.m.mode != ModeInteractive && t.m.mode != ModeDiffEditor {
+		return errSelectionBlocked
+	}
+
+	hunks := 0
+
+	for _, selector := range selectors {
+		n, err := selector.Select(t.m.changes, t.m.selection)
+		if err != nil {
+			return fmt.Errorf("selecting: %w", err)
+		}
+
+		hunks += n
+	}
+
+	t.m.notice = fmt.Sprintf("Selected changes in %d hunk(s)", hunks)
+
+	return nil
+}
+
+// Tag starts the multi-way split when it is not already running, so :tag works without pressing S
+// first.
+func (t commandTarget) Tag(tag rune) error {
+	if t.m.mode != ModeInteractive {
+		return errNotInteractive
+	}
+
+	if !t.m.diffSource.SupportsRevisions() {
+		return errNotSplittable
+	}
+
+	if !t.m.hasCurrentHunk() {
+		return errNoCurrentHunk
+	}
+
+	if !t.m.multiSplitState.Active {
+		t.m.multiSplitState.Active = true
+	}
+
+	*t.m, _ = t.m.toggleTagSelection(SplitTag(tag))
+
+	return nil
+}
+
+// Assign works on a split whether or not its modal is open, so a replay can assign without it.
+func (t commandTarget) Assign(tag rune, revision, description string) error {
+	if t.m.mode != ModeInteractive || !t.m.multiSplitState.Active {
+		return errNotSplitting
+	}
+
+	if _, ok := t.m.multiSplitState.Selections[SplitTag(tag)]; !ok {
+		return errNoSuchTag
+	}
+
+	if revision == "" {
+		t.m.splitAssign.AssignNewCommitToTag(splitassign.SplitTag(tag), description)
+	} else {
+		t.m.splitAssign.AssignRevisionToTag(splitassign.SplitTag(tag), revision)
+	}
+
+	return nil
+}
+
+func (t commandTarget) ApplySplit() (tea.Cmd, error) {
+	if t.m.mode != ModeInteractive || !t.m.multiSplitState.Active {
+		return nil, errNotSplitting
+	}
+
+	if len(t.m.splitAssign.GetDestinations()) == 0 {
+		return nil, errNoDestinationsAssigned
+	}
+
+	return t.m.applySplit(), nil
+}
+
+// SetDestination checks how the diff would apply to the new destination, as picking one does, so the
+// markers are not left blank after a typed one.
+func (t commandTarget) SetDestination(revision string) (tea.Cmd, error) {
+	if t.m.mode != ModeInteractive || !t.m.appliesToRevisions() {
+		return nil, errNotInteractive
+	}
+
+	t.m.destination = revision
+
+	return t.m.checkPreflight(), nil
+}
+
+func (t commandTarget) Apply(partial bool) (tea.Cmd, error) {
+	switch {
+	case t.m.mode == ModeBrowse:
+		return nil, errNotInteractive
+	case t.m.mode == ModeInteractive && t.m.destination == "":
+		return nil, errNoDestination
+	}
+
+	var cmd tea.Cmd
+
+	*t.m, cmd = t.m.applyCurrentMode(partial)
+
+	return cmd, nil
+}
+
+func (t commandTarget) Expand(side command.Side, lines int) (tea.Cmd, error) {
+	if !t.m.hasCurrentHunk() {
+		return nil, errNoCurrentHunk
+	}
+
+	return t.m.expandContext(side, lines)
+}
+
+func (t commandTarget) SplitHunk() (tea.Cmd, error) {
+	if !t.m.hasCurrentHunk() {
+		return nil, errNoCurrentHunk
+	}
+
+	return t.m.splitHunk()
+}
+
+func (t commandTarget) JoinHunk() (tea.Cmd, error) {
+	if !t.m.hasCurrentHunk() {
+		return nil, errNoCurrentHunk
+	}
+
+	return t.m.joinHunk()
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// Refusals for revealing context the diff on screen has no file to read it from.
+var (
+	errNoFileContents = errors.New("a patch has no files to read context from")
+	errWholeFile      = errors.New("an added or deleted file's hunk already holds the whole file")
+)
+
+// contentLoadedMsg carries a file's new side, read to reveal context around one of its hunks. The
+// hunk and counts are the request that read it, applied once the content arrives, and text is the
+// diff it was made against, so a read that a reload overtook is dropped.
+type contentLoadedMsg struct {
+	err     error
+	path    string
+	content string
+	text    string
+	hunk    int
+	above   int
+	below   int
+}
+
+// expandContext reveals context around the hunk under the cursor. A file already read this load is
+// expanded at once; otherwise its new side is read in the background, from jj for a revision and
+// from the right tree in the diff editor.
+//
+//nolint:nilnil // a file already read is expanded in place and starts nothing.
+func (m *Model) expandContext(side command.Side, lines int) (tea.Cmd, error) {
+	file := m.changes[m.selectedFile]
+	if file.ChangeType == diff.ChangeTypeAdded || file.ChangeType == diff.ChangeTypeDeleted {
+		return nil, errWholeFile
+	}
+
+	above, below := lines, lines
+
+	switch side {
+	case command.SideAbove:
+		below = 0
+	case command.SideBelow:
+		above = 0
+	case command.SideBoth:
+	}
+
+	request := contentLoadedMsg{
+		path: file.Path, text: m.diffText, hunk: m.selectedHunk, above: above, below: below,
+	}
+
+	if content, ok := m.contents[file.Path]; ok {
+		request.content = content
+		*m = m.revealContext(request)
+
+		return nil, nil
+	}
+
+	read, ok := m.contentReader()
+	if !ok {
+		return nil, errNoFileContents
+	}
+
+	return m.commands.track(commandLoadContent, func(ctx context.Context) tea.Msg {
+		request.content, request.err = read(ctx, request.path)
+
+		return request
+	}), nil
+}
+
+// contentReader returns how to read a file's new side from the diff on screen: the revision or the
+// later tree jj diffed, or the diff editor's right directory. A patch has no files behind it.
+func (m *Model) contentReader() (func(ctx context.Context, path string) (string, error), bool) {
+	var revision string
+
+	switch source := m.diffSource.(type) {
+	case *diff.RevisionSource:
+		revision = source.Revision
+	case *diff.EvolutionSource:
+		revision = source.CommitID
+	case *diff.InterdiffSource:
+		revision = source.To
+	case *diff.DirectorySource:
+		return func(_ context.Context, path string) (string, error) { return source.NewContent(path) }, true
+	default:
+		return nil, false
+	}
+
+	if m.client == nil {
+		return nil, false
+	}
+
+	return func(ctx context.Context, path string) (string, error) {
+		return m.client.FileShow(ctx, revision, path)
+	}, true
+}
+
+// revealContext applies a read to the file it was read for, keeping the content for the next
+// request. A read for a file the diff no longer shows is dropped. The selection and the cursor follow
+// the hunk's lines down by however many went in above it.
+func (m Model) revealContext(msg contentLoadedMsg) Model {
+	if msg.text != m.diffText {
+		return m
+	}
+
+	if msg.err != nil {
+		m.notice = fmt.Sprintf("Reading %s failed: %v", msg.path, msg.err)
+
+		return m
+	}
+
+	m.contents[msg.path] = msg.content
+
+	fileIdx, ok := indexOfPath(m.changes, msg.path)
+	if !ok {
+		return m
+	}
+
+	expanded, shift := diff.ExpandContext(m.changes[fileIdx], msg.hunk, msg.content, msg.above, msg.below)
+	if expanded.TotalLines() == m.changes[fileIdx].TotalLines() {
+		m.notice = "No more context to reveal"
+
+		return m
+	}
+
+	m.changes = slices.Clone(m.changes)
+	m.changes[fileIdx] = expanded
+	m.fileList.SetFiles(m.changes)
+
+	m.selection.ShiftLines(msg.path, msg.hunk, shift)
+	for _, selection := range m.multiSplitState.Selections {
+		selection.ShiftLines(msg.path, msg.hunk, shift)
+	}
+
+	if fileIdx == m.selectedFile {
+		if msg.hunk == m.selectedHunk {
+			m.lineCursor += shift
+			m.visualAnchor += shift
+		}
+
+		offset := m.diffView.Offset()
+		m.diffView.SetFileChange(expanded)
+		m.diffView.SetOffset(offset)
+	}
+
+	if m.searchState != nil && m.searchState.IsActive {
+		m.searchState.ExecuteSearch(m.changes)
+	}
+
+	return m
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/jj"
+)
+
+// ExportedPatch is one patch a dry run produced instead of sending it to jj. Tag is zero for a move
+// and the split tag otherwise. Destination names where the patch would have gone: a revision, or the
+// description of the commit a split would have created.
+type ExportedPatch struct {
+	Destination string
+	Patch       string
+	Tag         rune
+}
+
+// patchesExportedMsg ends a dry run with the patches the move or split would have applied.
+type patchesExportedMsg struct {
+	patches []ExportedPatch
+}
+
+// WithDryRun makes applying a move or split export its patches and quit instead of writing to the
+// repository. The caller reads them back from the final model with ExportedPatches.
+func (m Model) WithDryRun() Model {
+	m.dryRun = true
+
+	return m
+}
+
+// ExportedPatches returns what the dry run produced, in the order jj would have applied it. It is
+// empty when the session ended without applying anything.
+func (m Model) ExportedPatches() []ExportedPatch {
+	return m.exported
+}
+
+// hasSelection reports whether any hunk is selected, in whole or in part.
+func (m Model) hasSelection() bool {
+	for _, file := range m.changes {
+		for hunkIdx := range file.Hunks {
+			if m.selection.IsHunkSelected(file.Path, hunkIdx) ||
+				m.selection.HasPartialSelection(file.Path, hunkIdx) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// exportSelection is applySelection for a dry run: it builds the same patch and hands it back
+// instead of moving it.
+func (m Model) exportSelection() tea.Cmd {
+	return func() tea.Msg {
+		if !m.hasSelection() {
+			return errMsg{errNoSelection}
+		}
+
+		return patchesExportedMsg{patches: []ExportedPatch{{
+			Destination: m.destination,
+			Patch:       diff.GeneratePatch(m.changes, m.selection),
+		}}}
+	}
+}
+
+// exportSplit is applySplit for a dry run, with one patch per tag. It writes nothing to the repository,
+// so it is not tracked as a split.
+func (m Model) exportSplit() tea.Cmd {
+	return func() tea.Msg {
+		plans, err := m.splitPlans(context.Background())
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return patchesExportedMsg{patches: ExportSplitPlans(plans)}
+	}
+}
+
+// ExportSplitPlans labels each plan with its tag and where it would land, as a dry-run split
+// writes them.
+func ExportSplitPlans(plans []jj.SplitPlan) []ExportedPatch {
+	patches := make([]ExportedPatch, 0, len(plans))
+	for _, plan := range plans {
+		destination := plan.Destination.ChangeID
+		if plan.Destination.Type == jj.SplitDestNewCommit {
+			destination = "new commit: " + plan.Destination.Description
+		}
+
+		patches = append(patches, ExportedPatch{Destination: destination, Patch: plan.Patch, Tag: plan.Tag})
+	}
+
+	return patches
+}
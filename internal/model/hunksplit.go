@@ -0,0 +1,94 @@
+package model
+
+import (
+	"errors"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// Refusals for splitting or joining a hunk that is already as small or as large as it gets.
+var (
+	errNothingToSplit = errors.New("the hunk has no unchanged lines between its changes")
+	errNothingToJoin  = errors.New("the hunk touches no other hunk")
+)
+
+// splitHunk breaks the hunk under the cursor into pieces at the unchanged lines between its changes,
+// so each piece can be selected, tagged, and moved on its own.
+func (m *Model) splitHunk() (tea.Cmd, error) {
+	split, pieces := diff.SplitHunk(m.changes[m.selectedFile], m.selectedHunk)
+	if pieces == 1 {
+		return nil, errNothingToSplit
+	}
+
+	return m.recutHunks(split), nil
+}
+
+// joinHunk merges the hunk under the cursor with the hunks it touches, which undoes a split.
+func (m *Model) joinHunk() (tea.Cmd, error) {
+	joined, _, count := diff.JoinHunks(m.changes[m.selectedFile], m.selectedHunk)
+	if count == 1 {
+		return nil, errNothingToJoin
+	}
+
+	return m.recutHunks(joined), nil
+}
+
+// recutHunks puts file in place of the selected file, whose lines it holds in the same order cut
+// into different hunks. The selections and the cursor follow their lines into the new hunks, and
+// visual mode ends, since its range may now span two. The pre-flight markers are per hunk, so they
+// are checked again.
+//
+// Like revealed context, the new cut lasts until the diff reloads.
+func (m *Model) recutHunks(file diff.FileChange) tea.Cmd {
+	before := m.changes[m.selectedFile].Hunks
+
+	m.selection.Regroup(file.Path, before, file.Hunks)
+	for _, selection := range m.multiSplitState.Selections {
+		selection.Regroup(file.Path, before, file.Hunks)
+	}
+
+	m.selectedHunk, m.lineCursor = locateLine(file.Hunks, linePosition(before, m.selectedHunk, m.lineCursor))
+	m.isVisualMode = false
+
+	m.changes = slices.Clone(m.changes)
+	m.changes[m.selectedFile] = file
+	m.fileList.SetFiles(m.changes)
+
+	offset := m.diffView.Offset()
+	m.diffView.SetFileChange(file)
+	m.diffView.SetOffset(offset)
+
+	if m.searchState != nil && m.searchState.IsActive {
+		m.searchState.ExecuteSearch(m.changes)
+	}
+
+	m.preflight = preflightState{}
+
+	return m.checkPreflight()
+}
+
+// linePosition is a line's position in hunks laid end to end.
+func linePosition(hunks []diff.Hunk, hunkIdx, lineIdx int) int {
+	position := lineIdx
+	for _, hunk := range hunks[:hunkIdx] {
+		position += len(hunk.Lines)
+	}
+
+	return position
+}
+
+// locateLine finds the hunk and line at a position in hunks laid end to end, clamped to the last.
+func locateLine(hunks []diff.Hunk, position int) (int, int) {
+	for hunkIdx, hunk := range hunks {
+		if position < len(hunk.Lines) || hunkIdx == len(hunks)-1 {
+			return hunkIdx, min(position, max(len(hunk.Lines)-1, 0))
+		}
+
+		position -= len(hunk.Lines)
+	}
+
+	return 0, 0
+}
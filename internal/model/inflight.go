@@ -0,0 +1,169 @@
+package model
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandKind names a jj call the model can have in flight. At most one of each kind runs at a time,
+// because starting a second one cancels the first.
+type commandKind int
+
+// The jj calls the model runs from a tea.Cmd. The order is the order the status bar lists them in.
+const (
+	commandLoadDiff commandKind = iota
+	commandLoadRevisions
+	commandLoadEvolog
+	commandLoadOpLog
+	commandLoadConflicts
+	commandLoadSmartlog
+	commandMove
+	commandSplit
+	commandRestore
+	commandNew
+	commandEdit
+	commandPreflight
+	commandLoadContent
+)
+
+func (k commandKind) String() string {
+	switch k {
+	case commandLoadDiff:
+		return "diff"
+	case commandLoadRevisions:
+		return "log"
+	case commandLoadEvolog:
+		return "evolog"
+	case commandLoadOpLog:
+		return "op log"
+	case commandLoadConflicts:
+		return "resolve --list"
+	case commandLoadSmartlog:
+		return "smartlog"
+	case commandMove:
+		return "move"
+	case commandSplit:
+		return "split"
+	case commandRestore:
+		return "restore"
+	case commandNew:
+		return "new"
+	case commandEdit:
+		return "edit"
+	case commandPreflight:
+		return "pre-flight"
+	case commandLoadContent:
+		return "file show"
+	default:
+		return "command"
+	}
+}
+
+// runningCommand is one tracked call. The id tells a superseded call's result apart from the result
+// of the call that replaced it.
+type runningCommand struct {
+	cancel context.CancelFunc
+	id     int
+}
+
+// commandTracker holds the cancel func of every jj call the model has in flight. Model is copied on
+// every Update, so the tracker is held by pointer and every copy sees the same calls. Only Update
+// touches it: the commands themselves never do.
+type commandTracker struct {
+	running map[commandKind]runningCommand
+	nextID  int
+}
+
+func newCommandTracker() *commandTracker {
+	return &commandTracker{running: make(map[commandKind]runningCommand)}
+}
+
+// commandFinishedMsg carries a tracked command's result back to Update. Cancelled records whether the
+// command's context was done by the time it returned, which is what tells a failure the user asked
+// for apart from one jj reported.
+type commandFinishedMsg struct {
+	result    tea.Msg
+	kind      commandKind
+	id        int
+	cancelled bool
+}
+
+// track wraps run as a cancellable command of the given kind, cancelling a command of that kind that
+// is already running. The context is created here rather than when the command runs, so ctrl+g can
+// cancel a command Bubble Tea has not started yet.
+func (t *commandTracker) track(kind commandKind, run func(ctx context.Context) tea.Msg) tea.Cmd {
+	if previous, ok := t.running[kind]; ok {
+		previous.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.nextID++
+	id := t.nextID
+	t.running[kind] = runningCommand{cancel: cancel, id: id}
+
+	return func() tea.Msg {
+		result := run(ctx)
+
+		return commandFinishedMsg{result: result, kind: kind, id: id, cancelled: ctx.Err() != nil}
+	}
+}
+
+// finish releases a command's context once its result has arrived, and reports false for the result
+// of a command that was superseded, which the caller should drop.
+func (t *commandTracker) finish(kind commandKind, id int) bool {
+	current, ok := t.running[kind]
+	if !ok || current.id != id {
+		return false
+	}
+
+	current.cancel()
+	delete(t.running, kind)
+
+	return true
+}
+
+// isRunning reports whether a command of any of the given kinds is in flight.
+func (t *commandTracker) isRunning(kinds ...commandKind) bool {
+	for _, kind := range kinds {
+		if _, ok := t.running[kind]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cancelAll cancels every running command and returns their kinds. The entries stay until each
+// result arrives, so a command that finished before it noticed the cancellation is still applied.
+func (t *commandTracker) cancelAll() []commandKind {
+	kinds := t.kinds()
+	for _, kind := range kinds {
+		t.running[kind].cancel()
+	}
+
+	return kinds
+}
+
+// kinds lists the running commands in a stable order.
+func (t *commandTracker) kinds() []commandKind {
+	kinds := make([]commandKind, 0, len(t.running))
+	for kind := range t.running {
+		kinds = append(kinds, kind)
+	}
+
+	slices.Sort(kinds)
+
+	return kinds
+}
+
+func joinKinds(kinds []commandKind) string {
+	names := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		names = append(names, kind.String())
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -3,28 +3,41 @@
 package model
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/components/applyreport"
+	"github.com/kyleking/jj-diff/internal/components/commandline"
 	"github.com/kyleking/jj-diff/internal/components/commitmsg"
+	"github.com/kyleking/jj-diff/internal/components/conflictlist"
 	"github.com/kyleking/jj-diff/internal/components/destpicker"
 	"github.com/kyleking/jj-diff/internal/components/diffview"
+	"github.com/kyleking/jj-diff/internal/components/evolutiontimeline"
 	"github.com/kyleking/jj-diff/internal/components/filefinder"
 	"github.com/kyleking/jj-diff/internal/components/filelist"
 	"github.com/kyleking/jj-diff/internal/components/help"
+	"github.com/kyleking/jj-diff/internal/components/oplog"
 	"github.com/kyleking/jj-diff/internal/components/searchmodal"
+	"github.com/kyleking/jj-diff/internal/components/smartlog"
 	"github.com/kyleking/jj-diff/internal/components/splitassign"
 	"github.com/kyleking/jj-diff/internal/components/splitpreview"
 	"github.com/kyleking/jj-diff/internal/components/statusbar"
 	"github.com/kyleking/jj-diff/internal/config"
 	"github.com/kyleking/jj-diff/internal/diff"
 	"github.com/kyleking/jj-diff/internal/jj"
+	"github.com/kyleking/jj-diff/internal/keymap"
 	"github.com/kyleking/jj-diff/internal/search"
+	"github.com/kyleking/jj-diff/internal/session"
 	"github.com/kyleking/jj-diff/internal/theme"
+	"github.com/kyleking/jj-diff/internal/watcher"
 )
 
 // OperatingMode is which of the three entry points the app was started for, which decides what the
@@ -49,32 +62,6 @@ const (
 	PanelDiffView
 )
 
-// HunkSelection is one hunk's selection. WholeHunk wins over SelectedLines, and selecting the whole
-// hunk discards the per-line set, so the two are never both meaningful.
-type HunkSelection struct {
-	SelectedLines map[int]bool
-	WholeHunk     bool
-}
-
-// FileSelection holds one file's selected hunks, keyed by the hunk's index in the parsed file. The
-// indices go stale when the diff is reloaded.
-type FileSelection struct {
-	Hunks map[int]*HunkSelection
-}
-
-// SelectionState is what the user has picked across every file, keyed by the diff's path. Build it
-// with NewSelectionState, because the mutators assume the map exists.
-type SelectionState struct {
-	Files map[string]*FileSelection
-}
-
-// NewSelectionState returns an empty selection.
-func NewSelectionState() *SelectionState {
-	return &SelectionState{
-		Files: make(map[string]*FileSelection),
-	}
-}
-
 // SplitTag is the single character a hunk carries while a multi-way split is being assembled. Tags
 // are handed out from 'A' upward.
 type SplitTag rune
@@ -109,6 +96,10 @@ const (
 // synchronous and the pickers are scrollable anyway.
 const revisionListLimit = 20
 
+// smartlogLimit caps how many revisions the smartlog draws. It is larger than the pickers' limit
+// because seeing the stack around a change is the point of the view.
+const smartlogLimit = 50
+
 // Key names the handlers branch on in more than one place.
 const (
 	keyBackspace = "backspace"
@@ -137,7 +128,7 @@ type DestinationSpec struct {
 // MultiSplitState is an in-progress multi-way split: one selection and one destination per tag. A tag
 // with a selection but no destination is incomplete and blocks the split from being applied.
 type MultiSplitState struct {
-	Selections   map[SplitTag]*SelectionState
+	Selections   map[SplitTag]*diff.Selection
 	Destinations map[SplitTag]*DestinationSpec
 	CurrentTag   SplitTag
 	Active       bool
@@ -147,150 +138,56 @@ type MultiSplitState struct {
 func NewMultiSplitState() *MultiSplitState {
 	return &MultiSplitState{
 		Active:       false,
-		Selections:   make(map[SplitTag]*SelectionState),
+		Selections:   make(map[SplitTag]*diff.Selection),
 		Destinations: make(map[SplitTag]*DestinationSpec),
 		CurrentTag:   'A',
 	}
 }
 
-// IsHunkSelected reports whether the whole hunk is selected, which is false for a hunk that only has
-// individual lines picked.
-func (s *SelectionState) IsHunkSelected(filePath string, hunkIdx int) bool {
-	if fileSelection, ok := s.Files[filePath]; ok {
-		if hunkSelection, ok := fileSelection.Hunks[hunkIdx]; ok {
-			return hunkSelection.WholeHunk
-		}
-	}
-
-	return false
-}
-
-// IsLineSelected reports whether one line is selected, which is true for every line of a hunk
-// selected as a whole.
-func (s *SelectionState) IsLineSelected(filePath string, hunkIdx, lineIdx int) bool {
-	if fileSelection, ok := s.Files[filePath]; ok {
-		if hunkSelection, ok := fileSelection.Hunks[hunkIdx]; ok {
-			if hunkSelection.WholeHunk {
-				return true
-			}
-
-			return hunkSelection.SelectedLines[lineIdx]
-		}
-	}
-
-	return false
-}
-
-// ToggleHunk flips whole-hunk selection, creating the file and hunk entries as needed. Selecting a
-// hunk discards any lines picked inside it, so a toggle out and back in loses the line selection.
-func (s *SelectionState) ToggleHunk(filePath string, hunkIdx int) {
-	if _, ok := s.Files[filePath]; !ok {
-		s.Files[filePath] = &FileSelection{
-			Hunks: make(map[int]*HunkSelection),
-		}
-	}
-
-	fileSelection := s.Files[filePath]
-	if _, ok := fileSelection.Hunks[hunkIdx]; !ok {
-		fileSelection.Hunks[hunkIdx] = &HunkSelection{
-			SelectedLines: make(map[int]bool),
-		}
-	}
-
-	hunkSelection := fileSelection.Hunks[hunkIdx]
-	hunkSelection.WholeHunk = !hunkSelection.WholeHunk
-	if hunkSelection.WholeHunk {
-		hunkSelection.SelectedLines = make(map[int]bool)
-	}
-}
-
-// ToggleLine flips one line's selection. It does nothing while the hunk is selected as a whole,
-// because that state has no per-line detail to change.
-func (s *SelectionState) ToggleLine(filePath string, hunkIdx, lineIdx int) {
-	if _, ok := s.Files[filePath]; !ok {
-		s.Files[filePath] = &FileSelection{
-			Hunks: make(map[int]*HunkSelection),
-		}
-	}
-
-	fileSelection := s.Files[filePath]
-	if _, ok := fileSelection.Hunks[hunkIdx]; !ok {
-		fileSelection.Hunks[hunkIdx] = &HunkSelection{
-			SelectedLines: make(map[int]bool),
-		}
-	}
-
-	hunkSelection := fileSelection.Hunks[hunkIdx]
-	if hunkSelection.WholeHunk {
-		return
-	}
-
-	hunkSelection.SelectedLines[lineIdx] = !hunkSelection.SelectedLines[lineIdx]
-}
-
-// SelectLineRange selects an inclusive range of lines, accepting the bounds in either order. It
-// clears whole-hunk selection, and it only adds, so lines already selected outside the range stay.
-func (s *SelectionState) SelectLineRange(filePath string, hunkIdx, startLine, endLine int) {
-	if startLine > endLine {
-		startLine, endLine = endLine, startLine
-	}
-
-	if _, ok := s.Files[filePath]; !ok {
-		s.Files[filePath] = &FileSelection{
-			Hunks: make(map[int]*HunkSelection),
-		}
-	}
-
-	fileSelection := s.Files[filePath]
-	if _, ok := fileSelection.Hunks[hunkIdx]; !ok {
-		fileSelection.Hunks[hunkIdx] = &HunkSelection{
-			SelectedLines: make(map[int]bool),
-		}
-	}
-
-	hunkSelection := fileSelection.Hunks[hunkIdx]
-	hunkSelection.WholeHunk = false
-
-	for i := startLine; i <= endLine; i++ {
-		hunkSelection.SelectedLines[i] = true
-	}
-}
-
-// HasPartialSelection reports whether a hunk has lines picked without being selected as a whole,
-// which is what the renderer draws the partial marker for.
-func (s *SelectionState) HasPartialSelection(filePath string, hunkIdx int) bool {
-	if fileSelection, ok := s.Files[filePath]; ok {
-		if hunkSelection, ok := fileSelection.Hunks[hunkIdx]; ok {
-			return !hunkSelection.WholeHunk && len(hunkSelection.SelectedLines) > 0
-		}
-	}
-
-	return false
-}
-
 // Model is the whole application state. Bubble Tea passes it by value, so Update returns the updated
 // copy and mutating a Model a handler received has no effect unless that copy is returned.
 type Model struct {
 	statusBar       statusbar.Model
 	diffSource      diff.Source
+	baseSource      diff.Source
 	err             error
-	selection       *SelectionState
+	selection       *diff.Selection
 	searchState     *search.State
 	multiSplitState *MultiSplitState
+	commands        *commandTracker
+	recorder        *session.Recorder
+	replay          replayState
 	client          *jj.Client
+	watcher         *watcher.Watcher
+	contents        map[string]string
+	preflight       preflightState
+	undoSpan        operationSpan
 	destination     string
 	source          string
+	notice          string
+	diffText        string
+	redoOps         []string
+	exported        []ExportedPatch
 	changes         []diff.FileChange
 	commitMsg       commitmsg.Model
 	help            help.Model
-	cfg             config.Config
+	commandLine     commandline.Model
+	applyReport     applyreport.Model
 	splitPreview    splitpreview.Model
 	fileFinder      filefinder.Model
+	cfg             config.Config
+	keys            keymap.Keymap
 	destPicker      destpicker.Model
+	opLog           oplog.Model
+	smartlog        smartlog.Model
+	conflictList    conflictlist.Model
 	searchModal     searchmodal.Model
+	bracket         bracketPrefix
 	splitAssign     splitassign.Model
 	fileList        filelist.Model
+	timeline        evolutiontimeline.Model
 	diffView        diffview.Model
+	recordedAt      cursorPosition
 	focusedPanel    FocusedPanel
 	lineCursor      int
 	selectedHunk    int
@@ -300,24 +197,111 @@ type Model struct {
 	height          int
 	mode            OperatingMode
 	isVisualMode    bool
+	refreshed       bool
+	refreshQueued   bool
+	dryRun          bool
 }
 
 type errMsg struct {
 	err error
 }
 
+// diffLoadedMsg carries a parsed diff and the text it was parsed from, which is what tells a reload
+// that changed nothing apart from one that did. Refresh marks a reload the watcher asked for rather
+// than the user or a command the model ran.
 type diffLoadedMsg struct {
+	text    string
 	changes []diff.FileChange
+	refresh bool
 }
 
 type revisionsLoadedMsg struct {
 	revisions []jj.RevisionEntry
 }
 
+// splitRevisionsLoadedMsg carries the revisions the split assignment modal offers as destinations.
+type splitRevisionsLoadedMsg struct {
+	revisions []jj.RevisionEntry
+}
+
 type destinationSelectedMsg struct {
 	changeID string
 }
 
+type evologLoadedMsg struct {
+	revision string
+	entries  []jj.EvologEntry
+}
+
+type opLogLoadedMsg struct {
+	entries []jj.OpLogEntry
+}
+
+type conflictsLoadedMsg struct {
+	files []jj.ConflictedFile
+}
+
+type smartlogLoadedMsg struct {
+	lines []jj.LogLine
+}
+
+// bracketPrefix remembers a prev-file or next-file key, [ or ] in the vim keymap, that may be the
+// first half of [x or ]x. The file step runs at once, so a lone bracket is not held back; the cursor
+// it left is kept so x can undo the step.
+type bracketPrefix struct {
+	action keymap.Action
+	file   int
+	hunk   int
+	line   int
+}
+
+// operationRestoredMsg reports an undo, redo, or restore that succeeded. It carries the redo stack as
+// it stands after the operation, which the command computed, so a failed operation leaves the model's
+// stack untouched.
+type operationRestoredMsg struct {
+	notice  string
+	redoOps []string
+}
+
+// repoChangedMsg reports that a command the model ran rewrote the repository, so the diff on screen
+// is stale and has to be reloaded. Span is set by a command that took several jj operations.
+type repoChangedMsg struct {
+	notice string
+	span   operationSpan
+}
+
+// splitAppliedMsg reports a split that succeeded. The split state it was built from is spent, so
+// Update clears it before handling the repository change.
+type splitAppliedMsg struct {
+	changed repoChangedMsg
+}
+
+// moveReportedMsg reports a move that committed but did not apply cleanly, so the report of which
+// hunks shifted or were rejected is shown over the reloaded diff for the user to keep or roll back.
+type moveReportedMsg struct {
+	changed repoChangedMsg
+	report  diff.ApplyReport
+}
+
+// preflightCheckedMsg carries the result of checking every hunk against the destination, with the
+// destination and diff text it was checked for, so a result that arrives after either changed is
+// dropped.
+type preflightCheckedMsg struct {
+	err         error
+	destination string
+	text        string
+	report      diff.ApplyReport
+}
+
+// operationSpan is the run of jj operations one command left behind: the operation that was current
+// before it started and the one current when it finished. A move or split takes several operations
+// and jj undo reverts only the last, so u restores the start of the span instead, as long as nothing
+// has run since.
+type operationSpan struct {
+	before string
+	after  string
+}
+
 // NewModel builds a model reading its diff from a jj revision.
 func NewModel(
 	client *jj.Client,
@@ -342,6 +326,7 @@ func NewModelWithSource(
 	m := Model{
 		client:          client,
 		diffSource:      source,
+		baseSource:      source,
 		mode:            mode,
 		source:          source.GetSourceLabel(),
 		destination:     destination,
@@ -351,57 +336,85 @@ func NewModelWithSource(
 		focusedPanel:    PanelFileList,
 		width:           defaultTerminalWidth,
 		height:          defaultTerminalHeight,
-		selection:       NewSelectionState(),
+		selection:       diff.NewSelection(),
 		multiSplitState: NewMultiSplitState(),
+		commands:        newCommandTracker(),
+		contents:        make(map[string]string),
 	}
 
 	m.fileList = filelist.New()
 	m.diffView = diffview.New(cfg)
 	m.statusBar = statusbar.New()
 	m.destPicker = destpicker.New()
+	m.timeline = evolutiontimeline.New()
+	m.opLog = oplog.New()
+	m.smartlog = smartlog.New()
+	m.conflictList = conflictlist.New()
 	m.splitAssign = splitassign.New()
 	m.splitPreview = splitpreview.New()
 	m.commitMsg = commitmsg.New()
+	m.applyReport = applyreport.New()
 	m.help = help.New()
 	m.searchModal = searchmodal.New()
 	m.searchState = search.NewState()
 	m.fileFinder = filefinder.New()
+	m.commandLine = commandline.New()
+
+	// LoadConfig has already reported the keymap's warnings.
+	m.keys, _ = cfg.KeyMap()
 
 	return m, nil
 }
 
-// Init starts the first diff load. Nothing is rendered until it returns and a window size arrives.
+// Init starts the first diff load, and the wait for the first repository change when a watcher is
+// attached. Nothing is rendered until the load returns and a window size arrives.
 func (m Model) Init() tea.Cmd {
+	if m.watcher != nil {
+		return tea.Batch(m.loadDiff(), m.watcher.Wait())
+	}
+
 	return m.loadDiff()
 }
 
+// loadDiff reads the diff from the source as a cancellable command, replacing a load that is still
+// running.
 func (m Model) loadDiff() tea.Cmd {
-	return func() tea.Msg {
-		diffText, err := m.diffSource.GetDiff()
+	return m.trackDiffLoad(false)
+}
+
+func (m Model) trackDiffLoad(refresh bool) tea.Cmd {
+	return m.commands.track(commandLoadDiff, func(ctx context.Context) tea.Msg {
+		diffText, err := m.diffSource.GetDiff(ctx)
 		if err != nil {
 			return errMsg{err}
 		}
 
 		changes := diff.Parse(diffText)
 
-		return diffLoadedMsg{changes}
-	}
+		return diffLoadedMsg{text: diffText, changes: changes, refresh: refresh}
+	})
 }
 
 func (m Model) loadRevisions() tea.Cmd {
-	return func() tea.Msg {
-		revisions, err := m.client.GetRevisions(revisionListLimit)
+	return m.commands.track(commandLoadRevisions, func(ctx context.Context) tea.Msg {
+		revisions, err := m.client.GetRevisions(ctx, revisionListLimit)
 		if err != nil {
 			return errMsg{err}
 		}
 
 		return revisionsLoadedMsg{revisions}
-	}
+	})
 }
 
 // Update handles one message and returns the model to use next. The concrete type is always Model, so
 // callers chaining updates can assert it.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+
+	return next.continueReplay(cmd)
+}
+
+func (m Model) update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
@@ -413,21 +426,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case diffLoadedMsg:
-		m.changes = msg.changes
-		m.fileList.SetFiles(m.changes)
-		if len(m.changes) > 0 {
-			m.diffView.SetFileChange(m.changes[0])
-		}
+		m = m.applyLoadedDiff(msg)
 
-		// jj's diff editor contract is subtractive: the right side starts as the
-		// commit's full content and the user removes what should not be kept.
-		// Starting empty here would discard every change on apply.
-		if m.mode == ModeDiffEditor {
-			m.selection = NewSelectionState()
-			diff.SelectAll(m.changes, m.selection)
-		}
+		return m, m.checkPreflight()
 
-		return m, nil
+	case preflightCheckedMsg:
+		return m.applyPreflight(msg), nil
+
+	case contentLoadedMsg:
+		return m.revealContext(msg), nil
+
+	case patchesExportedMsg:
+		m.exported = msg.patches
+
+		return m, tea.Quit
+
+	case watcher.RefreshMsg:
+		return m.refresh()
 
 	case errMsg:
 		m.err = msg.err
@@ -443,29 +458,145 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case destinationSelectedMsg:
 		m.destination = msg.changeID
 		m.destPicker.Hide()
+		m.record(command.SetDestination{Revision: msg.changeID})
+
+		return m, m.loadDiff()
+
+	case evologLoadedMsg:
+		m.closeAllModals()
+		m.timeline.SetEntries(msg.revision, msg.entries)
+		m.timeline.Show()
+
+		return m, nil
+
+	case repoChangedMsg:
+		m.notice = msg.notice
+		m.redoOps = nil
+		m.undoSpan = msg.span
+
+		return m, m.loadDiff()
+
+	case splitAppliedMsg:
+		m.multiSplitState = NewMultiSplitState()
+		m.splitPreview.Hide()
+		m.splitAssign = splitassign.New()
+
+		return m.update(msg.changed)
+
+	case moveReportedMsg:
+		m.closeAllModals()
+		m.applyReport.SetReport(msg.report, m.destination)
+		m.applyReport.Show()
+
+		return m.update(msg.changed)
+
+	case splitRevisionsLoadedMsg:
+		m.closeAllModals()
+		m.splitAssign.SetRevisions(msg.revisions)
+		m.splitAssign.Show()
+
+		return m, nil
+
+	case opLogLoadedMsg:
+		m.closeAllModals()
+		m.opLog.SetEntries(msg.entries)
+		m.opLog.Show()
+
+		return m, nil
+
+	case smartlogLoadedMsg:
+		m.closeAllModals()
+		m.smartlog.SetLines(msg.lines)
+		m.smartlog.Show()
+
+		return m, nil
+
+	case conflictsLoadedMsg:
+		m.closeAllModals()
+		m.conflictList.SetEntries(m.conflictEntries(msg.files))
+		m.conflictList.Show()
+
+		return m, nil
+
+	case operationRestoredMsg:
+		m.notice = msg.notice
+		m.redoOps = msg.redoOps
+		m.undoSpan = operationSpan{}
 
 		return m, m.loadDiff()
 
 	case diffEditorAppliedMsg:
 		return m, tea.Quit
+
+	case commandFinishedMsg:
+		return m.handleCommandFinished(msg)
+
+	case command.Msg:
+		return m.runTypedCommand(msg.Command)
+
+	case replayStepMsg:
+		return m.runReplayStep()
+	}
+
+	return m, nil
+}
+
+// handleCommandFinished releases a tracked command and handles its result. A failure the user asked
+// for with ctrl+g becomes a notice rather than the error screen, unless the rollback behind it also
+// failed, because then the repository may not be where the user left it.
+func (m Model) handleCommandFinished(msg commandFinishedMsg) (Model, tea.Cmd) {
+	if !m.commands.finish(msg.kind, msg.id) {
+		return m, nil
 	}
 
+	if failed, ok := msg.result.(errMsg); ok && msg.cancelled &&
+		!errors.Is(failed.err, jj.ErrRollbackFailed) {
+		m.notice = fmt.Sprintf("Cancelled %s", msg.kind)
+
+		return m.runQueuedRefresh(nil)
+	}
+
+	next, cmd := m.update(msg.result)
+
+	return next.runQueuedRefresh(cmd)
+}
+
+// cancelCommands cancels every jj call in flight. Each one reports back through
+// handleCommandFinished, so the model is left as it was before the call started.
+func (m Model) cancelCommands() (Model, tea.Cmd) {
+	cancelled := m.commands.cancelAll()
+	if len(cancelled) == 0 {
+		m.notice = "Nothing to cancel"
+
+		return m, nil
+	}
+
+	m.notice = "Cancelling " + joinKinds(cancelled)
+
 	return m, nil
 }
 
 func (m Model) handleKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 	key := msg.String()
+	m.notice = ""
+	m.refreshed = false
 
 	if key == "esc" {
 		return m.handleEscape()
 	}
 
-	if key == "?" && !m.destPicker.IsVisible() {
+	action, bound := m.keys.Action(key)
+
+	if action == keymap.Cancel {
+		return m.cancelCommands()
+	}
+
+	if action == keymap.Help && !m.destPicker.IsVisible() && !m.commandLine.IsVisible() {
 		return m.toggleHelp()
 	}
 
 	if m.help.IsVisible() {
-		if key == "q" {
+		if action == keymap.Quit {
 			m.help.Hide()
 		}
 
@@ -476,47 +607,82 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 		return model, cmd
 	}
 
-	return m.handleAppKey(key)
+	bracket := m.bracket
+	m.bracket = bracketPrefix{}
+
+	if key == "x" && bracket.action != "" {
+		return m.jumpToConflictFromBracket(bracket)
+	}
+
+	if !bound {
+		return m.handleTagKey(key)
+	}
+
+	return m.handleAction(action)
 }
 
-// handleAppKey handles a key no overlay claimed, so it acts on the two panels or on the mode. Keys
-// that fall through the switch are offered to the multi-way split as tag letters.
-func (m *Model) handleAppKey(key string) (Model, tea.Cmd) {
-	if model, cmd, handled := m.handleNavigationKey(key); handled {
+// handleAction runs the action a key is bound to when no overlay claimed the key, so it acts on the
+// two panels or on the mode. An action that is a command runs it, so the key and the same command
+// typed at the prompt take one path.
+func (m *Model) handleAction(action keymap.Action) (Model, tea.Cmd) {
+	if c, ok := actionCommands[action]; ok {
+		if action == keymap.PrevFile || action == keymap.NextFile {
+			m.bracket = bracketPrefix{action: action, file: m.selectedFile, hunk: m.selectedHunk, line: m.lineCursor}
+		}
+
+		model, cmd, _ := m.runCommand(c)
+
 		return model, cmd
 	}
 
-	if model, handled := m.handleViewOptionKey(key); handled {
+	if model, cmd, handled := m.handleNavigationAction(action); handled {
+		return model, cmd
+	}
+
+	if model, handled := m.handleViewOptionAction(action); handled {
 		return model, nil
 	}
 
-	if model, cmd, handled := m.handleActionKey(key); handled {
+	if model, cmd, handled := m.handleOverlayAction(action); handled {
 		return model, cmd
 	}
 
-	return m.handleTagKey(key)
+	return *m, nil
+}
+
+// handleTagKey offers a key no action is bound to to the multi-way split as a tag letter.
+func (m *Model) handleTagKey(key string) (Model, tea.Cmd) {
+	if c, ok := m.tagCommand(key); ok {
+		model, cmd, _ := m.runCommand(c)
+
+		return model, cmd
+	}
+
+	return *m, nil
 }
 
-// handleScrollKey scrolls the diff view by a page or half a page. The scroll functions are method
+// handleScrollAction scrolls the diff view by a page or half a page. The scroll functions are method
 // values bound to the model's own diff view, so the movement lands on the model this call returns.
-func (m *Model) handleScrollKey(key string) (Model, bool) {
-	switch key {
-	case "ctrl+d":
+func (m *Model) handleScrollAction(action keymap.Action) (Model, bool) {
+	switch action {
+	case keymap.HalfPageDown:
 		return m.scrollDiffView(m.diffView.ScrollHalfPageDown), true
-	case "ctrl+u":
+	case keymap.HalfPageUp:
 		return m.scrollDiffView(m.diffView.ScrollHalfPageUp), true
-	case "ctrl+f":
+	case keymap.PageDown:
 		return m.scrollDiffView(m.diffView.ScrollFullPageDown), true
-	case "ctrl+b":
+	case keymap.PageUp:
 		return m.scrollDiffView(m.diffView.ScrollFullPageUp), true
 	}
 
 	return *m, false
 }
 
-// handleNavigationKey moves a cursor or scrolls a panel without changing the diff or the selection.
-func (m *Model) handleNavigationKey(key string) (Model, tea.Cmd, bool) {
-	if model, handled := m.handleScrollKey(key); handled {
+// handleNavigationAction moves a cursor or scrolls a panel without changing the diff or the
+// selection. The plain cursor movements are commands, in actionCommands; these are the actions that
+// depend on more than the cursor, such as next-hunk following a search.
+func (m *Model) handleNavigationAction(action keymap.Action) (Model, tea.Cmd, bool) {
+	if model, handled := m.handleScrollAction(action); handled {
 		return model, nil, true
 	}
 
@@ -525,27 +691,13 @@ func (m *Model) handleNavigationKey(key string) (Model, tea.Cmd, bool) {
 		cmd   tea.Cmd
 	)
 
-	switch key {
-	case "tab":
-		model = m.toggleFocusedPanel()
-	case "[":
-		model = m.selectAdjacentFile(-1)
-	case "]":
-		model = m.selectAdjacentFile(1)
-	case "j", keyDown:
-		model, cmd = m.navigate(1)
-	case "k", "up":
-		model, cmd = m.navigate(-1)
-	case "g":
-		model = m.jumpToFile(0)
-	case "G":
-		model = m.jumpToFile(len(m.changes) - 1)
-	case "n":
+	switch action {
+	case keymap.Focus:
+		model, cmd, _ = m.runCommand(m.focusCommand())
+	case keymap.NextHunk:
 		model, cmd = m.nextMatchOrHunk()
-	case "N":
+	case keymap.PrevMatch:
 		model, cmd = m.prevMatchOrHunk()
-	case "p":
-		model, cmd = m.selectAdjacentHunk(-1)
 	default:
 		return *m, nil, false
 	}
@@ -553,16 +705,16 @@ func (m *Model) handleNavigationKey(key string) (Model, tea.Cmd, bool) {
 	return model, cmd, true
 }
 
-// handleViewOptionKey toggles how the diff is rendered, which never touches the selection.
-func (m *Model) handleViewOptionKey(key string) (Model, bool) {
-	switch key {
-	case "w":
+// handleViewOptionAction toggles how the diff is rendered, which never touches the selection.
+func (m *Model) handleViewOptionAction(action keymap.Action) (Model, bool) {
+	switch action {
+	case keymap.Whitespace:
 		m.diffView.ToggleWhitespace()
-	case "W":
+	case keymap.WordDiff:
 		m.diffView.ToggleWordDiff()
-	case "s":
+	case keymap.SideBySide:
 		m.diffView.ToggleSideBySide()
-	case "l":
+	case keymap.LineNumbers:
 		m.diffView.ToggleLineNumbers()
 	default:
 		return *m, false
@@ -571,41 +723,47 @@ func (m *Model) handleViewOptionKey(key string) (Model, bool) {
 	return *m, true
 }
 
-// handleActionKey opens an overlay, changes the selection, or applies it.
-func (m *Model) handleActionKey(key string) (Model, tea.Cmd, bool) {
+// handleOverlayAction opens an overlay or runs jj.
+func (m *Model) handleOverlayAction(action keymap.Action) (Model, tea.Cmd, bool) {
 	var (
 		model Model
 		cmd   tea.Cmd
 	)
 
-	switch key {
-	case "q", keyCtrlC:
-		return *m, tea.Quit, true
-	case "d":
+	switch action {
+	case keymap.CommandLine:
+		model = m.openCommandLine()
+	case keymap.Destination:
 		model, cmd = m.openDestinationPicker()
-	case "/":
+	case keymap.Search:
 		m.closeAllModals()
 		model, cmd = m.enterSearchMode()
-	case "f":
+	case keymap.Filter:
 		m.closeAllModals()
 		m.focusedPanel = PanelFileList
 		m.fileList.SetFilterMode(true)
 
 		model = *m
-	case "v":
-		model = m.enterVisualMode()
-	case "r":
+	case keymap.Refresh:
 		return *m, m.loadDiff(), true
-	case " ":
-		model = m.toggleCurrentSelection()
-	case "a":
-		model, cmd = m.applyCurrentMode()
-	case "S":
+	case keymap.MultiSplit:
 		model = m.toggleMultiSplit()
-	case "D":
+	case keymap.SplitAssign:
 		model, cmd = m.openSplitAssign()
-	case "P":
+	case keymap.SplitPreview:
 		model = m.openSplitPreview()
+	case keymap.Evolution:
+		model, cmd = m.openEvolutionTimeline()
+	case keymap.Conflicts:
+		model, cmd = m.openConflictList()
+	case keymap.OpLog:
+		model, cmd = m.openOpLog()
+	case keymap.Smartlog:
+		model, cmd = m.openSmartlog()
+	case keymap.Undo:
+		model, cmd = m.undoOperation()
+	case keymap.Redo:
+		model, cmd = m.redoOperation()
 	default:
 		return *m, nil, false
 	}
@@ -625,21 +783,414 @@ func (m *Model) hasCurrentHunk() bool {
 		return false
 	}
 
-	hunks := m.changes[m.selectedFile].Hunks
+	hunks := m.changes[m.selectedFile].Hunks
+
+	return m.selectedHunk >= 0 && m.selectedHunk < len(hunks)
+}
+
+func (m *Model) hasSearchMatches() bool {
+	return m.searchState != nil && m.searchState.IsActive && len(m.searchState.Matches) > 0
+}
+
+// appliesToRevisions reports whether the selection can be sent to a destination revision: moved out
+// of the revision on screen, or applied from a patch that came from outside the repository.
+func (m *Model) appliesToRevisions() bool {
+	return m.diffSource.SupportsRevisions() || m.fromPatch()
+}
+
+// fromPatch reports whether the diff on screen is a patch file or stdin, whose hunks have no source
+// revision to be taken out of.
+func (m *Model) fromPatch() bool {
+	_, ok := m.diffSource.(*diff.PatchSource)
+
+	return ok
+}
+
+func (m *Model) openDestinationPicker() (Model, tea.Cmd) {
+	if m.mode == ModeInteractive && m.appliesToRevisions() {
+		return *m, m.loadRevisions()
+	}
+
+	return *m, nil
+}
+
+// openEvolutionTimeline loads the evolog of the revision the session started on. It stays on that
+// revision while an older evolution is on screen, so E always lists the same change.
+func (m *Model) openEvolutionTimeline() (Model, tea.Cmd) {
+	revSource, ok := m.baseSource.(*diff.RevisionSource)
+	if !ok || m.client == nil {
+		return *m, nil
+	}
+
+	revision := revSource.Revision
+
+	return *m, m.commands.track(commandLoadEvolog, func(ctx context.Context) tea.Msg {
+		entries, err := m.client.GetEvolog(ctx, revision)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return evologLoadedMsg{revision: revision, entries: entries}
+	})
+}
+
+// openConflictList asks jj which files are conflicted in the revision on screen. An interdiff or a
+// diff-editor session has no single revision to ask about, so C does nothing there.
+func (m *Model) openConflictList() (Model, tea.Cmd) {
+	var revision string
+
+	switch source := m.diffSource.(type) {
+	case *diff.RevisionSource:
+		revision = source.Revision
+	case *diff.EvolutionSource:
+		revision = source.CommitID
+	default:
+		return *m, nil
+	}
+
+	return *m, m.commands.track(commandLoadConflicts, func(ctx context.Context) tea.Msg {
+		files, err := m.client.ListConflicts(ctx, revision)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return conflictsLoadedMsg{files: files}
+	})
+}
+
+// conflictEntries pairs jj's conflicted files with the number of conflicts the loaded diff shows in
+// each, which is how the list tells a conflict this revision wrote from one it only inherited.
+func (m *Model) conflictEntries(files []jj.ConflictedFile) []conflictlist.Entry {
+	counts := make(map[string]int, len(m.changes))
+	for _, change := range m.changes {
+		counts[change.Path] = len(change.Conflicts)
+	}
+
+	entries := make([]conflictlist.Entry, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, conflictlist.Entry{
+			Path:        file.Path,
+			Description: file.Description,
+			Count:       counts[file.Path],
+		})
+	}
+
+	return entries
+}
+
+// jumpToConflictFile shows path in the diff view with the cursor on its first conflict, or on its
+// first hunk when the diff shows none.
+func (m *Model) jumpToConflictFile(path string) Model {
+	m.conflictList.Hide()
+
+	for fileIdx, change := range m.changes {
+		if change.Path != path {
+			continue
+		}
+
+		m.jumpToFile(fileIdx)
+		m.focusedPanel = PanelDiffView
+
+		if len(change.Conflicts) > 0 {
+			m.selectedHunk = change.Conflicts[0].HunkIdx
+			m.lineCursor = change.Conflicts[0].StartLine
+		}
+
+		return *m
+	}
+
+	m.notice = path + " is not in this diff"
+
+	return *m
+}
+
+// jumpToConflictFromBracket finishes [x or ]x. The bracket already stepped to the adjacent file, so
+// the cursor goes back to where it was before searching from there.
+func (m *Model) jumpToConflictFromBracket(bracket bracketPrefix) (Model, tea.Cmd) {
+	if bracket.file != m.selectedFile && bracket.file >= 0 && bracket.file < len(m.changes) {
+		m.jumpToFile(bracket.file)
+	}
+
+	m.selectedHunk = bracket.hunk
+	m.lineCursor = bracket.line
+
+	if bracket.action == keymap.PrevFile {
+		return m.jumpToConflict(-1), nil
+	}
+
+	return m.jumpToConflict(1), nil
+}
+
+// jumpToConflict moves the cursor to the next conflict after it, or the previous one before it,
+// across files and wrapping at both ends as the hunk cursor does.
+func (m *Model) jumpToConflict(delta int) Model {
+	type position struct{ file, hunk, line int }
+
+	var positions []position
+	for fileIdx, change := range m.changes {
+		for _, region := range change.Conflicts {
+			positions = append(positions, position{fileIdx, region.HunkIdx, region.StartLine})
+		}
+	}
+
+	if len(positions) == 0 {
+		m.notice = "No conflicts in this diff"
+		return *m
+	}
+
+	current := position{m.selectedFile, m.selectedHunk, m.lineCursor}
+	compare := func(a, b position) int {
+		if a.file != b.file {
+			return a.file - b.file
+		}
+
+		if a.hunk != b.hunk {
+			return a.hunk - b.hunk
+		}
+
+		return a.line - b.line
+	}
+
+	target := positions[0]
+	if delta < 0 {
+		target = positions[len(positions)-1]
+		for i := len(positions) - 1; i >= 0; i-- {
+			if compare(positions[i], current) < 0 {
+				target = positions[i]
+				break
+			}
+		}
+	} else {
+		for _, pos := range positions {
+			if compare(pos, current) > 0 {
+				target = pos
+				break
+			}
+		}
+	}
+
+	if target.file != m.selectedFile {
+		m.jumpToFile(target.file)
+	}
+
+	m.focusedPanel = PanelDiffView
+	m.selectedHunk = target.hunk
+	m.lineCursor = target.line
+
+	return *m
+}
+
+// openOpLog loads the operation log. Like undo and redo it needs a jj client, so it does nothing in
+// diff-editor mode, where jj itself is mid-operation.
+func (m *Model) openOpLog() (Model, tea.Cmd) {
+	if m.client == nil {
+		return *m, nil
+	}
+
+	return *m, m.commands.track(commandLoadOpLog, func(ctx context.Context) tea.Msg {
+		entries, err := m.client.GetOpLog(ctx, revisionListLimit)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return opLogLoadedMsg{entries: entries}
+	})
+}
+
+// openSmartlog loads the change graph. It needs a jj client, so it does nothing in diff-editor mode.
+func (m *Model) openSmartlog() (Model, tea.Cmd) {
+	if m.client == nil {
+		return *m, nil
+	}
+
+	return *m, m.commands.track(commandLoadSmartlog, func(ctx context.Context) tea.Msg {
+		lines, err := m.client.GetLog(ctx, smartlogLimit)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return smartlogLoadedMsg{lines: lines}
+	})
+}
+
+// viewChange puts the picked change's diff on screen. It becomes the revision the session is about,
+// so E lists its evolutions rather than those of the revision the session started on.
+func (m *Model) viewChange(entry jj.LogEntry) (Model, tea.Cmd) {
+	m.smartlog.Hide()
+
+	source := diff.NewRevisionSource(m.client, entry.ChangeID)
+	m.baseSource = source
+	m.setDiffSource(source)
+
+	return *m, m.loadDiff()
+}
+
+// newChange runs jj new on the picked change, which moves the working copy to a fresh empty child.
+func (m *Model) newChange(entry jj.LogEntry) (Model, tea.Cmd) {
+	m.smartlog.Hide()
+
+	return *m, m.commands.track(commandNew, func(ctx context.Context) tea.Msg {
+		if err := m.client.NewChange(ctx, entry.ChangeID); err != nil {
+			return errMsg{err}
+		}
+
+		return repoChangedMsg{notice: "Created a new change on " + entry.ChangeID + " (u undoes)"}
+	})
+}
+
+// editChange runs jj edit on the picked change, which makes it the working copy.
+func (m *Model) editChange(entry jj.LogEntry) (Model, tea.Cmd) {
+	m.smartlog.Hide()
+
+	return *m, m.commands.track(commandEdit, func(ctx context.Context) tea.Msg {
+		if err := m.client.Edit(ctx, entry.ChangeID); err != nil {
+			return errMsg{err}
+		}
+
+		return repoChangedMsg{notice: "Editing " + entry.ChangeID + " (u undoes)"}
+	})
+}
+
+// chooseSmartlogDestination makes the picked change the move destination, as the destination picker
+// does. A destination means nothing outside interactive mode, so there it only says so.
+func (m *Model) chooseSmartlogDestination(entry jj.LogEntry) (Model, tea.Cmd) {
+	if m.mode != ModeInteractive {
+		m.notice = "Destinations apply in interactive mode (-i)"
+		return *m, nil
+	}
+
+	m.smartlog.Hide()
+
+	return *m, func() tea.Msg {
+		return destinationSelectedMsg{changeID: entry.ChangeID}
+	}
+}
+
+// undoOperation runs jj undo and pushes the operation it undid onto the redo stack. When the last
+// command the model ran took several operations and is still the latest thing in the op log, it
+// restores the operation from before the command instead, so one u undoes the whole command.
+func (m *Model) undoOperation() (Model, tea.Cmd) {
+	if m.client == nil {
+		return *m, nil
+	}
+
+	redoOps := m.redoOps
+	span := m.undoSpan
+
+	return *m, m.commands.track(commandRestore, func(ctx context.Context) tea.Msg {
+		if span.before != "" {
+			if current, err := m.client.CurrentOperationID(ctx); err == nil && current == span.after {
+				if err := m.client.RestoreOperation(ctx, span.before); err != nil {
+					return errMsg{err}
+				}
+
+				return operationRestoredMsg{
+					notice:  "Undid operations back to " + jj.ShortOperationID(span.before),
+					redoOps: append(slices.Clone(redoOps), span.after),
+				}
+			}
+		}
+
+		undone, err := m.client.Undo(ctx)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return operationRestoredMsg{
+			notice:  "Undid operation " + jj.ShortOperationID(undone),
+			redoOps: append(slices.Clone(redoOps), undone),
+		}
+	})
+}
+
+// redoOperation restores the operation the last undo undid. jj has no redo of its own, but restoring
+// the operation from before the undo puts the repository back exactly as it was.
+func (m *Model) redoOperation() (Model, tea.Cmd) {
+	if m.client == nil {
+		return *m, nil
+	}
+
+	if len(m.redoOps) == 0 {
+		m.notice = "Nothing to redo"
+		return *m, nil
+	}
+
+	last := len(m.redoOps) - 1
+	opID, redoOps := m.redoOps[last], slices.Clone(m.redoOps[:last])
+
+	return *m, m.commands.track(commandRestore, func(ctx context.Context) tea.Msg {
+		if err := m.client.RestoreOperation(ctx, opID); err != nil {
+			return errMsg{err}
+		}
+
+		return operationRestoredMsg{
+			notice:  "Redid operation " + jj.ShortOperationID(opID),
+			redoOps: redoOps,
+		}
+	})
+}
+
+// restoreOperation restores the operation picked in the op log. It starts a new history, so the redo
+// stack is dropped rather than left to restore over the top of it.
+func (m *Model) restoreOperation(entry jj.OpLogEntry) (Model, tea.Cmd) {
+	m.opLog.Hide()
 
-	return m.selectedHunk >= 0 && m.selectedHunk < len(hunks)
+	return *m, m.commands.track(commandRestore, func(ctx context.Context) tea.Msg {
+		if err := m.client.RestoreOperation(ctx, entry.ID); err != nil {
+			return errMsg{err}
+		}
+
+		return operationRestoredMsg{notice: "Restored operation " + jj.ShortOperationID(entry.ID)}
+	})
 }
 
-func (m *Model) hasSearchMatches() bool {
-	return m.searchState != nil && m.searchState.IsActive && len(m.searchState.Matches) > 0
+// viewEvolution puts one evolution's diff on screen. Index 0 is the change as it is now, so picking
+// it goes back to the source the session started on rather than pinning today's commit ID.
+func (m *Model) viewEvolution(entry jj.EvologEntry, idx int) (Model, tea.Cmd) {
+	m.timeline.Hide()
+
+	if idx == 0 {
+		m.setDiffSource(m.baseSource)
+	} else {
+		m.setDiffSource(diff.NewEvolutionSource(m.client, m.timeline.Revision(), entry.CommitID))
+	}
+
+	return *m, m.loadDiff()
 }
 
-func (m *Model) openDestinationPicker() (Model, tea.Cmd) {
-	if m.mode == ModeInteractive && m.diffSource.SupportsRevisions() {
-		return *m, m.loadRevisions()
+// viewInterdiff puts the diff between the marked and highlighted evolutions on screen, oldest to
+// newest. Short commit IDs go into the source so the header stays readable; jj resolves them
+// against hidden commits too.
+func (m *Model) viewInterdiff() (Model, tea.Cmd) {
+	older, newer, ok := m.timeline.GetInterdiffRange()
+	if !ok {
+		return *m, nil
 	}
 
-	return *m, nil
+	m.timeline.Hide()
+	m.setDiffSource(diff.NewInterdiffSource(
+		m.client,
+		jj.ShortCommitID(older.CommitID),
+		jj.ShortCommitID(newer.CommitID),
+	))
+
+	return *m, m.loadDiff()
+}
+
+// setDiffSource swaps the diff the model shows. Selections and cursors index into the old diff's
+// hunks, so they are reset rather than carried over, and the next load is treated as a first load
+// rather than a refresh of the old diff.
+func (m *Model) setDiffSource(source diff.Source) {
+	m.diffSource = source
+	m.source = source.GetSourceLabel()
+	m.selection = diff.NewSelection()
+	m.multiSplitState = NewMultiSplitState()
+	m.selectedFile = 0
+	m.selectedHunk = 0
+	m.lineCursor = 0
+	m.isVisualMode = false
+	m.diffText = ""
+	m.fileList.SetSelected(0)
 }
 
 func (m *Model) enterVisualMode() Model {
@@ -668,16 +1219,6 @@ func (m *Model) toggleCurrentSelection() Model {
 	return *m
 }
 
-func (m *Model) toggleFocusedPanel() Model {
-	if m.focusedPanel == PanelFileList {
-		m.focusedPanel = PanelDiffView
-	} else {
-		m.focusedPanel = PanelFileList
-	}
-
-	return *m
-}
-
 // jumpToFile moves the file cursor to an absolute index, which is how g and G reach the ends of the
 // list. An empty change list leaves the diff view showing whatever it had.
 func (m *Model) jumpToFile(idx int) Model {
@@ -761,7 +1302,9 @@ func (m *Model) nextMatchOrHunk() (Model, tea.Cmd) {
 		return m.nextSearchMatch()
 	}
 
-	return m.selectAdjacentHunk(1)
+	model, cmd, _ := m.runCommand(command.Navigate{Unit: command.UnitHunk, Delta: 1})
+
+	return model, cmd
 }
 
 // prevMatchOrHunk is the N counterpart to nextMatchOrHunk.
@@ -770,12 +1313,14 @@ func (m *Model) prevMatchOrHunk() (Model, tea.Cmd) {
 		return m.prevSearchMatch()
 	}
 
-	return m.selectAdjacentHunk(-1)
+	model, cmd, _ := m.runCommand(command.Navigate{Unit: command.UnitHunk, Delta: -1})
+
+	return model, cmd
 }
 
-func (m *Model) applyCurrentMode() (Model, tea.Cmd) {
-	if m.mode == ModeInteractive && m.destination != "" {
-		return *m, m.applySelection()
+func (m *Model) applyCurrentMode(partial bool) (Model, tea.Cmd) {
+	if m.mode == ModeInteractive && m.destination != "" && m.appliesToRevisions() {
+		return *m, m.applySelection(partial)
 	}
 
 	if m.mode == ModeDiffEditor {
@@ -786,7 +1331,7 @@ func (m *Model) applyCurrentMode() (Model, tea.Cmd) {
 }
 
 func (m *Model) toggleMultiSplit() Model {
-	if m.mode != ModeInteractive || m.focusedPanel != PanelDiffView {
+	if m.mode != ModeInteractive || m.focusedPanel != PanelDiffView || !m.diffSource.SupportsRevisions() {
 		return *m
 	}
 
@@ -831,22 +1376,6 @@ func (m *Model) openSplitPreview() Model {
 	return *m
 }
 
-// handleTagKey claims a letter key for the multi-way split, which is why the tag letters are not
-// listed as cases: they only mean anything while a split is being assembled.
-func (m *Model) handleTagKey(key string) (Model, tea.Cmd) {
-	if !m.multiSplitState.Active || m.mode != ModeInteractive ||
-		m.focusedPanel != PanelDiffView || len(key) != 1 {
-		return *m, nil
-	}
-
-	tag, ok := splitTagFromKey(key[0])
-	if !ok {
-		return *m, nil
-	}
-
-	return m.toggleTagSelection(tag)
-}
-
 // splitTagFromKey maps a letter to its tag, folding lowercase onto the uppercase tag it shares.
 func splitTagFromKey(char byte) (SplitTag, bool) {
 	switch {
@@ -859,37 +1388,76 @@ func splitTagFromKey(char byte) (SplitTag, bool) {
 	return 0, false
 }
 
-func (m Model) applySelection() tea.Cmd {
-	return func() tea.Msg {
-		hasSelection := false
-		for _, file := range m.changes {
-			for hunkIdx := range file.Hunks {
-				if m.selection.IsHunkSelected(file.Path, hunkIdx) ||
-					m.selection.HasPartialSelection(file.Path, hunkIdx) {
-					hasSelection = true
-					break
-				}
-			}
-			if hasSelection {
-				break
-			}
-		}
+// applySelection moves the selected changes into the destination. A move is all or nothing unless
+// partial asks for each hunk to be applied on its own, with the configured fuzz, so a destination
+// that drifted from the source's parent still takes what fits; a strict move that failed on a hunk
+// says how to ask. A patch file always lands what fits, since it was never written against the
+// destination. When anything shifted or was left behind, the report opens so the user can keep the
+// result or roll it back.
+func (m Model) applySelection(partial bool) tea.Cmd {
+	if m.dryRun {
+		return m.exportSelection()
+	}
 
-		if !hasSelection {
+	return m.commands.track(commandMove, func(ctx context.Context) tea.Msg {
+		if !m.hasSelection() {
 			return errMsg{errNoSelection}
 		}
 
 		patch := diff.GeneratePatch(m.changes, m.selection)
 
-		err := m.client.MoveChanges(patch, m.source, m.destination)
+		// A patch from outside the repository has no source revision, so its hunks are only applied.
+		verb, action, fromPatch := "Moved", "move", m.fromPatch()
+		if fromPatch {
+			verb, action = "Applied", "apply"
+		}
+
+		var report diff.ApplyReport
+
+		span, err := m.recordOperations(ctx, func() (err error) {
+			switch {
+			case fromPatch:
+				report, err = m.client.ApplyPatch(ctx, patch, m.destination, m.cfg.ApplyFuzz)
+			case partial:
+				report, err = m.client.MoveChangesPartial(ctx, patch, m.source, m.destination, m.cfg.ApplyFuzz)
+			default:
+				err = m.client.MoveChanges(ctx, patch, m.source, m.destination)
+			}
+
+			return err
+		})
 		if err != nil {
-			return errMsg{fmt.Errorf("failed to move changes: %w", err)}
+			return errMsg{m.moveError(action, err, partial || fromPatch)}
+		}
+
+		changed := repoChangedMsg{notice: verb + " changes to " + m.destination + " (u undoes)", span: span}
+		if len(report.Hunks) == 0 || report.Clean() {
+			return changed
 		}
 
-		// Inside a tea.Cmd the caller expects a tea.Msg, so the reload command has
-		// to be run rather than handed back as a message Update cannot match.
-		return m.loadDiff()()
+		landed := len(report.Hunks) - report.Count(diff.HunkRejected)
+		changed.notice = fmt.Sprintf(
+			"%s %d of %d hunks to %s (u undoes)", verb, landed, len(report.Hunks), m.destination,
+		)
+
+		return moveReportedMsg{report: report, changed: changed}
+	})
+}
+
+// moveError says why a move or apply failed. When a strict move failed because a hunk does not fit
+// the destination, it points at the partial move, which would land the rest.
+func (m Model) moveError(action string, err error, partial bool) error {
+	var hunkErr *diff.HunkError
+	if partial || !errors.As(err, &hunkErr) {
+		return fmt.Errorf("failed to %s changes: %w", action, err)
 	}
+
+	how := ":" + command.Apply{Partial: true}.String()
+	if key := m.keys.Key(keymap.ApplyPartial); key != "" {
+		how = key + " or " + how
+	}
+
+	return fmt.Errorf("failed to %s changes: %w (%s moves the hunks that fit)", action, err, how)
 }
 
 type diffEditorAppliedMsg struct{}
@@ -916,14 +1484,26 @@ func (m Model) handleEscape() (Model, tea.Cmd) {
 	switch {
 	case m.help.IsVisible():
 		m.help.Hide()
+	case m.commandLine.IsVisible():
+		m.commandLine.Hide()
 	case m.destPicker.IsVisible():
 		m.destPicker.Hide()
+	case m.timeline.IsVisible():
+		m.timeline.Hide()
+	case m.opLog.IsVisible():
+		m.opLog.Hide()
+	case m.smartlog.IsVisible():
+		m.smartlog.Hide()
+	case m.conflictList.IsVisible():
+		m.conflictList.Hide()
 	case m.splitAssign.IsVisible():
 		m.splitAssign.Hide()
 	case m.splitPreview.IsVisible():
 		m.splitPreview.Hide()
 	case m.commitMsg.IsVisible():
 		m.commitMsg.Hide()
+	case m.applyReport.IsVisible():
+		m.applyReport.Hide()
 	case m.searchModal.IsVisible():
 		if m.searchState != nil {
 			origState := m.searchState.RestoreOriginalState()
@@ -961,12 +1541,15 @@ func (m Model) toggleHelp() (Model, tea.Cmd) {
 	switch m.mode {
 	case ModeInteractive:
 		modeText = "Interactive"
+		if m.dryRun {
+			modeText = "Interactive (dry run)"
+		}
 	case ModeDiffEditor:
 		modeText = "Diff-Editor"
 	case ModeBrowse:
 	}
 
-	m.help.Show(modeText)
+	m.help.Show(modeText, m.keys)
 
 	return m, nil
 }
@@ -980,14 +1563,26 @@ func (m Model) routeToOverlay(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 	)
 
 	switch {
+	case m.commandLine.IsVisible():
+		model, cmd = m.handleCommandLineKeyPress(msg)
 	case m.destPicker.IsVisible():
 		model, cmd = m.handleDestPickerKeyPress(msg)
+	case m.timeline.IsVisible():
+		model, cmd = m.handleEvolutionTimelineKeyPress(msg)
+	case m.opLog.IsVisible():
+		model, cmd = m.handleOpLogKeyPress(msg)
+	case m.smartlog.IsVisible():
+		model, cmd = m.handleSmartlogKeyPress(msg)
+	case m.conflictList.IsVisible():
+		model, cmd = m.handleConflictListKeyPress(msg)
 	case m.splitAssign.IsVisible():
 		model, cmd = m.handleSplitAssignKeyPress(msg)
 	case m.splitPreview.IsVisible():
 		model, cmd = m.handleSplitPreviewKeyPress(msg)
 	case m.commitMsg.IsVisible():
 		model, cmd = m.handleCommitMsgKeyPress(msg)
+	case m.applyReport.IsVisible():
+		model, cmd = m.handleApplyReportKeyPress(msg)
 	case m.searchModal.IsVisible():
 		model, cmd = m.handleSearchKeyPress(msg)
 	case m.fileFinder.IsVisible():
@@ -1001,20 +1596,31 @@ func (m Model) routeToOverlay(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 	return model, cmd, true
 }
 
+// overlayAction is the action msg's key is bound to, for the list overlays. They share down, up and
+// quit, which closes them, with the main screen, so a rebinding moves through them too; each
+// overlay's own keys, such as enter, stay fixed.
+func (m Model) overlayAction(msg tea.KeyMsg) keymap.Action {
+	action, _ := m.keys.Action(msg.String())
+
+	return action
+}
+
 func (m Model) handleDestPickerKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch msg.String() {
-	case "q", keyCtrlC:
+	switch m.overlayAction(msg) {
+	case keymap.Quit:
 		m.destPicker.Hide()
 		return m, nil
 
-	case "j", keyDown:
+	case keymap.Down:
 		m.destPicker.MoveDown()
 		return m, nil
 
-	case "k", "up":
+	case keymap.Up:
 		m.destPicker.MoveUp()
 		return m, nil
+	}
 
+	switch msg.String() {
 	case keyEnter:
 		if selected := m.destPicker.GetSelected(); selected != nil {
 			return m, func() tea.Msg {
@@ -1028,26 +1634,161 @@ func (m Model) handleDestPickerKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) handleSplitAssignKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+func (m Model) handleEvolutionTimelineKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch m.overlayAction(msg) {
+	case keymap.Quit:
+		m.timeline.Hide()
+		return m, nil
+
+	case keymap.Down:
+		m.timeline.MoveDown()
+		return m, nil
+
+	case keymap.Up:
+		m.timeline.MoveUp()
+		return m, nil
+	}
+
 	switch msg.String() {
-	case "q", keyCtrlC:
+	case " ":
+		m.timeline.ToggleMark()
+		return m, nil
+
+	case "I":
+		return m.viewInterdiff()
+
+	case keyEnter:
+		if entry, idx := m.timeline.GetSelected(); entry != nil {
+			return m.viewEvolution(*entry, idx)
+		}
+
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) handleOpLogKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch m.overlayAction(msg) {
+	case keymap.Quit:
+		m.opLog.Hide()
+		return m, nil
+
+	case keymap.Down:
+		m.opLog.MoveDown()
+		return m, nil
+
+	case keymap.Up:
+		m.opLog.MoveUp()
+		return m, nil
+
+	case keymap.Undo:
+		m.opLog.Hide()
+		return m.undoOperation()
+
+	case keymap.Redo:
+		m.opLog.Hide()
+		return m.redoOperation()
+	}
+
+	switch msg.String() {
+	case keyEnter:
+		if entry, idx := m.opLog.GetSelected(); entry != nil && idx > 0 {
+			return m.restoreOperation(*entry)
+		}
+
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) handleSmartlogKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch m.overlayAction(msg) {
+	case keymap.Quit:
+		m.smartlog.Hide()
+		return m, nil
+
+	case keymap.Down:
+		m.smartlog.MoveDown()
+		return m, nil
+
+	case keymap.Up:
+		m.smartlog.MoveUp()
+		return m, nil
+	}
+
+	entry := m.smartlog.GetSelected()
+	if entry == nil {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case keyEnter:
+		return m.viewChange(*entry)
+	case "n":
+		return m.newChange(*entry)
+	case "e":
+		return m.editChange(*entry)
+	case "d":
+		return m.chooseSmartlogDestination(*entry)
+	}
+
+	return m, nil
+}
+
+func (m Model) handleConflictListKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch m.overlayAction(msg) {
+	case keymap.Quit:
+		m.conflictList.Hide()
+		return m, nil
+
+	case keymap.Down:
+		m.conflictList.MoveDown()
+		return m, nil
+
+	case keymap.Up:
+		m.conflictList.MoveUp()
+		return m, nil
+	}
+
+	switch msg.String() {
+	case keyEnter:
+		if entry := m.conflictList.GetSelected(); entry != nil {
+			return m.jumpToConflictFile(entry.Path), nil
+		}
+
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) handleSplitAssignKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch m.overlayAction(msg) {
+	case keymap.Quit:
 		m.splitAssign.Hide()
 		return m, nil
 
-	case "j", keyDown:
+	case keymap.Down:
 		m.splitAssign.MoveDown()
 		return m, nil
 
-	case "k", "up":
+	case keymap.Up:
 		m.splitAssign.MoveUp()
 		return m, nil
+	}
 
+	switch msg.String() {
 	case "tab":
 		m.splitAssign.ToggleFocus()
 		return m, nil
 
 	case keyEnter:
-		m.splitAssign.AssignRevisionToCurrentTag()
+		if tag, rev, ok := m.splitAssign.SelectedRevision(); ok {
+			return m.runTypedCommand(command.Assign{Tag: rune(tag), Revision: rev.ChangeID})
+		}
+
 		return m, nil
 
 	case "N":
@@ -1062,17 +1803,18 @@ func (m Model) handleSplitAssignKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 }
 
 func (m Model) handleSplitPreviewKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch msg.String() {
-	case "q", keyCtrlC:
+	if m.overlayAction(msg) == keymap.Quit {
 		m.splitPreview.Hide()
 		return m, nil
+	}
 
+	switch msg.String() {
 	case "e":
 		m.splitPreview.Hide()
 		return m, m.loadRevisionsForSplitAssign()
 
 	case keyEnter:
-		return m, m.applySplit()
+		return m.runTypedCommand(command.Split{})
 	}
 
 	return m, nil
@@ -1088,13 +1830,13 @@ func (m Model) handleCommitMsgKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 
 	case keyEnter:
 		message := m.commitMsg.GetMessage()
-		if message != "" {
-			tag := m.commitMsg.GetTag()
-			m.splitAssign.AssignNewCommitToTag(splitassign.SplitTag(tag), message)
-		}
 		m.commitMsg.Hide()
 		m.splitAssign.Show()
 
+		if message != "" {
+			return m.runTypedCommand(command.Assign{Tag: rune(m.commitMsg.GetTag()), Description: message})
+		}
+
 		return m, nil
 
 	case keyBackspace:
@@ -1110,18 +1852,33 @@ func (m Model) handleCommitMsgKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 	}
 }
 
+// handleApplyReportKeyPress keeps or rolls back a move that did not apply cleanly. The move is
+// already committed, so keeping it only closes the report, and rolling it back is the same undo u
+// runs, which restores the operation from before the move.
+func (m Model) handleApplyReportKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if msg.String() == keyEnter || m.overlayAction(msg) == keymap.Quit {
+		m.applyReport.Hide()
+
+		return m, nil
+	}
+
+	if msg.String() == "r" {
+		m.applyReport.Hide()
+
+		return m.undoOperation()
+	}
+
+	return m, nil
+}
+
 func (m Model) loadRevisionsForSplitAssign() tea.Cmd {
-	return func() tea.Msg {
-		revisions, err := m.client.GetRevisions(revisionListLimit)
+	return m.commands.track(commandLoadRevisions, func(ctx context.Context) tea.Msg {
+		revisions, err := m.client.GetRevisions(ctx, revisionListLimit)
 		if err != nil {
 			return errMsg{err}
 		}
-		m.closeAllModals()
-		m.splitAssign.SetRevisions(revisions)
-		m.splitAssign.Show()
-
-		return nil
-	}
+		return splitRevisionsLoadedMsg{revisions}
+	})
 }
 
 func (m Model) buildSplitSummaries(
@@ -1168,52 +1925,120 @@ func (m Model) buildSplitSummaries(
 }
 
 func (m Model) applySplit() tea.Cmd {
-	return func() tea.Msg {
-		destinations := m.splitAssign.GetDestinations()
-		if len(destinations) == 0 {
-			return errMsg{errNoDestinationsAssigned}
+	if m.dryRun {
+		return m.exportSplit()
+	}
+
+	return m.commands.track(commandSplit, func(ctx context.Context) tea.Msg {
+		plans, err := m.splitPlans(ctx)
+		if err != nil {
+			return errMsg{err}
 		}
 
-		var plans []jj.SplitPlan
-		for tag, dest := range destinations {
-			tagSelection := m.multiSplitState.Selections[SplitTag(tag)]
-			if tagSelection == nil {
-				continue
-			}
+		span, err := m.recordOperations(ctx, func() error {
+			return m.client.ApplySplit(ctx, plans, m.source)
+		})
+		if err != nil {
+			return errMsg{fmt.Errorf("failed to apply split: %w", err)}
+		}
 
-			patch := diff.GeneratePatchForTag(m.changes, tagSelection)
-			if patch == "" {
-				continue
-			}
+		return splitAppliedMsg{changed: repoChangedMsg{
+			notice: fmt.Sprintf("Applied %d split plans (u undoes)", len(plans)),
+			span:   span,
+		}}
+	})
+}
 
-			jjDest := jj.SplitDestination{
-				Type:        jj.SplitDestinationType(dest.Type),
-				ChangeID:    dest.ChangeID,
-				Description: dest.Description,
-			}
+// splitPlans builds one plan per tag that has both a destination and a non-empty patch, sorted by
+// tag. New commits stack in plan order, so sorting by tag puts tag a nearest the parent, and each
+// tag's patch is written against the tags before it that land beneath the source.
+func (m Model) splitPlans(ctx context.Context) ([]jj.SplitPlan, error) {
+	destinations := m.splitAssign.GetDestinations()
+	if len(destinations) == 0 {
+		return nil, errNoDestinationsAssigned
+	}
 
-			plans = append(plans, jj.SplitPlan{
-				Tag:         rune(tag),
-				Patch:       patch,
-				Destination: jjDest,
-			})
+	tags := slices.Sorted(maps.Keys(destinations))
+	stack := diff.NewPatchStack(m.changes)
+
+	var plans []jj.SplitPlan
+
+	for _, tag := range tags {
+		dest := destinations[tag]
+
+		tagSelection := m.multiSplitState.Selections[SplitTag(tag)]
+		if tagSelection == nil {
+			continue
 		}
 
-		if len(plans) == 0 {
-			return errMsg{errNoSplitPlans}
+		patch := stack.Patch(tagSelection)
+		if patch == "" {
+			continue
 		}
 
-		if err := m.client.ApplySplit(plans, m.source); err != nil {
-			return errMsg{fmt.Errorf("failed to apply split: %w", err)}
+		jjDest := jj.SplitDestination{
+			Type:        jj.SplitDestinationType(dest.Type),
+			ChangeID:    dest.ChangeID,
+			Description: dest.Description,
+		}
+
+		plans = append(plans, jj.SplitPlan{
+			Tag:         rune(tag),
+			Patch:       patch,
+			Destination: jjDest,
+		})
+
+		beneath, err := m.stacksBeneath(ctx, jjDest)
+		if err != nil {
+			return nil, err
 		}
 
-		// The model this closure captured is a copy, so it cannot clear the split state or hide the
-		// preview; both stay as the user left them until the reload lands. FINDINGS.md carries it.
-		//
-		// Inside a tea.Cmd the caller expects a tea.Msg, so the reload command has
-		// to be run rather than handed back as a message Update cannot match.
-		return m.loadDiff()()
+		if beneath {
+			stack.Push(tagSelection)
+		}
+	}
+
+	if len(plans) == 0 {
+		return nil, errNoSplitPlans
+	}
+
+	return plans, nil
+}
+
+// stacksBeneath is jj.Client.StacksBeneath for the revision being split. Without a client only a new
+// commit is known to land beneath it.
+func (m Model) stacksBeneath(ctx context.Context, destination jj.SplitDestination) (bool, error) {
+	if m.client == nil {
+		return destination.Type == jj.SplitDestNewCommit, nil
+	}
+
+	beneath, err := m.client.StacksBeneath(ctx, destination, m.source)
+	if err != nil {
+		return false, fmt.Errorf("failed to place %s: %w", destination.ChangeID, err)
+	}
+
+	return beneath, nil
+}
+
+// recordOperations runs write, a command that takes several jj operations, and returns the span of
+// operations it covered. A failed write has already been rolled back, so it has no span. A write that
+// succeeded but whose final operation cannot be read gets an empty span, and u falls back to jj undo.
+func (m Model) recordOperations(ctx context.Context, write func() error) (operationSpan, error) {
+	before, err := m.client.CurrentOperationID(ctx)
+	if err != nil {
+		return operationSpan{}, fmt.Errorf("failed to record the starting operation: %w", err)
+	}
+
+	if err := write(); err != nil {
+		return operationSpan{}, err
+	}
+
+	after, err := m.client.CurrentOperationID(ctx)
+	if err != nil {
+		return operationSpan{}, nil //nolint:nilerr // The write succeeded; only its undo shortcut is lost.
 	}
+
+	return operationSpan{before: before, after: after}, nil
 }
 
 func (m Model) handleNavigation(delta int) (Model, tea.Cmd) {
@@ -1254,10 +2079,16 @@ func (m Model) handleVisualNavigation(delta int) (Model, tea.Cmd) {
 
 func (m *Model) closeAllModals() {
 	m.help.Hide()
+	m.commandLine.Hide()
 	m.destPicker.Hide()
+	m.timeline.Hide()
+	m.opLog.Hide()
+	m.smartlog.Hide()
+	m.conflictList.Hide()
 	m.splitAssign.Hide()
 	m.splitPreview.Hide()
 	m.commitMsg.Hide()
+	m.applyReport.Hide()
 	m.searchModal.Hide()
 	m.fileFinder.Hide()
 	m.fileList.SetFilterMode(false)
@@ -1288,7 +2119,7 @@ func (m Model) toggleTagSelection(tag SplitTag) (Model, tea.Cmd) {
 	}
 
 	if _, ok := m.multiSplitState.Selections[tag]; !ok {
-		m.multiSplitState.Selections[tag] = NewSelectionState()
+		m.multiSplitState.Selections[tag] = diff.NewSelection()
 	}
 
 	tagSelection := m.multiSplitState.Selections[tag]
@@ -1499,6 +2330,8 @@ func (m Model) handleFileFinderKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 			}
 			m.focusedPanel = PanelDiffView
 			m.fileFinder.Hide()
+			m.record(command.JumpToFile{Index: fileIdx})
+			m.record(command.Focus{Panel: command.PanelDiff})
 		}
 
 		return m, nil
@@ -1563,6 +2396,7 @@ func (m Model) View() string {
 	m.pushSelectionState()
 	m.pushSearchState()
 	m.pushTagState()
+	m.pushApplicability()
 
 	fileListView := m.fileList.View(m.width, fileListHeight, fileListExpanded)
 	diffViewHeight := m.height - fileListHeight - chromeHeight
@@ -1583,12 +2417,22 @@ func (m Model) overlayView() string {
 		return m.help.View(m.width, m.height)
 	case m.destPicker.IsVisible():
 		return m.destPicker.View(m.width, m.height)
+	case m.timeline.IsVisible():
+		return m.timeline.View(m.width, m.height)
+	case m.opLog.IsVisible():
+		return m.opLog.View(m.width, m.height)
+	case m.smartlog.IsVisible():
+		return m.smartlog.View(m.width, m.height)
+	case m.conflictList.IsVisible():
+		return m.conflictList.View(m.width, m.height)
 	case m.splitAssign.IsVisible():
 		return m.splitAssign.View(m.width, m.height)
 	case m.splitPreview.IsVisible():
 		return m.splitPreview.View(m.width, m.height)
 	case m.commitMsg.IsVisible():
 		return m.commitMsg.View(m.width, m.height)
+	case m.applyReport.IsVisible():
+		return m.applyReport.View(m.width, m.height)
 	case m.searchModal.IsVisible():
 		return m.searchModal.View(m.width, m.height)
 	case m.fileFinder.IsVisible():
@@ -1600,7 +2444,7 @@ func (m Model) overlayView() string {
 
 // pushSelectionState hands the diff view the callbacks it needs to draw the current selection. Browse
 // mode reports nothing selected, so the view still highlights the hunk cursor without marking it.
-func (m Model) pushSelectionState() {
+func (m *Model) pushSelectionState() {
 	if m.selectedFile < 0 || m.selectedFile >= len(m.changes) {
 		return
 	}
@@ -1628,7 +2472,7 @@ func (m Model) pushSelectionState() {
 	)
 }
 
-func (m Model) pushSearchState() {
+func (m *Model) pushSearchState() {
 	if m.searchState == nil || !m.searchState.IsActive {
 		m.fileList.SetSearchState(false, nil)
 		m.diffView.SetSearchState(false, nil)
@@ -1648,7 +2492,7 @@ func (m Model) pushSearchState() {
 	})
 }
 
-func (m Model) pushTagState() {
+func (m *Model) pushTagState() {
 	if m.selectedFile < 0 || m.selectedFile >= len(m.changes) {
 		return
 	}
@@ -1665,6 +2509,34 @@ func (m Model) pushTagState() {
 	})
 }
 
+// pushApplicability hands both panels the pre-flight result, or clears their markers when there is
+// none for the diff and destination on screen.
+func (m *Model) pushApplicability() {
+	if !m.preflight.matches(m.destination, m.diffText) {
+		m.fileList.SetApplicability(nil)
+		m.diffView.SetApplicability(nil)
+
+		return
+	}
+
+	m.fileList.SetApplicability(func(fileIdx int) (diff.HunkStatus, bool) {
+		if fileIdx < 0 || fileIdx >= len(m.changes) {
+			return diff.HunkApplied, false
+		}
+
+		return m.preflight.fileStatus(m.changes[fileIdx].Path)
+	})
+
+	if m.selectedFile < 0 || m.selectedFile >= len(m.changes) {
+		return
+	}
+
+	currentFile := m.changes[m.selectedFile]
+	m.diffView.SetApplicability(func(hunkIdx int) (diff.HunkStatus, bool) {
+		return m.preflight.hunkStatus(currentFile.Path, hunkIdx)
+	})
+}
+
 // renderDiffView dims the whole pane while the file list has focus, so the two panels read as one
 // focused and one inactive.
 func (m Model) renderDiffView(height int) string {
@@ -1686,6 +2558,10 @@ func (m Model) renderDiffView(height int) string {
 }
 
 func (m Model) renderStatusBar() string {
+	if m.commandLine.IsVisible() {
+		return m.commandLine.View(m.width)
+	}
+
 	focusedPanelStr := "files"
 	if m.focusedPanel == PanelDiffView {
 		focusedPanelStr = "diff"
@@ -1702,10 +2578,23 @@ func (m Model) renderStatusBar() string {
 	}
 
 	return m.statusBar.ViewWithContext(m.width, statusbar.Context{
+		Keys:         m.keys,
 		Destination:  m.destination,
 		FocusedPanel: focusedPanelStr,
 		IsVisualMode: m.isVisualMode,
 		Mode:         modeText,
+		Notice:       m.statusNotice(),
 		Source:       m.source,
+		Refreshed:    m.refreshed,
 	})
 }
+
+// statusNotice is the message the status bar carries: the jj calls still running while there are
+// any, and otherwise the outcome of the last one.
+func (m Model) statusNotice() string {
+	if running := m.commands.kinds(); len(running) > 0 {
+		return fmt.Sprintf("Running %s (ctrl+g cancels)", joinKinds(running))
+	}
+
+	return m.notice
+}
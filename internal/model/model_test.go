@@ -4,14 +4,43 @@
 package model
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/components/splitassign"
+	"github.com/kyleking/jj-diff/internal/config"
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/jj"
+	"github.com/kyleking/jj-diff/internal/session"
+	"github.com/kyleking/jj-diff/internal/watcher"
 )
 
 var errTest = errors.New("test error")
 
+// blockingSource is a diff source whose GetDiff never returns until its context is cancelled, which
+// stands in for a slow jj diff on a large repository.
+type blockingSource struct{}
+
+func (blockingSource) GetDiff(ctx context.Context) (string, error) {
+	<-ctx.Done()
+
+	return "", ctx.Err()
+}
+
+func (blockingSource) GetSourceLabel() string { return "@" }
+
+func (blockingSource) SupportsRevisions() bool { return false }
+
 // TestModelNavigation tests file and hunk navigation workflows.
 func TestModelNavigation(t *testing.T) {
 	t.Parallel()
@@ -249,154 +278,6 @@ func TestModelHelpOverlay(t *testing.T) {
 	}
 }
 
-// TestSelectionState_ToggleHunk tests SelectionState hunk toggling.
-func TestSelectionState_ToggleHunk(t *testing.T) {
-	t.Parallel()
-
-	s := NewSelectionState()
-
-	if s.IsHunkSelected("file.txt", 0) {
-		t.Error("Expected hunk to not be selected initially")
-	}
-
-	s.ToggleHunk("file.txt", 0)
-	if !s.IsHunkSelected("file.txt", 0) {
-		t.Error("Expected hunk to be selected after toggle")
-	}
-
-	s.ToggleHunk("file.txt", 0)
-	if s.IsHunkSelected("file.txt", 0) {
-		t.Error("Expected hunk to be deselected after second toggle")
-	}
-}
-
-// TestSelectionState_ToggleLine tests SelectionState line toggling.
-func TestSelectionState_ToggleLine(t *testing.T) {
-	t.Parallel()
-
-	s := NewSelectionState()
-
-	s.ToggleLine("file.txt", 0, 5)
-	if !s.IsLineSelected("file.txt", 0, 5) {
-		t.Error("Expected line to be selected")
-	}
-
-	s.ToggleLine("file.txt", 0, 5)
-	if s.IsLineSelected("file.txt", 0, 5) {
-		t.Error("Expected line to be deselected")
-	}
-
-	// Line toggle should not work if whole hunk is selected
-	s.ToggleHunk("file.txt", 0)
-	initialLineState := s.IsLineSelected("file.txt", 0, 10)
-
-	s.ToggleLine("file.txt", 0, 10)
-	if s.IsLineSelected("file.txt", 0, 10) != initialLineState {
-		t.Error("Expected line toggle to be ignored when whole hunk is selected")
-	}
-}
-
-// TestSelectionState_WholeHunkClearsLines tests that selecting whole hunk clears line selection map.
-func TestSelectionState_WholeHunkClearsLines(t *testing.T) {
-	t.Parallel()
-
-	s := NewSelectionState()
-
-	// Select individual lines
-	s.ToggleLine("file.txt", 0, 1)
-	s.ToggleLine("file.txt", 0, 2)
-	s.ToggleLine("file.txt", 0, 3)
-
-	if !s.IsLineSelected("file.txt", 0, 1) {
-		t.Error("Expected line 1 to be selected")
-	}
-
-	// Toggle whole hunk - this clears the SelectedLines map
-	s.ToggleHunk("file.txt", 0)
-
-	// Whole hunk should be selected
-	if !s.IsHunkSelected("file.txt", 0) {
-		t.Error("Expected whole hunk to be selected")
-	}
-
-	// Lines should still appear selected because WholeHunk=true means all lines are selected
-	if !s.IsLineSelected("file.txt", 0, 1) {
-		t.Error("Expected lines to be selected when whole hunk is selected")
-	}
-
-	// Verify the internal SelectedLines map was cleared
-	fileSelection := s.Files["file.txt"]
-	hunkSelection := fileSelection.Hunks[0]
-	if len(hunkSelection.SelectedLines) != 0 {
-		t.Errorf(
-			"Expected SelectedLines map to be cleared, got %d entries",
-			len(hunkSelection.SelectedLines),
-		)
-	}
-}
-
-// TestSelectionState_SelectLineRange tests selecting a range of lines.
-func TestSelectionState_SelectLineRange(t *testing.T) {
-	t.Parallel()
-
-	s := NewSelectionState()
-
-	s.SelectLineRange("file.txt", 0, 2, 5)
-
-	for i := 2; i <= 5; i++ {
-		if !s.IsLineSelected("file.txt", 0, i) {
-			t.Errorf("Expected line %d to be selected", i)
-		}
-	}
-
-	if s.IsLineSelected("file.txt", 0, 1) {
-		t.Error("Expected line 1 to not be selected")
-	}
-	if s.IsLineSelected("file.txt", 0, 6) {
-		t.Error("Expected line 6 to not be selected")
-	}
-
-	if s.IsHunkSelected("file.txt", 0) {
-		t.Error("Expected whole hunk to not be selected")
-	}
-}
-
-// TestSelectionState_SelectLineRangeReversed tests selecting line range with reversed bounds.
-func TestSelectionState_SelectLineRangeReversed(t *testing.T) {
-	t.Parallel()
-
-	s := NewSelectionState()
-
-	s.SelectLineRange("file.txt", 0, 5, 2)
-
-	for i := 2; i <= 5; i++ {
-		if !s.IsLineSelected("file.txt", 0, i) {
-			t.Errorf("Expected line %d to be selected", i)
-		}
-	}
-}
-
-// TestSelectionState_HasPartialSelection tests partial selection detection.
-func TestSelectionState_HasPartialSelection(t *testing.T) {
-	t.Parallel()
-
-	s := NewSelectionState()
-
-	if s.HasPartialSelection("file.txt", 0) {
-		t.Error("Expected no partial selection initially")
-	}
-
-	s.ToggleLine("file.txt", 0, 3)
-	if !s.HasPartialSelection("file.txt", 0) {
-		t.Error("Expected partial selection after selecting individual line")
-	}
-
-	s.ToggleHunk("file.txt", 0)
-	if s.HasPartialSelection("file.txt", 0) {
-		t.Error("Expected no partial selection when whole hunk is selected")
-	}
-}
-
 // TestModelVisualMode tests entering and exiting visual mode.
 func TestModelVisualMode(t *testing.T) {
 	t.Parallel()
@@ -706,3 +587,1456 @@ func TestScrollingOnlyInDiffPanel(t *testing.T) {
 	m.focusedPanel = PanelDiffView
 	Update(t, m, tea.KeyMsg{Type: tea.KeyCtrlD})
 }
+
+// TestCancelRunningDiffLoad tests that ctrl+g aborts a diff load and leaves a notice instead of the
+// error screen.
+func TestCancelRunningDiffLoad(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewModelWithSource(blockingSource{}, nil, "", ModeBrowse, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	cmd := m.Init()
+	if got := m.statusNotice(); got != "Running diff (ctrl+g cancels)" {
+		t.Errorf("Expected running notice, got %q", got)
+	}
+
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeyCtrlG})
+	m = Update(t, m, cmd())
+
+	Assert(t, m).HasNoError()
+
+	if m.notice != "Cancelled diff" {
+		t.Errorf("Expected cancelled notice, got %q", m.notice)
+	}
+
+	if kinds := m.commands.kinds(); len(kinds) != 0 {
+		t.Errorf("Expected no running commands, got %v", kinds)
+	}
+}
+
+// TestSupersededDiffLoadIsDropped tests that a reload replaces one still in flight and that the
+// replaced load's result is ignored when it finally arrives.
+func TestSupersededDiffLoadIsDropped(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewModelWithSource(blockingSource{}, nil, "", ModeBrowse, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	first := m.Init()
+	m = Update(t, m, KeyPress('r'))
+	m = Update(t, m, first())
+
+	Assert(t, m).HasNoError()
+
+	if kinds := m.commands.kinds(); len(kinds) != 1 || kinds[0] != commandLoadDiff {
+		t.Errorf("Expected the replacing load to still be running, got %v", kinds)
+	}
+
+	m.commands.cancelAll()
+}
+
+// TestCancelWithNothingRunning tests that ctrl+g is safe to press when no jj call is in flight.
+func TestCancelWithNothingRunning(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeyCtrlG})
+
+	Assert(t, m).HasNoError()
+	Assert(t, m).HasChanges(3)
+
+	if m.notice != "Nothing to cancel" {
+		t.Errorf("Expected nothing-to-cancel notice, got %q", m.notice)
+	}
+}
+
+// TestEvolutionTimeline tests that picking an older evolution swaps the diff source, that picking the
+// newest one swaps back, and that selections from the old diff do not survive either swap.
+func TestEvolutionTimeline(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges()).WithDestination("abc123")
+	m.focusedPanel = PanelDiffView
+	base := m.diffSource
+
+	m = Update(t, m, KeyPress(' '))
+	Assert(t, m).HasHunkSelected("file1.txt", 0)
+
+	m = Update(t, m, evologLoadedMsg{revision: "@", entries: []jj.EvologEntry{
+		{CommitID: "1111111111111111", Operation: "snapshot working copy", Description: "fix typo"},
+		{CommitID: "2222222222222222", Operation: "new empty commit", Description: "initial"},
+	}})
+
+	if !m.timeline.IsVisible() {
+		t.Fatal("Expected evolution timeline to be visible")
+	}
+
+	m = Update(t, m, KeyPress('j'))
+	newModel, cmd := m.Update(SpecialKey(tea.KeyEnter))
+	m = assertModel(t, newModel)
+
+	Assert(t, m).NoModalsVisible()
+	Assert(t, m).HasHunkNotSelected("file1.txt", 0)
+
+	source, ok := m.diffSource.(*diff.EvolutionSource)
+	if !ok {
+		t.Fatalf("Expected an evolution source, got %T", m.diffSource)
+	}
+
+	if source.CommitID != "2222222222222222" || m.source != "@ @ 222222222222" {
+		t.Errorf("Expected the older evolution, got %q labelled %q", source.CommitID, m.source)
+	}
+
+	if cmd == nil {
+		t.Error("Expected a diff load after picking an evolution")
+	}
+
+	m.commands.cancelAll()
+
+	m = Update(t, m, evologLoadedMsg{revision: "@", entries: []jj.EvologEntry{{CommitID: source.CommitID}}})
+	m = Update(t, m, SpecialKey(tea.KeyEnter))
+
+	if m.diffSource != base {
+		t.Errorf("Expected the newest evolution to restore the base source, got %T", m.diffSource)
+	}
+}
+
+// TestEvolutionInterdiff tests that marking one evolution and pressing I on another shows the diff
+// between them, oldest to newest whichever was marked first.
+func TestEvolutionInterdiff(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	m = Update(t, m, evologLoadedMsg{revision: "@", entries: []jj.EvologEntry{
+		{CommitID: "3333333333333333"},
+		{CommitID: "2222222222222222"},
+		{CommitID: "1111111111111111"},
+	}})
+
+	m = Update(t, m, KeyPress('I'))
+	if !m.timeline.IsVisible() {
+		t.Fatal("Expected I without a mark to leave the timeline open")
+	}
+
+	m = Update(t, m, KeyPress(' '))
+	m = Update(t, m, KeyPress('j'))
+	m = Update(t, m, KeyPress('j'))
+
+	newModel, cmd := m.Update(KeyPress('I'))
+	m = assertModel(t, newModel)
+	m.commands.cancelAll()
+
+	Assert(t, m).NoModalsVisible()
+
+	if m.source != "interdiff 111111111111 → 333333333333" {
+		t.Errorf("Expected the interdiff label, got %q", m.source)
+	}
+
+	if cmd == nil {
+		t.Error("Expected a diff load after picking an interdiff")
+	}
+}
+
+// TestEvolutionTimelineKeys tests that j and k stop at the ends of the timeline, that space marks and
+// unmarks, and that the quit key closes the timeline without changing the diff.
+func TestEvolutionTimelineKeys(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	base := m.diffSource
+	m = Update(t, m, evologLoadedMsg{revision: "@", entries: []jj.EvologEntry{
+		{CommitID: "2222222222222222"},
+		{CommitID: "1111111111111111"},
+	}})
+
+	m = Update(t, m, KeyPress('k'))
+	if _, idx := m.timeline.GetSelected(); idx != 0 {
+		t.Errorf("Expected k on the newest to stay put, got %d", idx)
+	}
+
+	m = Update(t, m, KeyPress('j'))
+	m = Update(t, m, KeyPress('j'))
+	if _, idx := m.timeline.GetSelected(); idx != 1 {
+		t.Errorf("Expected j on the oldest to stay put, got %d", idx)
+	}
+
+	m = Update(t, m, KeyPress(' '))
+	m = Update(t, m, KeyPress('k'))
+	if _, _, ok := m.timeline.GetInterdiffRange(); !ok {
+		t.Error("Expected space to mark the oldest evolution")
+	}
+
+	m = Update(t, m, KeyPress('j'))
+	m = Update(t, m, KeyPress(' '))
+	m = Update(t, m, KeyPress('k'))
+	if _, _, ok := m.timeline.GetInterdiffRange(); ok {
+		t.Error("Expected a second space to clear the mark")
+	}
+
+	m = Update(t, m, KeyPress('q'))
+	Assert(t, m).NoModalsVisible()
+
+	if m.diffSource != base {
+		t.Errorf("Expected closing the timeline to keep the diff, got %T", m.diffSource)
+	}
+}
+
+// TestOpLogAndRedoStack tests that the op log opens, that the current operation cannot be restored
+// onto itself, and that the redo stack follows undo, redo, and any other repository change.
+func TestOpLogAndRedoStack(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+
+	m = Update(t, m, opLogLoadedMsg{entries: []jj.OpLogEntry{{ID: "bbbb"}, {ID: "aaaa"}}})
+	if !m.opLog.IsVisible() {
+		t.Fatal("Expected op log to be visible")
+	}
+
+	newModel, cmd := m.Update(SpecialKey(tea.KeyEnter))
+	m = assertModel(t, newModel)
+
+	if cmd != nil || !m.opLog.IsVisible() {
+		t.Error("Expected enter on the current operation to do nothing")
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEsc))
+	Assert(t, m).NoModalsVisible()
+
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.notice != "Nothing to redo" {
+		t.Errorf("Expected nothing-to-redo notice, got %q", m.notice)
+	}
+
+	m = Update(t, m, operationRestoredMsg{notice: "Undid operation bbbb", redoOps: []string{"bbbb"}})
+	m.commands.cancelAll()
+
+	if m.notice != "Undid operation bbbb" || len(m.redoOps) != 1 {
+		t.Errorf("Expected one redoable operation, got %q and %v", m.notice, m.redoOps)
+	}
+
+	m = Update(t, m, repoChangedMsg{notice: "Moved changes to @-"})
+	m.commands.cancelAll()
+
+	if len(m.redoOps) != 0 {
+		t.Errorf("Expected a new operation to clear the redo stack, got %v", m.redoOps)
+	}
+}
+
+// TestOpLogKeys tests that enter on an older operation restores it, and that u and ctrl+r close the
+// op log and undo or redo from inside it.
+func TestOpLogKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		keys []tea.KeyMsg
+	}{
+		"enter restores": {keys: []tea.KeyMsg{KeyPress('j'), KeyPress('j'), SpecialKey(tea.KeyEnter)}},
+		"u undoes":       {keys: []tea.KeyMsg{KeyPress('u')}},
+		"ctrl+r redoes":  {keys: []tea.KeyMsg{{Type: tea.KeyCtrlR}}},
+	}
+
+	for name, tt := range tests {
+		m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+		m.redoOps = []string{"cccc"}
+		m = Update(t, m, opLogLoadedMsg{entries: []jj.OpLogEntry{{ID: "bbbb"}, {ID: "aaaa"}}})
+
+		for _, key := range tt.keys {
+			m = Update(t, m, key)
+		}
+
+		if m.opLog.IsVisible() {
+			t.Errorf("%s: expected the op log to close", name)
+		}
+
+		if !m.commands.isRunning(commandRestore) {
+			t.Errorf("%s: expected a restore to start", name)
+		}
+
+		m.commands.cancelAll()
+	}
+}
+
+// TestSplitApplied tests that the split assignment modal opens with the loaded revisions, and that a
+// split that succeeded clears the spent split state and keeps the span of operations for u.
+func TestSplitApplied(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m.multiSplitState.Active = true
+	m.multiSplitState.Selections['A'] = diff.NewSelection()
+	m.multiSplitState.Selections['A'].ToggleHunk("file1.txt", 0)
+
+	m = Update(t, m, splitRevisionsLoadedMsg{revisions: []jj.RevisionEntry{{ChangeID: "pppppppp"}}})
+	if !m.splitAssign.IsVisible() {
+		t.Fatal("Expected the split assignment modal to open")
+	}
+
+	m.splitAssign.Hide()
+	m.splitPreview.Show()
+
+	span := operationSpan{before: "aaaa", after: "cccc"}
+	m = Update(t, m, splitAppliedMsg{changed: repoChangedMsg{notice: "Applied 1 split plans", span: span}})
+	m.commands.cancelAll()
+
+	Assert(t, m).NoModalsVisible()
+
+	if m.multiSplitState.Active || len(m.multiSplitState.Selections) != 0 {
+		t.Errorf("Expected the split state cleared, got %+v", m.multiSplitState)
+	}
+
+	if m.notice != "Applied 1 split plans" || m.undoSpan != span {
+		t.Errorf("Expected the notice and undo span kept, got %q and %+v", m.notice, m.undoSpan)
+	}
+
+	m = Update(t, m, operationRestoredMsg{notice: "Undid operations back to aaaa", redoOps: []string{"cccc"}})
+	m.commands.cancelAll()
+
+	if m.undoSpan != (operationSpan{}) {
+		t.Errorf("Expected an undo to drop the span, got %+v", m.undoSpan)
+	}
+}
+
+// TestDryRunExportsPatches tests that a dry run hands back the patch a would have moved, or one patch
+// per split tag, and quits without starting a jj command.
+func TestDryRunExportsPatches(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges()).WithDestination("pppppppp").WithDryRun()
+	m.selection.ToggleHunk("file2.txt", 0)
+
+	newModel, cmd := m.Update(KeyPress('a'))
+	m = assertModel(t, newModel)
+
+	if m.commands.isRunning(commandMove) {
+		t.Fatal("Expected a dry run not to start a move")
+	}
+
+	if cmd == nil {
+		t.Fatal("Expected a to export the patch")
+	}
+
+	newModel, cmd = m.Update(cmd())
+	m = assertModel(t, newModel)
+
+	exported := m.ExportedPatches()
+	if len(exported) != 1 || exported[0].Destination != "pppppppp" || exported[0].Tag != 0 {
+		t.Fatalf("Expected one patch for pppppppp, got %+v", exported)
+	}
+
+	if !strings.Contains(exported[0].Patch, "+first line") || strings.Contains(exported[0].Patch, "file1.txt") {
+		t.Errorf("Expected only the selected hunk in the patch, got:\n%s", exported[0].Patch)
+	}
+
+	if cmd == nil {
+		t.Fatal("Expected the dry run to quit")
+	}
+
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("Expected the dry run to quit once the patch was exported")
+	}
+
+	split := NewTestModel(t, ModeInteractive).WithChanges(TestChanges()).WithDryRun()
+	split.multiSplitState.Selections['B'] = diff.NewSelection()
+	split.multiSplitState.Selections['B'].ToggleHunk("file1.txt", 0)
+	split.multiSplitState.Selections['A'] = diff.NewSelection()
+	split.multiSplitState.Selections['A'].ToggleHunk("file2.txt", 0)
+	split.splitAssign.SetTags([]splitassign.SplitTag{'A', 'B'})
+	split.splitAssign.AssignNewCommitToTag('A', "add file2")
+	split.splitAssign.AssignNewCommitToTag('B', "edit file1")
+
+	msg, ok := split.applySplit()().(patchesExportedMsg)
+	if !ok {
+		t.Fatal("Expected a dry-run split to export its patches")
+	}
+
+	if len(msg.patches) != 2 || msg.patches[0].Tag != 'A' || msg.patches[1].Tag != 'B' {
+		t.Fatalf("Expected one patch per tag in tag order, got %+v", msg.patches)
+	}
+
+	if msg.patches[0].Destination != "new commit: add file2" {
+		t.Errorf("Expected the new commit's description as the destination, got %q", msg.patches[0].Destination)
+	}
+
+	if split.commands.isRunning(commandSplit) {
+		t.Error("Expected a dry run not to start a split")
+	}
+}
+
+// TestPatchSourceAppliesToRevisions tests that a patch opened from outside the repository can pick a
+// destination and be applied in interactive mode, but cannot be split, having no revision to split.
+func TestPatchSourceAppliesToRevisions(t *testing.T) {
+	t.Parallel()
+
+	source := &diff.PatchSource{Text: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-old\n+new\n"}
+
+	m, err := NewModelWithSource(source, jj.NewClient(t.TempDir()), "", ModeInteractive, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	m = m.WithChanges(diff.Parse(source.Text))
+
+	picking := Update(t, m, KeyPress('d'))
+	if !picking.commands.isRunning(commandLoadRevisions) {
+		t.Error("Expected d to load revisions for a patch's destination")
+	}
+	picking.commands.cancelAll()
+
+	m = m.WithDestination("pppppppp")
+	m.selection.ToggleHunk("a.txt", 0)
+	m.focusedPanel = PanelDiffView
+
+	applying := Update(t, m, KeyPress('a'))
+	if !applying.commands.isRunning(commandMove) {
+		t.Error("Expected a to apply the patch's selected hunks")
+	}
+	applying.commands.cancelAll()
+
+	if splitting := m.toggleMultiSplit(); splitting.multiSplitState.Active {
+		t.Error("Expected a patch not to offer a multi-way split")
+	}
+}
+
+// TestMoveReported tests that a move that did not apply cleanly opens the report over the reloaded
+// diff, that enter keeps the move, and that r rolls it back through the undo path.
+func TestMoveReported(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m.destination = "pppppppp"
+
+	report := diff.ApplyReport{Hunks: []diff.HunkResult{
+		{Path: "file1.txt", Status: diff.HunkShifted, Offset: 3},
+		{
+			Path:   "file2.txt",
+			Status: diff.HunkRejected,
+			Err:    &diff.HunkError{Path: "file2.txt", Err: diff.ErrHunkMismatch},
+		},
+	}}
+	span := operationSpan{before: "aaaa", after: "cccc"}
+
+	m = Update(t, m, moveReportedMsg{
+		report:  report,
+		changed: repoChangedMsg{notice: "Moved 1 of 2 hunks to pppppppp", span: span},
+	})
+	m.commands.cancelAll()
+
+	if !m.applyReport.IsVisible() {
+		t.Fatal("Expected the apply report to open")
+	}
+
+	if m.undoSpan != span {
+		t.Errorf("Expected the move's span kept for rolling back, got %+v", m.undoSpan)
+	}
+
+	if view := m.applyReport.View(120, 40); !strings.Contains(view, "1 shifted, 1 rejected") {
+		t.Errorf("Expected the report to count shifted and rejected hunks, got:\n%s", view)
+	}
+
+	kept := Update(t, m, SpecialKey(tea.KeyEnter))
+	Assert(t, kept).NoModalsVisible()
+
+	if kept.commands.isRunning(commandRestore) {
+		t.Error("Expected enter to keep the move without restoring")
+	}
+
+	rolledBack := Update(t, m, KeyPress('r'))
+	if !rolledBack.commands.isRunning(commandRestore) {
+		t.Error("Expected r to start restoring the operation before the move")
+	}
+	rolledBack.commands.cancelAll()
+
+	Assert(t, rolledBack).NoModalsVisible()
+}
+
+// TestApplyReportKeys tests that the report ignores keys other than its own, so a stray j cannot
+// dismiss it, and that the quit key keeps the move as enter does.
+func TestApplyReportKeys(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m.destination = "pppppppp"
+	m = Update(t, m, moveReportedMsg{
+		report:  diff.ApplyReport{Hunks: []diff.HunkResult{{Path: "file1.txt", Status: diff.HunkShifted}}},
+		changed: repoChangedMsg{notice: "Moved 1 of 1 hunks to pppppppp"},
+	})
+	m.commands.cancelAll()
+
+	m = Update(t, m, KeyPress('j'))
+	if !m.applyReport.IsVisible() {
+		t.Fatal("Expected j to leave the report open")
+	}
+
+	m = Update(t, m, KeyPress('q'))
+	Assert(t, m).NoModalsVisible()
+
+	if m.commands.isRunning(commandRestore) {
+		t.Error("Expected the quit key to keep the move without restoring")
+	}
+}
+
+// TestStrictMoveOffersPartial checks that a move that failed on a hunk points at the partial move,
+// and that alt+a asks for one.
+func TestStrictMoveOffersPartial(t *testing.T) {
+	t.Parallel()
+
+	var recording bytes.Buffer
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges()).WithRecorder(session.NewRecorder(&recording))
+	rejected := &diff.PatchError{Hunks: []*diff.HunkError{
+		{Path: "file1.txt", Err: diff.ErrHunkMismatch},
+	}}
+
+	if err := m.moveError("move", rejected, false); !strings.Contains(err.Error(), "Alt-a or :apply partial") {
+		t.Errorf("Expected a strict move's failure to offer the partial move, got %q", err)
+	}
+
+	if err := m.moveError("move", rejected, true); strings.Contains(err.Error(), "apply partial") {
+		t.Errorf("Expected a partial move's failure to offer nothing more, got %q", err)
+	}
+
+	if err := m.moveError("move", errNoSelection, false); strings.Contains(err.Error(), "apply partial") {
+		t.Errorf("Expected a failure unrelated to a hunk to offer nothing, got %q", err)
+	}
+
+	m.destination = "pppppppp"
+	m.selection.ToggleHunk("file1.txt", 0)
+
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a"), Alt: true})
+	m.commands.cancelAll()
+
+	if got := recordedCommands(t, recording.String()); !strings.HasSuffix(got, "apply partial") {
+		t.Errorf("Expected alt+a to run apply partial, got %q", got)
+	}
+}
+
+// TestPreflightMarksHunks checks that a pre-flight result marks each hunk in the diff view and each
+// file in the file list, and that a result for another destination is dropped.
+func TestPreflightMarksHunks(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges()).WithDestination("pppppppp")
+	m = Update(t, m, tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	report := diff.ApplyReport{Hunks: []diff.HunkResult{
+		{Path: "file1.txt", Index: 0, Status: diff.HunkApplied},
+		{
+			Path:   "file1.txt",
+			Index:  1,
+			Status: diff.HunkRejected,
+			Err:    &diff.HunkError{Path: "file1.txt", Index: 1, Err: diff.ErrHunkMismatch},
+		},
+		{Path: "file2.txt", Index: 0, Status: diff.HunkShifted, Offset: 2},
+	}}
+
+	stale := Update(t, m, preflightCheckedMsg{destination: "qqqqqqqq", report: report})
+	if view := stale.View(); strings.Contains(view, "[clean]") {
+		t.Errorf("Expected a result for another destination to be dropped, got:\n%s", view)
+	}
+
+	m = Update(t, m, preflightCheckedMsg{destination: "pppppppp", report: report})
+
+	view := m.View()
+	for _, want := range []string{"[clean]", "[conflicting]"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the diff view to mark a hunk %s, got:\n%s", want, view)
+		}
+	}
+
+	if status, ok := m.preflight.fileStatus("file1.txt"); !ok || status != diff.HunkRejected {
+		t.Errorf("Expected file1.txt to take its worst hunk's status, got %v (%v)", status, ok)
+	}
+
+	if _, ok := m.preflight.fileStatus("file3.txt"); ok {
+		t.Error("Expected no status for a file the check did not report")
+	}
+
+	failed := Update(t, m, preflightCheckedMsg{destination: "pppppppp", err: diff.ErrFileMissing})
+	if failed.preflight.matches("pppppppp", "") {
+		t.Error("Expected a failed check to clear the markers")
+	}
+
+	if !strings.Contains(failed.notice, "Pre-flight check failed") {
+		t.Errorf("Expected the failure in the status bar, got %q", failed.notice)
+	}
+
+	Assert(t, failed).HasNoError()
+}
+
+// TestTypedDestinationChecksPreflight checks that :dest starts a pre-flight check, as a pick in the
+// destination picker does through its reload.
+func TestTypedDestinationChecksPreflight(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+
+	m, cmd := m.runTypedCommand(command.SetDestination{Revision: "pppppppp"})
+	if cmd == nil || !m.commands.isRunning(commandPreflight) {
+		t.Error("Expected :dest to start a pre-flight check")
+	}
+
+	m.commands.cancelAll()
+}
+
+// TestPreflightSkipsNonRevisionSources checks that an interdiff, whose label is not a revision, is
+// never checked against the destination left over from the revision on screen before it.
+func TestPreflightSkipsNonRevisionSources(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithDestination("pppppppp")
+	m.setDiffSource(diff.NewInterdiffSource(m.client, "1111111111111111", "3333333333333333"))
+
+	newModel, cmd := m.Update(diffLoadedMsg{text: twoFilePatch, changes: diff.Parse(twoFilePatch)})
+	loaded := assertModel(t, newModel)
+
+	if cmd != nil || loaded.commands.isRunning(commandPreflight) {
+		t.Errorf("Expected no pre-flight check of %q", loaded.source)
+	}
+}
+
+// TestViewMarksSelectedHunks checks that the selection reaches the diff view when it renders.
+func TestViewMarksSelectedHunks(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m = Update(t, m, tea.WindowSizeMsg{Width: 120, Height: 40})
+	m.selection.ToggleHunk("file1.txt", 0)
+
+	if view := m.View(); !strings.Contains(view, "[X]") {
+		t.Errorf("Expected the selected hunk to be marked, got:\n%s", view)
+	}
+}
+
+// conflictedChanges is a clean file followed by a file with two jj conflicts in separate hunks.
+func conflictedChanges() []diff.FileChange {
+	return diff.Parse(`diff --git a/clean.txt b/clean.txt
+--- a/clean.txt
++++ b/clean.txt
+@@ -1,1 +1,2 @@
+ one
++two
+diff --git a/conflicted.txt b/conflicted.txt
+--- a/conflicted.txt
++++ b/conflicted.txt
+@@ -1,1 +1,5 @@
+ top
++<<<<<<< Conflict 1 of 2
++ours
++>>>>>>> Conflict 1 of 2 ends
+@@ -20,1 +24,5 @@
+ bottom
++<<<<<<< Conflict 2 of 2
++theirs
++>>>>>>> Conflict 2 of 2 ends
+`)
+}
+
+// TestConflictNavigation tests that ]x and [x jump between conflicts across files, wrapping at the
+// ends, and that a lone ] still steps to the next file.
+func TestConflictNavigation(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(conflictedChanges())
+	m.focusedPanel = PanelDiffView
+
+	m = Update(t, m, KeyPress(']'))
+	m = Update(t, m, KeyPress('x'))
+	Assert(t, m).HasSelectedFile(1)
+	Assert(t, m).HasSelectedHunk(0)
+	Assert(t, m).HasLineCursor(1)
+
+	m = Update(t, m, KeyPress(']'))
+	m = Update(t, m, KeyPress('x'))
+	Assert(t, m).HasSelectedFile(1)
+	Assert(t, m).HasSelectedHunk(1)
+
+	m = Update(t, m, KeyPress(']'))
+	m = Update(t, m, KeyPress('x'))
+	Assert(t, m).HasSelectedHunk(0)
+
+	m = Update(t, m, KeyPress('['))
+	m = Update(t, m, KeyPress('x'))
+	Assert(t, m).HasSelectedHunk(1)
+
+	m = Update(t, m, KeyPress('['))
+	Assert(t, m).HasSelectedFile(0)
+	m = Update(t, m, KeyPress('j'))
+	m = Update(t, m, KeyPress('x'))
+	Assert(t, m).HasSelectedFile(0)
+}
+
+// TestConflictList tests that the list counts each file's conflicts from the loaded diff and that
+// enter lands on the file's first conflict.
+func TestConflictList(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(conflictedChanges())
+	m = Update(t, m, conflictsLoadedMsg{files: []jj.ConflictedFile{
+		{Path: "conflicted.txt", Description: "2-sided conflict"},
+		{Path: "elsewhere.txt", Description: "2-sided conflict"},
+	}})
+
+	if !m.conflictList.IsVisible() {
+		t.Fatal("Expected conflict list to be visible")
+	}
+
+	if entry := m.conflictList.GetSelected(); entry == nil || entry.Count != 2 {
+		t.Fatalf("Expected conflicted.txt with 2 conflicts, got %+v", entry)
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEnter))
+	Assert(t, m).NoModalsVisible()
+	Assert(t, m).HasSelectedFile(1)
+	Assert(t, m).FocusedPanelIs(PanelDiffView)
+	Assert(t, m).HasLineCursor(1)
+}
+
+// TestConflictListKeys tests that j and k stop at the ends of the list, that enter on a file the diff
+// does not show says so, and that the quit key closes the list where it was.
+func TestConflictListKeys(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(conflictedChanges())
+	m = Update(t, m, conflictsLoadedMsg{files: []jj.ConflictedFile{
+		{Path: "conflicted.txt", Description: "2-sided conflict"},
+		{Path: "elsewhere.txt", Description: "2-sided conflict"},
+	}})
+
+	m = Update(t, m, KeyPress('k'))
+	if entry := m.conflictList.GetSelected(); entry.Path != "conflicted.txt" {
+		t.Errorf("Expected k on the first file to stay put, got %s", entry.Path)
+	}
+
+	m = Update(t, m, KeyPress('j'))
+	m = Update(t, m, KeyPress('j'))
+	if entry := m.conflictList.GetSelected(); entry.Path != "elsewhere.txt" {
+		t.Errorf("Expected j on the last file to stay put, got %s", entry.Path)
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEnter))
+	Assert(t, m).NoModalsVisible()
+	Assert(t, m).HasSelectedFile(0)
+
+	if m.notice != "elsewhere.txt is not in this diff" {
+		t.Errorf("Expected a notice for a file outside the diff, got %q", m.notice)
+	}
+
+	m = Update(t, m, conflictsLoadedMsg{files: []jj.ConflictedFile{{Path: "conflicted.txt"}}})
+	m = Update(t, m, SpecialKey(tea.KeyEsc))
+	Assert(t, m).NoModalsVisible()
+	Assert(t, m).HasSelectedFile(0)
+}
+
+// refreshBefore has two hunks in a.txt. refreshAfter is the same diff after a change landed above
+// both, so each hunk keeps its changed lines but moves down one index.
+const (
+	refreshBefore = `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -10,3 +10,4 @@
+ ten
++eleven
++twelve
+ thirteen
+@@ -40,3 +41,3 @@
+ forty
+-old
++new
+ forty two
+`
+	refreshAfter = `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,3 @@
+ one
++inserted
+ two
+@@ -10,3 +11,4 @@
+ ten
++eleven
++twelve
+ thirteen
+@@ -40,3 +42,3 @@
+ forty
+-old
++new
+ forty two
+`
+)
+
+// TestBackgroundRefresh tests that a refresh keeps the cursor and the selection on the hunks they were
+// on, flags the change in the status bar until the next key, and ignores a refresh that changed
+// nothing.
+func TestBackgroundRefresh(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive)
+	m = Update(t, m, diffLoadedMsg{text: refreshBefore, changes: diff.Parse(refreshBefore)})
+
+	m.focusedPanel = PanelDiffView
+	m.selectedHunk = 1
+	m.lineCursor = 2
+	m.selection.ToggleHunk("a.txt", 1)
+	m.selection.ToggleLine("a.txt", 0, 2)
+
+	m = Update(t, m, diffLoadedMsg{text: refreshBefore, changes: diff.Parse(refreshBefore), refresh: true})
+	if m.refreshed {
+		t.Error("Expected an unchanged refresh to leave no marker")
+	}
+
+	m = Update(t, m, diffLoadedMsg{text: refreshAfter, changes: diff.Parse(refreshAfter), refresh: true})
+
+	Assert(t, m).HasSelectedHunk(2)
+	Assert(t, m).HasLineCursor(2)
+	Assert(t, m).HasHunkSelected("a.txt", 2)
+	Assert(t, m).HasHunkNotSelected("a.txt", 1)
+
+	if !m.selection.IsLineSelected("a.txt", 1, 2) || m.selection.IsLineSelected("a.txt", 1, 1) {
+		t.Error("Expected the selected line to follow its hunk")
+	}
+
+	if !m.refreshed {
+		t.Fatal("Expected a changed refresh to raise the marker")
+	}
+
+	m = Update(t, m, KeyPress('j'))
+	if m.refreshed {
+		t.Error("Expected the next key to clear the marker")
+	}
+}
+
+// TestRefreshWaitsForRunningLoad tests that a refresh arriving while a load is in flight is held
+// until that load finishes, so the two never race.
+func TestRefreshWaitsForRunningLoad(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewModelWithSource(blockingSource{}, nil, "", ModeBrowse, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	first := m.Init()
+	m = Update(t, m, watcher.RefreshMsg{})
+
+	if !m.refreshQueued {
+		t.Fatal("Expected the refresh to be queued behind the running load")
+	}
+
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeyCtrlG})
+	m = Update(t, m, first())
+
+	if m.refreshQueued || !m.commands.isRunning(commandLoadDiff) {
+		t.Error("Expected the queued refresh to start once the load finished")
+	}
+
+	m.commands.cancelAll()
+}
+
+// smartlogLines is the graph jj log draws for a working copy on top of a bookmarked change, with
+// the edge line between them.
+func smartlogLines() []jj.LogLine {
+	return []jj.LogLine{
+		{Graph: "@", Entry: &jj.LogEntry{ChangeID: "wwwwwwww", IsWorkingCopy: true, Empty: true}},
+		{Graph: "│"},
+		{Graph: "○", Entry: &jj.LogEntry{ChangeID: "pppppppp", Bookmarks: []string{"feature"}}},
+		{Graph: "~"},
+	}
+}
+
+// TestSmartlog tests that the cursor skips the graph's edge lines, that enter makes the picked change
+// the revision on screen, and that d only sets a destination in interactive mode.
+func TestSmartlog(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	m = Update(t, m, smartlogLoadedMsg{lines: smartlogLines()})
+
+	if !m.smartlog.IsVisible() {
+		t.Fatal("Expected the smartlog to open")
+	}
+
+	m = Update(t, m, KeyPress('j'))
+	m = Update(t, m, KeyPress('j'))
+
+	if got := m.smartlog.GetSelected(); got == nil || got.ChangeID != "pppppppp" {
+		t.Fatalf("Expected the cursor on the parent, got %+v", got)
+	}
+
+	m = Update(t, m, KeyPress('d'))
+	if !m.smartlog.IsVisible() || m.destination != "" {
+		t.Error("Expected d to leave browse mode without a destination")
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEnter))
+	m.commands.cancelAll()
+
+	Assert(t, m).NoModalsVisible()
+
+	if revSource, ok := m.baseSource.(*diff.RevisionSource); !ok || revSource.Revision != "pppppppp" {
+		t.Errorf("Expected the picked change as the session's revision, got %+v", m.baseSource)
+	}
+
+	if m.source != "pppppppp" {
+		t.Errorf("Expected the source label to follow, got %q", m.source)
+	}
+
+	m = NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m = Update(t, m, smartlogLoadedMsg{lines: smartlogLines()})
+	m = Update(t, m, KeyPress('j'))
+
+	newModel, cmd := m.Update(KeyPress('d'))
+	m = assertModel(t, newModel)
+	m = Update(t, m, cmd())
+	m.commands.cancelAll()
+
+	Assert(t, m).HasDestination("pppppppp")
+}
+
+// TestSmartlogKeys tests that n and e close the smartlog and run jj new or jj edit on the picked
+// change, and that the quit key closes it without acting.
+func TestSmartlogKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		want commandKind
+		key  rune
+	}{
+		"n creates a change": {key: 'n', want: commandNew},
+		"e edits the change": {key: 'e', want: commandEdit},
+	}
+
+	for name, tt := range tests {
+		m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+		m = Update(t, m, smartlogLoadedMsg{lines: smartlogLines()})
+		m = Update(t, m, KeyPress('j'))
+		m = Update(t, m, KeyPress(tt.key))
+
+		if m.smartlog.IsVisible() {
+			t.Errorf("%s: expected the smartlog to close", name)
+		}
+
+		if !m.commands.isRunning(tt.want) {
+			t.Errorf("%s: expected the command to start", name)
+		}
+
+		m.commands.cancelAll()
+	}
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	m = Update(t, m, smartlogLoadedMsg{lines: smartlogLines()})
+	m = Update(t, m, KeyPress('q'))
+	Assert(t, m).NoModalsVisible()
+
+	if m.commands.isRunning(commandNew) || m.commands.isRunning(commandEdit) {
+		t.Error("Expected the quit key to close the smartlog without acting")
+	}
+}
+
+// typeCommand opens the : prompt, types text a key at a time, and presses enter.
+func typeCommand(t *testing.T, m Model, text string) Model {
+	t.Helper()
+
+	m = Update(t, m, KeyPress(':'))
+	for _, char := range text {
+		m = Update(t, m, KeyPress(char))
+	}
+
+	return Update(t, m, SpecialKey(tea.KeyEnter))
+}
+
+// TestCommandLine tests that commands typed at the : prompt select, tag, and move as their keys do,
+// and that a refused or unknown command says why in the status bar.
+func TestCommandLine(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m = Update(t, m, KeyPress(':'))
+
+	if !m.commandLine.IsVisible() || !strings.Contains(m.renderStatusBar(), ":") {
+		t.Fatal("Expected : to open the prompt in the status bar")
+	}
+
+	m = Update(t, m, KeyPress('?'))
+	if m.help.IsVisible() || m.commandLine.Value() != "?" {
+		t.Error("Expected ? to be typed into the prompt rather than open help")
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEsc))
+	Assert(t, m).NoModalsVisible()
+
+	m = typeCommand(t, m, "select file2.txt")
+	Assert(t, m).NoModalsVisible()
+	Assert(t, m).HasHunkSelected("file2.txt", 0)
+	Assert(t, m).HasHunkNotSelected("file1.txt", 0)
+
+	m = typeCommand(t, m, "tag b")
+	if !m.multiSplitState.Active || !m.multiSplitState.Selections['B'].IsHunkSelected("file1.txt", 0) {
+		t.Errorf("Expected :tag b to start the split and tag the current hunk, got %+v", m.multiSplitState)
+	}
+
+	m = typeCommand(t, m, "apply")
+	if !strings.Contains(m.notice, "no destination") {
+		t.Errorf("Expected :apply without a destination to say so, got %q", m.notice)
+	}
+
+	m = typeCommand(t, m, "move @--")
+	Assert(t, m).HasDestination("@--")
+
+	if !m.commands.isRunning(commandMove) {
+		t.Error("Expected :move to start the move")
+	}
+
+	m.commands.cancelAll()
+
+	m = typeCommand(t, m, "frobnicate")
+	if !strings.Contains(m.notice, "unknown command: frobnicate") {
+		t.Errorf("Expected an unknown command to be named, got %q", m.notice)
+	}
+
+	browse := typeCommand(t, NewTestModel(t, ModeBrowse).WithChanges(TestChanges()), "toggle")
+	if !strings.HasPrefix(browse.notice, "toggle: ") {
+		t.Errorf("Expected browse mode to refuse :toggle, got %q", browse.notice)
+	}
+}
+
+// TestCommandLineBackspace tests that backspace edits the prompt and closes it once it is empty.
+func TestCommandLineBackspace(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	m = Update(t, m, KeyPress(':'))
+	m = Update(t, m, KeyPress('G'))
+	m = Update(t, m, SpecialKey(tea.KeyBackspace))
+
+	if !m.commandLine.IsVisible() || m.commandLine.Value() != "" {
+		t.Fatalf("Expected backspace to delete the G, got %q", m.commandLine.Value())
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyBackspace))
+	Assert(t, m).NoModalsVisible()
+	Assert(t, m).HasSelectedFile(0)
+}
+
+// TestCommandLineInput tests that the prompt takes a space from either form Bubble Tea sends it in
+// and pasted text whole, and that keys which type nothing, such as tab and arrows, leave it alone.
+func TestCommandLineInput(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeBrowse).WithChanges(TestChanges())
+	m = Update(t, m, KeyPress(':'))
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("jump")})
+	m = Update(t, m, SpecialKey(tea.KeySpace))
+	m = Update(t, m, SpecialKey(tea.KeyTab))
+	m = Update(t, m, SpecialKey(tea.KeyDown))
+	m = Update(t, m, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = Update(t, m, KeyPress('2'))
+
+	if m.commandLine.Value() != "jump  2" {
+		t.Errorf("Expected only the typed text in the prompt, got %q", m.commandLine.Value())
+	}
+
+	Assert(t, m).HasSelectedFile(0)
+}
+
+// TestCommandMsg tests that a command sent through Update runs as its key would.
+func TestCommandMsg(t *testing.T) {
+	t.Parallel()
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(TestChanges())
+	m = Update(t, m, command.Msg{Command: command.JumpToFile{Index: -1}})
+	Assert(t, m).HasSelectedFile(len(TestChanges()) - 1)
+
+	m = Update(t, m, command.Msg{Command: command.SetDestination{Revision: "pppppppp"}})
+	Assert(t, m).HasDestination("pppppppp")
+
+	m = Update(t, m, command.Msg{Command: command.ToggleSelection{}})
+	if !strings.HasPrefix(m.notice, "toggle: ") {
+		t.Errorf("Expected toggling from the file list to be refused, got %q", m.notice)
+	}
+}
+
+const twoFilePatch = "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-old\n+new\n" +
+	"diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-before\n+after\n"
+
+// patchModel opens text as a patch in interactive dry-run mode, so a replay can apply without jj.
+func patchModel(t *testing.T, text string) Model {
+	t.Helper()
+
+	source := &diff.PatchSource{Text: text}
+
+	m, err := NewModelWithSource(source, jj.NewClient(t.TempDir()), "", ModeInteractive, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	return m.WithDryRun()
+}
+
+// TestRecordAndReplay tests that keys and typed commands are recorded against the diff they ran on,
+// and that replaying the recording headlessly reaches the same selection.
+func TestRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	var recording bytes.Buffer
+
+	m := patchModel(t, twoFilePatch).WithRecorder(session.NewRecorder(&recording))
+	m = Update(t, m, diffLoadedMsg{text: twoFilePatch, changes: diff.Parse(twoFilePatch)})
+	m = Update(t, m, KeyPress('G'))
+	m = Update(t, m, SpecialKey(tea.KeyTab))
+	m = Update(t, m, KeyPress(' '))
+	m = Update(t, m, KeyPress('x'))
+	m = typeCommand(t, m, "dest pppppppp")
+	m = typeCommand(t, m, "tag A")
+
+	m.commands.cancelAll()
+
+	lines := strings.Split(strings.TrimSpace(recording.String()), "\n")
+	want := []string{"last-file", "focus diff", "toggle", "dest pppppppp"}
+
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d recorded commands, got:\n%s", len(want), recording.String())
+	}
+
+	for i, line := range lines {
+		wantLine := `{"command":"` + want[i] + `","diff":"` + session.Fingerprint(twoFilePatch) + `"}`
+		if line != wantLine {
+			t.Errorf("Line %d = %s, want %s", i+1, line, wantLine)
+		}
+	}
+
+	steps, err := session.Read(strings.NewReader(recording.String() + `{"command":"apply","diff":"` +
+		session.Fingerprint(twoFilePatch) + `"}` + "\n"))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	final, err := RunHeadless(patchModel(t, twoFilePatch).WithReplay(steps, true))
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	exported := final.ExportedPatches()
+	if len(exported) != 1 || exported[0].Destination != "pppppppp" ||
+		!strings.Contains(exported[0].Patch, "+after") || strings.Contains(exported[0].Patch, "+new") {
+		t.Errorf("Expected the replay to export only b.txt's hunk to pppppppp, got %+v", exported)
+	}
+
+	_, err = RunHeadless(patchModel(t, strings.ReplaceAll(twoFilePatch, "after", "later")).WithReplay(steps, true))
+	if !errors.Is(err, session.ErrMismatch) || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected a changed diff to stop the replay at line 1, got %v", err)
+	}
+}
+
+// TestRecordCursorJumps tests that a search jump is recorded as the cursor position it reached, and
+// that the split assignment modal and the split preview record the commands they run, so a replay
+// acts on the same hunks.
+func TestRecordCursorJumps(t *testing.T) {
+	t.Parallel()
+
+	var recording bytes.Buffer
+
+	m := patchModel(t, twoFilePatch).WithRecorder(session.NewRecorder(&recording))
+	m = Update(t, m, diffLoadedMsg{text: twoFilePatch, changes: diff.Parse(twoFilePatch)})
+	m = Update(t, m, SpecialKey(tea.KeyTab))
+	m = Update(t, m, KeyPress('/'))
+
+	for _, char := range "after" {
+		m = Update(t, m, KeyPress(char))
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEnter))
+	m = Update(t, m, KeyPress(' '))
+	m = typeCommand(t, m, "dest pppppppp")
+
+	want := "focus diff\ngoto 2 1 1\ntoggle\ndest pppppppp"
+	if got := recordedCommands(t, recording.String()); got != want {
+		t.Fatalf("Expected the search jump recorded as a goto, got %q", got)
+	}
+
+	steps, err := session.Read(strings.NewReader(recording.String() + `{"command":"apply","diff":"` +
+		session.Fingerprint(twoFilePatch) + `"}` + "\n"))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	final, err := RunHeadless(patchModel(t, twoFilePatch).WithReplay(steps, true))
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	if exported := final.ExportedPatches(); len(exported) != 1 || !strings.Contains(exported[0].Patch, "+after") ||
+		strings.Contains(exported[0].Patch, "+new") {
+		t.Errorf("Expected the replay to select the hunk the search found, got %+v", exported)
+	}
+
+	recording.Reset()
+
+	split := NewTestModel(t, ModeInteractive).WithChanges(TestChanges()).WithDryRun().
+		WithRecorder(session.NewRecorder(&recording))
+	split = typeCommand(t, split, "tag A")
+	split.commitMsg.SetTag('A')
+	split.commitMsg.Show()
+
+	for _, char := range "add it" {
+		split = Update(t, split, KeyPress(char))
+	}
+
+	split = Update(t, split, SpecialKey(tea.KeyEnter))
+	split.splitAssign.Hide()
+	split.splitPreview.Show()
+
+	_, cmd := split.Update(SpecialKey(tea.KeyEnter))
+	if _, ok := cmd().(patchesExportedMsg); !ok {
+		t.Error("Expected the preview to apply the split")
+	}
+
+	want = "tag A\nassign A new add it\nsplit"
+	if got := recordedCommands(t, recording.String()); got != want {
+		t.Errorf("Expected the split assignment and preview recorded, got %q", got)
+	}
+}
+
+// recordedCommands lists the commands of a recording, one per line.
+func recordedCommands(t *testing.T, recording string) string {
+	t.Helper()
+
+	steps, err := session.Read(strings.NewReader(recording))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	commands := make([]string, len(steps))
+	for i, step := range steps {
+		commands[i] = step.Command.String()
+	}
+
+	return strings.Join(commands, "\n")
+}
+
+// TestKeymap tests that the configured keymap drives the keys, the help overlay, and the status bar
+// hints, and that the list overlays move with the same keys as the main screen.
+func TestKeymap(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DefaultConfig()
+	cfg.Keymap = "emacs"
+	cfg.Keys = map[string][]string{"apply": {"ctrl+a"}}
+
+	m, err := NewModel(jj.NewClient(t.TempDir()), "@", "", ModeInteractive, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	m = m.WithChanges(TestChanges())
+	m.width, m.height = 120, 60
+
+	m = Update(t, m, KeyPress('j'))
+	Assert(t, m).HasSelectedFile(0)
+
+	m = Update(t, m, SpecialKey(tea.KeyCtrlN))
+	Assert(t, m).HasSelectedFile(1)
+
+	if status := m.renderStatusBar(); !strings.Contains(status, "Ctrl-N/Ctrl-P:nav") {
+		t.Errorf("Expected the status bar to hint the emacs keys, got %q", status)
+	}
+
+	m.focusedPanel = PanelDiffView
+	if status := m.renderStatusBar(); !strings.Contains(status, "Ctrl-A:apply") {
+		t.Errorf("Expected the status bar to hint the rebound apply key, got %q", status)
+	}
+
+	m = Update(t, m, KeyPress('?'))
+	if view := m.help.View(m.width, m.height); !strings.Contains(view, "Ctrl-A") || !strings.Contains(view, "Alt-x") {
+		t.Error("Expected the help to list the rebound and preset keys")
+	}
+
+	m = Update(t, m, SpecialKey(tea.KeyEsc))
+	m.smartlog.Show()
+	m = Update(t, m, KeyPress('q'))
+	Assert(t, m).NoModalsVisible()
+}
+
+// TestExpandContext checks that the expand keys read the diff editor's right tree and grow the hunk
+// under the cursor, carrying the cursor and a line selection down with the lines revealed above.
+func TestExpandContext(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	left, right := filepath.Join(base, "left"), filepath.Join(base, "right")
+
+	var oldContent, newContent strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&oldContent, "line %d\n", i)
+
+		if i == 15 {
+			newContent.WriteString("changed\n")
+		} else {
+			fmt.Fprintf(&newContent, "line %d\n", i)
+		}
+	}
+
+	for dir, content := range map[string]string{left: oldContent.String(), right: newContent.String()} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := diff.NewDirectorySource(left, right)
+	source.Context = 1
+
+	text, err := source.GetDiff(context.Background())
+	if err != nil {
+		t.Fatalf("GetDiff: %v", err)
+	}
+
+	m, err := NewModelWithSource(source, nil, "", ModeInteractive, config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	m.diffText = text
+	m = m.WithChanges(diff.Parse(text))
+	m.focusedPanel = PanelDiffView
+	m.lineCursor = 2
+	m.selection.ToggleLine("f.txt", 0, 2)
+
+	newModel, cmd := m.Update(KeyPress('{'))
+	m = assertModel(t, newModel)
+	m = Update(t, m, cmd())
+
+	hunk := m.changes[0].Hunks[0]
+	if hunk.Header != "@@ -4,13 +4,13 @@" || hunk.Lines[0].Content != "line 4" {
+		t.Fatalf("Expected ten lines revealed above, got %s from %q", hunk.Header, hunk.Lines[0].Content)
+	}
+
+	if m.lineCursor != 12 || !m.selection.IsLineSelected("f.txt", 0, 12) {
+		t.Errorf("Expected the cursor and the selection to follow the line, got cursor %d", m.lineCursor)
+	}
+
+	m = Update(t, m, KeyPress('='))
+
+	hunk = m.changes[0].Hunks[0]
+	if hunk.Header != "@@ -1,30 +1,30 @@" || m.lineCursor != 15 {
+		t.Errorf("Expected the whole file from the cached read, got %s with cursor %d", hunk.Header, m.lineCursor)
+	}
+
+	m = Update(t, m, KeyPress('}'))
+	if m.notice != "No more context to reveal" {
+		t.Errorf("Expected a notice once the hunk spans the file, got %q", m.notice)
+	}
+
+	m = m.WithChanges(TestChanges())
+	m.selectedFile = 1
+	m = typeCommand(t, m, "expand down")
+
+	if !strings.Contains(m.notice, errWholeFile.Error()) {
+		t.Errorf("Expected an added file to refuse, got %q", m.notice)
+	}
+}
+
+// TestSplitAndJoinHunk checks that splitting a hunk carries the cursor and a line selection into the
+// piece that holds them, and that joining it back keeps each piece's selection.
+func TestSplitAndJoinHunk(t *testing.T) {
+	t.Parallel()
+
+	text := "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n@@ -2,11 +2,11 @@\n" +
+		" line 2\n line 3\n line 4\n-line 5\n+FIVE\n line 6\n line 7\n line 8\n-line 9\n+NINE\n" +
+		" line 10\n line 11\n line 12\n"
+
+	m := NewTestModel(t, ModeInteractive).WithChanges(diff.Parse(text))
+	m.focusedPanel = PanelDiffView
+	m.lineCursor = 9
+	m.selection.ToggleLine("f.txt", 0, 9)
+
+	m = Update(t, m, KeyPress('|'))
+
+	if hunks := m.changes[0].Hunks; len(hunks) != 2 || hunks[1].Header != "@@ -8,5 +8,5 @@" {
+		t.Fatalf("Expected the hunk split at line 8, got %+v", hunks)
+	}
+
+	Assert(t, m).HasSelectedHunk(1)
+
+	if m.lineCursor != 2 || !m.selection.IsLineSelected("f.txt", 1, 2) {
+		t.Errorf("Expected the cursor and the selection on the second piece's addition, got cursor %d",
+			m.lineCursor)
+	}
+
+	m.selection.ToggleHunk("f.txt", 0)
+	m = Update(t, m, KeyPress('J'))
+
+	if len(m.changes[0].Hunks) != 1 || m.changes[0].Hunks[0].Header != "@@ -2,11 +2,11 @@" {
+		t.Fatalf("Expected the pieces joined back, got %+v", m.changes[0].Hunks)
+	}
+
+	Assert(t, m).HasSelectedHunk(0)
+
+	if m.lineCursor != 9 || !m.selection.IsLineSelected("f.txt", 0, 4) ||
+		!m.selection.IsLineSelected("f.txt", 0, 9) || m.selection.IsLineSelected("f.txt", 0, 8) {
+		t.Errorf("Expected the first piece's lines and the picked line selected, got %+v",
+			m.selection.Files["f.txt"].Hunks[0])
+	}
+
+	m = typeCommand(t, m, "join-hunk")
+	if !strings.Contains(m.notice, errNothingToJoin.Error()) {
+		t.Errorf("Expected a lone hunk to refuse to join, got %q", m.notice)
+	}
+}
+
+// TestReloadKeepsSplitPieceSelections tests that a reload which changed the diff carries a selection
+// and a tag on the pieces of a split hunk onto the hunk as it comes back from jj.
+func TestReloadKeepsSplitPieceSelections(t *testing.T) {
+	t.Parallel()
+
+	text := "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n@@ -2,11 +2,11 @@\n" +
+		" line 2\n line 3\n line 4\n-line 5\n+FIVE\n line 6\n line 7\n line 8\n-line 9\n+NINE\n" +
+		" line 10\n line 11\n line 12\n"
+	reloaded := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,1 +1,1 @@\n-a\n+A\n" + text
+
+	m := NewTestModel(t, ModeInteractive)
+	m = Update(t, m, diffLoadedMsg{text: text, changes: diff.Parse(text)})
+	m.focusedPanel = PanelDiffView
+	m.lineCursor = 9
+
+	m = Update(t, m, KeyPress('|'))
+	m.selection.ToggleHunk("f.txt", 1)
+	m.multiSplitState.Selections['A'] = diff.NewSelection()
+	m.multiSplitState.Selections['A'].ToggleHunk("f.txt", 0)
+
+	m = Update(t, m, diffLoadedMsg{text: reloaded, changes: diff.Parse(reloaded), refresh: true})
+
+	if m.selectedFile != 1 || m.selectedHunk != 0 || m.lineCursor != 9 {
+		t.Errorf("Expected the cursor back on NINE, got file %d hunk %d line %d",
+			m.selectedFile, m.selectedHunk, m.lineCursor)
+	}
+
+	for tag, selection := range map[string]*diff.Selection{
+		"the selection": m.selection, "tag A": m.multiSplitState.Selections['A'],
+	} {
+		var got []int
+
+		for lineIdx := range m.changes[1].Hunks[0].Lines {
+			if selection.IsLineSelected("f.txt", 0, lineIdx) {
+				got = append(got, lineIdx)
+			}
+		}
+
+		want := []int{8, 9}
+		if tag == "tag A" {
+			want = []int{3, 4}
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected %s on lines %v of the reloaded hunk, got %v", tag, want, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// preflightState is the last pre-flight check: how each hunk of the diff on screen would apply to
+// the move destination, by file path and hunk index, with each file's worst hunk alongside. It holds
+// the destination and diff text it was checked for, because it says nothing about any other.
+type preflightState struct {
+	hunks       map[string]map[int]diff.HunkStatus
+	files       map[string]diff.HunkStatus
+	destination string
+	text        string
+}
+
+func newPreflightState(msg preflightCheckedMsg) preflightState {
+	state := preflightState{
+		hunks:       make(map[string]map[int]diff.HunkStatus),
+		files:       make(map[string]diff.HunkStatus),
+		destination: msg.destination,
+		text:        msg.text,
+	}
+
+	for _, hunk := range msg.report.Hunks {
+		if state.hunks[hunk.Path] == nil {
+			state.hunks[hunk.Path] = make(map[int]diff.HunkStatus)
+		}
+
+		state.hunks[hunk.Path][hunk.Index] = hunk.Status
+
+		// Statuses are declared from best to worst, so the file takes the highest.
+		if worst, ok := state.files[hunk.Path]; !ok || hunk.Status > worst {
+			state.files[hunk.Path] = hunk.Status
+		}
+	}
+
+	return state
+}
+
+// matches reports whether the check was made for this destination and diff.
+func (s preflightState) matches(destination, text string) bool {
+	return s.hunks != nil && s.destination == destination && s.text == text
+}
+
+func (s preflightState) hunkStatus(path string, hunkIdx int) (diff.HunkStatus, bool) {
+	status, ok := s.hunks[path][hunkIdx]
+
+	return status, ok
+}
+
+func (s preflightState) fileStatus(path string) (diff.HunkStatus, bool) {
+	status, ok := s.files[path]
+
+	return status, ok
+}
+
+// checkPreflight checks in the background how every hunk would apply to the destination, so both
+// panels mark what a move would do before a is pressed rather than after it rolls back. It runs only
+// in interactive mode once a destination is set and while the diff on screen can be applied to one,
+// so never for an interdiff or an evolution, whose label is not a revision. It reads the repository
+// without writing to it, and a newer check supersedes one still running.
+func (m Model) checkPreflight() tea.Cmd {
+	if m.mode != ModeInteractive || m.client == nil || m.destination == "" || len(m.changes) == 0 ||
+		!m.appliesToRevisions() {
+		return nil
+	}
+
+	all := diff.NewSelection()
+	diff.SelectAll(m.changes, all)
+
+	patch := diff.GeneratePatch(m.changes, all)
+	if patch == "" {
+		return nil
+	}
+
+	source, destination, text, fuzz := m.source, m.destination, m.diffText, m.cfg.ApplyFuzz
+	fromPatch := m.fromPatch()
+
+	return m.commands.track(commandPreflight, func(ctx context.Context) tea.Msg {
+		var report diff.ApplyReport
+		var err error
+
+		if fromPatch {
+			report, err = m.client.CheckApply(ctx, patch, destination, fuzz)
+		} else {
+			report, err = m.client.CheckMove(ctx, patch, source, destination, fuzz)
+		}
+
+		return preflightCheckedMsg{err: err, destination: destination, text: text, report: report}
+	})
+}
+
+// applyPreflight installs a check's result. A result for a destination or diff no longer on screen
+// is dropped, and a failed check clears the markers and says why in the status bar rather than
+// taking over the screen, because nothing the user asked for failed.
+func (m Model) applyPreflight(msg preflightCheckedMsg) Model {
+	if msg.destination != m.destination || msg.text != m.diffText {
+		return m
+	}
+
+	if msg.err != nil {
+		m.preflight = preflightState{}
+		m.notice = fmt.Sprintf("Pre-flight check failed: %v", msg.err)
+
+		return m
+	}
+
+	m.preflight = newPreflightState(msg)
+
+	return m
+}
@@ -0,0 +1,368 @@
+package model
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/watcher"
+)
+
+// refreshBlockers are the commands a watcher refresh waits for. A load already running will see the
+// change, and a command rewriting the repository fires the watcher itself, so reloading mid-command
+// would show a half-applied state and mark the view as changed by someone else.
+var refreshBlockers = []commandKind{
+	commandLoadDiff,
+	commandMove,
+	commandSplit,
+	commandRestore,
+	commandNew,
+	commandEdit,
+}
+
+// WithWatcher attaches a watcher whose changes reload the diff. Init issues the first wait, and each
+// refresh issues the next.
+func (m Model) WithWatcher(w *watcher.Watcher) Model {
+	m.watcher = w
+
+	return m
+}
+
+// refresh reloads the diff because the repository changed, or queues the reload behind a command
+// that is still running.
+func (m Model) refresh() (Model, tea.Cmd) {
+	var wait tea.Cmd
+	if m.watcher != nil {
+		wait = m.watcher.Wait()
+	}
+
+	if m.commands.isRunning(refreshBlockers...) {
+		m.refreshQueued = true
+
+		return m, wait
+	}
+
+	return m, tea.Batch(wait, m.trackDiffLoad(true))
+}
+
+// runQueuedRefresh starts the refresh a running command held back, once the last blocker finishes.
+func (m Model) runQueuedRefresh(cmd tea.Cmd) (Model, tea.Cmd) {
+	if !m.refreshQueued || m.commands.isRunning(refreshBlockers...) {
+		return m, cmd
+	}
+
+	m.refreshQueued = false
+
+	return m, tea.Batch(cmd, m.trackDiffLoad(true))
+}
+
+// applyLoadedDiff installs a loaded diff. The first load starts on the first file; a reload keeps the
+// cursor on the same file, hunk, and scroll offset and carries the selection onto the new hunks. A
+// refresh that changed the diff raises the status bar marker, and one that changed nothing is
+// dropped so the cursor does not move under the user.
+func (m Model) applyLoadedDiff(msg diffLoadedMsg) Model {
+	previous := m.changes
+	firstLoad := m.diffText == "" || len(previous) == 0
+
+	if msg.refresh && !firstLoad && msg.text == m.diffText {
+		return m
+	}
+
+	m.diffText = msg.text
+	m.changes = msg.changes
+	m.contents = make(map[string]string)
+	m.fileList.SetFiles(m.changes)
+	m.refreshed = msg.refresh
+
+	if firstLoad {
+		m.selectedFile = 0
+		m.selectedHunk = 0
+		m.lineCursor = 0
+		m.fileList.SetSelected(0)
+
+		if len(m.changes) > 0 {
+			m.diffView.SetFileChange(m.changes[0])
+		}
+
+		// jj's diff editor contract is subtractive: the right side starts as the
+		// commit's full content and the user removes what should not be kept.
+		// Starting empty here would discard every change on apply.
+		if m.mode == ModeDiffEditor {
+			m.selection = diff.NewSelection()
+			diff.SelectAll(m.changes, m.selection)
+		}
+
+		return m
+	}
+
+	previous = m.joinPieces(previous, m.changes)
+	mapping := mapHunks(previous, m.changes)
+
+	m.selection = remapSelection(m.selection, previous, m.changes, mapping)
+	for tag, selection := range m.multiSplitState.Selections {
+		m.multiSplitState.Selections[tag] = remapSelection(selection, previous, m.changes, mapping)
+	}
+
+	m.restoreCursor(previous, mapping)
+
+	if m.searchState != nil && m.searchState.IsActive {
+		current := m.searchState.CurrentIdx
+		m.searchState.ExecuteSearch(m.changes)
+
+		if current < len(m.searchState.Matches) {
+			m.searchState.CurrentIdx = current
+		}
+	}
+
+	return m
+}
+
+// restoreCursor puts the file cursor back on the file it was on, by path, and the hunk cursor on the
+// hunk it was on, by content. When either is gone the index is kept and clamped instead, so the
+// cursor lands near where it was. The scroll offset is kept only when the file survived.
+func (m *Model) restoreCursor(previous []diff.FileChange, mapping hunkMapping) {
+	if len(m.changes) == 0 {
+		m.selectedFile, m.selectedHunk, m.lineCursor = 0, 0, 0
+		m.isVisualMode = false
+
+		return
+	}
+
+	path := ""
+	if m.selectedFile >= 0 && m.selectedFile < len(previous) {
+		path = previous[m.selectedFile].Path
+	}
+
+	fileIdx, sameFile := indexOfPath(m.changes, path)
+	if !sameFile {
+		fileIdx = min(max(m.selectedFile, 0), len(m.changes)-1)
+		m.selectedHunk = 0
+		m.lineCursor = 0
+		m.isVisualMode = false
+	}
+
+	if newIdx, ok := mapping[path][m.selectedHunk]; ok && sameFile {
+		m.selectedHunk = newIdx
+	}
+
+	file := m.changes[fileIdx]
+	m.selectedFile = fileIdx
+	m.selectedHunk = min(max(m.selectedHunk, 0), max(len(file.Hunks)-1, 0))
+
+	lastLine := 0
+	if m.selectedHunk < len(file.Hunks) {
+		lastLine = max(len(file.Hunks[m.selectedHunk].Lines)-1, 0)
+	}
+
+	m.lineCursor = min(max(m.lineCursor, 0), lastLine)
+	m.visualAnchor = min(max(m.visualAnchor, 0), lastLine)
+
+	offset := m.diffView.Offset()
+	m.fileList.SetSelected(fileIdx)
+	m.diffView.SetFileChange(file)
+
+	if sameFile {
+		m.diffView.SetOffset(offset)
+	}
+}
+
+// joinPieces joins the pieces of each hunk a split cut back together, as the reloaded diff has them,
+// so mapHunks can match them by their changed lines. A run of touching hunks is only joined where
+// the reloaded diff has a hunk with all their changes, since hunks revealed context grew into each
+// other come back apart. The selections, the tags, and the cursor follow their lines into the joined
+// hunks, as they do for a join.
+func (m *Model) joinPieces(previous, current []diff.FileChange) []diff.FileChange {
+	joined := slices.Clone(previous)
+
+	for fileIdx, file := range previous {
+		newIdx, ok := indexOfPath(current, file.Path)
+		if !ok {
+			continue
+		}
+
+		keys := make(map[string]bool)
+		for _, hunk := range current[newIdx].Hunks {
+			keys[changedLinesKey(hunk)] = true
+		}
+
+		whole := file
+		for hunkIdx := range len(file.Hunks) {
+			if hunkIdx >= len(whole.Hunks) {
+				break
+			}
+
+			if count := piecesOf(whole.Hunks[hunkIdx:], keys); count > 1 {
+				run, _, _ := diff.JoinHunks(diff.FileChange{Hunks: whole.Hunks[hunkIdx : hunkIdx+count]}, 0)
+				whole.Hunks = slices.Concat(whole.Hunks[:hunkIdx], run.Hunks, whole.Hunks[hunkIdx+count:])
+			}
+		}
+
+		if len(whole.Hunks) == len(file.Hunks) {
+			continue
+		}
+
+		m.selection.Regroup(file.Path, file.Hunks, whole.Hunks)
+		for _, selection := range m.multiSplitState.Selections {
+			selection.Regroup(file.Path, file.Hunks, whole.Hunks)
+		}
+
+		if fileIdx == m.selectedFile {
+			line := m.lineCursor
+			m.selectedHunk, m.lineCursor = locateLine(whole.Hunks, linePosition(file.Hunks, m.selectedHunk, line))
+			m.visualAnchor += m.lineCursor - line
+		}
+
+		joined[fileIdx] = whole
+	}
+
+	return joined
+}
+
+// piecesOf counts the most leading hunks that touch one another and whose changes, together, are a
+// hunk in keys, or returns 1 when no two do.
+func piecesOf(hunks []diff.Hunk, keys map[string]bool) int {
+	count := 1
+	key := changedLinesKey(hunks[0])
+
+	for end := 1; end < len(hunks); end++ {
+		if !diff.Touches(hunks[end-1], hunks[end]) {
+			break
+		}
+
+		key += changedLinesKey(hunks[end])
+		if keys[key] {
+			count = end + 1
+		}
+	}
+
+	return count
+}
+
+// hunkMapping maps, per file path, an old hunk's index to the index of the same hunk in a reloaded
+// diff. A hunk missing from the map did not survive the reload.
+type hunkMapping map[string]map[int]int
+
+// mapHunks matches hunks across two versions of a diff by their changed lines. Line numbers and
+// context shift when something above a hunk changes, but the lines it adds and removes do not, so a
+// hunk keeps its identity through an edit elsewhere in the file. Identical hunks pair up in order.
+func mapHunks(previous, current []diff.FileChange) hunkMapping {
+	mapping := make(hunkMapping)
+
+	for _, oldFile := range previous {
+		newIdx, ok := indexOfPath(current, oldFile.Path)
+		if !ok {
+			continue
+		}
+
+		newHunks := current[newIdx].Hunks
+		used := make([]bool, len(newHunks))
+		fileMapping := make(map[int]int)
+
+		for oldHunkIdx, oldHunk := range oldFile.Hunks {
+			key := changedLinesKey(oldHunk)
+
+			for newHunkIdx, newHunk := range newHunks {
+				if !used[newHunkIdx] && changedLinesKey(newHunk) == key {
+					used[newHunkIdx] = true
+					fileMapping[oldHunkIdx] = newHunkIdx
+
+					break
+				}
+			}
+		}
+
+		mapping[oldFile.Path] = fileMapping
+	}
+
+	return mapping
+}
+
+// remapSelection rebuilds a selection against a reloaded diff. A whole-hunk selection follows its
+// hunk; a line selection follows each selected added or removed line by its position among the
+// hunk's changes, which mapHunks guarantees match. Selected context lines and hunks that did not
+// survive are dropped.
+func remapSelection(
+	selection *diff.Selection,
+	previous, current []diff.FileChange,
+	mapping hunkMapping,
+) *diff.Selection {
+	remapped := diff.NewSelection()
+
+	for path, fileSelection := range selection.Files {
+		oldIdx, oldOK := indexOfPath(previous, path)
+		newIdx, newOK := indexOfPath(current, path)
+
+		if !oldOK || !newOK {
+			continue
+		}
+
+		for oldHunkIdx, hunkSelection := range fileSelection.Hunks {
+			newHunkIdx, ok := mapping[path][oldHunkIdx]
+			if !ok || oldHunkIdx >= len(previous[oldIdx].Hunks) {
+				continue
+			}
+
+			if hunkSelection.WholeHunk {
+				remapped.ToggleHunk(path, newHunkIdx)
+
+				continue
+			}
+
+			oldChanged := changedLineIndices(previous[oldIdx].Hunks[oldHunkIdx])
+			newChanged := changedLineIndices(current[newIdx].Hunks[newHunkIdx])
+
+			for ordinal, lineIdx := range oldChanged {
+				if hunkSelection.SelectedLines[lineIdx] {
+					remapped.ToggleLine(path, newHunkIdx, newChanged[ordinal])
+				}
+			}
+		}
+	}
+
+	return remapped
+}
+
+func changedLinesKey(hunk diff.Hunk) string {
+	var builder strings.Builder
+
+	for _, line := range hunk.Lines {
+		switch line.Type {
+		case diff.LineAddition:
+			builder.WriteString("+")
+		case diff.LineDeletion:
+			builder.WriteString("-")
+		case diff.LineContext:
+			continue
+		}
+
+		builder.WriteString(line.Content)
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// changedLineIndices lists the indices of a hunk's added and removed lines, in order.
+func changedLineIndices(hunk diff.Hunk) []int {
+	var indices []int
+
+	for i, line := range hunk.Lines {
+		if line.Type != diff.LineContext {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+func indexOfPath(changes []diff.FileChange, path string) (int, bool) {
+	for i, file := range changes {
+		if file.Path == path {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/session"
+)
+
+// replayStepDelay is the pause between replayed commands in a visible replay, long enough to follow
+// the cursor. A headless replay does not pause.
+const replayStepDelay = 300 * time.Millisecond
+
+// replayState is the recording a replay is working through. Queued is set while the message for the
+// next step is on its way, so a step is never issued twice.
+type replayState struct {
+	steps    []session.Step
+	next     int
+	active   bool
+	queued   bool
+	headless bool
+}
+
+// replayStepMsg asks the model to run the replay's next step.
+type replayStepMsg struct{}
+
+// WithRecorder logs every command the session runs to r.
+func (m Model) WithRecorder(r *session.Recorder) Model {
+	m.recorder = r
+
+	return m
+}
+
+// WithReplay runs steps once the first diff has loaded, one after another as each one's jj calls
+// finish. A headless replay quits when it is done or stops; a visible one stays open.
+func (m Model) WithReplay(steps []session.Step, headless bool) Model {
+	m.replay = replayState{steps: steps, active: true, headless: headless}
+
+	return m
+}
+
+// cursorPosition is where the cursor and focus were when a command was last recorded.
+type cursorPosition struct {
+	file, hunk, line int
+	panel            FocusedPanel
+}
+
+func (m *Model) cursor() cursorPosition {
+	return cursorPosition{file: m.selectedFile, hunk: m.selectedHunk, line: m.lineCursor, panel: m.focusedPanel}
+}
+
+// recordCursor logs a goto, and a focus, for a cursor that moved since the last recorded command
+// without a command of its own, as a search, a conflict jump, or a click moves it. Without them a
+// replay would run the next command on a different hunk of the same diff.
+func (m *Model) recordCursor() {
+	if m.recorder == nil {
+		return
+	}
+
+	current, last := m.cursor(), m.recordedAt
+	if current.file != last.file || current.hunk != last.hunk || current.line != last.line {
+		m.record(command.GoTo{File: current.file, Hunk: current.hunk, Line: current.line})
+	}
+
+	if current.panel != last.panel {
+		panel := command.PanelFiles
+		if m.focusedPanel == PanelDiffView {
+			panel = command.PanelDiff
+		}
+
+		m.record(command.Focus{Panel: panel})
+	}
+}
+
+// record logs c against the diff on screen. A recording that cannot be written is reported rather
+// than fatal, because the session itself is unaffected.
+func (m *Model) record(c command.Command) {
+	if m.recorder == nil {
+		return
+	}
+
+	if err := m.recorder.Record(c, session.Fingerprint(m.diffText)); err != nil {
+		m.notice = err.Error()
+	}
+
+	m.recordedAt = m.cursor()
+}
+
+// continueReplay issues the next replay step once nothing is running: the first diff load, and any
+// move or reload the previous step started, have to finish before the next command sees the diff.
+func (m Model) continueReplay(cmd tea.Cmd) (Model, tea.Cmd) {
+	if !m.replay.active || m.replay.queued || m.err != nil || len(m.commands.kinds()) > 0 {
+		return m, cmd
+	}
+
+	m.replay.queued = true
+
+	if m.replay.headless {
+		return m, tea.Batch(cmd, func() tea.Msg { return replayStepMsg{} })
+	}
+
+	return m, tea.Batch(cmd, tea.Tick(replayStepDelay, func(time.Time) tea.Msg { return replayStepMsg{} }))
+}
+
+// runReplayStep runs the next recorded command, or stops the replay when the diff no longer matches
+// the recording or the command is refused.
+func (m Model) runReplayStep() (Model, tea.Cmd) {
+	m.replay.queued = false
+
+	if m.replay.next >= len(m.replay.steps) {
+		m.replay.active = false
+		m.notice = fmt.Sprintf("Replayed %d command(s)", len(m.replay.steps))
+
+		return m, m.replayQuit()
+	}
+
+	step := m.replay.steps[m.replay.next]
+	m.replay.next++
+
+	if got := session.Fingerprint(m.diffText); got != step.Diff {
+		return m.stopReplay(fmt.Errorf("line %d, %s: %w (recorded %s, now %s)",
+			step.Line, step.Command, session.ErrMismatch, step.Diff, got))
+	}
+
+	m, cmd, err := m.runCommand(step.Command)
+	if err != nil {
+		return m.stopReplay(fmt.Errorf("line %d: %w", step.Line, err))
+	}
+
+	return m, cmd
+}
+
+// stopReplay ends the replay on the error screen.
+func (m Model) stopReplay(err error) (Model, tea.Cmd) {
+	m.replay.active = false
+	m.err = fmt.Errorf("replay stopped at %w", err)
+
+	return m, m.replayQuit()
+}
+
+// replayQuit ends a headless replay, which has no one to press q.
+func (m Model) replayQuit() tea.Cmd {
+	if m.replay.headless {
+		return tea.Quit
+	}
+
+	return nil
+}
+
+// RunHeadless drives the model as Bubble Tea would, without a terminal: each command runs in turn and
+// its message goes back through Update, until the model quits or has nothing left to run. The model
+// it returns is the final one, and its error is the replay's.
+func RunHeadless(m Model) (Model, error) {
+	queue := []tea.Cmd{m.Init()}
+
+	for len(queue) > 0 {
+		cmd := queue[0]
+		queue = queue[1:]
+
+		if cmd == nil {
+			continue
+		}
+
+		switch msg := cmd().(type) {
+		case tea.QuitMsg:
+			return m, m.err
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		default:
+			var next tea.Cmd
+
+			m, next = m.update(msg)
+			m, next = m.continueReplay(next)
+			queue = append(queue, next)
+		}
+	}
+
+	return m, m.err
+}
@@ -219,6 +219,9 @@ func (a *Assertion) SearchIsNotVisible() {
 	if a.m.searchModal.IsVisible() {
 		a.t.Error("Expected search modal to NOT be visible")
 	}
+	if a.m.commandLine.IsVisible() {
+		a.t.Error("Expected command line to NOT be visible")
+	}
 }
 
 // FileListFilterModeEnabled checks that the file list is capturing keys for its inline filter.
@@ -252,6 +255,21 @@ func (a *Assertion) NoModalsVisible() {
 	if a.m.destPicker.IsVisible() {
 		a.t.Error("Expected dest picker modal to NOT be visible")
 	}
+	if a.m.timeline.IsVisible() {
+		a.t.Error("Expected evolution timeline to NOT be visible")
+	}
+	if a.m.opLog.IsVisible() {
+		a.t.Error("Expected op log to NOT be visible")
+	}
+	if a.m.smartlog.IsVisible() {
+		a.t.Error("Expected smartlog to NOT be visible")
+	}
+	if a.m.conflictList.IsVisible() {
+		a.t.Error("Expected conflict list to NOT be visible")
+	}
+	if a.m.applyReport.IsVisible() {
+		a.t.Error("Expected apply report to NOT be visible")
+	}
 	if a.m.fileList.IsFilterMode() {
 		a.t.Error("Expected file list filter mode to NOT be enabled")
 	}
@@ -0,0 +1,90 @@
+// Package pager renders a diff as text for a pipe or a pager rather than the terminal UI. Each file is
+// drawn by the diff view itself, so the layouts, syntax highlighting, and word-level spans match the
+// TUI, and text around the diff, such as a commit's log lines, passes through as it came.
+package pager
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyleking/jj-diff/internal/components/diffview"
+	"github.com/kyleking/jj-diff/internal/config"
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/theme"
+)
+
+// DefaultWidth is the width to render at when neither $COLUMNS nor the terminal says otherwise.
+const DefaultWidth = 80
+
+// noHunk is a hunk index no file has, so no hunk draws as the cursor.
+const noHunk = -1
+
+// ansiEscapeRE matches the escape sequences jj and git color their output with. The input is parsed
+// as a diff, so the colors have to go before it is.
+var ansiEscapeRE = regexp.MustCompile(`\x1b\[[0-9;:]*[A-Za-z]`)
+
+// Options controls how Render lays the diff out. Plain drops the padding that fills each row out to
+// Width, which only shows as trailing spaces once there is no background color to fill.
+type Options struct {
+	Config config.Config
+	Width  int
+	Plain  bool
+}
+
+// Render writes text to w with every file section drawn as the diff view draws it, in the layout
+// opts.Config chooses, and everything else copied through. Colors follow lipgloss's color profile,
+// which the caller sets.
+func Render(w io.Writer, text string, opts Options) error {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	var out strings.Builder
+
+	for _, segment := range diff.SplitPatch(ansiEscapeRE.ReplaceAllString(text, "")) {
+		if !segment.IsDiff {
+			out.WriteString(segment.Text)
+
+			continue
+		}
+
+		for _, file := range diff.Parse(segment.Text) {
+			out.WriteString(renderFile(file, width, opts))
+		}
+	}
+
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return fmt.Errorf("writing the diff: %w", err)
+	}
+
+	return nil
+}
+
+// renderFile draws one file: a header naming it, then its hunks with no cursor or selection.
+func renderFile(file diff.FileChange, width int, opts Options) string {
+	view := diffview.New(opts.Config)
+	view.SetFileChange(file)
+	view.SetSelection(noHunk, nil)
+
+	header := lipgloss.NewStyle().Bold(true).Foreground(theme.Primary).
+		Render(fmt.Sprintf("%s %s", file.ChangeType, file.Path))
+
+	// A rename or a binary file has no hunks to draw, and the side-by-side headings would head nothing.
+	if len(file.Hunks) == 0 {
+		return header + "\n"
+	}
+
+	lines := append([]string{header}, strings.Split(view.View(width, view.ContentHeight(), false), "\n")...)
+	if opts.Plain {
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " ")
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
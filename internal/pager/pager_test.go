@@ -0,0 +1,81 @@
+package pager_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/config"
+	"github.com/kyleking/jj-diff/internal/pager"
+)
+
+// logWithPatch is what jj log -p --git prints for one commit, colored as jj colors it for a pager.
+const logWithPatch = "@  qpvuntsm someone@example.com 2024-01-01\n" +
+	"│  Fix the greeting\n" +
+	"\x1b[1mdiff --git a/hello.go b/hello.go\x1b[0m\n" +
+	"--- a/hello.go\n" +
+	"+++ b/hello.go\n" +
+	"@@ -1,3 +1,3 @@\n" +
+	" package main\n" +
+	"-var greeting = \"helo\"\n" +
+	"+var greeting = \"hello\"\n" +
+	" \n" +
+	"◆  zzzzzzzz root()\n"
+
+func TestRender_DrawsFilesAndPassesTheRestThrough(t *testing.T) {
+	t.Parallel()
+
+	opts := pager.Options{Config: config.DefaultConfig(), Width: 60, Plain: true}
+
+	var out strings.Builder
+	if err := pager.Render(&out, logWithPatch, opts); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	text := out.String()
+
+	for _, want := range []string{
+		"@  qpvuntsm someone@example.com",
+		"M hello.go",
+		"@@ -1,3 +1,3 @@",
+		"- var greeting = \"helo\"",
+		"+ var greeting = \"hello\"",
+		"◆  zzzzzzzz root()",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, text)
+		}
+	}
+
+	if strings.Contains(text, "\x1b[") {
+		t.Errorf("Expected the input's colors stripped in plain output, got %q", text)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasSuffix(line, " ") {
+			t.Errorf("Expected no padding in plain output, got %q", line)
+		}
+	}
+}
+
+func TestRender_SideBySide(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DefaultConfig()
+	cfg.ViewMode = config.ViewModeSideBySide
+
+	var out strings.Builder
+	if err := pager.Render(&out, logWithPatch, pager.Options{Config: cfg, Width: 100, Plain: true}); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.Contains(out.String(), "OLD") || !strings.Contains(out.String(), "NEW") {
+		t.Errorf("Expected the two-column headings, got:\n%s", out.String())
+	}
+
+	for _, line := range lines {
+		if strings.Contains(line, "helo") && !strings.Contains(line, "hello") {
+			t.Errorf("Expected the changed line paired with its replacement, got %q", line)
+		}
+	}
+}
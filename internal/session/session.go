@@ -0,0 +1,110 @@
+// Package session records the commands of a UI session to a JSON Lines file and reads them back for
+// a replay. Each line holds one command's text form and a fingerprint of the diff it ran against, so a
+// replay can stop where the repository no longer matches the recording.
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kyleking/jj-diff/internal/command"
+)
+
+// Sentinel errors for a session file that cannot be read and a replay that has drifted from it.
+var (
+	ErrInvalidSession = errors.New("invalid session")
+	ErrMismatch       = errors.New("diff does not match the recording")
+)
+
+// fingerprintLength is how many hex digits of the diff's SHA-256 a line keeps. Sixteen is plenty to
+// tell two diffs of one repository apart, and keeps the file readable.
+const fingerprintLength = 16
+
+// Fingerprint identifies a diff's text.
+func Fingerprint(diffText string) string {
+	sum := sha256.Sum256([]byte(diffText))
+
+	return hex.EncodeToString(sum[:])[:fingerprintLength]
+}
+
+// entry is one line of a session file.
+type entry struct {
+	Command string `json:"command"`
+	Diff    string `json:"diff"`
+}
+
+// Step is one recorded command, with the fingerprint of the diff it ran against and the line it was
+// read from.
+type Step struct {
+	Command command.Command
+	Diff    string
+	Line    int
+}
+
+// Recorder appends commands to a session file as they run.
+type Recorder struct {
+	encoder *json.Encoder
+}
+
+// NewRecorder writes to w, one line per command. The caller owns w and closes it.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Record writes c and the fingerprint of the diff it ran against.
+func (r *Recorder) Record(c command.Command, fingerprint string) error {
+	if err := r.encoder.Encode(entry{Command: c.String(), Diff: fingerprint}); err != nil {
+		return fmt.Errorf("recording %s: %w", c, err)
+	}
+
+	return nil
+}
+
+// Load reads a session file, parsing every command up front so a bad line fails before the replay
+// touches the repository.
+func Load(path string) ([]Step, error) {
+	//nolint:gosec // G304: the path is the session the user asked to replay.
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading session: %w", err)
+	}
+
+	return Read(bytes.NewReader(content))
+}
+
+// Read parses session lines from r. Blank lines are skipped.
+func Read(r io.Reader) ([]Step, error) {
+	var steps []Step
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidSession, line, err)
+		}
+
+		c, err := command.Parse(e.Command)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidSession, line, err)
+		}
+
+		steps = append(steps, Step{Command: c, Diff: e.Diff, Line: line})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading session: %w", err)
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,95 @@
+package session_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/command"
+	"github.com/kyleking/jj-diff/internal/session"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	a, b := session.Fingerprint("+a\n"), session.Fingerprint("+b\n")
+	if a == b || len(a) != 16 || a != session.Fingerprint("+a\n") {
+		t.Errorf("Expected short, stable, distinct fingerprints, got %q and %q", a, b)
+	}
+}
+
+func TestRecordThenRead(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	recorder := session.NewRecorder(&buf)
+	commands := []command.Command{
+		command.Focus{Panel: command.PanelDiff},
+		command.Navigate{Unit: command.UnitHunk, Delta: 2},
+		command.Tag{Tag: 'B'},
+		command.Move{Destination: "@--"},
+	}
+
+	for _, c := range commands {
+		if err := recorder.Record(c, "0123456789abcdef"); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	if !strings.HasPrefix(buf.String(), `{"command":"focus diff","diff":"0123456789abcdef"}`+"\n") {
+		t.Errorf("Expected one JSON object per line, got:\n%s", buf.String())
+	}
+
+	steps, err := session.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if len(steps) != len(commands) {
+		t.Fatalf("Expected %d steps, got %d", len(commands), len(steps))
+	}
+
+	for i, step := range steps {
+		if step.Command.String() != commands[i].String() || step.Line != i+1 || step.Diff != "0123456789abcdef" {
+			t.Errorf("Step %d = %+v, want %s", i, step, commands[i])
+		}
+	}
+}
+
+func TestRead_RejectsBadLines(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"not json":        "{\"command\":\"down\",\"diff\":\"x\"}\nnot json\n",
+		"unknown command": "\n{\"command\":\"frobnicate\",\"diff\":\"x\"}\n",
+	}
+
+	for name, text := range tests {
+		_, err := session.Read(strings.NewReader(text))
+		if !errors.Is(err, session.ErrInvalidSession) || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%s: expected an invalid session naming line 2, got %v", name, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte(`{"command":"apply","diff":"x"}`+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	steps, err := session.Load(path)
+	if err != nil || len(steps) != 1 || steps[0].Command.String() != "apply" {
+		t.Errorf("Expected the one apply step, got %+v, %v", steps, err)
+	}
+
+	if _, err := session.Load(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,220 @@
+// Package splitspec reads split spec files, which describe a multi-way split as ordered groups of
+// selectors, so a change that is split the same way every time, such as vendored code apart from the
+// code that uses it, can be split again without tagging hunks by hand.
+package splitspec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+)
+
+// ErrInvalidSpec marks a spec file that parsed as TOML but does not describe a split.
+var ErrInvalidSpec = errors.New("invalid split spec")
+
+// Group is one [[group]] of a spec: the changes it takes and where they go. Files holds paths or
+// globs, Hunks regular expressions matched against each hunk's changed lines, and Lines PATH:N or
+// PATH:START-END selectors. A group goes to a new commit with Description as its message, or to the
+// existing revision Destination names.
+type Group struct {
+	Description string   `toml:"description"`
+	Destination string   `toml:"destination"`
+	Files       []string `toml:"files"`
+	Hunks       []string `toml:"hunks"`
+	Lines       []string `toml:"lines"`
+	selectors   []diff.Selector
+}
+
+// Spec is a parsed spec file. Groups run in file order, and a change an earlier group took is not
+// offered to a later one, so a broad group at the end can sweep up whatever the others left.
+type Spec struct {
+	Groups []Group `toml:"group"`
+}
+
+// Load reads and checks the spec at path.
+func Load(path string) (Spec, error) {
+	//nolint:gosec // G304: the path is the spec the user asked to apply.
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, fmt.Errorf("reading the split spec: %w", err)
+	}
+
+	spec, err := Parse(string(content))
+	if err != nil {
+		return Spec{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return spec, nil
+}
+
+// Parse reads a spec from text. Unknown keys are rejected rather than ignored, because a misspelled
+// files or hunks would otherwise quietly leave a group empty.
+func Parse(text string) (Spec, error) {
+	var spec Spec
+
+	meta, err := toml.Decode(text, &spec)
+	if err != nil {
+		return Spec{}, fmt.Errorf("parsing the split spec: %w", err)
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+
+		return Spec{}, fmt.Errorf("%w: unknown keys %s", ErrInvalidSpec, strings.Join(keys, ", "))
+	}
+
+	if len(spec.Groups) == 0 {
+		return Spec{}, fmt.Errorf("%w: no [[group]] tables", ErrInvalidSpec)
+	}
+
+	for i := range spec.Groups {
+		if err := spec.Groups[i].compile(); err != nil {
+			return Spec{}, fmt.Errorf("group %d: %w", i+1, err)
+		}
+	}
+
+	return spec, nil
+}
+
+// compile checks the group and parses its selectors once, so a bad pattern is reported before
+// anything is split.
+func (g *Group) compile() error {
+	if g.Description == "" && g.Destination == "" {
+		return fmt.Errorf("%w: a group needs a description or a destination", ErrInvalidSpec)
+	}
+
+	if len(g.Files)+len(g.Hunks)+len(g.Lines) == 0 {
+		return fmt.Errorf("%w: a group needs files, hunks, or lines", ErrInvalidSpec)
+	}
+
+	for _, pattern := range g.Files {
+		selector, err := diff.NewGlobSelector(pattern)
+		if err != nil {
+			return fmt.Errorf("files: %w", err)
+		}
+
+		g.selectors = append(g.selectors, selector)
+	}
+
+	for _, pattern := range g.Hunks {
+		selector, err := diff.NewHunkMatchSelector(pattern)
+		if err != nil {
+			return fmt.Errorf("hunks: %w", err)
+		}
+
+		g.selectors = append(g.selectors, selector)
+	}
+
+	for _, spec := range g.Lines {
+		selector, err := diff.ParseSelector(spec)
+		if err != nil {
+			return fmt.Errorf("lines: %w", err)
+		}
+
+		if selector.Kind != diff.SelectHunk && selector.Kind != diff.SelectLines {
+			return fmt.Errorf("%w: lines entry %q is not PATH:N or PATH:START-END", ErrInvalidSpec, spec)
+		}
+
+		g.selectors = append(g.selectors, selector)
+	}
+
+	return nil
+}
+
+// Label names the group for messages: its description, or its destination when it has none.
+func (g Group) Label() string {
+	if g.Description != "" {
+		return g.Description
+	}
+
+	return g.Destination
+}
+
+// Assignment is what one group took from a diff. Hunks is zero for a group that matched nothing, or
+// only changes an earlier group had already taken.
+type Assignment struct {
+	Selection *diff.Selection
+	Group     Group
+	Hunks     int
+}
+
+// lineKey names one line of one hunk.
+type lineKey struct {
+	path string
+	hunk int
+	line int
+}
+
+// Assign runs the groups over files in order and returns one assignment per group. Only added and
+// deleted lines are assigned, and each to the first group that selects it.
+func (s Spec) Assign(files []diff.FileChange) []Assignment {
+	claimed := make(map[lineKey]bool)
+	assignments := make([]Assignment, len(s.Groups))
+
+	for i, group := range s.Groups {
+		selection := diff.NewSelection()
+
+		for _, selector := range group.selectors {
+			// A selector that matches nothing is expected: the same spec is run over changes that do
+			// not all touch every file it names.
+			_, _ = selector.Select(files, selection)
+		}
+
+		assignments[i] = claim(files, selection, claimed)
+		assignments[i].Group = group
+	}
+
+	return assignments
+}
+
+// claim keeps the lines of selection that no earlier group took, and marks them taken. A hunk whose
+// changed lines all survive is selected as a whole.
+func claim(files []diff.FileChange, selection *diff.Selection, claimed map[lineKey]bool) Assignment {
+	assignment := Assignment{Selection: diff.NewSelection()}
+
+	for _, file := range files {
+		for hunkIdx, hunk := range file.Hunks {
+			var changed, picked []int
+
+			for lineIdx, line := range hunk.Lines {
+				if line.Type == diff.LineContext {
+					continue
+				}
+
+				changed = append(changed, lineIdx)
+
+				key := lineKey{path: file.Path, hunk: hunkIdx, line: lineIdx}
+				if selection.IsLineSelected(file.Path, hunkIdx, lineIdx) && !claimed[key] {
+					picked = append(picked, lineIdx)
+					claimed[key] = true
+				}
+			}
+
+			if len(picked) == 0 {
+				continue
+			}
+
+			assignment.Hunks++
+
+			if len(picked) == len(changed) {
+				assignment.Selection.ToggleHunk(file.Path, hunkIdx)
+
+				continue
+			}
+
+			for _, lineIdx := range picked {
+				assignment.Selection.SelectLineRange(file.Path, hunkIdx, lineIdx, lineIdx)
+			}
+		}
+	}
+
+	return assignment
+}
@@ -0,0 +1,165 @@
+package splitspec_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyleking/jj-diff/internal/diff"
+	"github.com/kyleking/jj-diff/internal/splitspec"
+)
+
+const generatedDiff = `diff --git a/vendor/lib/lib.go b/vendor/lib/lib.go
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/vendor/lib/lib.go
+@@ -0,0 +1,2 @@
++package lib
++func Lib() {}
+diff --git a/app/main.go b/app/main.go
+index 2222222..3333333 100644
+--- a/app/main.go
++++ b/app/main.go
+@@ -1,3 +1,5 @@
+ package main
++import "lib"
+ func main() {
++	lib.Lib()
+ }
+diff --git a/app/gen.pb.go b/app/gen.pb.go
+index 4444444..5555555 100644
+--- a/app/gen.pb.go
++++ b/app/gen.pb.go
+@@ -1,2 +1,2 @@
+-// Code generated by protoc v1. DO NOT EDIT.
++// Code generated by protoc v2. DO NOT EDIT.
+ package app
+`
+
+const vendorSpec = `
+[[group]]
+description = "Vendor lib"
+files = ["vendor/**"]
+
+[[group]]
+description = "Regenerate"
+hunks = ["Code generated"]
+
+[[group]]
+description = "Import"
+lines = ["app/main.go:2-2"]
+
+[[group]]
+description = "Everything else"
+files = ["**"]
+`
+
+func TestParse_RejectsInvalidSpecs(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"no groups":           `title = "x"`,
+		"unknown key":         "[[group]]\ndescription = \"x\"\nfile = [\"a\"]\n",
+		"no destination":      "[[group]]\nfiles = [\"a\"]\n",
+		"no selectors":        "[[group]]\ndescription = \"x\"\n",
+		"bad regex":           "[[group]]\ndescription = \"x\"\nhunks = [\"(\"]\n",
+		"lines without range": "[[group]]\ndescription = \"x\"\nlines = [\"a.go\"]\n",
+	}
+
+	for name, text := range tests {
+		if _, err := splitspec.Parse(text); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err := splitspec.Parse("[[group]]\ndescription = \"x\"\nfile = [\"a\"]\n")
+	if !errors.Is(err, splitspec.ErrInvalidSpec) || !strings.Contains(err.Error(), "group.file") {
+		t.Errorf("Expected the unknown key to be named, got %v", err)
+	}
+}
+
+func TestAssign_EarlierGroupsWin(t *testing.T) {
+	t.Parallel()
+
+	spec, err := splitspec.Parse(vendorSpec)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	files := diff.Parse(generatedDiff)
+	assignments := spec.Assign(files)
+
+	if len(assignments) != 4 {
+		t.Fatalf("Expected one assignment per group, got %d", len(assignments))
+	}
+
+	wantHunks := []int{1, 1, 1, 1}
+	for i, want := range wantHunks {
+		if assignments[i].Hunks != want {
+			t.Errorf("Group %d (%s) took %d hunks, want %d",
+				i+1, assignments[i].Group.Label(), assignments[i].Hunks, want)
+		}
+	}
+
+	patches := make([]string, len(assignments))
+	for i, assignment := range assignments {
+		patches[i] = diff.GeneratePatch(files, assignment.Selection)
+	}
+
+	if !strings.Contains(patches[0], "vendor/lib/lib.go") || strings.Contains(patches[0], "app/") {
+		t.Errorf("Expected the vendor group to hold only vendor/, got:\n%s", patches[0])
+	}
+
+	if !strings.Contains(patches[1], "+// Code generated by protoc v2") {
+		t.Errorf("Expected the generated hunk in the second group, got:\n%s", patches[1])
+	}
+
+	if !strings.Contains(patches[2], `+import "lib"`) || strings.Contains(patches[2], "+\tlib.Lib()") {
+		t.Errorf("Expected only the import line in the third group, got:\n%s", patches[2])
+	}
+
+	last := patches[3]
+	if !strings.Contains(last, "+\tlib.Lib()") || strings.Contains(last, `+import "lib"`) ||
+		strings.Contains(last, "vendor/") || strings.Contains(last, "gen.pb.go") {
+		t.Errorf("Expected the catch-all group to hold only what was left, got:\n%s", last)
+	}
+}
+
+func TestAssign_GroupThatMatchesNothing(t *testing.T) {
+	t.Parallel()
+
+	spec, err := splitspec.Parse("[[group]]\ndestination = \"@-\"\nfiles = [\"docs/**\"]\n")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assignments := spec.Assign(diff.Parse(generatedDiff))
+	if assignments[0].Hunks != 0 || assignments[0].Group.Label() != "@-" {
+		t.Errorf("Expected an empty assignment labelled by its destination, got %+v", assignments[0])
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "split.toml")
+	if err := os.WriteFile(path, []byte(vendorSpec), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	spec, err := splitspec.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(spec.Groups) != 4 || spec.Groups[0].Files[0] != "vendor/**" {
+		t.Errorf("Expected the four groups in order, got %+v", spec.Groups)
+	}
+
+	if _, err := splitspec.Load(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,194 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// ErrInvalidDefinition is returned for a theme definition entry that cannot be used. The entry is
+// skipped and the base theme's value kept.
+var ErrInvalidDefinition = errors.New("invalid theme")
+
+// hexColor is the form a slot's color takes in a definition.
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Highest color numbers of the 256-color and 16-color palettes.
+const (
+	maxANSI256 = 255
+	maxANSI    = 15
+)
+
+// slots are the color slots a definition may set, by the names the config uses.
+var slots = map[string]func(*Theme) *Slot{
+	"primary":         func(t *Theme) *Slot { return &t.Primary },
+	"accent":          func(t *Theme) *Slot { return &t.Accent },
+	"secondary":       func(t *Theme) *Slot { return &t.Secondary },
+	"text":            func(t *Theme) *Slot { return &t.Text },
+	"selected-bg":     func(t *Theme) *Slot { return &t.SelectedBg },
+	"muted-bg":        func(t *Theme) *Slot { return &t.MutedBg },
+	"soft-muted-bg":   func(t *Theme) *Slot { return &t.SoftMutedBg },
+	"modal-bg":        func(t *Theme) *Slot { return &t.ModalBg },
+	"added":           func(t *Theme) *Slot { return &t.AddedLine },
+	"deleted":         func(t *Theme) *Slot { return &t.DeletedLine },
+	"word-added-bg":   func(t *Theme) *Slot { return &t.WordDiffAddedBg },
+	"word-deleted-bg": func(t *Theme) *Slot { return &t.WordDiffDelBg },
+	"conflict":        func(t *Theme) *Slot { return &t.ConflictLine },
+	"conflict-marker": func(t *Theme) *Slot { return &t.ConflictMarker },
+}
+
+// SlotNames lists the color slots a definition may set.
+func SlotNames() []string {
+	return slices.Sorted(maps.Keys(slots))
+}
+
+// Definition is a theme from the config: a base theme with some of its slots, and perhaps its syntax
+// style, replaced.
+type Definition struct {
+	Slots  map[string]Slot
+	Base   string
+	Syntax string
+}
+
+// ParseDefinition reads one theme's table from the config:
+//
+//	base = "mocha"
+//	syntax = "dracula"
+//	added = "#a6e3a1"
+//	deleted = { hex = "#f38ba8", ansi256 = 211, ansi = 9 }
+//
+// Every entry is optional. The entries it cannot use are returned joined, and the rest still apply.
+func ParseDefinition(table map[string]any) (Definition, error) {
+	def := Definition{Slots: make(map[string]Slot)}
+
+	var errs []error
+
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		if err := def.set(key, table[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	return def, errors.Join(errs...)
+}
+
+func (d *Definition) set(key string, value any) error {
+	switch key {
+	case "base":
+		name, ok := value.(string)
+		if _, builtin := Builtin(name); !ok || (!builtin && name != Auto) {
+			return fmt.Errorf("%w: %v is not a built-in theme", ErrInvalidDefinition, value)
+		}
+
+		d.Base = name
+	case "syntax":
+		name, ok := value.(string)
+		if !ok || !slices.Contains(styles.Names(), name) {
+			return fmt.Errorf("%w: %v is not a chroma style", ErrInvalidDefinition, value)
+		}
+
+		d.Syntax = name
+	default:
+		if _, ok := slots[key]; !ok {
+			return fmt.Errorf("%w: no such slot, expected syntax, base, or one of %s",
+				ErrInvalidDefinition, strings.Join(SlotNames(), ", "))
+		}
+
+		s, err := parseSlot(value)
+		if err != nil {
+			return err
+		}
+
+		d.Slots[key] = s
+	}
+
+	return nil
+}
+
+// parseSlot reads a slot's hex color, or a table of the hex color and its fallbacks.
+func parseSlot(value any) (Slot, error) {
+	if hex, ok := value.(string); ok {
+		value = map[string]any{"hex": hex}
+	}
+
+	table, ok := value.(map[string]any)
+	if !ok {
+		return Slot{}, fmt.Errorf("%w: %v, expected a hex color or a table of hex, ansi256 and ansi",
+			ErrInvalidDefinition, value)
+	}
+
+	var s Slot
+
+	for key, v := range table {
+		var err error
+
+		switch key {
+		case "hex":
+			s.Hex, ok = v.(string)
+			if !ok || !hexColor.MatchString(s.Hex) {
+				err = fmt.Errorf("%w: %v, expected #rrggbb", ErrInvalidDefinition, v)
+			}
+		case "ansi256":
+			s.ANSI256, err = paletteIndex(v, maxANSI256)
+		case "ansi":
+			s.ANSI, err = paletteIndex(v, maxANSI)
+		default:
+			err = fmt.Errorf("%w: %s, expected hex, ansi256 or ansi", ErrInvalidDefinition, key)
+		}
+
+		if err != nil {
+			return Slot{}, err
+		}
+	}
+
+	if s.Hex == "" {
+		return Slot{}, fmt.Errorf("%w: no hex color", ErrInvalidDefinition)
+	}
+
+	return s, nil
+}
+
+// paletteIndex reads a color number, as a TOML integer or a string, up to highest.
+func paletteIndex(value any, highest int) (string, error) {
+	n, ok := -1, false
+
+	switch v := value.(type) {
+	case int64:
+		n, ok = int(v), true
+	case string:
+		parsed, err := strconv.Atoi(v)
+		n, ok = parsed, err == nil
+	}
+
+	if !ok || n < 0 || n > highest {
+		return "", fmt.Errorf("%w: %v, expected 0 to %d", ErrInvalidDefinition, value, highest)
+	}
+
+	return strconv.Itoa(n), nil
+}
+
+// Adjust sets the definition's slots and syntax style on t, keeping its name.
+func (d Definition) Adjust(t Theme) Theme {
+	return d.apply(t.Name, t)
+}
+
+// apply names t and sets the definition's slots and syntax style on it.
+func (d Definition) apply(name string, t Theme) Theme {
+	t.Name = name
+
+	if d.Syntax != "" {
+		t.Syntax = d.Syntax
+	}
+
+	for key, s := range d.Slots {
+		*slots[key](&t) = s
+	}
+
+	return t
+}
@@ -0,0 +1,116 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidColor is returned for a color in jj's config that jj-diff cannot follow. The slot keeps
+// the theme's color.
+var ErrInvalidColor = errors.New("invalid color")
+
+// jjColorSlots are the labels of jj's [colors] table that jj-diff follows, with the slot each sets and
+// the part of the label's style it takes: the foreground of a line, the background of a changed word.
+// The plain diff token label comes before the added and removed ones, so they override it.
+var jjColorSlots = []struct {
+	label string
+	part  string
+	slot  string
+}{
+	{label: "diff added", part: "fg", slot: "added"},
+	{label: "diff removed", part: "fg", slot: "deleted"},
+	{label: "diff token", part: "bg", slot: "word-added-bg"},
+	{label: "diff token", part: "bg", slot: "word-deleted-bg"},
+	{label: "diff added token", part: "bg", slot: "word-added-bg"},
+	{label: "diff removed token", part: "bg", slot: "word-deleted-bg"},
+}
+
+// jjColorNames are the 16 colors jj names, in palette order. The bright ones are prefixed "bright ".
+var jjColorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ParseJJColors reads the diff labels of jj's [colors] table into a definition, so jj-diff colors a
+// diff the way jj diff does:
+//
+//	"diff added" = "green"
+//	"diff removed" = { fg = "#f38ba8", bold = true }
+//	"diff token" = { bg = "ansi-color-238", underline = true }
+//
+// A style's other attributes, a label without the part jj-diff takes, and jj's "default" color leave
+// the slot alone. The colors it cannot read are returned joined, and the rest still apply.
+func ParseJJColors(colors map[string]any) (Definition, error) {
+	def := Definition{Slots: make(map[string]Slot)}
+
+	var errs []error
+
+	for _, entry := range jjColorSlots {
+		value, ok := colors[entry.label]
+		if !ok {
+			continue
+		}
+
+		// A bare color is the foreground.
+		if name, ok := value.(string); ok {
+			value = map[string]any{"fg": name}
+		}
+
+		style, ok := value.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%q: %w: %v, expected a color or a table of fg and bg",
+				entry.label, ErrInvalidColor, value))
+
+			continue
+		}
+
+		name, ok := style[entry.part].(string)
+		if !ok || name == "default" {
+			continue
+		}
+
+		s, err := parseJJColor(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", entry.label, err))
+
+			continue
+		}
+
+		def.Slots[entry.slot] = s
+	}
+
+	return def, errors.Join(errs...)
+}
+
+// parseJJColor reads a color as jj writes it: a name such as "bright green", "ansi-color-N", or a
+// hex color.
+func parseJJColor(name string) (Slot, error) {
+	if hexColor.MatchString(name) {
+		return Slot{Hex: name}, nil
+	}
+
+	if index, ok := strings.CutPrefix(name, "ansi-color-"); ok {
+		n, err := paletteIndex(index, maxANSI256)
+		if err != nil {
+			return Slot{}, fmt.Errorf("%w: %s, expected ansi-color-0 to ansi-color-%d",
+				ErrInvalidColor, name, maxANSI256)
+		}
+
+		return Slot{ANSI256: n}, nil
+	}
+
+	base, bright := strings.CutPrefix(name, "bright ")
+
+	for i, known := range jjColorNames {
+		if base != known {
+			continue
+		}
+
+		if bright {
+			i += len(jjColorNames)
+		}
+
+		return Slot{ANSI: strconv.Itoa(i)}, nil
+	}
+
+	return Slot{}, fmt.Errorf("%w: %q, expected a color name, ansi-color-N, or #rrggbb", ErrInvalidColor, name)
+}
@@ -1,26 +1,32 @@
-// Package theme holds the Catppuccin palette as package-level colors that every component reads
-// directly. Init must run before any component builds a style, so the colors are set once at
-// startup and treated as immutable afterwards.
+// Package theme holds the session's palette as package-level colors that every component reads
+// directly. The palette is a built-in theme, Catppuccin or an accessible one, or one defined in the
+// config by its named slots. Init must run before any component builds a style, so the colors are
+// set once at startup and treated as immutable afterwards.
 package theme
 
 import "github.com/charmbracelet/lipgloss"
 
 // Exported color variables.
 var (
-	Primary         lipgloss.Color
-	Accent          lipgloss.Color
-	Secondary       lipgloss.Color
-	Text            lipgloss.Color
-	SelectedBg      lipgloss.Color
-	MutedBg         lipgloss.Color
-	SoftMutedBg     lipgloss.Color
-	ModalBg         lipgloss.Color
-	AddedLine       lipgloss.Color
-	DeletedLine     lipgloss.Color
-	WordDiffAddedBg lipgloss.Color
-	WordDiffDelBg   lipgloss.Color
+	Primary         lipgloss.TerminalColor
+	Accent          lipgloss.TerminalColor
+	Secondary       lipgloss.TerminalColor
+	Text            lipgloss.TerminalColor
+	SelectedBg      lipgloss.TerminalColor
+	MutedBg         lipgloss.TerminalColor
+	SoftMutedBg     lipgloss.TerminalColor
+	ModalBg         lipgloss.TerminalColor
+	AddedLine       lipgloss.TerminalColor
+	DeletedLine     lipgloss.TerminalColor
+	WordDiffAddedBg lipgloss.TerminalColor
+	WordDiffDelBg   lipgloss.TerminalColor
+	ConflictLine    lipgloss.TerminalColor
+	ConflictMarker  lipgloss.TerminalColor
 )
 
+// Syntax names the chroma style that colors code in the diff.
+var Syntax string
+
 // Exported style variables.
 var (
 	HeaderStyle            lipgloss.Style
@@ -36,28 +42,32 @@ var (
 	BorderStyle            lipgloss.Style
 	WordDiffAddedStyle     lipgloss.Style
 	WordDiffDeletedStyle   lipgloss.Style
+	ConflictLineStyle      lipgloss.Style
+	ConflictMarkerStyle    lipgloss.Style
 )
 
-// Init detects the appropriate theme and initializes all colors and styles.
-func Init() {
-	t := Detect()
+// Init sets every color and style from t, which Select resolves from the configured name.
+func Init(t Theme) {
 	applyTheme(&t)
 }
 
 // applyTheme sets color variables and recomputes all styles.
 func applyTheme(t *Theme) {
-	Primary = t.Primary
-	Accent = t.Accent
-	Secondary = t.Secondary
-	Text = t.Text
-	SelectedBg = t.SelectedBg
-	MutedBg = t.MutedBg
-	SoftMutedBg = t.SoftMutedBg
-	ModalBg = t.ModalBg
-	AddedLine = t.AddedLine
-	DeletedLine = t.DeletedLine
-	WordDiffAddedBg = t.WordDiffAddedBg
-	WordDiffDelBg = t.WordDiffDelBg
+	Syntax = t.Syntax
+	Primary = t.Primary.Color()
+	Accent = t.Accent.Color()
+	Secondary = t.Secondary.Color()
+	Text = t.Text.Color()
+	SelectedBg = t.SelectedBg.Color()
+	MutedBg = t.MutedBg.Color()
+	SoftMutedBg = t.SoftMutedBg.Color()
+	ModalBg = t.ModalBg.Color()
+	AddedLine = t.AddedLine.Color()
+	DeletedLine = t.DeletedLine.Color()
+	WordDiffAddedBg = t.WordDiffAddedBg.Color()
+	WordDiffDelBg = t.WordDiffDelBg.Color()
+	ConflictLine = t.ConflictLine.Color()
+	ConflictMarker = t.ConflictMarker.Color()
 
 	HeaderStyle = lipgloss.NewStyle().
 		Foreground(Primary).
@@ -111,6 +121,14 @@ func applyTheme(t *Theme) {
 	WordDiffDeletedStyle = lipgloss.NewStyle().
 		Background(WordDiffDelBg).
 		Foreground(DeletedLine)
+
+	ConflictLineStyle = lipgloss.NewStyle().
+		Foreground(ConflictLine)
+
+	ConflictMarkerStyle = lipgloss.NewStyle().
+		Background(ConflictMarker).
+		Foreground(ModalBg).
+		Bold(true)
 }
 
 // PaneStyle returns a dynamic border style for panes.
@@ -1,73 +1,286 @@
 package theme
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Theme defines semantic color roles.
+// ErrUnknownTheme is returned for a theme name that is neither built in nor defined in the config.
+var ErrUnknownTheme = errors.New("unknown theme")
+
+// Auto picks Latte or Macchiato from the terminal's background, or the theme CATPPUCCIN_THEME names.
+const Auto = "auto"
+
+// Slot is one named color of a theme: a hex color, with the colors to use instead on terminals
+// without truecolor. An empty fallback is the nearest color to Hex in that palette. A slot without
+// Hex is a color of the terminal's own palette, as the color names in jj's config are.
+type Slot struct {
+	Hex     string
+	ANSI256 string
+	ANSI    string
+}
+
+// Color is the slot as lipgloss renders it, which picks the color for the terminal's profile.
+//
+//nolint:ireturn // lipgloss takes the interface, and which color type fits depends on the fallbacks.
+func (s Slot) Color() lipgloss.TerminalColor {
+	if s.Hex == "" {
+		return lipgloss.Color(or(s.ANSI, s.ANSI256))
+	}
+
+	if s.ANSI256 == "" && s.ANSI == "" {
+		return lipgloss.Color(s.Hex)
+	}
+
+	// A hex string in a fallback field is converted to the nearest color of that palette.
+	return lipgloss.CompleteColor{TrueColor: s.Hex, ANSI256: or(s.ANSI256, s.Hex), ANSI: or(s.ANSI, s.Hex)}
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// slot is a Slot with a 16-color fallback and the nearest 256-color one.
+func slot(hex, ansi string) Slot {
+	return Slot{Hex: hex, ANSI: ansi}
+}
+
+// Theme defines semantic color roles, and the chroma style that colors syntax in the diff.
 type Theme struct {
-	Primary         lipgloss.Color
-	Accent          lipgloss.Color
-	Secondary       lipgloss.Color
-	Text            lipgloss.Color
-	SelectedBg      lipgloss.Color
-	MutedBg         lipgloss.Color
-	SoftMutedBg     lipgloss.Color
-	ModalBg         lipgloss.Color
-	AddedLine       lipgloss.Color
-	DeletedLine     lipgloss.Color
-	WordDiffAddedBg lipgloss.Color
-	WordDiffDelBg   lipgloss.Color
+	Name            string
+	Syntax          string
+	Primary         Slot
+	Accent          Slot
+	Secondary       Slot
+	Text            Slot
+	SelectedBg      Slot
+	MutedBg         Slot
+	SoftMutedBg     Slot
+	ModalBg         Slot
+	AddedLine       Slot
+	DeletedLine     Slot
+	WordDiffAddedBg Slot
+	WordDiffDelBg   Slot
+	ConflictLine    Slot
+	ConflictMarker  Slot
 }
 
 // Latte returns Catppuccin Latte (light theme).
 func Latte() Theme {
 	return Theme{
-		Primary:         lipgloss.Color("#8839ef"), // mauve
-		Accent:          lipgloss.Color("#179299"), // teal
-		Secondary:       lipgloss.Color("#df8e1d"), // yellow
-		Text:            lipgloss.Color("#4c4f69"),
-		SelectedBg:      lipgloss.Color("#7287fd"), // lavender
-		MutedBg:         lipgloss.Color("#dce0e8"), // surface0
-		SoftMutedBg:     lipgloss.Color("#ccd0da"), // surface1
-		ModalBg:         lipgloss.Color("#eff1f5"), // base
-		AddedLine:       lipgloss.Color("#40a02b"), // green
-		DeletedLine:     lipgloss.Color("#d20f39"), // red
-		WordDiffAddedBg: lipgloss.Color("#acf2bd"), // light green bg
-		WordDiffDelBg:   lipgloss.Color("#ffc0c0"), // light red bg
+		Name:            "latte",
+		Syntax:          "catppuccin-latte",
+		Primary:         slot("#8839ef", "5"),  // mauve
+		Accent:          slot("#179299", "6"),  // teal
+		Secondary:       slot("#df8e1d", "3"),  // yellow
+		Text:            slot("#4c4f69", "0"),  // text
+		SelectedBg:      slot("#7287fd", "12"), // lavender
+		MutedBg:         slot("#dce0e8", "7"),  // surface0
+		SoftMutedBg:     slot("#ccd0da", "7"),  // surface1
+		ModalBg:         slot("#eff1f5", "15"), // base
+		AddedLine:       slot("#40a02b", "2"),  // green
+		DeletedLine:     slot("#d20f39", "1"),  // red
+		WordDiffAddedBg: slot("#acf2bd", "7"),  // light green bg
+		WordDiffDelBg:   slot("#ffc0c0", "7"),  // light red bg
+		ConflictLine:    slot("#fe640b", "3"),  // peach
+		ConflictMarker:  slot("#e64553", "9"),  // maroon
+	}
+}
+
+// Frappe returns Catppuccin Frappé (muted dark theme).
+func Frappe() Theme {
+	return Theme{
+		Name:            "frappe",
+		Syntax:          "catppuccin-frappe",
+		Primary:         slot("#ca9ee6", "13"), // mauve
+		Accent:          slot("#81c8be", "14"), // teal
+		Secondary:       slot("#ef9f76", "11"), // peach
+		Text:            slot("#c6d0f5", "15"), // text
+		SelectedBg:      slot("#babbf1", "4"),  // lavender
+		MutedBg:         slot("#414559", "8"),  // surface0
+		SoftMutedBg:     slot("#51576d", "8"),  // surface1
+		ModalBg:         slot("#303446", "0"),  // base
+		AddedLine:       slot("#a6d189", "10"), // green
+		DeletedLine:     slot("#e78284", "9"),  // red
+		WordDiffAddedBg: slot("#3e5042", "8"),  // dark green bg
+		WordDiffDelBg:   slot("#5a3d45", "8"),  // dark red bg
+		ConflictLine:    slot("#ef9f76", "11"), // peach
+		ConflictMarker:  slot("#ea999c", "13"), // maroon
 	}
 }
 
 // Macchiato returns Catppuccin Macchiato (dark theme).
 func Macchiato() Theme {
 	return Theme{
-		Primary:         lipgloss.Color("#c6a0f6"), // mauve
-		Accent:          lipgloss.Color("#8bd5ca"), // teal
-		Secondary:       lipgloss.Color("#f5a97f"), // peach
-		Text:            lipgloss.Color("#cad3f5"),
-		SelectedBg:      lipgloss.Color("#b7bdf8"), // lavender
-		MutedBg:         lipgloss.Color("#363a4f"), // surface0
-		SoftMutedBg:     lipgloss.Color("#494d64"), // surface1
-		ModalBg:         lipgloss.Color("#24273a"), // base
-		AddedLine:       lipgloss.Color("#a6da95"), // green
-		DeletedLine:     lipgloss.Color("#ed8796"), // red
-		WordDiffAddedBg: lipgloss.Color("#2d4a3e"), // dark green bg
-		WordDiffDelBg:   lipgloss.Color("#4a2d2d"), // dark red bg
+		Name:            "macchiato",
+		Syntax:          "catppuccin-macchiato",
+		Primary:         slot("#c6a0f6", "13"), // mauve
+		Accent:          slot("#8bd5ca", "14"), // teal
+		Secondary:       slot("#f5a97f", "11"), // peach
+		Text:            slot("#cad3f5", "15"), // text
+		SelectedBg:      slot("#b7bdf8", "4"),  // lavender
+		MutedBg:         slot("#363a4f", "8"),  // surface0
+		SoftMutedBg:     slot("#494d64", "8"),  // surface1
+		ModalBg:         slot("#24273a", "0"),  // base
+		AddedLine:       slot("#a6da95", "10"), // green
+		DeletedLine:     slot("#ed8796", "9"),  // red
+		WordDiffAddedBg: slot("#2d4a3e", "8"),  // dark green bg
+		WordDiffDelBg:   slot("#4a2d2d", "8"),  // dark red bg
+		ConflictLine:    slot("#f5a97f", "11"), // peach
+		ConflictMarker:  slot("#ee99a0", "13"), // maroon
+	}
+}
+
+// Mocha returns Catppuccin Mocha (darkest theme).
+func Mocha() Theme {
+	return Theme{
+		Name:            "mocha",
+		Syntax:          "catppuccin-mocha",
+		Primary:         slot("#cba6f7", "13"), // mauve
+		Accent:          slot("#94e2d5", "14"), // teal
+		Secondary:       slot("#fab387", "11"), // peach
+		Text:            slot("#cdd6f4", "15"), // text
+		SelectedBg:      slot("#b4befe", "4"),  // lavender
+		MutedBg:         slot("#313244", "8"),  // surface0
+		SoftMutedBg:     slot("#45475a", "8"),  // surface1
+		ModalBg:         slot("#1e1e2e", "0"),  // base
+		AddedLine:       slot("#a6e3a1", "10"), // green
+		DeletedLine:     slot("#f38ba8", "9"),  // red
+		WordDiffAddedBg: slot("#2a3f33", "8"),  // dark green bg
+		WordDiffDelBg:   slot("#47283a", "8"),  // dark red bg
+		ConflictLine:    slot("#fab387", "11"), // peach
+		ConflictMarker:  slot("#eba0ac", "13"), // maroon
 	}
 }
 
+// HighContrast returns a dark theme of saturated colors on black, for low-vision use and washed-out
+// displays.
+func HighContrast() Theme {
+	return Theme{
+		Name:            "high-contrast",
+		Syntax:          "hr_high_contrast",
+		Primary:         slot("#ffff00", "11"),
+		Accent:          slot("#00ffff", "14"),
+		Secondary:       slot("#ffaf00", "3"),
+		Text:            slot("#ffffff", "15"),
+		SelectedBg:      slot("#005fd7", "4"),
+		MutedBg:         slot("#303030", "8"),
+		SoftMutedBg:     slot("#4e4e4e", "8"),
+		ModalBg:         slot("#000000", "0"),
+		AddedLine:       slot("#00ff00", "10"),
+		DeletedLine:     slot("#ff5f5f", "9"),
+		WordDiffAddedBg: slot("#005f00", "2"),
+		WordDiffDelBg:   slot("#870000", "1"),
+		ConflictLine:    slot("#ff8700", "11"),
+		ConflictMarker:  slot("#ff00ff", "13"),
+	}
+}
+
+// Deuteranopia returns a dark theme from the Okabe-Ito palette, which tells additions from deletions
+// by blue and orange rather than green and red.
+func Deuteranopia() Theme {
+	return Theme{
+		Name:            "deuteranopia",
+		Syntax:          "modus-vivendi",
+		Primary:         slot("#cc79a7", "13"), // reddish purple
+		Accent:          slot("#56b4e9", "14"), // sky blue
+		Secondary:       slot("#f0e442", "11"), // yellow
+		Text:            slot("#e0e0e0", "15"),
+		SelectedBg:      slot("#0072b2", "4"), // blue
+		MutedBg:         slot("#333333", "8"),
+		SoftMutedBg:     slot("#4d4d4d", "8"),
+		ModalBg:         slot("#1a1a1a", "0"),
+		AddedLine:       slot("#56b4e9", "12"), // sky blue
+		DeletedLine:     slot("#e69f00", "3"),  // orange
+		WordDiffAddedBg: slot("#10384f", "4"),
+		WordDiffDelBg:   slot("#4d3500", "8"),
+		ConflictLine:    slot("#d55e00", "9"),  // vermillion
+		ConflictMarker:  slot("#f0e442", "11"), // yellow
+	}
+}
+
+// builtins are the themes a config can name without defining them.
+var builtins = map[string]func() Theme{
+	"latte":         Latte,
+	"frappe":        Frappe,
+	"macchiato":     Macchiato,
+	"mocha":         Mocha,
+	"high-contrast": HighContrast,
+	"deuteranopia":  Deuteranopia,
+}
+
+// Names lists the built-in themes, light to dark and then the accessible ones.
+func Names() []string {
+	return []string{"latte", "frappe", "macchiato", "mocha", "high-contrast", "deuteranopia"}
+}
+
+// Builtin returns the built-in theme called name. "light", "dark" and "frappé" are accepted as the
+// names CATPPUCCIN_THEME has always taken.
+func Builtin(name string) (Theme, bool) {
+	switch strings.ToLower(name) {
+	case "light":
+		return Latte(), true
+	case "dark":
+		return Macchiato(), true
+	case "frappé":
+		return Frappe(), true
+	}
+
+	build, ok := builtins[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, false
+	}
+
+	return build(), true
+}
+
+// Select resolves a theme name: one defined in the config, then a built-in one, then Auto. A name
+// that is none of those is an error, with the Auto theme returned in its place.
+func Select(name string, custom map[string]Definition) (Theme, error) {
+	if def, ok := custom[name]; ok {
+		base := def.Base
+		if base == "" {
+			// A definition named after a built-in theme adjusts it, rather than starting over from Auto.
+			base = name
+		}
+
+		t, _ := Select(base, nil)
+
+		return def.apply(name, t), nil
+	}
+
+	if t, ok := Builtin(name); ok {
+		return t, nil
+	}
+
+	return Detect(), Check(name, custom)
+}
+
+// Check returns the error Select would for name, without detecting the background.
+func Check(name string, custom map[string]Definition) error {
+	_, defined := custom[name]
+	_, builtin := Builtin(name)
+
+	if defined || builtin || name == "" || name == Auto {
+		return nil
+	}
+
+	return fmt.Errorf("%w %q, expected %s, auto, or one defined under themes",
+		ErrUnknownTheme, name, strings.Join(Names(), ", "))
+}
+
 // Detect returns the appropriate theme based on environment.
 func Detect() Theme {
-	if env := os.Getenv("CATPPUCCIN_THEME"); env != "" {
-		switch strings.ToLower(env) {
-		case "latte", "light":
-			return Latte()
-		case "macchiato", "dark":
-			return Macchiato()
-		}
+	if t, ok := Builtin(os.Getenv("CATPPUCCIN_THEME")); ok {
+		return t
 	}
 
 	if lipgloss.HasDarkBackground() {
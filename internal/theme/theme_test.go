@@ -0,0 +1,144 @@
+package theme_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kyleking/jj-diff/internal/theme"
+)
+
+func TestBuiltins(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range theme.Names() {
+		th, ok := theme.Builtin(name)
+		if !ok || th.Name != name {
+			t.Errorf("Expected %s to be built in, got %+v", name, th)
+
+			continue
+		}
+
+		if !slices.Contains(styles.Names(), th.Syntax) {
+			t.Errorf("Expected %s's syntax style %q to be a chroma style", name, th.Syntax)
+		}
+
+		if th.AddedLine.ANSI == "" || th.DeletedLine.ANSI == "" {
+			t.Errorf("Expected %s to give its diff colors a 16-color fallback", name)
+		}
+	}
+
+	if th, ok := theme.Builtin("frappé"); !ok || th.Name != "frappe" {
+		t.Errorf("Expected the accented name to select Frappé, got %+v", th)
+	}
+}
+
+func TestSlotColor(t *testing.T) {
+	t.Parallel()
+
+	if got := (theme.Slot{Hex: "#a6e3a1"}).Color(); got != lipgloss.Color("#a6e3a1") {
+		t.Errorf("Expected a slot without fallbacks to leave the conversion to lipgloss, got %#v", got)
+	}
+
+	want := lipgloss.CompleteColor{TrueColor: "#a6e3a1", ANSI256: "#a6e3a1", ANSI: "10"}
+	if got := (theme.Slot{Hex: "#a6e3a1", ANSI: "10"}).Color(); got != want {
+		t.Errorf("Expected the missing 256-color fallback to be the nearest to the hex, got %#v", got)
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	t.Parallel()
+
+	def, err := theme.ParseDefinition(map[string]any{
+		"base":    "mocha",
+		"syntax":  "dracula",
+		"added":   "#00ff00",
+		"deleted": map[string]any{"hex": "#ff0000", "ansi256": int64(196), "ansi": "9"},
+		"text":    "white",
+		"colour":  "#ffffff",
+		"primary": map[string]any{"hex": "#ff00ff", "ansi": int64(16)},
+	})
+
+	if !errors.Is(err, theme.ErrInvalidDefinition) {
+		t.Fatalf("Expected the bad entries to be reported, got %v", err)
+	}
+
+	if len(def.Slots) != 2 || def.Slots["deleted"].ANSI256 != "196" {
+		t.Errorf("Expected only the good slots, got %+v", def.Slots)
+	}
+
+	custom := map[string]theme.Definition{"mine": def}
+
+	th, err := theme.Select("mine", custom)
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	mocha := theme.Mocha()
+	if th.Name != "mine" || th.Syntax != "dracula" || th.AddedLine.Hex != "#00ff00" || th.Primary != mocha.Primary {
+		t.Errorf("Expected Mocha with the definition's changes, got %+v", th)
+	}
+}
+
+func TestSelect_AdjustsABuiltin(t *testing.T) {
+	t.Parallel()
+
+	def, err := theme.ParseDefinition(map[string]any{"added": "#00ff00"})
+	if err != nil {
+		t.Fatalf("ParseDefinition failed: %v", err)
+	}
+
+	th, err := theme.Select("latte", map[string]theme.Definition{"latte": def})
+	if err != nil || th.AddedLine.Hex != "#00ff00" || th.Primary != theme.Latte().Primary {
+		t.Errorf("Expected Latte with one slot changed, got %+v, %v", th, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"", theme.Auto, "mocha", "dark", "mine"} {
+		if err := theme.Check(name, map[string]theme.Definition{"mine": {}}); err != nil {
+			t.Errorf("Check(%q) = %v", name, err)
+		}
+	}
+
+	if err := theme.Check("solarized", nil); !errors.Is(err, theme.ErrUnknownTheme) {
+		t.Errorf("Expected an unknown theme, got %v", err)
+	}
+}
+
+func TestParseJJColors(t *testing.T) {
+	t.Parallel()
+
+	def, err := theme.ParseJJColors(map[string]any{
+		"diff added":         "bright green",
+		"diff removed":       map[string]any{"fg": "#ff0000", "bold": true},
+		"diff token":         map[string]any{"bg": "ansi-color-238", "underline": true},
+		"diff added token":   map[string]any{"fg": "green"},
+		"diff removed token": map[string]any{"bg": "default"},
+		"diff header":        "yellow",
+	})
+	if err != nil {
+		t.Fatalf("ParseJJColors failed: %v", err)
+	}
+
+	want := map[string]theme.Slot{
+		"added":           {ANSI: "10"},
+		"deleted":         {Hex: "#ff0000"},
+		"word-added-bg":   {ANSI256: "238"},
+		"word-deleted-bg": {ANSI256: "238"},
+	}
+	if !reflect.DeepEqual(def.Slots, want) {
+		t.Errorf("Expected %+v, got %+v", want, def.Slots)
+	}
+
+	def, err = theme.ParseJJColors(map[string]any{"diff added": "chartreuse", "diff removed": "red"})
+	if !errors.Is(err, theme.ErrInvalidColor) || len(def.Slots) != 1 || def.Slots["deleted"].ANSI != "1" {
+		t.Errorf("Expected the unknown color skipped and the rest kept, got %+v, %v", def.Slots, err)
+	}
+}
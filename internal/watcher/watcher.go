@@ -0,0 +1,254 @@
+// Package watcher notices when the repository changes outside the app, so the diff on screen can be
+// reloaded instead of going stale. It watches jj's operation heads, which every jj command that
+// writes the repository replaces, and optionally the working copy, whose edits jj only records the
+// next time it runs.
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the watcher waits after the last event before it reports a change. One
+// jj command touches several files, and an editor saving a file touches several more, so reporting
+// every event would reload the diff once per file.
+const DefaultDebounce = 300 * time.Millisecond
+
+// ErrNotJJRepo is returned when no directory from the starting one up to the root holds a .jj
+// directory.
+var ErrNotJJRepo = errors.New("not inside a jj repository")
+
+// skippedDirs are the working-copy directories the watcher never descends into: jj's own state,
+// which the operation heads already cover, and a colocated git repository, which jj rewrites on
+// every operation.
+var skippedDirs = map[string]bool{
+	".jj":  true,
+	".git": true,
+}
+
+// RefreshMsg tells the model the repository changed and the diff should be reloaded. Watcher.Wait
+// delivers it.
+type RefreshMsg struct{}
+
+// Options selects what is watched. A zero Debounce means DefaultDebounce.
+type Options struct {
+	Debounce    time.Duration
+	WorkingCopy bool
+}
+
+// Watcher reports debounced repository changes. Build it with New and stop it with Close.
+type Watcher struct {
+	fsWatcher *fsnotify.Watcher
+	changes   chan struct{}
+	done      chan struct{}
+	debounce  time.Duration
+	closeOnce sync.Once
+	recursive bool
+}
+
+// New starts watching the jj repository that contains dir. It fails when dir is not inside a jj
+// repository or the operation heads cannot be watched; a working-copy directory that cannot be
+// watched is skipped instead, because the operation heads still catch every jj command.
+func New(dir string, opts Options) (*Watcher, error) {
+	workspaceRoot, err := findWorkspaceRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repoDir, err := resolveRepoDir(filepath.Join(workspaceRoot, ".jj"))
+	if err != nil {
+		return nil, err
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	// jj replaces the file under op_heads/heads on every operation. Older repositories keep the
+	// heads directly in op_heads, so both directories are watched.
+	opHeads := filepath.Join(repoDir, "op_heads")
+	for _, path := range []string{opHeads, filepath.Join(opHeads, "heads")} {
+		if _, statErr := os.Stat(path); statErr != nil {
+			continue
+		}
+
+		if err := fsWatcher.Add(path); err != nil {
+			_ = fsWatcher.Close()
+
+			return nil, fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+	}
+
+	if len(fsWatcher.WatchList()) == 0 {
+		_ = fsWatcher.Close()
+
+		return nil, fmt.Errorf("%s: %w", opHeads, ErrNotJJRepo)
+	}
+
+	w := &Watcher{
+		fsWatcher: fsWatcher,
+		changes:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		debounce:  opts.Debounce,
+		recursive: opts.WorkingCopy,
+	}
+
+	if w.debounce <= 0 {
+		w.debounce = DefaultDebounce
+	}
+
+	if opts.WorkingCopy {
+		w.addTree(workspaceRoot)
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// Wait returns a command that blocks until the next change and then delivers a RefreshMsg. The model
+// issues it again after each refresh, so exactly one is pending at a time. After Close it delivers
+// nil, which Update ignores.
+func (w *Watcher) Wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-w.changes:
+			return RefreshMsg{}
+		case <-w.done:
+			return nil
+		}
+	}
+}
+
+// Close stops watching. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	var err error
+
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fsWatcher.Close()
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to close file watcher: %w", err)
+	}
+
+	return nil
+}
+
+// run folds bursts of events into one change. Each event restarts the quiet period, and the change is
+// reported once it passes. The channel holds one change, so changes that pile up while the model is
+// still reloading collapse into the single reload that follows.
+func (w *Watcher) run() {
+	var quiet <-chan time.Time
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if w.recursive && event.Has(fsnotify.Create) {
+				w.addTree(event.Name)
+			}
+
+			quiet = time.After(w.debounce)
+		case _, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+		case <-quiet:
+			quiet = nil
+
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// addTree watches root and every directory under it except skippedDirs. fsnotify does not recurse,
+// so a directory created later is added when its Create event arrives. Directories that vanish or
+// cannot be read mid-walk are skipped.
+func (w *Watcher) addTree(root string) {
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil //nolint:nilerr // an unreadable directory is skipped, not fatal
+		}
+
+		if skippedDirs[entry.Name()] {
+			return filepath.SkipDir
+		}
+
+		_ = w.fsWatcher.Add(path)
+
+		return nil
+	})
+}
+
+// findWorkspaceRoot walks up from dir to the first directory holding a .jj directory.
+func findWorkspaceRoot(dir string) (string, error) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	for {
+		if info, statErr := os.Stat(filepath.Join(current, ".jj")); statErr == nil && info.IsDir() {
+			return current, nil
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("%s: %w", dir, ErrNotJJRepo)
+		}
+
+		current = parent
+	}
+}
+
+// resolveRepoDir returns the store behind a workspace's .jj directory. In the main workspace
+// .jj/repo is the store itself; in a workspace added with jj workspace add it is a file holding the
+// path to the main workspace's store, relative to the .jj directory.
+func resolveRepoDir(jjDir string) (string, error) {
+	repoPath := filepath.Join(jjDir, "repo")
+
+	info, err := os.Stat(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", repoPath, ErrNotJJRepo)
+	}
+
+	if info.IsDir() {
+		return repoPath, nil
+	}
+
+	target, err := os.ReadFile(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", repoPath, err)
+	}
+
+	resolved := strings.TrimSpace(string(target))
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(jjDir, resolved)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,170 @@
+package watcher_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kyleking/jj-diff/internal/watcher"
+)
+
+const (
+	testDebounce = 20 * time.Millisecond
+	waitTimeout  = 2 * time.Second
+)
+
+// newRepo lays out the part of a jj repository the watcher reads: a workspace root holding
+// .jj/repo/op_heads/heads.
+func newRepo(t *testing.T) (string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	heads := filepath.Join(root, ".jj", "repo", "op_heads", "heads")
+
+	if err := os.MkdirAll(heads, 0o755); err != nil {
+		t.Fatalf("Failed to create op heads: %v", err)
+	}
+
+	return root, heads
+}
+
+// newWatcher starts a watcher that the test closes when it ends.
+func newWatcher(t *testing.T, dir string, opts watcher.Options) *watcher.Watcher {
+	t.Helper()
+
+	w, err := watcher.New(dir, opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := w.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+
+	return w
+}
+
+// waitFor runs a Wait command and returns its message, or nil when nothing arrives in time.
+func waitFor(t *testing.T, w *watcher.Watcher, timeout time.Duration) tea.Msg {
+	t.Helper()
+
+	result := make(chan tea.Msg, 1)
+	go func() { result <- w.Wait()() }()
+
+	select {
+	case msg := <-result:
+		return msg
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestWatcher_DebouncesOperationHeads(t *testing.T) {
+	t.Parallel()
+
+	root, heads := newRepo(t)
+
+	w := newWatcher(t, root, watcher.Options{Debounce: testDebounce})
+
+	for _, name := range []string{"op1", "op2", "op3"} {
+		writeFile(t, filepath.Join(heads, name))
+	}
+
+	if _, ok := waitFor(t, w, waitTimeout).(watcher.RefreshMsg); !ok {
+		t.Fatal("Expected a RefreshMsg after the operation heads changed")
+	}
+
+	if msg := waitFor(t, w, 5*testDebounce); msg != nil {
+		t.Errorf("Expected one refresh for a burst of events, got a second %T", msg)
+	}
+}
+
+func TestWatcher_WorkingCopy(t *testing.T) {
+	t.Parallel()
+
+	root, _ := newRepo(t)
+	subdir := filepath.Join(root, "src")
+
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatalf("Failed to create src: %v", err)
+	}
+
+	w := newWatcher(t, subdir, watcher.Options{Debounce: testDebounce, WorkingCopy: true})
+
+	writeFile(t, filepath.Join(subdir, "main.go"))
+
+	if _, ok := waitFor(t, w, waitTimeout).(watcher.RefreshMsg); !ok {
+		t.Fatal("Expected a RefreshMsg after a working-copy file changed")
+	}
+}
+
+func TestWatcher_SecondaryWorkspace(t *testing.T) {
+	t.Parallel()
+
+	mainRoot, heads := newRepo(t)
+	workspace := t.TempDir()
+	jjDir := filepath.Join(workspace, ".jj")
+
+	if err := os.Mkdir(jjDir, 0o755); err != nil {
+		t.Fatalf("Failed to create .jj: %v", err)
+	}
+
+	target := filepath.Join(mainRoot, ".jj", "repo")
+	if err := os.WriteFile(filepath.Join(jjDir, "repo"), []byte(target), 0o600); err != nil {
+		t.Fatalf("Failed to write repo pointer: %v", err)
+	}
+
+	w := newWatcher(t, workspace, watcher.Options{Debounce: testDebounce})
+
+	writeFile(t, filepath.Join(heads, "op1"))
+
+	if _, ok := waitFor(t, w, waitTimeout).(watcher.RefreshMsg); !ok {
+		t.Fatal("Expected the workspace to follow the main repository's operation heads")
+	}
+}
+
+func TestWatcher_NotARepository(t *testing.T) {
+	t.Parallel()
+
+	_, err := watcher.New(t.TempDir(), watcher.Options{})
+	if !errors.Is(err, watcher.ErrNotJJRepo) {
+		t.Errorf("Expected ErrNotJJRepo, got %v", err)
+	}
+}
+
+func TestWatcher_CloseReleasesWait(t *testing.T) {
+	t.Parallel()
+
+	root, _ := newRepo(t)
+
+	w, err := watcher.New(root, watcher.Options{Debounce: testDebounce})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Expected a second Close to be harmless, got %v", err)
+	}
+
+	if msg := w.Wait()(); msg != nil {
+		t.Errorf("Expected nil after Close, got %T", msg)
+	}
+}
@@ -1,10 +1,17 @@
 package integration_test
 
 import (
+	"context"
+	"errors"
+	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/kyleking/jj-diff/internal/diff"
 	"github.com/kyleking/jj-diff/internal/jj"
 	"github.com/kyleking/jj-diff/tests/integration"
 )
@@ -37,7 +44,7 @@ func TestMoveChanges_CoreWorkflow(t *testing.T) {
 	client := jj.NewClient(repo.Dir)
 
 	// Execute: Move changes from @ to @-
-	err := client.MoveChanges(patch, "@", "@-")
+	err := client.MoveChanges(context.Background(), patch, "@", "@-")
 	if err != nil {
 		t.Fatalf("MoveChanges failed: %v", err)
 	}
@@ -79,7 +86,7 @@ func TestMoveChanges_RollbackOnError(t *testing.T) {
 +invalid change
 `
 
-	err := client.MoveChanges(invalidPatch, "@", "@-")
+	err := client.MoveChanges(context.Background(), invalidPatch, "@", "@-")
 
 	// Verify: Operation should fail
 	if err == nil {
@@ -109,6 +116,106 @@ func TestMoveChanges_RollbackOnError(t *testing.T) {
 	}
 }
 
+// cancelOnWrite is a context that is done once the repository has an operation it did not start
+// with. exec checks Done as each jj command starts, so a move is cancelled at the first jj call after
+// its first write, the point a ctrl+g partway through would leave it.
+type cancelOnWrite struct {
+	context.Context //nolint:containedctx // the parent, which supplies Deadline and Value.
+
+	done  chan struct{}
+	once  *sync.Once
+	heads string
+	start []string
+}
+
+func newCancelOnWrite(t *testing.T, repoDir string) cancelOnWrite {
+	t.Helper()
+
+	c := cancelOnWrite{
+		Context: context.Background(),
+		done:    make(chan struct{}),
+		once:    &sync.Once{},
+		heads:   filepath.Join(repoDir, ".jj", "repo", "op_heads", "heads"),
+	}
+
+	c.start = c.operationHeads()
+	if len(c.start) == 0 {
+		t.Fatalf("no operation heads in %s", c.heads)
+	}
+
+	return c
+}
+
+func (c cancelOnWrite) operationHeads() []string {
+	entries, _ := os.ReadDir(c.heads)
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+
+	return names
+}
+
+func (c cancelOnWrite) Done() <-chan struct{} {
+	if !slices.Equal(c.operationHeads(), c.start) {
+		c.once.Do(func() { close(c.done) })
+	}
+
+	return c.done
+}
+
+func (c cancelOnWrite) Err() error {
+	select {
+	case <-c.done:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+// TestMoveChanges_CancelledMidMoveRestoresTheOperation cancels a move after its first write and
+// checks that the operation recorded before it started is restored.
+func TestMoveChanges_CancelledMidMoveRestoresTheOperation(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("Initial commit")
+	repo.WriteFile("file1.txt", "line 1\nline 2\n")
+
+	// Snapshot the edit now, so the first new operation is the move's own.
+	repo.MustRun("status")
+
+	client := jj.NewClient(repo.Dir)
+
+	opID, err := client.CurrentOperationID(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentOperationID failed: %v", err)
+	}
+
+	commits := `commit_id ++ "\n"`
+	before := repo.MustRun("log", "-r", "all()", "--no-graph", "-T", commits)
+
+	err = client.MoveChanges(newCancelOnWrite(t, repo.Dir), repo.GetDiff("@"), "@", "@-")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the move to stop as cancelled, got %v", err)
+	}
+
+	if after := repo.MustRun("log", "-r", "all()", "--no-graph", "-T", commits); after != before {
+		t.Errorf("Expected the commits from before the move, got:\n%s\nwant:\n%s", after, before)
+	}
+
+	restored := repo.MustRun("op", "log", "--no-graph", "--limit", "1", "-T", "description")
+	if !strings.Contains(restored, opID) {
+		t.Errorf("Expected the last operation to restore %s, got %q", opID, restored)
+	}
+
+	repo.AssertFileContent("file1.txt", "line 1\nline 2\n")
+	repo.AssertDiffContains("@", "+line 2")
+}
+
 // TestMoveChanges_WorkingCopyPreservation tests that operations complete successfully
 // This verifies the workflow completes and repository state is consistent.
 func TestMoveChanges_WorkingCopyPreservation(t *testing.T) {
@@ -129,7 +236,7 @@ func TestMoveChanges_WorkingCopyPreservation(t *testing.T) {
 	client := jj.NewClient(repo.Dir)
 
 	// Execute: Move changes to @-
-	err := client.MoveChanges(patch, "@", "@-")
+	err := client.MoveChanges(context.Background(), patch, "@", "@-")
 	if err != nil {
 		t.Fatalf("MoveChanges failed: %v", err)
 	}
@@ -193,7 +300,7 @@ func TestMoveChanges_LeavesUnselectedChangesAlone(t *testing.T) {
 `
 
 	client := jj.NewClient(repo.Dir)
-	if err := client.MoveChanges(patch, "@", "@-"); err != nil {
+	if err := client.MoveChanges(context.Background(), patch, "@", "@-"); err != nil {
 		t.Fatalf("MoveChanges failed: %v", err)
 	}
 
@@ -236,7 +343,7 @@ func TestMoveChanges_ResolvesDestinationBeforeMoving(t *testing.T) {
 	patch := repo.GetDiff("@")
 
 	client := jj.NewClient(repo.Dir)
-	if err := client.MoveChanges(patch, "@", "@-"); err != nil {
+	if err := client.MoveChanges(context.Background(), patch, "@", "@-"); err != nil {
 		t.Fatalf("MoveChanges failed: %v", err)
 	}
 
@@ -271,7 +378,7 @@ func TestMoveChanges_CleansUpScratchWorkspaceOnFailure(t *testing.T) {
 `
 
 	client := jj.NewClient(repo.Dir)
-	if err := client.MoveChanges(invalidPatch, "@", "@-"); err == nil {
+	if err := client.MoveChanges(context.Background(), invalidPatch, "@", "@-"); err == nil {
 		t.Fatal("expected MoveChanges to fail with an invalid patch")
 	}
 
@@ -292,9 +399,353 @@ func TestMoveChanges_CleansUpScratchWorkspaceOnFailure(t *testing.T) {
 	repo.AssertFileContent("file1.txt", "line 1\nline 2\nline 3\n")
 }
 
+// TestMoveChanges_FromAnOlderRevision moves out of a source that is not @. The source used to be
+// ignored, which only worked because @ is rebased onto an ancestor destination; from @- the change
+// stayed in the source as well as landing in the destination.
+func TestMoveChanges_FromAnOlderRevision(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("first")
+	repo.WriteFile("file1.txt", "line 1\nMOVED\n")
+	repo.WriteFile("file2.txt", "stays\n")
+	repo.Commit("second")
+
+	patch := `diff --git a/file1.txt b/file1.txt
+--- a/file1.txt
++++ b/file1.txt
+@@ -1 +1,2 @@
+ line 1
++MOVED
+`
+
+	client := jj.NewClient(repo.Dir)
+	if err := client.MoveChanges(context.Background(), patch, "@-", "@--"); err != nil {
+		t.Fatalf("MoveChanges failed: %v", err)
+	}
+
+	repo.AssertDiffContains("@--", "+MOVED")
+	repo.AssertDiffNotContains("@-", "MOVED")
+	repo.AssertDiffContains("@-", "+stays")
+	repo.AssertDiffEmpty("@")
+	repo.AssertFileContent("file1.txt", "line 1\nMOVED\n")
+}
+
+// TestMoveChangesPartial_MovesWhatLandsPastChurn moves two edits two commits down, past a commit
+// that touched a line of one edit's context and the line the other edit changes. The first lands with
+// fuzz and the second stays behind in the source.
+func TestMoveChangesPartial_MovesWhatLandsPastChurn(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i + 1)
+	}
+
+	content := func(edits map[int]string) string {
+		edited := slices.Clone(lines)
+		for line, text := range edits {
+			edited[line-1] = text
+		}
+
+		return strings.Join(edited, "\n") + "\n"
+	}
+
+	repo.WriteFile("file1.txt", content(nil))
+	repo.Commit("base")
+	repo.WriteFile("file1.txt", content(map[int]string{3: "3 churn", 15: "15 churn"}))
+	repo.Commit("churn")
+
+	final := content(map[int]string{3: "3 churn", 6: "6 fixed", 15: "15 fixed"})
+	repo.WriteFile("file1.txt", final)
+
+	patch := repo.GetDiff("@")
+
+	client := jj.NewClient(repo.Dir)
+
+	checked, err := client.CheckMove(context.Background(), patch, "@", "@--", 1)
+	if err != nil {
+		t.Fatalf("CheckMove failed: %v", err)
+	}
+
+	if checked.Count(diff.HunkShifted) != 1 || checked.Count(diff.HunkRejected) != 1 {
+		t.Errorf("Expected the check to predict one shifted and one rejected hunk, got %+v", checked.Hunks)
+	}
+
+	repo.AssertDiffNotContains("@--", "6 fixed")
+
+	if err := client.MoveChanges(context.Background(), patch, "@", "@--"); err == nil {
+		t.Fatal("Expected MoveChanges to refuse a patch that does not apply whole")
+	}
+
+	report, err := client.MoveChangesPartial(context.Background(), patch, "@", "@--", 1)
+	if err != nil {
+		t.Fatalf("MoveChangesPartial failed: %v", err)
+	}
+
+	if report.Count(diff.HunkShifted) != 1 || report.Count(diff.HunkRejected) != 1 {
+		t.Errorf("Expected one shifted and one rejected hunk, got %+v", report.Hunks)
+	}
+
+	repo.AssertDiffContains("@--", "+6 fixed")
+	repo.AssertDiffNotContains("@", "6 fixed")
+	repo.AssertDiffContains("@", "+15 fixed")
+	repo.AssertFileContent("file1.txt", final)
+}
+
+// TestApplyPatch_LandsAPatchFromOutside applies a patch that came from no revision into the parent,
+// which the working copy then sees through it, and leaves the working copy's own change alone.
+func TestApplyPatch_LandsAPatchFromOutside(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\nline 2\n")
+	repo.Commit("base")
+	repo.WriteFile("file2.txt", "working copy\n")
+
+	patch := "diff --git a/file1.txt b/file1.txt\n--- a/file1.txt\n+++ b/file1.txt\n" +
+		"@@ -1,2 +1,3 @@\n line 1\n+mailed\n line 2\n"
+
+	client := jj.NewClient(repo.Dir)
+
+	checked, err := client.CheckApply(context.Background(), patch, "@-", 0)
+	if err != nil || !checked.Clean() {
+		t.Fatalf("Expected the patch to check clean, got %+v, %v", checked.Hunks, err)
+	}
+
+	report, err := client.ApplyPatch(context.Background(), patch, "@-", 0)
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+
+	if !report.Clean() {
+		t.Errorf("Expected the patch to apply cleanly, got %+v", report.Hunks)
+	}
+
+	repo.AssertDiffContains("@-", "+mailed")
+	repo.AssertDiffNotContains("@", "mailed")
+	repo.AssertDiffContains("@", "+working copy")
+	repo.AssertFileContent("file1.txt", "line 1\nmailed\nline 2\n")
+}
+
+// TestApplyPatch_IntoTheWorkingCopy applies a patch into @, as piping git format-patch into
+// jj-diff -d @ does. The files on disk take the patch, and the workspace is not left stale.
+func TestApplyPatch_IntoTheWorkingCopy(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\nline 2\n")
+	repo.Commit("base")
+	repo.WriteFile("file2.txt", "working copy\n")
+
+	patch := "diff --git a/file1.txt b/file1.txt\n--- a/file1.txt\n+++ b/file1.txt\n" +
+		"@@ -1,2 +1,3 @@\n line 1\n+mailed\n line 2\n"
+
+	client := jj.NewClient(repo.Dir)
+
+	if _, err := client.ApplyPatch(context.Background(), patch, "@", 0); err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+
+	repo.AssertDiffContains("@", "+mailed")
+	repo.AssertDiffContains("@", "+working copy")
+	repo.AssertFileContent("file1.txt", "line 1\nmailed\nline 2\n")
+	repo.AssertFileContent("file2.txt", "working copy\n")
+}
+
+// TestMoveChanges_IntoADescendant moves a change later in history: it leaves the source and lands in
+// the destination, and the content of the destination, which already saw the change, is unchanged.
+func TestMoveChanges_IntoADescendant(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("first")
+	repo.WriteFile("file1.txt", "line 1\nMOVED\n")
+	repo.Commit("second")
+	repo.WriteFile("file2.txt", "working copy\n")
+
+	patch := repo.GetDiff("@-")
+
+	client := jj.NewClient(repo.Dir)
+	if err := client.MoveChanges(context.Background(), patch, "@-", "@"); err != nil {
+		t.Fatalf("MoveChanges failed: %v", err)
+	}
+
+	repo.AssertDiffEmpty("@-")
+	repo.AssertDiffContains("@", "+MOVED")
+	repo.AssertDiffContains("@", "+working copy")
+	repo.AssertFileContent("file1.txt", "line 1\nMOVED\n")
+	repo.AssertFileContent("file2.txt", "working copy\n")
+}
+
+// TestMoveChanges_IntoASibling moves between revisions where neither is an ancestor of the other, so
+// the change has to be taken out of one and put into the other.
+func TestMoveChanges_IntoASibling(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("base")
+
+	base := repo.GetChangeID("@-")
+
+	repo.WriteFile("file1.txt", "line 1\nMOVED\n")
+	repo.MustRun("describe", "-m", "source")
+
+	source := repo.GetChangeID("@")
+
+	repo.MustRun("new", base, "-m", "sibling")
+
+	patch := repo.GetDiff(source)
+
+	client := jj.NewClient(repo.Dir)
+	if err := client.MoveChanges(context.Background(), patch, source, "@"); err != nil {
+		t.Fatalf("MoveChanges failed: %v", err)
+	}
+
+	repo.AssertDiffEmpty(source)
+	repo.AssertDiffContains("@", "+MOVED")
+	repo.AssertFileContent("file1.txt", "line 1\nMOVED\n")
+}
+
+// TestMoveChanges_OutOfTheWorkingCopy moves the working copy's change into a sibling. The scratch
+// workspace rewrites @, so the files on disk have to follow and the next jj command must not find the
+// workspace stale.
+func TestMoveChanges_OutOfTheWorkingCopy(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("base")
+
+	base := repo.GetChangeID("@-")
+
+	repo.WriteFile("file2.txt", "sibling\n")
+	repo.MustRun("describe", "-m", "sibling")
+
+	sibling := repo.GetChangeID("@")
+
+	repo.MustRun("new", base)
+	repo.WriteFile("file1.txt", "line 1\nMOVED\n")
+
+	patch := repo.GetDiff("@")
+
+	client := jj.NewClient(repo.Dir)
+	if err := client.MoveChanges(context.Background(), patch, "@", sibling); err != nil {
+		t.Fatalf("MoveChanges failed: %v", err)
+	}
+
+	repo.AssertDiffEmpty("@")
+	repo.AssertDiffContains(sibling, "+MOVED")
+	repo.AssertFileContent("file1.txt", "line 1\n")
+}
+
+// TestMoveChanges_RejectsTheSourceAsDestination checks the relationship before anything is written.
+func TestMoveChanges_RejectsTheSourceAsDestination(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("first")
+	repo.WriteFile("file1.txt", "line 1\nADDED\n")
+
+	patch := repo.GetDiff("@")
+	before := repo.MustRun("op", "log", "--no-graph", "--limit", "1", "-T", "id")
+
+	client := jj.NewClient(repo.Dir)
+	if err := client.MoveChanges(context.Background(), patch, "@", "@"); err == nil {
+		t.Fatal("expected a move onto its own source to be rejected")
+	}
+
+	if after := repo.MustRun("op", "log", "--no-graph", "--limit", "1", "-T", "id"); after != before {
+		t.Errorf("a rejected move ran jj operations: %s became %s", before, after)
+	}
+
+	repo.AssertDiffContains("@", "+ADDED")
+}
+
+// TestApplySplit_NewCommitsStackBeforeTheSource covers jj split semantics: each new-commit plan
+// becomes a described commit between the source and its parent, in plan order, and the source keeps
+// its content and change ID while its diff shrinks to what no plan took.
+func TestApplySplit_NewCommitsStackBeforeTheSource(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("a.txt", "a1\n")
+	repo.WriteFile("b.txt", "b1\n")
+	repo.WriteFile("c.txt", "c1\n")
+	repo.Commit("Initial commit")
+
+	repo.WriteFile("a.txt", "a1\nA-ADDED\n")
+	repo.WriteFile("b.txt", "b1\nB-ADDED\n")
+	repo.WriteFile("c.txt", "c1\nC-ADDED\n")
+
+	originalWC := repo.GetChangeID("@")
+	originalParent := repo.GetChangeID("@-")
+
+	client := jj.NewClient(repo.Dir)
+	plans := []jj.SplitPlan{
+		{
+			Tag:         'a',
+			Patch:       "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1,2 @@\n a1\n+A-ADDED\n",
+			Destination: jj.SplitDestination{Type: jj.SplitDestNewCommit, Description: "split: a only"},
+		},
+		{
+			Tag:         'b',
+			Patch:       "diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1,2 @@\n b1\n+B-ADDED\n",
+			Destination: jj.SplitDestination{Type: jj.SplitDestNewCommit, Description: "split: b only"},
+		},
+	}
+
+	if err := client.ApplySplit(context.Background(), plans, "@"); err != nil {
+		t.Fatalf("ApplySplit failed: %v", err)
+	}
+
+	repo.AssertFileContent("a.txt", "a1\nA-ADDED\n")
+	repo.AssertFileContent("b.txt", "b1\nB-ADDED\n")
+	repo.AssertFileContent("c.txt", "c1\nC-ADDED\n")
+
+	if currentWC := repo.GetChangeID("@"); currentWC != originalWC {
+		t.Errorf("working copy moved:\nExpected: %s\nActual:   %s", originalWC, currentWC)
+	}
+
+	descriptions := repo.MustRun("log", "--no-graph", "-r", "@--::@-", "-T", `description.first_line() ++ "\n"`)
+	if descriptions != "split: b only\nsplit: a only\n" {
+		t.Errorf("expected the new commits stacked a then b under @, got:\n%s", descriptions)
+	}
+
+	if parent := repo.GetChangeID("@---"); parent != originalParent {
+		t.Errorf("new commits not inserted on the original parent:\nExpected: %s\nActual:   %s", originalParent, parent)
+	}
+
+	repo.AssertDiffContains("@--", "+A-ADDED")
+	repo.AssertDiffNotContains("@--", "B-ADDED")
+	repo.AssertDiffContains("@-", "+B-ADDED")
+	repo.AssertDiffNotContains("@-", "A-ADDED")
+	repo.AssertDiffContains("@", "+C-ADDED")
+	repo.AssertDiffNotContains("@", "A-ADDED")
+	repo.AssertDiffNotContains("@", "B-ADDED")
+
+	if conflicts := repo.MustRun("log", "--no-graph", "-r", "conflicts()", "-T", "change_id"); conflicts != "" {
+		t.Errorf("split left conflicted revisions: %s", conflicts)
+	}
+}
+
 // TestApplySplit_FailedPlanLeavesTheWorkingCopyIntact covers the split path's safety property. The
-// new-commit destination cannot apply its patch today (see FINDINGS.md), so what this asserts is that
-// the failure is reported and rolled back rather than taking the working copy with it.
+// second plan cannot apply, so the commit the first plan created has to be rolled back with it rather
+// than left behind.
 func TestApplySplit_FailedPlanLeavesTheWorkingCopyIntact(t *testing.T) {
 	t.Parallel()
 
@@ -308,6 +759,7 @@ func TestApplySplit_FailedPlanLeavesTheWorkingCopyIntact(t *testing.T) {
 	repo.WriteFile("b.txt", "b1\nB-ADDED\n")
 
 	originalWC := repo.GetChangeID("@")
+	originalParent := repo.GetChangeID("@-")
 
 	patchA := `diff --git a/a.txt b/a.txt
 --- a/a.txt
@@ -315,29 +767,50 @@ func TestApplySplit_FailedPlanLeavesTheWorkingCopyIntact(t *testing.T) {
 @@ -1 +1,2 @@
  a1
 +A-ADDED
+`
+
+	invalidPatch := `diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -99,1 +99,2 @@
+ this line doesn't exist
++invalid change
 `
 
 	client := jj.NewClient(repo.Dir)
-	plans := []jj.SplitPlan{{
-		Tag:   'a',
-		Patch: patchA,
-		Destination: jj.SplitDestination{
-			Type:        jj.SplitDestNewCommit,
-			Description: "split: a only",
+	plans := []jj.SplitPlan{
+		{
+			Tag:         'a',
+			Patch:       patchA,
+			Destination: jj.SplitDestination{Type: jj.SplitDestNewCommit, Description: "split: a only"},
+		},
+		{
+			Tag:         'b',
+			Patch:       invalidPatch,
+			Destination: jj.SplitDestination{Type: jj.SplitDestNewCommit, Description: "split: b only"},
 		},
-	}}
+	}
 
-	if err := client.ApplySplit(plans, "@"); err == nil {
+	if err := client.ApplySplit(context.Background(), plans, "@"); err == nil {
 		t.Fatal("expected ApplySplit to report the failed plan")
 	}
 
 	repo.AssertFileContent("a.txt", "a1\nA-ADDED\n")
 	repo.AssertFileContent("b.txt", "b1\nB-ADDED\n")
+	repo.AssertDiffContains("@", "+A-ADDED")
 
 	if currentWC := repo.GetChangeID("@"); currentWC != originalWC {
 		t.Errorf("working copy moved:\nExpected: %s\nActual:   %s", originalWC, currentWC)
 	}
 
+	if parent := repo.GetChangeID("@-"); parent != originalParent {
+		t.Errorf(
+			"the first plan's commit survived the rollback:\nExpected parent: %s\nActual:          %s",
+			originalParent,
+			parent,
+		)
+	}
+
 	workspaces := repo.MustRun("workspace", "list")
 	if strings.Contains(workspaces, "jj-diff-scratch") {
 		t.Errorf("scratch workspace leaked into jj workspace list:\n%s", workspaces)
@@ -359,7 +832,7 @@ func TestGetRevisions_ParsesRealLogOutput(t *testing.T) {
 
 	client := jj.NewClient(repo.Dir)
 
-	revisions, err := client.GetRevisions(20)
+	revisions, err := client.GetRevisions(context.Background(), 20)
 	if err != nil {
 		t.Fatalf("GetRevisions failed: %v", err)
 	}
@@ -387,3 +860,195 @@ func TestGetRevisions_ParsesRealLogOutput(t *testing.T) {
 		t.Errorf("Expected a revision described %q, got %+v", "feat: second", revisions)
 	}
 }
+
+// TestGetLog_GraphLinesCarryEntries guards the smartlog template: every revision
+// line of the graph must parse into an entry with its bookmark and description,
+// and the working copy must be the one flagged as such.
+func TestGetLog_GraphLinesCarryEntries(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("feat: first")
+	repo.MustRun("bookmark", "create", "-r", "@-", "feature")
+
+	client := jj.NewClient(repo.Dir)
+
+	lines, err := client.GetLog(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("GetLog failed: %v", err)
+	}
+
+	var entries []*jj.LogEntry
+	for _, line := range lines {
+		if line.Graph == "" {
+			t.Errorf("Expected graph glyphs on every line, got %+v", line)
+		}
+
+		if line.Entry != nil {
+			entries = append(entries, line.Entry)
+		}
+	}
+
+	if len(entries) < 2 {
+		t.Fatalf("Expected at least 2 revisions, got %d: %+v", len(entries), lines)
+	}
+
+	if !entries[0].IsWorkingCopy || !entries[0].Empty {
+		t.Errorf("Expected the empty working copy first, got %+v", entries[0])
+	}
+
+	if entries[1].Description != "feat: first" || !slices.Contains(entries[1].Bookmarks, "feature") {
+		t.Errorf("Expected the bookmarked parent second, got %+v", entries[1])
+	}
+}
+
+// TestGetEvolog_ListsEveryRewrite guards the jj evolog template the same way:
+// describing a change twice leaves three evolutions, and each must parse into
+// its own entry with the newest first.
+func TestGetEvolog_ListsEveryRewrite(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.MustRun("describe", "-m", "first draft")
+	repo.MustRun("describe", "-m", "second draft")
+
+	client := jj.NewClient(repo.Dir)
+
+	entries, err := client.GetEvolog(context.Background(), "@")
+	if err != nil {
+		t.Fatalf("GetEvolog failed: %v", err)
+	}
+
+	if len(entries) < 3 {
+		t.Fatalf("Expected at least 3 evolutions, got %d: %+v", len(entries), entries)
+	}
+
+	if entries[0].Description != "second draft" || entries[1].Description != "first draft" {
+		t.Errorf("Expected the newest description first, got %+v", entries[:2])
+	}
+
+	for _, entry := range entries {
+		if entry.CommitID == "" || entry.Timestamp == "" {
+			t.Errorf("Evolution is missing its commit ID or timestamp: %+v", entry)
+		}
+	}
+}
+
+// TestDiffRange_ShowsOnlyWhatChangedBetween tests that an interdiff between two
+// commits leaves out the lines both of them add.
+func TestDiffRange_ShowsOnlyWhatChangedBetween(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.Commit("first")
+	repo.WriteFile("file1.txt", "line 1\nline 2\n")
+	repo.Commit("second")
+
+	client := jj.NewClient(repo.Dir)
+
+	patch, err := client.DiffRange(context.Background(), "@--", "@-")
+	if err != nil {
+		t.Fatalf("DiffRange failed: %v", err)
+	}
+
+	if !strings.Contains(patch, "+line 2") {
+		t.Errorf("Expected the interdiff to add line 2, got:\n%s", patch)
+	}
+
+	if strings.Contains(patch, "+line 1") {
+		t.Errorf("Expected line 1 to be common to both ends, got:\n%s", patch)
+	}
+}
+
+// TestUndoAndRestoreOperation_RoundTrip tests that restoring the operation Undo
+// reports brings back what the undo removed, which is how the TUI redoes.
+func TestUndoAndRestoreOperation_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file1.txt", "line 1\n")
+	repo.MustRun("describe", "-m", "described")
+
+	client := jj.NewClient(repo.Dir)
+	ctx := context.Background()
+
+	ops, err := client.GetOpLog(ctx, 5)
+	if err != nil {
+		t.Fatalf("GetOpLog failed: %v", err)
+	}
+
+	if len(ops) == 0 || ops[0].ID == "" || ops[0].Time == "" {
+		t.Fatalf("Expected the current operation first, got %+v", ops)
+	}
+
+	if !strings.Contains(ops[0].Description, "describe") {
+		t.Errorf("Expected the describe operation first, got %+v", ops[0])
+	}
+
+	undone, err := client.Undo(ctx)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	if undone != ops[0].ID {
+		t.Errorf("Expected Undo to report %s, got %s", ops[0].ID, undone)
+	}
+
+	if desc := repo.MustRun("log", "-r", "@", "--no-graph", "-T", "description"); strings.Contains(desc, "described") {
+		t.Errorf("Expected the undo to drop the description, got %q", desc)
+	}
+
+	if err := client.RestoreOperation(ctx, undone); err != nil {
+		t.Fatalf("RestoreOperation failed: %v", err)
+	}
+
+	if desc := repo.MustRun("log", "-r", "@", "--no-graph", "-T", "description"); !strings.Contains(desc, "described") {
+		t.Errorf("Expected the restore to bring the description back, got %q", desc)
+	}
+}
+
+// TestListConflicts_MergeOfTwoEdits tests that a merge of two edits to one
+// line lists that file, and that a clean revision lists nothing rather than
+// failing the way jj resolve --list does.
+func TestListConflicts_MergeOfTwoEdits(t *testing.T) {
+	t.Parallel()
+
+	repo := integration.NewTestRepo(t)
+
+	repo.WriteFile("file.txt", "base\n")
+	repo.Commit("base")
+	repo.WriteFile("file.txt", "side one\n")
+	repo.Commit("side one")
+	repo.MustRun("new", "description(base)")
+	repo.WriteFile("file.txt", "side two\n")
+	repo.Commit("side two")
+
+	client := jj.NewClient(repo.Dir)
+
+	clean, err := client.ListConflicts(context.Background(), "@")
+	if err != nil {
+		t.Fatalf("ListConflicts on a clean revision failed: %v", err)
+	}
+
+	if len(clean) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", clean)
+	}
+
+	repo.MustRun("new", "description(\"side one\")", "description(\"side two\")")
+
+	conflicts, err := client.ListConflicts(context.Background(), "@")
+	if err != nil {
+		t.Fatalf("ListConflicts failed: %v", err)
+	}
+
+	if len(conflicts) != 1 || conflicts[0].Path != "file.txt" {
+		t.Errorf("Expected file.txt to be conflicted, got %+v", conflicts)
+	}
+}